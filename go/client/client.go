@@ -0,0 +1,542 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package client is a typed client of the HTTP API served by arewefastyet, the requests
+// and responses are those described by the OpenAPI document at /api/openapi.json.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vitessio/arewefastyet/go/exec"
+	"github.com/vitessio/arewefastyet/go/server/api"
+	"github.com/vitessio/arewefastyet/go/tools/export"
+	"github.com/vitessio/arewefastyet/go/tools/github"
+	"github.com/vitessio/arewefastyet/go/tools/macrobench"
+	"github.com/vitessio/arewefastyet/go/tools/microbench"
+)
+
+// Error is returned when the API responds with an unsuccessful status.
+type Error struct {
+	StatusCode int
+
+	// Message is the error reported by the API, or the body of the response if
+	// it is not a JSON error.
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Comparison configures how the macrobenchmark results are compared, the zero value
+// uses the defaults of the server. See macrobench.NewComparisonMethod.
+type Comparison struct {
+	Method     string
+	Alpha      float64
+	Confidence float64
+	Correction string
+
+	// Threshold is the percentage beyond which a significant change of a microbenchmark
+	// is reported, as a regression or as a step change of its history.
+	Threshold float64
+}
+
+func (cmp Comparison) addTo(query url.Values) {
+	setIfNotEmpty(query, "method", cmp.Method)
+	if cmp.Alpha != 0 {
+		query.Set("alpha", strconv.FormatFloat(cmp.Alpha, 'f', -1, 64))
+	}
+	if cmp.Confidence != 0 {
+		query.Set("confidence", strconv.FormatFloat(cmp.Confidence, 'f', -1, 64))
+	}
+	setIfNotEmpty(query, "correction", cmp.Correction)
+	if cmp.Threshold != 0 {
+		query.Set("threshold", strconv.FormatFloat(cmp.Threshold, 'f', -1, 64))
+	}
+}
+
+// Client sends requests to the HTTP API of an arewefastyet server.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+
+	// key authorizes the administrative requests, see WithKey.
+	key string
+}
+
+// New returns a Client of the server at baseURL, e.g. https://benchmark.vitess.io.
+// http.DefaultClient is used if httpClient is nil.
+func New(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+// WithKey returns a copy of the client sending key as a bearer token with its requests.
+// The key is the one configured on the server with web-request-run-key, it is required
+// to request, cancel, delete and invalidate runs and to read their logs.
+func (c *Client) WithKey(key string) *Client {
+	cp := *c
+	cp.key = key
+	return &cp
+}
+
+// Workloads returns the workloads benchmarked by the server.
+func (c *Client) Workloads(ctx context.Context) ([]string, error) {
+	var workloads []string
+	_, err := c.getJSON(ctx, api.PathWorkloads, nil, &workloads)
+	return workloads, err
+}
+
+// RecentExecutions returns a page of the most recent executions matching filter along
+// with the cursor of the next page, which is empty on the last page.
+func (c *Client) RecentExecutions(ctx context.Context, filter exec.ExecutionFilter, page exec.Page) (*api.RecentExecutionsResponse, string, error) {
+	query := url.Values{}
+	addExecutionFilter(query, filter)
+	addPage(query, page)
+	var resp api.RecentExecutionsResponse
+	header, err := c.getJSON(ctx, api.PathRecent, query, &resp)
+	if err != nil {
+		return nil, "", err
+	}
+	return &resp, header.Get(api.HeaderNextCursor), nil
+}
+
+// Queue returns the executions waiting in the queue of the server.
+func (c *Client) Queue(ctx context.Context) (*api.ExecutionQueueResponse, error) {
+	var resp api.ExecutionQueueResponse
+	if _, err := c.getJSON(ctx, api.PathQueue, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// VitessRefs returns the latest release tags and branches of vitess.
+func (c *Client) VitessRefs(ctx context.Context) (*api.VitessGitRefReleases, error) {
+	var resp api.VitessGitRefReleases
+	if _, err := c.getJSON(ctx, api.PathVitessRefs, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CompareMacrobenchmarks compares the macrobenchmark results of two git refs for every
+// workload. The git refs can be SHAs, branches or tags.
+func (c *Client) CompareMacrobenchmarks(ctx context.Context, oldRef, newRef string, cmp Comparison) ([]api.CompareMacrobench, error) {
+	query := url.Values{"old": {oldRef}, "new": {newRef}}
+	cmp.addTo(query)
+	var resp []api.CompareMacrobench
+	_, err := c.getJSON(ctx, api.PathMacrobenchCompare, query, &resp)
+	return resp, err
+}
+
+// CompareMicrobenchmarks compares the microbenchmark results of two git refs, the
+// Regressions of each comparison are found with cmp.Threshold.
+func (c *Client) CompareMicrobenchmarks(ctx context.Context, leftRef, rightRef string, cmp Comparison) (microbench.ComparisonArray, error) {
+	query := url.Values{"ltag": {leftRef}, "rtag": {rightRef}}
+	cmp.addTo(query)
+	var resp microbench.ComparisonArray
+	_, err := c.getJSON(ctx, api.PathMicrobenchCompare, query, &resp)
+	return resp, err
+}
+
+// MicrobenchHistory returns the history of the sub-benchmarks of a microbenchmark over
+// the cron runs selected by filter, whose PkgName and Name are required. The step changes
+// are found with the default window of the server and cmp.Threshold.
+func (c *Client) MicrobenchHistory(ctx context.Context, filter microbench.HistoryFilter, cmp Comparison) ([]microbench.BenchmarkHistory, error) {
+	query := url.Values{"pkg": {filter.PkgName}, "name": {filter.Name}}
+	setTime(query, "since", filter.Since)
+	setTime(query, "until", filter.Until)
+	if filter.Last > 0 {
+		query.Set("last", strconv.Itoa(filter.Last))
+	}
+	cmp.addTo(query)
+	var resp []microbench.BenchmarkHistory
+	_, err := c.getJSON(ctx, api.PathMicrobenchHistory, query, &resp)
+	return resp, err
+}
+
+// MicrobenchProfile returns the most recent profile of a microbenchmark at a git ref, in
+// the pprof format, profileType is microbench.ProfileCPU or microbench.ProfileMem. The
+// caller must close it.
+func (c *Client) MicrobenchProfile(ctx context.Context, ref, pkgName, name, profileType string) (io.ReadCloser, error) {
+	query := url.Values{"sha": {ref}, "pkg": {pkgName}, "name": {name}, "type": {profileType}}
+	return c.getBody(ctx, api.PathMicrobenchProfile, query)
+}
+
+// DiffMicrobenchProfiles compares the profiles of a microbenchmark at two git refs and
+// returns the top functions whose share of the samples changed the most. The default
+// sample type of the profile type is used if sampleType is empty, and all the functions
+// are returned if top is 0.
+func (c *Client) DiffMicrobenchProfiles(ctx context.Context, leftRef, rightRef, pkgName, name, profileType, sampleType string, top int) (*microbench.ProfileDiff, error) {
+	query := url.Values{
+		"ltag": {leftRef}, "rtag": {rightRef}, "pkg": {pkgName}, "name": {name}, "type": {profileType},
+		"top": {strconv.Itoa(top)},
+	}
+	if sampleType != "" {
+		query.Set("sample", sampleType)
+	}
+	var resp microbench.ProfileDiff
+	if _, err := c.getJSON(ctx, api.PathMicrobenchProfileDiff, query, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Search returns the macrobenchmark results of a git ref for every workload.
+func (c *Client) Search(ctx context.Context, ref string) (*api.SearchResult, error) {
+	var resp api.SearchResult
+	if _, err := c.getJSON(ctx, api.PathSearch, url.Values{"sha": {ref}}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// History returns a page of the git refs that were fully benchmarked along with the
+// cursor of the next page, which is empty on the last page.
+func (c *Client) History(ctx context.Context, filter exec.ExecutionFilter, page exec.Page) ([]*exec.History, string, error) {
+	query := url.Values{}
+	addExecutionFilter(query, filter)
+	addPage(query, page)
+	var resp []*exec.History
+	header, err := c.getJSON(ctx, api.PathHistory, query, &resp)
+	if err != nil {
+		return nil, "", err
+	}
+	return resp, header.Get(api.HeaderNextCursor), nil
+}
+
+// CompareQueries compares the query plans of a workload on two git refs.
+func (c *Client) CompareQueries(ctx context.Context, leftRef, rightRef, workload string) ([]macrobench.VTGateQueryPlanComparer, error) {
+	query := url.Values{"ltag": {leftRef}, "rtag": {rightRef}, "workload": {workload}}
+	var resp []macrobench.VTGateQueryPlanComparer
+	_, err := c.getJSON(ctx, api.PathMacrobenchCompareQueries, query, &resp)
+	return resp, err
+}
+
+// CompareFKs compares the macrobenchmark results of two foreign key workloads on the same git ref.
+func (c *Client) CompareFKs(ctx context.Context, ref, oldWorkload, newWorkload string, cmp Comparison) (macrobench.StatisticalCompareResults, error) {
+	query := url.Values{"sha": {ref}, "oldWorkload": {oldWorkload}, "newWorkload": {newWorkload}}
+	cmp.addTo(query)
+	var resp macrobench.StatisticalCompareResults
+	_, err := c.getJSON(ctx, api.PathFKCompare, query, &resp)
+	return resp, err
+}
+
+// CompareFKQueries compares the query plans of two foreign key workloads on the same git ref.
+func (c *Client) CompareFKQueries(ctx context.Context, ref, oldWorkload, newWorkload string) ([]macrobench.VTGateQueryPlanComparer, error) {
+	query := url.Values{"gitRef": {ref}, "oldWorkload": {oldWorkload}, "newWorkload": {newWorkload}}
+	var resp []macrobench.VTGateQueryPlanComparer
+	_, err := c.getJSON(ctx, api.PathFKCompareQueries, query, &resp)
+	return resp, err
+}
+
+// MacrobenchLatencyHistogram returns the latency histogram of a macrobenchmark execution.
+func (c *Client) MacrobenchLatencyHistogram(ctx context.Context, execUUID string) (*macrobench.ExecutionLatencyHistogram, error) {
+	var resp macrobench.ExecutionLatencyHistogram
+	if _, err := c.getJSON(ctx, api.PathMacrobenchLatencyHistogram, url.Values{"uuid": {execUUID}}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// MacrobenchIntervals returns the per-interval reports of a macrobenchmark execution.
+func (c *Client) MacrobenchIntervals(ctx context.Context, execUUID string) (*macrobench.ExecutionIntervals, error) {
+	var resp macrobench.ExecutionIntervals
+	if _, err := c.getJSON(ctx, api.PathMacrobenchIntervals, url.Values{"uuid": {execUUID}}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// PullRequests returns the pull requests that were benchmarked.
+func (c *Client) PullRequests(ctx context.Context) ([]github.PRInfo, error) {
+	var resp []github.PRInfo
+	_, err := c.getJSON(ctx, api.PathPullRequestList, nil, &resp)
+	return resp, err
+}
+
+// PullRequest returns a pull request along with the git refs of its benchmarks.
+func (c *Client) PullRequest(ctx context.Context, nb int) (*github.PRInfo, error) {
+	var resp github.PRInfo
+	if _, err := c.getJSON(ctx, api.PathPullRequestInfo+strconv.Itoa(nb), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DailySummary returns the QPS of the daily benchmarks of the last 30 days for the given
+// workloads, or for all of them if none is given.
+func (c *Client) DailySummary(ctx context.Context, workloads ...string) ([]api.DailySummary, error) {
+	query := url.Values{}
+	for _, workload := range workloads {
+		query.Add("workloads", workload)
+	}
+	var resp []api.DailySummary
+	_, err := c.getJSON(ctx, api.PathDailySummary, query, &resp)
+	return resp, err
+}
+
+// Daily returns the results of the daily benchmarks of the last 30 days for a workload.
+func (c *Client) Daily(ctx context.Context, workload string) ([]macrobench.StatisticalSingleResult, error) {
+	var resp []macrobench.StatisticalSingleResult
+	_, err := c.getJSON(ctx, api.PathDaily, url.Values{"workload": {workload}}, &resp)
+	return resp, err
+}
+
+// StatusStats returns statistics about the executions.
+func (c *Client) StatusStats(ctx context.Context) (*exec.BenchmarkStats, error) {
+	var resp exec.BenchmarkStats
+	if _, err := c.getJSON(ctx, api.PathStatusStats, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RunRequest describes the custom runs added to the queue by Client.RequestRun.
+type RunRequest struct {
+	// SHA is the full SHA of the commit to benchmark.
+	SHA string
+
+	// Workloads are benchmarked in one run each.
+	Workloads []string
+
+	// Version is the major version of vitess at SHA.
+	Version int
+
+	// Planner is the planner version of vtgate, the server uses Gen4 if empty.
+	Planner string
+
+	// VtgateFlags and VttabletFlags are added to the flags of vtgate and vttablet.
+	VtgateFlags, VttabletFlags string
+}
+
+// RequestRun adds custom runs to the queue of the server and returns the executions that
+// were queued, a run already queued or with enough results is not added again.
+// It requires the key of the client.
+func (c *Client) RequestRun(ctx context.Context, req RunRequest) ([]api.ExecutionQueue, error) {
+	query := url.Values{
+		"sha":      {req.SHA},
+		"workload": {strings.Join(req.Workloads, ",")},
+		"version":  {strconv.Itoa(req.Version)},
+	}
+	setIfNotEmpty(query, "planner", req.Planner)
+	setIfNotEmpty(query, "vtgate_flags", req.VtgateFlags)
+	setIfNotEmpty(query, "vttablet_flags", req.VttabletFlags)
+	var resp api.RunRequestResponse
+	if _, err := c.getJSON(ctx, api.PathRunRequest, query, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Executions, nil
+}
+
+// DeleteRun deletes a custom run, it requires the key of the client.
+func (c *Client) DeleteRun(ctx context.Context, execUUID, sha string) error {
+	query := url.Values{"uuid": {execUUID}, "sha": {sha}}
+	_, err := c.getJSON(ctx, api.PathRunDelete, query, nil)
+	return err
+}
+
+// RunStatus returns the status of the run with the given UUID, queued or executed.
+func (c *Client) RunStatus(ctx context.Context, runUUID string) (*api.RunStatus, error) {
+	var resp api.RunStatus
+	if _, err := c.getJSON(ctx, api.PathRunStatus, url.Values{"uuid": {runUUID}}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RunLogs returns the logs of a run starting at offset, along with the offset of the
+// next chunk. The standard error is read instead of the standard output if stderr is
+// true. It requires the key of the client.
+func (c *Client) RunLogs(ctx context.Context, runUUID string, stderr bool, offset int64) ([]byte, int64, error) {
+	query := url.Values{"uuid": {runUUID}, "offset": {strconv.FormatInt(offset, 10)}}
+	if stderr {
+		query.Set("stream", "stderr")
+	}
+	body, header, err := c.do(ctx, http.MethodGet, api.PathRunLogs, query)
+	if err != nil {
+		return nil, offset, err
+	}
+	defer body.Close()
+
+	logs, err := io.ReadAll(body)
+	if err != nil {
+		return nil, offset, err
+	}
+	next, err := strconv.ParseInt(header.Get(api.HeaderLogOffset), 10, 64)
+	if err != nil {
+		return nil, offset, fmt.Errorf("parsing the %s header: %w", api.HeaderLogOffset, err)
+	}
+	return logs, next, nil
+}
+
+// CancelRun removes a run that is not executing yet from the queue, it requires the
+// key of the client.
+func (c *Client) CancelRun(ctx context.Context, runUUID string) error {
+	return c.post(ctx, api.PathRunCancel, url.Values{"uuid": {runUUID}})
+}
+
+// InvalidateRun invalidates a finished execution so its results are not used anymore,
+// it requires the key of the client.
+func (c *Client) InvalidateRun(ctx context.Context, execUUID string) error {
+	return c.post(ctx, api.PathRunInvalidate, url.Values{"uuid": {execUUID}})
+}
+
+// ExportMacrobenchmarks returns the export of the macrobenchmark results matching filter,
+// the caller must close it.
+func (c *Client) ExportMacrobenchmarks(ctx context.Context, filter export.Filter, format export.Format) (io.ReadCloser, error) {
+	return c.getBody(ctx, api.PathExportMacrobench, exportQuery(filter, format))
+}
+
+// ExportMicrobenchmarks returns the export of the microbenchmark results matching filter,
+// the caller must close it.
+func (c *Client) ExportMicrobenchmarks(ctx context.Context, filter export.Filter, format export.Format) (io.ReadCloser, error) {
+	return c.getBody(ctx, api.PathExportMicrobench, exportQuery(filter, format))
+}
+
+// getJSON sends a GET request and decodes the JSON body of the response in dest, which
+// is ignored if nil. The headers of the response are returned.
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, dest interface{}) (http.Header, error) {
+	body, header, err := c.get(ctx, path, query)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	if dest == nil {
+		_, err = io.Copy(io.Discard, body)
+		return header, err
+	}
+	if err := json.NewDecoder(body).Decode(dest); err != nil {
+		return nil, fmt.Errorf("decoding the response of %s: %w", path, err)
+	}
+	return header, nil
+}
+
+func (c *Client) getBody(ctx context.Context, path string, query url.Values) (io.ReadCloser, error) {
+	body, _, err := c.get(ctx, path, query)
+	return body, err
+}
+
+// post sends a POST request and discards the body of the response.
+func (c *Client) post(ctx context.Context, path string, query url.Values) error {
+	body, _, err := c.do(ctx, http.MethodPost, path, query)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	_, err = io.Copy(io.Discard, body)
+	return err
+}
+
+// get sends a GET request and returns the body of the response if its status is
+// successful, an *Error is returned otherwise.
+func (c *Client) get(ctx context.Context, path string, query url.Values) (io.ReadCloser, http.Header, error) {
+	return c.do(ctx, http.MethodGet, path, query)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values) (io.ReadCloser, http.Header, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.key != "" {
+		req.Header.Set("Authorization", "Bearer "+c.key)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, nil, newError(resp)
+	}
+	return resp.Body, resp.Header, nil
+}
+
+func newError(resp *http.Response) error {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	var apiErr api.ErrorAPI
+	if err := json.Unmarshal(body, &apiErr); err == nil && apiErr.Error != "" {
+		return &Error{StatusCode: resp.StatusCode, Message: apiErr.Error}
+	}
+	return &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+}
+
+func setIfNotEmpty(query url.Values, key, value string) {
+	if value != "" {
+		query.Set(key, value)
+	}
+}
+
+func setTime(query url.Values, key string, t *time.Time) {
+	if t != nil {
+		query.Set(key, t.Format(time.RFC3339))
+	}
+}
+
+func addExecutionFilter(query url.Values, filter exec.ExecutionFilter) {
+	setIfNotEmpty(query, "source", filter.Source)
+	setIfNotEmpty(query, "workload", filter.Workload)
+	setIfNotEmpty(query, "status", filter.Status)
+	setIfNotEmpty(query, "go_version", filter.GolangVersion)
+	setIfNotEmpty(query, "git_ref", filter.GitRefPrefix)
+	if filter.PullNB != 0 {
+		query.Set("pr", strconv.Itoa(filter.PullNB))
+	}
+	setTime(query, "since", filter.Since)
+	setTime(query, "until", filter.Until)
+}
+
+func addPage(query url.Values, page exec.Page) {
+	if page.Limit != 0 {
+		query.Set("limit", strconv.Itoa(page.Limit))
+	}
+	setIfNotEmpty(query, "cursor", page.Cursor)
+}
+
+func exportQuery(filter export.Filter, format export.Format) url.Values {
+	query := url.Values{}
+	setIfNotEmpty(query, "sha", strings.Join(filter.GitRefs, ","))
+	setIfNotEmpty(query, "workload", strings.Join(filter.Workloads, ","))
+	setIfNotEmpty(query, "source", filter.Source)
+	setTime(query, "since", filter.Since)
+	setTime(query, "until", filter.Until)
+	setIfNotEmpty(query, "format", string(format))
+	return query
+}
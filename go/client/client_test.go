@@ -0,0 +1,245 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vitessio/arewefastyet/go/exec"
+	"github.com/vitessio/arewefastyet/go/server"
+	"github.com/vitessio/arewefastyet/go/server/api"
+	"github.com/vitessio/arewefastyet/go/tools/export"
+	"github.com/vitessio/arewefastyet/go/tools/microbench"
+)
+
+// recorder is an HTTP server recording the requests it receives and responding
+// with the body registered for their path.
+type recorder struct {
+	requests []*http.Request
+	bodies   map[string]string
+	headers  map[string]http.Header
+}
+
+func (rec *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec.requests = append(rec.requests, r)
+	for k, v := range rec.headers[r.URL.Path] {
+		w.Header()[k] = v
+	}
+	body, ok := rec.bodies[r.URL.Path]
+	if !ok {
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, "null")
+		return
+	}
+	_, _ = io.WriteString(w, body)
+}
+
+func newTestClient(t *testing.T, rec *recorder) *Client {
+	srv := httptest.NewServer(rec)
+	t.Cleanup(srv.Close)
+	return New(srv.URL+"/", srv.Client())
+}
+
+func TestClient_queries(t *testing.T) {
+	c := qt.New(t)
+	rec := &recorder{
+		bodies: map[string]string{
+			"/api/recent":  `{"executions":[{"uuid":"u1","git_ref":"abc"}],"workloads":["OLTP"]}`,
+			"/api/history": `[{"sha":"abc","source":"cron","workloads_benchmarked":3}]`,
+		},
+		headers: map[string]http.Header{
+			"/api/recent":  {"X-Next-Cursor": {"next"}},
+			"/api/history": {"X-Next-Cursor": {"cursor-2"}},
+		},
+	}
+	client := newTestClient(t, rec)
+	ctx := context.Background()
+	since := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	recent, next, err := client.RecentExecutions(ctx, exec.ExecutionFilter{Source: "cron", PullNB: 12, Since: &since}, exec.Page{Limit: 10})
+	c.Assert(err, qt.IsNil)
+	c.Assert(recent.Executions, qt.HasLen, 1)
+	c.Assert(recent.Executions[0].GitRef, qt.Equals, "abc")
+	c.Assert(recent.Workloads, qt.DeepEquals, []string{"OLTP"})
+	c.Assert(next, qt.Equals, "next")
+	c.Assert(rec.requests[0].URL.RawQuery, qt.Equals, "limit=10&pr=12&since=2024-01-02T00%3A00%3A00Z&source=cron")
+
+	history, next, err := client.History(ctx, exec.ExecutionFilter{}, exec.Page{Cursor: "cursor-1"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(history, qt.HasLen, 1)
+	c.Assert(history[0].WorkloadsBenchmarked, qt.Equals, 3)
+	c.Assert(next, qt.Equals, "cursor-2")
+	c.Assert(rec.requests[1].URL.RawQuery, qt.Equals, "cursor=cursor-1")
+
+	_, err = client.CompareMacrobenchmarks(ctx, "main", "v19.0.0", Comparison{Method: "welch-t-test", Alpha: 0.01})
+	c.Assert(err, qt.IsNil)
+	c.Assert(rec.requests[2].URL.RawQuery, qt.Equals, "alpha=0.01&method=welch-t-test&new=v19.0.0&old=main")
+
+	_, err = client.DailySummary(ctx, "OLTP", "TPCC")
+	c.Assert(err, qt.IsNil)
+	c.Assert(rec.requests[3].URL.RawQuery, qt.Equals, "workloads=OLTP&workloads=TPCC")
+
+	body, err := client.ExportMacrobenchmarks(ctx, export.Filter{GitRefs: []string{"abc", "def"}, Workloads: []string{"oltp"}}, export.FormatJSONL)
+	c.Assert(err, qt.IsNil)
+	c.Assert(body.Close(), qt.IsNil)
+	c.Assert(rec.requests[4].URL.RawQuery, qt.Equals, "format=jsonl&sha=abc%2Cdef&workload=oltp")
+
+	_, err = client.CompareMicrobenchmarks(ctx, "main", "v19.0.0", Comparison{Threshold: 5})
+	c.Assert(err, qt.IsNil)
+	c.Assert(rec.requests[5].URL.RawQuery, qt.Equals, "ltag=main&rtag=v19.0.0&threshold=5")
+}
+
+func TestClient_run(t *testing.T) {
+	c := qt.New(t)
+	rec := &recorder{
+		bodies: map[string]string{
+			"/api/run/request": `{"executions":[{"uuid":"u1","source":"custom_run","git_ref":"abc","workload":"OLTP"}]}`,
+			"/api/run/logs":    "line 1\nline 2\n",
+		},
+		headers: map[string]http.Header{
+			"/api/run/logs": {"X-Log-Offset": {"114"}},
+		},
+	}
+	client := newTestClient(t, rec).WithKey("secret")
+	ctx := context.Background()
+
+	queued, err := client.RequestRun(ctx, RunRequest{SHA: "abc", Workloads: []string{"OLTP", "TPCC"}, Version: 19, VtgateFlags: "--foo=bar"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(queued, qt.DeepEquals, []api.ExecutionQueue{{UUID: "u1", Source: "custom_run", GitRef: "abc", Workload: "OLTP"}})
+	c.Assert(rec.requests[0].URL.RawQuery, qt.Equals, "sha=abc&version=19&vtgate_flags=--foo%3Dbar&workload=OLTP%2CTPCC")
+	c.Assert(rec.requests[0].Header.Get("Authorization"), qt.Equals, "Bearer secret")
+
+	logs, next, err := client.RunLogs(ctx, "u1", true, 100)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(logs), qt.Equals, "line 1\nline 2\n")
+	c.Assert(next, qt.Equals, int64(114))
+	c.Assert(rec.requests[1].URL.RawQuery, qt.Equals, "offset=100&stream=stderr&uuid=u1")
+
+	c.Assert(client.CancelRun(ctx, "u1"), qt.IsNil)
+	c.Assert(rec.requests[2].Method, qt.Equals, http.MethodPost)
+	c.Assert(rec.requests[2].URL.Path, qt.Equals, "/api/run/cancel")
+}
+
+func TestClient_error(t *testing.T) {
+	c := qt.New(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/search" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"error":"sha: git ref not found"}`)
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = io.WriteString(w, "upstream unavailable\n")
+	}))
+	defer srv.Close()
+	client := New(srv.URL, nil)
+
+	_, err := client.Search(context.Background(), "unknown")
+	var apiErr *Error
+	c.Assert(errors.As(err, &apiErr), qt.IsTrue)
+	c.Assert(apiErr, qt.DeepEquals, &Error{StatusCode: http.StatusNotFound, Message: "sha: git ref not found"})
+
+	err = client.DeleteRun(context.Background(), "uuid", "sha")
+	c.Assert(err, qt.ErrorMatches, "502 Bad Gateway: upstream unavailable")
+}
+
+// TestClient_OpenAPI makes sure every method of the client requests an operation
+// described by the OpenAPI document of the server.
+func TestClient_OpenAPI(t *testing.T) {
+	c := qt.New(t)
+	rec := &recorder{}
+	client := newTestClient(t, rec)
+	ctx := context.Background()
+
+	_, _ = client.Workloads(ctx)
+	_, _, _ = client.RecentExecutions(ctx, exec.ExecutionFilter{}, exec.Page{})
+	_, _ = client.Queue(ctx)
+	_, _ = client.VitessRefs(ctx)
+	_, _ = client.CompareMacrobenchmarks(ctx, "a", "b", Comparison{})
+	_, _ = client.CompareMicrobenchmarks(ctx, "a", "b", Comparison{Method: "mann-whitney", Alpha: 0.01})
+	_, _ = client.MicrobenchHistory(ctx, microbench.HistoryFilter{PkgName: "vitess.io/vitess/go/vt/sqlparser", Name: "BenchmarkParse", Last: 10}, Comparison{})
+	_, _ = client.DiffMicrobenchProfiles(ctx, "a", "b", "vitess.io/vitess/go/vt/sqlparser", "BenchmarkParse", "cpu", "", 10)
+	_, _ = client.Search(ctx, "a")
+	_, _, _ = client.History(ctx, exec.ExecutionFilter{}, exec.Page{})
+	_, _ = client.CompareQueries(ctx, "a", "b", "OLTP")
+	_, _ = client.CompareFKs(ctx, "a", "TPCC_FK", "TPCC_FK_UNMANAGED", Comparison{})
+	_, _ = client.CompareFKQueries(ctx, "a", "TPCC_FK", "TPCC_FK_UNMANAGED")
+	_, _ = client.MacrobenchIntervals(ctx, "uuid")
+	_, _ = client.MacrobenchLatencyHistogram(ctx, "uuid")
+	_, _ = client.PullRequests(ctx)
+	_, _ = client.PullRequest(ctx, 42)
+	_, _ = client.DailySummary(ctx)
+	_, _ = client.Daily(ctx, "OLTP")
+	_, _ = client.StatusStats(ctx)
+	_, _ = client.RequestRun(ctx, RunRequest{SHA: "sha", Workloads: []string{"OLTP"}, Version: 19})
+	_ = client.DeleteRun(ctx, "uuid", "sha")
+	_, _ = client.RunStatus(ctx, "uuid")
+	_, _, _ = client.RunLogs(ctx, "uuid", false, 0)
+	_ = client.CancelRun(ctx, "uuid")
+	_ = client.InvalidateRun(ctx, "uuid")
+	for _, exportFunc := range []func(context.Context, export.Filter, export.Format) (io.ReadCloser, error){client.ExportMacrobenchmarks, client.ExportMicrobenchmarks} {
+		body, err := exportFunc(ctx, export.Filter{}, export.FormatCSV)
+		c.Assert(err, qt.IsNil)
+		_ = body.Close()
+	}
+	profile, err := client.MicrobenchProfile(ctx, "a", "vitess.io/vitess/go/vt/sqlparser", "BenchmarkParse", "cpu")
+	c.Assert(err, qt.IsNil)
+	_ = profile.Close()
+
+	raw, err := server.OpenAPIDocument()
+	c.Assert(err, qt.IsNil)
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	c.Assert(json.Unmarshal(raw, &doc), qt.IsNil)
+
+	requested := map[string]bool{}
+	for _, r := range rec.requests {
+		var found bool
+		for path, methods := range doc.Paths {
+			if _, ok := methods[strings.ToLower(r.Method)]; ok && matchPath(path, r.URL.Path) {
+				found = true
+				requested[path] = true
+			}
+		}
+		c.Assert(found, qt.IsTrue, qt.Commentf("%s %s is not described", r.Method, r.URL.Path))
+	}
+	c.Assert(requested, qt.HasLen, len(doc.Paths), qt.Commentf("some operations have no client method"))
+}
+
+// matchPath returns true if path matches the OpenAPI path template.
+func matchPath(template, path string) bool {
+	tparts, parts := strings.Split(template, "/"), strings.Split(path, "/")
+	if len(tparts) != len(parts) {
+		return false
+	}
+	for i := range tparts {
+		if !strings.HasPrefix(tparts[i], "{") && tparts[i] != parts[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compare
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/vitessio/arewefastyet/go/tools/compare"
+	"github.com/vitessio/arewefastyet/go/tools/microbench"
+)
+
+func CompareCmd() *cobra.Command {
+	cfg := compare.Config{}
+
+	cmd := &cobra.Command{
+		Use:   "compare <old> <new>",
+		Short: "Compare the benchmark results of two git refs",
+		Long: `Compare the macrobenchmark results, or the microbenchmark results with --micro, of two git refs and print them as tables.
+The results are read from the database if one is configured, otherwise they are requested from the API server which also accepts branches, tags and abbreviated SHAs.
+Significant improvements are printed in green and significant regressions in red. The command fails if a significant regression is larger than --compare-max-regression, which makes it usable in release scripts.`,
+		Example: `arewefastyet compare v19.0.0 main --workload oltp,tpcc
+arewefastyet compare v19.0.0 main --compare-max-regression 5
+arewefastyet compare <old sha> <new sha> --micro --config config.yaml --secrets secrets.yaml`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// a regression is not a usage error
+			cmd.SilenceUsage = true
+			if err := microbench.LoadUnitDirections(viper.GetViper()); err != nil {
+				return err
+			}
+			return compare.Run(cmd.Context(), cfg, args[0], args[1], cmd.OutOrStdout())
+		},
+	}
+
+	cfg.AddToCommand(cmd)
+	return cmd
+}
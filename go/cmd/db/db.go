@@ -0,0 +1,34 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func DBCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db <command>",
+		Short: "Manage the schema of the database",
+		Long:  "Top level command to apply the schema migrations embedded in the binary, to show their status and to maintain the data derived from the results",
+	}
+
+	cmd.AddCommand(migrateCmd())
+	cmd.AddCommand(statusCmd())
+	cmd.AddCommand(refreshSummariesCmd())
+	return cmd
+}
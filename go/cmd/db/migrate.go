@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/vitessio/arewefastyet/go/storage/database"
+	"github.com/vitessio/arewefastyet/go/storage/migrations"
+)
+
+func migrateCmd() *cobra.Command {
+	dbConfig := database.NewConfig()
+
+	cmd := &cobra.Command{
+		Use:     "migrate",
+		Short:   "Apply the schema migrations that were not yet applied to the database",
+		Example: "arewefastyet db migrate --config config.yaml --secrets secrets.yaml",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := dbConfig.NewClient()
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			applied, err := migrations.Migrate(client)
+			for _, m := range applied {
+				fmt.Fprintf(cmd.OutOrStdout(), "applied %04d_%s\n", m.Version, m.Name)
+			}
+			if err != nil {
+				return err
+			}
+			if len(applied) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "the database schema is up to date")
+			}
+			return nil
+		},
+	}
+
+	dbConfig.AddToCommand(cmd)
+	return cmd
+}
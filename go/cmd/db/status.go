@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vitessio/arewefastyet/go/storage/database"
+	"github.com/vitessio/arewefastyet/go/storage/migrations"
+)
+
+func statusCmd() *cobra.Command {
+	dbConfig := database.NewConfig()
+
+	cmd := &cobra.Command{
+		Use:     "status",
+		Short:   "Show which schema migrations are applied to the database",
+		Example: "arewefastyet db status --config config.yaml --secrets secrets.yaml",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := dbConfig.NewClient()
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			status, err := migrations.GetStatus(client)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+			for _, s := range status {
+				appliedAt := "pending"
+				if s.AppliedAt != nil {
+					appliedAt = s.AppliedAt.Format(time.RFC3339)
+				}
+				fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+			}
+			if err = w.Flush(); err != nil {
+				return err
+			}
+			return migrations.CheckVersion(client)
+		},
+	}
+
+	dbConfig.AddToCommand(cmd)
+	return cmd
+}
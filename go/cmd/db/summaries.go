@@ -0,0 +1,53 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/vitessio/arewefastyet/go/storage/database"
+	"github.com/vitessio/arewefastyet/go/tools/macrobench"
+)
+
+func refreshSummariesCmd() *cobra.Command {
+	dbConfig := database.NewConfig()
+
+	cmd := &cobra.Command{
+		Use:     "refresh-summaries",
+		Short:   "Recompute the summary of the macrobenchmark results of every git ref",
+		Long:    "Recompute the summary of the macrobenchmark results of every git ref, workload, planner and source. Summaries are maintained when executions finish or are deleted, this command fills them for results stored before they existed.",
+		Example: "arewefastyet db refresh-summaries --config config.yaml --secrets secrets.yaml",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := dbConfig.NewClient()
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			refreshed, err := macrobench.RefreshAllSummaries(client)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "refreshed %d summaries\n", refreshed)
+			return nil
+		},
+	}
+
+	dbConfig.AddToCommand(cmd)
+	return cmd
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/vitessio/arewefastyet/go/tools/export"
+)
+
+func ExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export <command>",
+		Short: "Export benchmark results as CSV, JSON Lines or benchstat text",
+		Long:  "Top level command to export the results of finished executions, selected by git refs, workloads, source or date range, as CSV, JSON Lines or benchstat text",
+	}
+
+	cmd.AddCommand(macrobenchCmd())
+	cmd.AddCommand(microbenchCmd())
+	return cmd
+}
+
+func macrobenchCmd() *cobra.Command {
+	cfg := export.Config{}
+
+	cmd := &cobra.Command{
+		Use:     "macrobench",
+		Aliases: []string{"mab"},
+		Short:   "Export the results of macrobenchmarks",
+		Long:    "Export the results of macrobenchmarks, one sample per execution, with their latency percentiles and resource metrics.",
+		Example: "arewefastyet export macrobench --config config.yaml --secrets secrets.yaml --export-workload oltp,tpcc --export-since 2024-01-01 --export-format benchstat",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return export.Macrobenchmarks(cfg)
+		},
+	}
+
+	cfg.AddToCommand(cmd)
+	return cmd
+}
+
+func microbenchCmd() *cobra.Command {
+	cfg := export.Config{}
+
+	cmd := &cobra.Command{
+		Use:     "microbench",
+		Aliases: []string{"mib"},
+		Short:   "Export the results of microbenchmarks",
+		Long:    "Export the results of microbenchmarks, one sample per benchmark and execution, with the custom metrics they report. The --export-workload flag is ignored.",
+		Example: "arewefastyet export microbench --config config.yaml --secrets secrets.yaml --export-git-ref <sha> --export-format csv --export-output micro.csv",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return export.Microbenchmarks(cfg)
+		},
+	}
+
+	cfg.AddToCommand(cmd)
+	return cmd
+}
@@ -20,32 +20,33 @@ package metrics
 
 import (
 	"log"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"github.com/vitessio/arewefastyet/go/exec"
 	"github.com/vitessio/arewefastyet/go/exec/metrics"
-	"github.com/vitessio/arewefastyet/go/storage/influxdb"
-	"github.com/vitessio/arewefastyet/go/storage/psdb"
+	"github.com/vitessio/arewefastyet/go/storage/database"
 )
 
 func GenExecMetricsCmd() *cobra.Command {
-	dbConfig := &psdb.Config{}
-	metricsDBConfig := &influxdb.Config{}
+	dbConfig := database.NewConfig()
+	metricsSourceConfig := metrics.NewSourceConfig()
 
 	cmd := &cobra.Command{
 		Use:   "exec_metrics",
-		Short: "For each execution, fetches the metrics from influxDB and store them to SQL if not already present.",
+		Short: "For each execution, fetches the metrics from the metrics source and store them to SQL if not already present.",
 		RunE: func(cmd *cobra.Command, args []string) (err error) {
 			clientSQL, err := dbConfig.NewClient()
 			if err != nil {
 				return err
 			}
-			clientMetrics, err := metricsDBConfig.NewClient()
+			definitions, err := metrics.LoadDefinitions(viper.GetViper())
 			if err != nil {
 				return err
 			}
 
-			rowsExecUUIDs, err := clientSQL.Read("select uuid from execution where status = ?", exec.StatusFinished)
+			rowsExecUUIDs, err := clientSQL.Read("select uuid, started_at, finished_at from execution where status = ?", exec.StatusFinished)
 			if err != nil {
 				return err
 			}
@@ -53,7 +54,8 @@ func GenExecMetricsCmd() *cobra.Command {
 
 			for rowsExecUUIDs.Next() {
 				var uuid string
-				err = rowsExecUUIDs.Scan(&uuid)
+				var startedAt, finishedAt *time.Time
+				err = rowsExecUUIDs.Scan(&uuid, &startedAt, &finishedAt)
 				if err != nil {
 					return err
 				}
@@ -68,7 +70,20 @@ func GenExecMetricsCmd() *cobra.Command {
 					continue
 				}
 
-				executionMetrics, err := metrics.GetExecutionMetrics(*clientMetrics, uuid, 0)
+				// the metrics are queried over the execution window
+				var start, end time.Time
+				if startedAt != nil {
+					start = *startedAt
+				}
+				if finishedAt != nil {
+					end = *finishedAt
+				}
+				source, err := metricsSourceConfig.NewSource(start, end)
+				if err != nil {
+					return err
+				}
+
+				executionMetrics, err := metrics.GetExecutionMetrics(source, uuid, 0, definitions)
 				if err != nil {
 					return err
 				}
@@ -86,6 +101,6 @@ func GenExecMetricsCmd() *cobra.Command {
 	}
 
 	dbConfig.AddToCommand(cmd)
-	metricsDBConfig.AddToCommand(cmd)
+	metricsSourceConfig.AddToCommand(cmd)
 	return cmd
 }
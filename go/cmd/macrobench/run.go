@@ -20,15 +20,15 @@ package macrobench
 
 import (
 	"github.com/spf13/cobra"
-	"github.com/vitessio/arewefastyet/go/storage/influxdb"
-	"github.com/vitessio/arewefastyet/go/storage/psdb"
+	"github.com/vitessio/arewefastyet/go/exec/metrics"
+	"github.com/vitessio/arewefastyet/go/storage/database"
 	"github.com/vitessio/arewefastyet/go/tools/macrobench"
 )
 
 func run() *cobra.Command {
 	mabcfg := macrobench.Config{
-		DatabaseConfig:        &psdb.Config{},
-		MetricsDatabaseConfig: &influxdb.Config{},
+		DatabaseConfig:      database.NewConfig(),
+		MetricsSourceConfig: metrics.NewSourceConfig(),
 	}
 
 	cmd := &cobra.Command{
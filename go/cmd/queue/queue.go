@@ -0,0 +1,40 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/vitessio/arewefastyet/go/tools/admin"
+)
+
+func QueueCmd() *cobra.Command {
+	cfg := admin.Config{}
+
+	cmd := &cobra.Command{
+		Use:     "queue",
+		Short:   "List the runs waiting in the queue of the API server",
+		Long:    "List the runs waiting in the queue of the API server with their UUID, source, git ref, workload and pull request. The run being executed is not part of the queue anymore, use 'arewefastyet run follow' to follow it.",
+		Example: "arewefastyet queue --admin-server-url https://benchmark.vitess.io",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return admin.Queue(cmd.Context(), cfg, cmd.OutOrStdout())
+		},
+	}
+
+	cfg.AddToCommand(cmd)
+	return cmd
+}
@@ -23,10 +23,15 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/vitessio/arewefastyet/go/cmd/api"
+	"github.com/vitessio/arewefastyet/go/cmd/compare"
+	"github.com/vitessio/arewefastyet/go/cmd/db"
 	"github.com/vitessio/arewefastyet/go/cmd/exec"
+	"github.com/vitessio/arewefastyet/go/cmd/export"
 	"github.com/vitessio/arewefastyet/go/cmd/gen"
 	"github.com/vitessio/arewefastyet/go/cmd/macrobench"
 	"github.com/vitessio/arewefastyet/go/cmd/microbench"
+	"github.com/vitessio/arewefastyet/go/cmd/queue"
+	"github.com/vitessio/arewefastyet/go/cmd/run"
 
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/viper"
@@ -67,6 +72,11 @@ func init() {
 	rootCmd.AddCommand(macrobench.MacroBenchCmd())
 	rootCmd.AddCommand(exec.ExecCmd())
 	rootCmd.AddCommand(gen.GenCmd())
+	rootCmd.AddCommand(db.DBCmd())
+	rootCmd.AddCommand(export.ExportCmd())
+	rootCmd.AddCommand(compare.CompareCmd())
+	rootCmd.AddCommand(queue.QueueCmd())
+	rootCmd.AddCommand(run.RunCmd())
 }
 
 // initConfig reads in config file and ENV variables if set.
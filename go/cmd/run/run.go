@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package run
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/vitessio/arewefastyet/go/tools/admin"
+)
+
+func RunCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run <command>",
+		Short: "Request, follow, cancel and invalidate runs on the API server",
+		Long: `Top level command to administer the runs of the API server.
+The requests are authorized by the key of the server, given with --web-request-run-key or read from the web-request-run-key entry of the secrets file used by the server.`,
+	}
+
+	cmd.AddCommand(requestCmd())
+	cmd.AddCommand(followCmd())
+	cmd.AddCommand(cancelCmd())
+	cmd.AddCommand(invalidateCmd())
+	return cmd
+}
+
+func requestCmd() *cobra.Command {
+	cfg := admin.RequestConfig{}
+
+	cmd := &cobra.Command{
+		Use:   "request <sha>",
+		Short: "Add custom runs of a commit to the queue",
+		Long:  "Add custom runs of a commit to the queue of the API server, one per workload, and print their UUID. With --run-follow, the status and the logs of the runs are printed until they end.",
+		Example: `arewefastyet run request <sha> --run-workload oltp,tpcc --run-vitess-version 20 --secrets secrets.yaml
+arewefastyet run request <sha> --run-workload oltp --run-vitess-version 20 --run-vtgate-flags "--queryserver-config-max-result-size=20000" --run-follow`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return admin.Request(cmd.Context(), cfg, args[0], cmd.OutOrStdout())
+		},
+	}
+
+	cfg.AddToCommand(cmd)
+	return cmd
+}
+
+func followCmd() *cobra.Command {
+	cfg := admin.Config{}
+
+	cmd := &cobra.Command{
+		Use:     "follow <uuid>",
+		Short:   "Print the status and the logs of a run until it ends",
+		Long:    "Print the status and the logs of a run until it ends, the command fails if the run fails.",
+		Example: "arewefastyet run follow <uuid> --secrets secrets.yaml",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return admin.Follow(cmd.Context(), cfg, args[0], cmd.OutOrStdout())
+		},
+	}
+
+	cfg.AddToCommand(cmd)
+	return cmd
+}
+
+func cancelCmd() *cobra.Command {
+	cfg := admin.Config{}
+
+	cmd := &cobra.Command{
+		Use:     "cancel <uuid>",
+		Short:   "Remove a run from the queue",
+		Long:    "Remove a run from the queue of the API server, a run that is already executing cannot be canceled.",
+		Example: "arewefastyet run cancel <uuid> --secrets secrets.yaml",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return admin.Cancel(cmd.Context(), cfg, args[0], cmd.OutOrStdout())
+		},
+	}
+
+	cfg.AddToCommand(cmd)
+	return cmd
+}
+
+func invalidateCmd() *cobra.Command {
+	cfg := admin.Config{}
+
+	cmd := &cobra.Command{
+		Use:     "invalidate <uuid>",
+		Short:   "Invalidate the results of a finished execution",
+		Long:    "Mark a finished execution as invalidated: its results are kept in the database but are not used in the comparisons, summaries and exports anymore.",
+		Example: "arewefastyet run invalidate <uuid> --secrets secrets.yaml",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return admin.Invalidate(cmd.Context(), cfg, args[0], cmd.OutOrStdout())
+		},
+	}
+
+	cfg.AddToCommand(cmd)
+	return cmd
+}
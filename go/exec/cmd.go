@@ -34,6 +34,7 @@ const (
 	flagServerAddress        = "exec-server-address"
 	flagVitessConfig         = "exec-vitess-config"
 	flagVitessSchema         = "exec-schema"
+	flagMicrobenchPackages   = "exec-microbench-packages"
 )
 
 func (e *Exec) AddToViper(v *viper.Viper) (err error) {
@@ -47,6 +48,7 @@ func (e *Exec) AddToViper(v *viper.Viper) (err error) {
 	_ = v.UnmarshalKey(flagServerAddress, &e.ServerAddress)
 	_ = v.UnmarshalKey(flagVitessConfig, &e.rawVitessConfig)
 	_ = v.UnmarshalKey(flagVitessSchema, &e.vitessSchemaPath)
+	_ = v.UnmarshalKey(flagMicrobenchPackages, &e.MicrobenchPackages)
 
 	e.AnsibleConfig.AddToViper(v)
 	e.configDB.AddToViper(v)
@@ -64,6 +66,7 @@ func (e *Exec) AddToCommand(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&e.GolangVersion, flagGolangVersion, "1.17", "Defines the golang version that will be used by this execution.")
 	cmd.Flags().StringVar(&e.ServerAddress, flagServerAddress, "", "The IP address of the server on which the benchmark will be executed.")
 	cmd.Flags().StringVar(&e.vitessSchemaPath, flagVitessSchema, "", "Path to the VSchema for this benchmark.")
+	cmd.Flags().StringSliceVar(&e.MicrobenchPackages, flagMicrobenchPackages, nil, "Import paths of the only packages to microbenchmark, all the packages are benchmarked if empty.")
 
 	_ = viper.BindPFlag(flagRootExec, cmd.Flags().Lookup(flagRootExec))
 	_ = viper.BindPFlag(flagGitRefExec, cmd.Flags().Lookup(flagGitRefExec))
@@ -73,6 +76,7 @@ func (e *Exec) AddToCommand(cmd *cobra.Command) {
 	_ = viper.BindPFlag(flagGolangVersion, cmd.Flags().Lookup(flagGolangVersion))
 	_ = viper.BindPFlag(flagServerAddress, cmd.Flags().Lookup(flagServerAddress))
 	_ = viper.BindPFlag(flagVitessSchema, cmd.Flags().Lookup(flagVitessSchema))
+	_ = viper.BindPFlag(flagMicrobenchPackages, cmd.Flags().Lookup(flagMicrobenchPackages))
 
 	e.AnsibleConfig.AddToPersistentCommand(cmd)
 	e.statsRemoteDBConfig.AddToCommand(cmd)
@@ -27,11 +27,13 @@ import (
 	"path"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/vitessio/arewefastyet/go/storage"
-	"github.com/vitessio/arewefastyet/go/storage/psdb"
+	"github.com/vitessio/arewefastyet/go/storage/database"
 	"github.com/vitessio/arewefastyet/go/tools/git"
+	"github.com/vitessio/arewefastyet/go/tools/macrobench"
 
 	"github.com/google/uuid"
 	"github.com/spf13/viper"
@@ -43,10 +45,22 @@ const (
 	stderrFile = "exec-stderr.log"
 	stdoutFile = "exec-stdout.log"
 
-	ErrorNotPrepared      = "exec is not prepared"
-	ErrorExecutionTimeout = "execution timeout"
+	ErrorNotPrepared          = "exec is not prepared"
+	ErrorExecutionTimeout     = "execution timeout"
+	ErrorExecutionNotFound    = "execution not found"
+	ErrorExecutionNotFinished = "execution is not finished"
 )
 
+// extraFlagsCondition matches the executions of the execution table "e" run with the
+// ExtraFlags given as the next two arguments of the query.
+const extraFlagsCondition = "IFNULL(e.vtgate_flags, '') = ? AND IFNULL(e.vttablet_flags, '') = ?"
+
+// ExtraFlags are the flags added to the flags of vtgate and vttablet by an execution.
+// The executions of a configuration run with different extra flags are distinct.
+type ExtraFlags struct {
+	Vtgate, Vttablet string
+}
+
 type Exec struct {
 	UUID          uuid.UUID
 	RawUUID       string
@@ -77,11 +91,15 @@ type Exec struct {
 	PullNB            int
 	PullBaseBranchRef string
 
+	// MicrobenchPackages lists the import paths of the only packages benchmarked by
+	// a microbenchmark execution, all the packages are benchmarked if it is empty.
+	MicrobenchPackages []string
+
 	// Configuration used to interact with the SQL database.
-	configDB *psdb.Config
+	configDB *database.Config
 
 	// Client to communicate with the SQL database.
-	clientDB *psdb.Client
+	clientDB storage.Client
 
 	// Configuration used to authenticate and insert execution stats
 	// data to a remote database system.
@@ -115,6 +133,11 @@ type Exec struct {
 
 	RepoDir string
 
+	// ExtraVtgateFlags and ExtraVttabletFlags are appended to the flags of vtgate and
+	// vttablet defined by the configuration, see the 'exec-vitess-config' flag below.
+	ExtraVtgateFlags   string
+	ExtraVttabletFlags string
+
 	// The configuration of the Vitess components (only vttablet and vtgate for now) can be
 	// customized through the configuration file. Some additional flags can be passed down
 	// to those two binaries.
@@ -153,7 +176,18 @@ type Exec struct {
 }
 
 const (
-	MaximumBenchmarkWithSameConfig = 10
+	// InitialBenchmarkWithSameConfig is the number of times a benchmark configuration
+	// is executed before we look at the spread of its results. benchmath needs at least
+	// 6 samples to compute a 95% confidence interval, the range is infinite below that.
+	InitialBenchmarkWithSameConfig = 6
+
+	// MaximumBenchmarkWithSameConfig is the maximum number of times a benchmark
+	// configuration can be executed, regardless of the spread of its results.
+	MaximumBenchmarkWithSameConfig = 15
+
+	// MaximumRangeWithSameConfig is the default width (in percent) of the confidence
+	// range of the key metrics above which more runs of a configuration are added.
+	MaximumRangeWithSameConfig = 2.0
 
 	SourceCron            = "cron"
 	SourcePullRequest     = "cron_pr"
@@ -182,7 +216,7 @@ func NewExec(uuidRaw string) (*Exec, error) {
 		stdout: os.Stdout,
 		stderr: os.Stderr,
 
-		configDB:      &psdb.Config{},
+		configDB:      database.NewConfig(),
 		clientDB:      nil,
 		configPath:    viper.ConfigFileUsed(),
 		AnsibleConfig: ansible.NewConfig(),
@@ -251,6 +285,22 @@ func (e *Exec) SetOutputToDefaultPath() error {
 	return nil
 }
 
+// OutputPath returns the path of the file holding the standard output of the execution
+// execUUID, or its standard error if stderr is true, given the configuration v used to
+// create the execution.
+func OutputPath(v *viper.Viper, execUUID string, stderr bool) (string, error) {
+	// parsing the UUID keeps the path inside of the root directory
+	parsedUUID, err := uuid.Parse(execUUID)
+	if err != nil {
+		return "", err
+	}
+	file := stdoutFile
+	if stderr {
+		file = stderrFile
+	}
+	return filepath.Abs(path.Join(v.GetString(flagRootExec), execDir, parsedUUID.String(), file))
+}
+
 // Prepare prepares the Exec for a future Execution.
 func (e *Exec) Prepare() error {
 	// Returns if the execution is already prepared
@@ -273,7 +323,7 @@ func (e *Exec) Prepare() error {
 
 	// insert new exec in SQL
 	if _, err = e.clientDB.Write(
-		"INSERT INTO execution(uuid, status, source, git_ref, workload, pull_nb, go_version) VALUES(?, ?, ?, ?, ?, ?, ?)",
+		"INSERT INTO execution(uuid, status, source, git_ref, workload, pull_nb, go_version, microbench_packages, vtgate_flags, vttablet_flags) VALUES(?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''))",
 		e.UUID.String(),
 		StatusCreated,
 		e.Source,
@@ -281,6 +331,9 @@ func (e *Exec) Prepare() error {
 		e.Workload,
 		e.PullNB,
 		e.GolangVersion,
+		strings.Join(e.MicrobenchPackages, ","),
+		e.ExtraVtgateFlags,
+		e.ExtraVttabletFlags,
 	); err != nil {
 		return err
 	}
@@ -308,6 +361,7 @@ func (e *Exec) Prepare() error {
 	if err != nil {
 		return err
 	}
+	e.vitessConfig.addExtraFlags(e.ExtraVtgateFlags, e.ExtraVttabletFlags)
 
 	err = e.prepareAnsibleForExecution()
 	if err != nil {
@@ -390,6 +444,9 @@ func (e *Exec) prepareAnsibleForExecution() error {
 		e.AnsibleConfig.AddExtraVar(ansible.KeyVitessVersionFetchPR, "refs/pull/"+strconv.Itoa(e.PullNB)+"/head")
 		e.AnsibleConfig.AddExtraVar(ansible.KeyVitessVersionPRNumber, e.PullNB)
 	}
+	if len(e.MicrobenchPackages) > 0 {
+		e.AnsibleConfig.AddExtraVar(ansible.KeyMicrobenchPackages, strings.Join(e.MicrobenchPackages, ","))
+	}
 	e.AnsibleConfig.AddExtraVar(ansible.KeyVtgatePlanner, e.VtgatePlannerVersion)
 	e.AnsibleConfig.AddExtraVar(ansible.KeyVitessMajorVersion, e.VitessVersion.Major)
 	e.AnsibleConfig.AddExtraVar(ansible.KeyVitessSchema, e.vitessSchemaPath)
@@ -414,7 +471,16 @@ func (e *Exec) Success() error {
 		return nil
 	}
 	_, err = e.clientDB.Write("UPDATE execution SET finished_at = CURRENT_TIME, status = ? WHERE uuid = ?", StatusFinished, e.UUID.String())
-	return err
+	if err != nil {
+		return err
+	}
+
+	// the results of the execution are now part of the summary of its group
+	groups, err := macrobench.GetSummaryGroups(e.clientDB, e.UUID.String())
+	if err != nil {
+		return err
+	}
+	return macrobench.RefreshSummaries(e.clientDB, groups)
 }
 
 func (e *Exec) handleStepEnd(err error) {
@@ -423,38 +489,66 @@ func (e *Exec) handleStepEnd(err error) {
 	}
 }
 
-func GetRecentExecutions(client storage.SQLClient) ([]*Exec, error) {
-	var res []*Exec
-	query := "SELECT uuid, status, git_ref, started_at, finished_at, source, workload, pull_nb, go_version FROM execution ORDER BY started_at DESC LIMIT 1000"
-	result, err := client.Read(query)
+// GetRecentExecutions returns a page of the executions matching filter, the most
+// recently started first, along with the cursor of the next page which is empty
+// if this is the last page.
+func GetRecentExecutions(client storage.SQLClient, filter ExecutionFilter, page Page) ([]*Exec, string, error) {
+	c, err := page.cursor()
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+	const startedAt = "COALESCE(started_at, '" + noStartedAt + "')"
+	after, afterArgs, err := c.after(startedAt, "uuid")
+	if err != nil {
+		return nil, "", err
+	}
+	where, args := filter.where()
+	query := "SELECT uuid, status, git_ref, started_at, finished_at, source, workload, pull_nb, go_version, microbench_packages FROM execution WHERE " +
+		where + " AND " + after + " ORDER BY " + startedAt + " DESC, uuid DESC LIMIT ?"
+	args = append(append(args, afterArgs...), page.limit()+1)
+
+	result, err := client.Read(query, args...)
+	if err != nil {
+		return nil, "", err
 	}
 	defer result.Close()
+	var res []*Exec
 	for result.Next() {
 		exec := &Exec{}
-		err = result.Scan(&exec.RawUUID, &exec.Status, &exec.GitRef, &exec.StartedAt, &exec.FinishedAt, &exec.Source, &exec.Workload, &exec.PullNB, &exec.GolangVersion)
+		var microbenchPackages sql.NullString
+		err = result.Scan(&exec.RawUUID, &exec.Status, &exec.GitRef, &exec.StartedAt, &exec.FinishedAt, &exec.Source, &exec.Workload, &exec.PullNB, &exec.GolangVersion, &microbenchPackages)
 		if err != nil {
-			return nil, err
+			return nil, "", err
+		}
+		if microbenchPackages.String != "" {
+			exec.MicrobenchPackages = strings.Split(microbenchPackages.String, ",")
 		}
 		res = append(res, exec)
 	}
-	return res, nil
+
+	if len(res) <= page.limit() {
+		return res, "", nil
+	}
+	res = res[:page.limit()]
+	last := res[len(res)-1]
+	return res, cursor{StartedAt: formatStartedAt(last.StartedAt), Keys: []string{last.RawUUID}}.encode(), nil
 }
 
-func GetFinishedExecution(client storage.SQLClient, gitRef, source, workload, plannerVersion string, pullNb int) (string, error) {
+// GetFinishedExecution returns the UUID of the last finished execution of the given
+// configuration, run with the given extra flags, or an empty string if there is none.
+func GetFinishedExecution(client storage.SQLClient, gitRef, source, workload, plannerVersion string, pullNb int, flags ExtraFlags) (string, error) {
 	var eUUID string
 	var result *sql.Rows
 	var err error
 	query := ""
 	if plannerVersion == "" {
 		// no plannerVersion, meaning we are dealing with a micro benchmark
-		query = "SELECT e.uuid FROM execution e WHERE e.source = ? AND e.status = ? AND e.workload = ? AND e.git_ref = ? AND e.pull_nb = ? ORDER BY e.finished_at DESC LIMIT 1"
-		result, err = client.Read(query, source, StatusFinished, workload, gitRef, pullNb)
+		query = "SELECT e.uuid FROM execution e WHERE e.source = ? AND e.status = ? AND e.workload = ? AND e.git_ref = ? AND e.pull_nb = ? AND " + extraFlagsCondition + " ORDER BY e.finished_at DESC LIMIT 1"
+		result, err = client.Read(query, source, StatusFinished, workload, gitRef, pullNb, flags.Vtgate, flags.Vttablet)
 	} else {
 		// we have a plannerVersion, meaning we are dealing with a macro benchmark
-		query = "SELECT e.uuid FROM execution e, macrobenchmark m WHERE e.uuid = m.exec_uuid AND m.vtgate_planner_version = ? AND e.source = ? AND e.status = ? AND e.workload = ? AND e.git_ref = ? AND e.pull_nb = ? ORDER BY e.finished_at DESC LIMIT 1"
-		result, err = client.Read(query, plannerVersion, source, StatusFinished, workload, gitRef, pullNb)
+		query = "SELECT e.uuid FROM execution e, macrobenchmark m WHERE e.uuid = m.exec_uuid AND m.vtgate_planner_version = ? AND e.source = ? AND e.status = ? AND e.workload = ? AND e.git_ref = ? AND e.pull_nb = ? AND " + extraFlagsCondition + " ORDER BY e.finished_at DESC LIMIT 1"
+		result, err = client.Read(query, plannerVersion, source, StatusFinished, workload, gitRef, pullNb, flags.Vtgate, flags.Vttablet)
 	}
 	if err != nil {
 		return "", err
@@ -556,9 +650,11 @@ func GetLatestDailyJobForMacrobenchmarks(client storage.SQLClient) (gitSha strin
 	return "", nil
 }
 
-func Exists(client storage.SQLClient, gitRef, source, workload, status string) (bool, error) {
-	query := "SELECT uuid FROM execution WHERE status = ? AND git_ref = ? AND workload = ? AND source = ?"
-	result, err := client.Read(query, status, gitRef, workload, source)
+// Exists returns true if an execution of the given configuration, run with the given
+// extra flags, has the given status.
+func Exists(client storage.SQLClient, gitRef, source, workload, status string, flags ExtraFlags) (bool, error) {
+	query := "SELECT uuid FROM execution AS e WHERE e.status = ? AND e.git_ref = ? AND e.workload = ? AND e.source = ? AND " + extraFlagsCondition
+	result, err := client.Read(query, status, gitRef, workload, source, flags.Vtgate, flags.Vttablet)
 	if err != nil {
 		return false, err
 	}
@@ -566,9 +662,24 @@ func Exists(client storage.SQLClient, gitRef, source, workload, status string) (
 	return result.Next(), nil
 }
 
-func CountMacroBenchmark(client storage.SQLClient, gitRef, source, workload, status, planner string) (int, error) {
-	query := "SELECT count(uuid) FROM execution e, macrobenchmark m WHERE e.status = ? AND e.git_ref = ? AND e.workload = ? AND e.source = ? AND m.vtgate_planner_version = ? AND e.uuid = m.exec_uuid"
-	result, err := client.Read(query, status, gitRef, workload, source, planner)
+// ExistsMicrobenchmark returns true if a microbenchmark execution of the given git ref and
+// source, limited to the given comma-separated packages or of all the packages if it is empty,
+// and run with the given extra flags, has the given status.
+func ExistsMicrobenchmark(client storage.SQLClient, gitRef, source, packages, status string, flags ExtraFlags) (bool, error) {
+	query := "SELECT uuid FROM execution AS e WHERE e.status = ? AND e.git_ref = ? AND e.workload = 'micro' AND e.source = ? AND IFNULL(e.microbench_packages, '') = ? AND " + extraFlagsCondition
+	result, err := client.Read(query, status, gitRef, source, packages, flags.Vtgate, flags.Vttablet)
+	if err != nil {
+		return false, err
+	}
+	defer result.Close()
+	return result.Next(), nil
+}
+
+// CountMacroBenchmark returns the number of executions of the given macrobenchmark
+// configuration, run with the given extra flags, that have the given status.
+func CountMacroBenchmark(client storage.SQLClient, gitRef, source, workload, status, planner string, flags ExtraFlags) (int, error) {
+	query := "SELECT count(uuid) FROM execution e, macrobenchmark m WHERE e.status = ? AND e.git_ref = ? AND e.workload = ? AND e.source = ? AND m.vtgate_planner_version = ? AND e.uuid = m.exec_uuid AND " + extraFlagsCondition
+	result, err := client.Read(query, status, gitRef, workload, source, planner, flags.Vtgate, flags.Vttablet)
 	if err != nil {
 		return 0, err
 	}
@@ -583,13 +694,81 @@ func CountMacroBenchmark(client storage.SQLClient, gitRef, source, workload, sta
 	return nb, nil
 }
 
+// DeleteExecution deletes the executions matching the given git ref, UUID and source, and
+// refreshes the summaries of the macrobenchmark groups they belonged to.
 func DeleteExecution(client storage.SQLClient, gitRef, UUID, source string) error {
-	query := fmt.Sprintf("DELETE FROM execution WHERE uuid LIKE '%%%s%%' AND git_ref LIKE '%%%s%%' AND source = '%s'", UUID, gitRef, source)
-	_, err := client.Read(query)
+	const condition = "uuid LIKE ? AND git_ref LIKE ? AND source = ?"
+	args := []interface{}{"%" + UUID + "%", "%" + gitRef + "%", source}
+
+	result, err := client.Read("SELECT uuid FROM execution WHERE "+condition, args...)
 	if err != nil {
 		return err
 	}
-	return nil
+	var uuids []string
+	for result.Next() {
+		var execUUID string
+		if err = result.Scan(&execUUID); err != nil {
+			result.Close()
+			return err
+		}
+		uuids = append(uuids, execUUID)
+	}
+	result.Close()
+
+	// the groups must be fetched before the executions are deleted
+	groups, err := macrobench.GetSummaryGroups(client, uuids...)
+	if err != nil {
+		return err
+	}
+	_, err = client.Write("DELETE FROM execution WHERE "+condition, args...)
+	if err != nil {
+		return err
+	}
+	return macrobench.RefreshSummaries(client, groups)
+}
+
+// GetExecution returns the execution with the given UUID.
+func GetExecution(client storage.SQLClient, execUUID string) (*Exec, error) {
+	result, err := client.Read("SELECT uuid, status, git_ref, started_at, finished_at, source, workload, pull_nb, go_version FROM execution WHERE uuid = ?", execUUID)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+	if !result.Next() {
+		if err := result.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: %s", ErrorExecutionNotFound, execUUID)
+	}
+	exec := &Exec{}
+	err = result.Scan(&exec.RawUUID, &exec.Status, &exec.GitRef, &exec.StartedAt, &exec.FinishedAt, &exec.Source, &exec.Workload, &exec.PullNB, &exec.GolangVersion)
+	if err != nil {
+		return nil, err
+	}
+	return exec, nil
+}
+
+// InvalidateExecution marks the finished execution with the given UUID as invalidated, its
+// results are kept but are not used anymore, and refreshes the summary of its group.
+func InvalidateExecution(client storage.SQLClient, execUUID string) error {
+	exec, err := GetExecution(client, execUUID)
+	if err != nil {
+		return err
+	}
+	if exec.Status != StatusFinished {
+		return fmt.Errorf("%s: %s is %s", ErrorExecutionNotFinished, execUUID, exec.Status)
+	}
+
+	// the group must be fetched before the execution is invalidated
+	groups, err := macrobench.GetSummaryGroups(client, execUUID)
+	if err != nil {
+		return err
+	}
+	_, err = client.Write("UPDATE execution SET status = ? WHERE uuid = ? AND status = ?", StatusInvalidated, execUUID, StatusFinished)
+	if err != nil {
+		return err
+	}
+	return macrobench.RefreshSummaries(client, groups)
 }
 
 type History struct {
@@ -599,8 +778,25 @@ type History struct {
 	StartedAt            *time.Time `json:"started_at"`
 }
 
-func GetHistory(client storage.SQLClient) ([]*History, error) {
+// GetHistory returns a page of the git refs that were fully benchmarked by a source, the
+// most recently started first, along with the cursor of the next page which is empty if
+// this is the last page. A workload is fully benchmarked once it has at least minRuns
+// finished executions. Only the finished executions matching filter are counted, the
+// status of filter is ignored.
+func GetHistory(client storage.SQLClient, filter ExecutionFilter, page Page, minRuns int) ([]*History, string, error) {
+	c, err := page.cursor()
+	if err != nil {
+		return nil, "", err
+	}
+	after, afterArgs, err := c.after("min_started_at", "git_ref", "source")
+	if err != nil {
+		return nil, "", err
+	}
+	filter.Status = StatusFinished
+	where, args := filter.where()
+
 	query := `
+		SELECT git_ref, source, distinct_workloads, min_started_at FROM (
 			SELECT
 				git_ref,
 				source,
@@ -615,23 +811,29 @@ func GetHistory(client storage.SQLClient) ([]*History, error) {
 				FROM
 					execution
 				WHERE
-					status = 'finished'
+					` + where + `
 				GROUP BY
 					git_ref,
 					source,
 					workload
 				HAVING
-					COUNT(*) >= 10
+					COUNT(*) >= ?
 			) AS subquery
 			GROUP BY
 				git_ref,
 				source
-			ORDER BY
-				min_started_at DESC;`
-
-	result, err := client.Read(query)
+		) AS history
+		WHERE
+			` + after + `
+		ORDER BY
+			min_started_at DESC, git_ref DESC, source DESC
+		LIMIT ?;`
+	args = append(args, minRuns)
+	args = append(append(args, afterArgs...), page.limit()+1)
+
+	result, err := client.Read(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer result.Close()
 	res := make([]*History, 0)
@@ -639,9 +841,15 @@ func GetHistory(client storage.SQLClient) ([]*History, error) {
 		history := &History{}
 		err = result.Scan(&history.SHA, &history.Source, &history.WorkloadsBenchmarked, &history.StartedAt)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		res = append(res, history)
 	}
-	return res, nil
+
+	if len(res) <= page.limit() {
+		return res, "", nil
+	}
+	res = res[:page.limit()]
+	last := res[len(res)-1]
+	return res, cursor{StartedAt: formatStartedAt(last.StartedAt), Keys: []string{last.SHA, last.Source}}.encode(), nil
 }
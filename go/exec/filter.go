@@ -0,0 +1,222 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/vitessio/arewefastyet/go/storage"
+)
+
+const (
+	ErrorInvalidCursor = "invalid cursor"
+
+	// DefaultPageSize is the number of rows returned when Page.Limit is not set.
+	DefaultPageSize = 1000
+
+	// MaxPageSize is the maximum number of rows that can be returned at once.
+	MaxPageSize = 1000
+
+	// noStartedAt replaces the started_at of executions that did not start yet
+	// when sorting them, they come after all the others.
+	noStartedAt = "1000-01-01 00:00:00"
+)
+
+type (
+	// ExecutionFilter restricts the executions that are listed, a zero field
+	// does not restrict anything.
+	ExecutionFilter struct {
+		Source        string
+		Workload      string
+		Status        string
+		GolangVersion string
+
+		// GitRefPrefix keeps the executions whose git ref starts with it.
+		GitRefPrefix string
+
+		// PullNB keeps the executions of the given pull request.
+		PullNB int
+
+		// Since and Until restrict the date at which the executions started.
+		Since *time.Time
+		Until *time.Time
+	}
+
+	// Page selects a range of rows in a sorted list. Cursor is empty to get
+	// the first page, otherwise it is the cursor returned with the previous page.
+	Page struct {
+		Limit  int
+		Cursor string
+	}
+
+	// ExecutionFacets holds the distinct values of the executions matching a filter.
+	ExecutionFacets struct {
+		Workloads []string
+		Sources   []string
+		Statuses  []string
+	}
+
+	// cursor is the sort key of the last row of a page.
+	cursor struct {
+		StartedAt string   `json:"t"`
+		Keys      []string `json:"k"`
+	}
+)
+
+// where returns the SQL conditions corresponding to the filter and their arguments.
+func (f ExecutionFilter) where() (string, []interface{}) {
+	conditions := []string{"1 = 1"}
+	var args []interface{}
+	add := func(condition string, arg interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, arg)
+	}
+
+	if f.Source != "" {
+		add("source = ?", f.Source)
+	}
+	if f.Workload != "" {
+		add("workload = ?", f.Workload)
+	}
+	if f.Status != "" {
+		add("status = ?", f.Status)
+	}
+	if f.GolangVersion != "" {
+		add("go_version = ?", f.GolangVersion)
+	}
+	if f.GitRefPrefix != "" {
+		add(`git_ref LIKE ? ESCAPE '\\'`, escapeLike(f.GitRefPrefix)+"%")
+	}
+	if f.PullNB != 0 {
+		add("pull_nb = ?", f.PullNB)
+	}
+	if f.Since != nil {
+		add("started_at >= ?", f.Since.UTC())
+	}
+	if f.Until != nil {
+		add("started_at < ?", f.Until.UTC())
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (p Page) limit() int {
+	if p.Limit <= 0 {
+		return DefaultPageSize
+	}
+	return min(p.Limit, MaxPageSize)
+}
+
+func (p Page) cursor() (*cursor, error) {
+	if p.Cursor == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(p.Cursor)
+	if err != nil {
+		return nil, errors.New(ErrorInvalidCursor)
+	}
+	var c cursor
+	if err = json.Unmarshal(data, &c); err != nil || c.StartedAt == "" {
+		return nil, errors.New(ErrorInvalidCursor)
+	}
+	return &c, nil
+}
+
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// after returns the condition selecting the rows that come after the cursor
+// when sorting by the given started_at expression and keys in descending order.
+func (c *cursor) after(startedAt string, keys ...string) (string, []interface{}, error) {
+	if c == nil {
+		return "1 = 1", nil, nil
+	}
+	if len(c.Keys) != len(keys) {
+		return "", nil, errors.New(ErrorInvalidCursor)
+	}
+	placeholders := "?" + strings.Repeat(", ?", len(keys))
+	args := []interface{}{c.StartedAt}
+	for _, key := range c.Keys {
+		args = append(args, key)
+	}
+	return "(" + startedAt + ", " + strings.Join(keys, ", ") + ") < (" + placeholders + ")", args, nil
+}
+
+func formatStartedAt(t *time.Time) string {
+	if t == nil {
+		return noStartedAt
+	}
+	return t.UTC().Format(time.DateTime)
+}
+
+// GetExecutionFacets returns the distinct workloads, sources and statuses of the
+// executions matching filter. The values of a facet ignore the criterion of the
+// filter on that same facet, so the other values remain selectable.
+func GetExecutionFacets(client storage.SQLClient, filter ExecutionFilter) (ExecutionFacets, error) {
+	var res ExecutionFacets
+
+	withoutWorkload, withoutSource, withoutStatus := filter, filter, filter
+	withoutWorkload.Workload = ""
+	withoutSource.Source = ""
+	withoutStatus.Status = ""
+
+	for _, facet := range []struct {
+		column string
+		filter ExecutionFilter
+		values *[]string
+	}{
+		{column: "workload", filter: withoutWorkload, values: &res.Workloads},
+		{column: "source", filter: withoutSource, values: &res.Sources},
+		{column: "status", filter: withoutStatus, values: &res.Statuses},
+	} {
+		where, args := facet.filter.where()
+		query := "SELECT " + facet.column + " FROM execution WHERE " + where + " GROUP BY " + facet.column + " ORDER BY " + facet.column
+		values, err := readStrings(client, query, args...)
+		if err != nil {
+			return ExecutionFacets{}, err
+		}
+		*facet.values = values
+	}
+	return res, nil
+}
+
+func readStrings(client storage.SQLClient, query string, args ...interface{}) ([]string, error) {
+	result, err := client.Read(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	res := []string{}
+	for result.Next() {
+		var value string
+		if err = result.Scan(&value); err != nil {
+			return nil, err
+		}
+		res = append(res, value)
+	}
+	return res, result.Err()
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestExecutionFilter_where(t *testing.T) {
+	since := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		filter   ExecutionFilter
+		want     string
+		wantArgs []interface{}
+	}{
+		{name: "no filter", want: "1 = 1"},
+		{
+			name:     "several fields",
+			filter:   ExecutionFilter{Source: "cron", Status: StatusFinished, PullNB: 42, Since: &since},
+			want:     "1 = 1 AND source = ? AND status = ? AND pull_nb = ? AND started_at >= ?",
+			wantArgs: []interface{}{"cron", StatusFinished, 42, since},
+		},
+		{
+			name:     "git ref prefix is escaped",
+			filter:   ExecutionFilter{GitRefPrefix: "ab_c%"},
+			want:     `1 = 1 AND git_ref LIKE ? ESCAPE '\\'`,
+			wantArgs: []interface{}{`ab\_c\%%`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			got, args := tt.filter.where()
+			c.Assert(got, qt.Equals, tt.want)
+			c.Assert(args, qt.DeepEquals, tt.wantArgs)
+		})
+	}
+}
+
+func TestPage(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(Page{}.limit(), qt.Equals, DefaultPageSize)
+	c.Assert(Page{Limit: 10}.limit(), qt.Equals, 10)
+	c.Assert(Page{Limit: MaxPageSize + 1}.limit(), qt.Equals, MaxPageSize)
+
+	first, err := Page{}.cursor()
+	c.Assert(err, qt.IsNil)
+	c.Assert(first, qt.IsNil)
+	after, args, err := first.after("started_at", "uuid")
+	c.Assert(err, qt.IsNil)
+	c.Assert(after, qt.Equals, "1 = 1")
+	c.Assert(args, qt.HasLen, 0)
+
+	startedAt := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	next := cursor{StartedAt: formatStartedAt(&startedAt), Keys: []string{"ref", "cron"}}.encode()
+	got, err := Page{Cursor: next}.cursor()
+	c.Assert(err, qt.IsNil)
+	after, args, err = got.after("min_started_at", "git_ref", "source")
+	c.Assert(err, qt.IsNil)
+	c.Assert(after, qt.Equals, "(min_started_at, git_ref, source) < (?, ?, ?)")
+	c.Assert(args, qt.DeepEquals, []interface{}{"2024-05-01 12:30:00", "ref", "cron"})
+
+	// the cursor of another listing
+	_, _, err = got.after("started_at", "uuid")
+	c.Assert(err, qt.ErrorMatches, ErrorInvalidCursor)
+
+	for _, invalid := range []string{"not base64!", "bm90IGpzb24"} {
+		_, err = Page{Cursor: invalid}.cursor()
+		c.Assert(err, qt.ErrorMatches, ErrorInvalidCursor)
+	}
+
+	c.Assert(formatStartedAt(nil), qt.Equals, noStartedAt)
+}
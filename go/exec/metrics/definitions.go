@@ -0,0 +1,144 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	ErrorDefinitionMissingName       = "metric definition is missing a name"
+	ErrorDefinitionInvalidName       = "metric definition name cannot contain a '.' or start with 'Total'"
+	ErrorDefinitionMissingSource     = "metric definition is missing a source"
+	ErrorDefinitionMissingComponents = "metric definition has no component"
+	ErrorDefinitionUnknownAggregate  = "unknown metric aggregation"
+	ErrorDefinitionDuplicatedName    = "metric definition is defined more than once"
+
+	// KeyDefinitions is the configuration key under which the metric definitions are listed.
+	KeyDefinitions = "metrics-definitions"
+
+	// AggregationDelta is the difference between the last and first value of a counter.
+	AggregationDelta = "delta"
+
+	// AggregationMax is the highest value of a gauge.
+	AggregationMax = "max"
+
+	// AggregationAvg is the average value of a gauge.
+	AggregationAvg = "avg"
+
+	// AggregationPerQuery is the AggregationDelta divided by the number of queries
+	// executed by the benchmark.
+	AggregationPerQuery = "per-query"
+
+	// CPUTimeMetric is the name of the metric measuring the CPU time of each component per query.
+	CPUTimeMetric = "ComponentsCPUTime"
+
+	// MemStatsAllocBytesMetric is the name of the metric measuring the number of bytes
+	// allocated, even if freed, by each component per query.
+	MemStatsAllocBytesMetric = "ComponentsMemStatsAllocBytes"
+)
+
+// Definition describes a metric gathered from the metrics database for each component
+// of a benchmark. Definitions are listed in the configuration file under KeyDefinitions:
+//
+//	metrics-definitions:
+//	  - name: GoroutinesCount
+//	    source: go_goroutines
+//	    aggregation: max
+//	    components: [vtgate, vttablet]
+type Definition struct {
+	// Name of the metric, it is used to store and compare the metric.
+	Name string `mapstructure:"name"`
+
+	// Source is the name of the measurement to query in the metrics database.
+	Source string `mapstructure:"source"`
+
+	// Aggregation defines how the values of Source are reduced to a single value,
+	// it is either AggregationDelta, AggregationMax, AggregationAvg or AggregationPerQuery.
+	Aggregation string `mapstructure:"aggregation"`
+
+	// Components lists the components for which this metric is gathered.
+	Components []string `mapstructure:"components"`
+}
+
+// DefaultDefinitions are the metric definitions used when none are configured.
+var DefaultDefinitions = []Definition{
+	{
+		Name:        CPUTimeMetric,
+		Source:      "process_cpu_seconds_total",
+		Aggregation: AggregationPerQuery,
+		Components:  []string{"vtgate", "vttablet"},
+	},
+	{
+		Name:        MemStatsAllocBytesMetric,
+		Source:      "go_memstats_alloc_bytes_total",
+		Aggregation: AggregationPerQuery,
+		Components:  []string{"vtgate", "vttablet"},
+	},
+}
+
+// LoadDefinitions returns the metric definitions listed in the configuration
+// under KeyDefinitions, or DefaultDefinitions if there are none.
+func LoadDefinitions(v *viper.Viper) ([]Definition, error) {
+	if !v.IsSet(KeyDefinitions) {
+		return DefaultDefinitions, nil
+	}
+
+	var defs []Definition
+	err := v.UnmarshalKey(KeyDefinitions, &defs)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for _, def := range defs {
+		if err = def.IsValid(); err != nil {
+			return nil, err
+		}
+		if seen[def.Name] {
+			return nil, fmt.Errorf("%s: %s", ErrorDefinitionDuplicatedName, def.Name)
+		}
+		seen[def.Name] = true
+	}
+	return defs, nil
+}
+
+// IsValid returns an error if the Definition cannot be used.
+func (def Definition) IsValid() error {
+	if def.Name == "" {
+		return errors.New(ErrorDefinitionMissingName)
+	}
+	if strings.Contains(def.Name, ".") || strings.HasPrefix(def.Name, totalPrefix) {
+		return fmt.Errorf("%s: %s", ErrorDefinitionInvalidName, def.Name)
+	}
+	if def.Source == "" {
+		return fmt.Errorf("%s: %s", ErrorDefinitionMissingSource, def.Name)
+	}
+	if len(def.Components) == 0 {
+		return fmt.Errorf("%s: %s", ErrorDefinitionMissingComponents, def.Name)
+	}
+	switch def.Aggregation {
+	case AggregationDelta, AggregationMax, AggregationAvg, AggregationPerQuery:
+	default:
+		return fmt.Errorf("%s: %s", ErrorDefinitionUnknownAggregate, def.Aggregation)
+	}
+	return nil
+}
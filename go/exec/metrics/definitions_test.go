@@ -0,0 +1,123 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/spf13/viper"
+)
+
+func TestLoadDefinitions(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    []Definition
+		wantErr string
+	}{
+		{name: "default definitions", config: "", want: DefaultDefinitions},
+		{
+			name: "custom definitions",
+			config: `
+metrics-definitions:
+  - name: GoroutinesCount
+    source: go_goroutines
+    aggregation: max
+    components: [vtgate, vttablet]
+`,
+			want: []Definition{{Name: "GoroutinesCount", Source: "go_goroutines", Aggregation: AggregationMax, Components: []string{"vtgate", "vttablet"}}},
+		},
+		{
+			name: "unknown aggregation",
+			config: `
+metrics-definitions:
+  - name: GoroutinesCount
+    source: go_goroutines
+    aggregation: median
+    components: [vtgate]
+`,
+			wantErr: ErrorDefinitionUnknownAggregate + ": median",
+		},
+		{
+			name: "invalid name",
+			config: `
+metrics-definitions:
+  - name: Goroutines.Count
+    source: go_goroutines
+    aggregation: max
+    components: [vtgate]
+`,
+			wantErr: ErrorDefinitionInvalidName + ": Goroutines.Count",
+		},
+		{
+			name: "duplicated name",
+			config: `
+metrics-definitions:
+  - name: GoroutinesCount
+    source: go_goroutines
+    aggregation: max
+    components: [vtgate]
+  - name: GoroutinesCount
+    source: go_goroutines
+    aggregation: avg
+    components: [vttablet]
+`,
+			wantErr: ErrorDefinitionDuplicatedName + ": GoroutinesCount",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			v := viper.New()
+			v.SetConfigType("yaml")
+			c.Assert(v.ReadConfig(strings.NewReader(tt.config)), qt.IsNil)
+
+			got, err := LoadDefinitions(v)
+			if tt.wantErr != "" {
+				c.Assert(err, qt.ErrorMatches, tt.wantErr)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(got, qt.DeepEquals, tt.want)
+		})
+	}
+}
+
+func TestExecutionMetricsSet(t *testing.T) {
+	c := qt.New(t)
+
+	em := NewExecMetrics(DefaultDefinitions)
+	em.Set("TotalComponentsCPUTime", 3)
+	em.Set("ComponentsCPUTime.vtgate", 1)
+	em.Set("ComponentsCPUTime.vttablet", 2)
+	em.Set("GoroutinesCount.vtgate", 42)
+
+	c.Assert(em, qt.DeepEquals, ExecutionMetrics{
+		CPUTimeMetric: {
+			Total:      3,
+			Components: map[string]float64{"vtgate": 1, "vttablet": 2},
+		},
+		MemStatsAllocBytesMetric: {
+			Components: map[string]float64{"vtgate": 0, "vttablet": 0},
+		},
+		"GoroutinesCount": {
+			Components: map[string]float64{"vtgate": 42},
+		},
+	})
+}
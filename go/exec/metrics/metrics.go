@@ -19,7 +19,7 @@
 package metrics
 
 import (
-	"fmt"
+	"context"
 	"strings"
 
 	"github.com/vitessio/arewefastyet/go/storage"
@@ -27,172 +27,139 @@ import (
 )
 
 const (
-	cpuSecondsPerComponentStart = `from(bucket:"%s")
+	querySelect = `from(bucket:"%s")
 			|> range(start: 0, stop: now())
-			|> filter(fn:(r) => r._measurement == "process_cpu_seconds_total" and r.exec_uuid == "%s" and r.component == "%s")
-			|> filter(fn: (r) => r._value > 0)
-			|> min()`
-
-	cpuSecondsPerComponentEnd = `from(bucket:"%s")
-			|> range(start: 0, stop: now())
-			|> filter(fn:(r) => r._measurement == "process_cpu_seconds_total" and r.exec_uuid == "%s" and r.component == "%s")
-			|> max()`
+			|> filter(fn:(r) => r._measurement == "%s" and r.exec_uuid == "%s" and r.component == "%s")`
 
-	memAllocBytesPerComponentStart = `from(bucket:"%s")
-			|> range(start: 0, stop: now())
-			|> filter(fn:(r) => r._measurement == "go_memstats_alloc_bytes_total" and r.exec_uuid == "%s" and r.component == "%s")
+	queryFirstCounterValue = querySelect + `
 			|> filter(fn: (r) => r._value > 0)
 			|> min()`
 
-	memAllocBytesPerComponentEnd = `from(bucket:"%s")
-			|> range(start: 0, stop: now())
-			|> filter(fn:(r) => r._measurement == "go_memstats_alloc_bytes_total" and r.exec_uuid == "%s" and r.component == "%s")
+	queryLastCounterValue = querySelect + `
 			|> max()`
-)
 
-var (
-	components = []string{
-		"vtgate",
-		"vttablet",
-	}
+	queryMeanValue = querySelect + `
+			|> mean()`
+
+	// totalPrefix is prepended to the name of a metric to store the sum of all its components.
+	totalPrefix = "Total"
 )
 
 type (
-	// ExecutionMetrics contains all the different system and service metrics
-	// that were gathered during the execution of a benchmark.
-	ExecutionMetrics struct {
-		// The sum of the time taken by every component to run one query on average.
-		TotalComponentsCPUTime float64
-
-		// Map of string/float that contains the name of the component as a key
-		// and the time taken by that component on average per query as a value.
-		ComponentsCPUTime map[string]float64
-
-		// TotalComponentsMemStatsAllocBytes represents the total number of bytes
-		// allocated even if freed, by all the components of the execution and on average per query.
-		// The underlying go metrics used is go_memstats_alloc_bytes_total.
-		TotalComponentsMemStatsAllocBytes float64
-
-		// ComponentsMemStatsAllocBytes represents the number of bytes allocated
-		// and freed that each component used on average per query. The go metrics used is go_memstats_alloc_bytes_total.
-		ComponentsMemStatsAllocBytes map[string]float64
+	// Metric holds the value of a single metric for each component and their sum.
+	Metric struct {
+		// Total is the sum of the values of all the components.
+		Total float64
+
+		// Components maps the name of the component to its value.
+		Components map[string]float64
 	}
 
-	// ExecutionMetricsArray is a slice of ExecutionMetrics, it has a Median method
-	// to compute the overall median of the slice.
+	// ExecutionMetrics contains all the different system and service metrics
+	// that were gathered during the execution of a benchmark. The key of the map
+	// is the name of the Definition used to gather the metric.
+	//
+	// By default, it contains CPUTimeMetric which is the time taken by every
+	// component to run one query on average, and MemStatsAllocBytesMetric which
+	// is the number of bytes allocated, even if freed, by every component on
+	// average per query.
+	ExecutionMetrics map[string]Metric
+
+	// ExecutionMetricsArray is a slice of ExecutionMetrics.
 	ExecutionMetricsArray []ExecutionMetrics
 )
 
-// GetExecutionMetrics fetches and computes a single execution's metrics.
-// Metrics are fetched using the given influxdb.Client and execUUID.
-func GetExecutionMetrics(client influxdb.Client, execUUID string, queries int) (ExecutionMetrics, error) {
-	execMetrics := NewExecMetrics()
-
-	for _, component := range components {
-		// CPU time
-		endValue, err := getSumFloatValueForQuery(client, fmt.Sprintf(cpuSecondsPerComponentEnd, client.Config.Database, execUUID, component))
-		if err != nil {
-			return ExecutionMetrics{}, err
-		}
-		startValue, err := getSumFloatValueForQuery(client, fmt.Sprintf(cpuSecondsPerComponentStart, client.Config.Database, execUUID, component))
-		if err != nil {
-			return ExecutionMetrics{}, err
+// GetExecutionMetrics fetches and computes a single execution's metrics according to the given definitions.
+// Metrics are fetched from the given Source using execUUID.
+func GetExecutionMetrics(source Source, execUUID string, queries int, definitions []Definition) (ExecutionMetrics, error) {
+	execMetrics := NewExecMetrics(definitions)
+
+	for _, def := range definitions {
+		metric := execMetrics[def.Name]
+		for _, component := range def.Components {
+			value, err := source.Aggregate(def, execUUID, component)
+			if err != nil {
+				return nil, err
+			}
+
+			// Divide the metric by the number of queries that were executed
+			if def.Aggregation == AggregationPerQuery && queries > 0 {
+				value = value / float64(queries)
+			}
+			metric.Components[component] = value
+			metric.Total += value
 		}
-		execMetrics.ComponentsCPUTime[component] = endValue - startValue
-		execMetrics.TotalComponentsCPUTime += execMetrics.ComponentsCPUTime[component]
-
-		// Memory
-		endValue, err = getSumFloatValueForQuery(client, fmt.Sprintf(memAllocBytesPerComponentEnd, client.Config.Database, execUUID, component))
-		if err != nil {
-			return ExecutionMetrics{}, err
-		}
-
-		startValue, err = getSumFloatValueForQuery(client, fmt.Sprintf(memAllocBytesPerComponentStart, client.Config.Database, execUUID, component))
-		if err != nil {
-			return ExecutionMetrics{}, err
-		}
-
-		execMetrics.ComponentsMemStatsAllocBytes[component] = endValue - startValue
-		execMetrics.TotalComponentsMemStatsAllocBytes += execMetrics.ComponentsMemStatsAllocBytes[component]
+		execMetrics[def.Name] = metric
 	}
+	return execMetrics, nil
+}
 
-	// Divide all metrics by the number of queries that were executed
-	if queries > 0 {
-		execMetrics.TotalComponentsCPUTime = execMetrics.TotalComponentsCPUTime / float64(queries)
-		execMetrics.TotalComponentsMemStatsAllocBytes = execMetrics.TotalComponentsMemStatsAllocBytes / float64(queries)
-		for key, val := range execMetrics.ComponentsCPUTime {
-			execMetrics.ComponentsCPUTime[key] = val / float64(queries)
-		}
-		for key, val := range execMetrics.ComponentsMemStatsAllocBytes {
-			execMetrics.ComponentsMemStatsAllocBytes[key] = val / float64(queries)
+// NewExecMetrics returns an ExecutionMetrics with a zero value for every component of the given definitions.
+func NewExecMetrics(definitions []Definition) ExecutionMetrics {
+	execMetrics := make(ExecutionMetrics, len(definitions))
+	for _, def := range definitions {
+		metric := Metric{Components: make(map[string]float64, len(def.Components))}
+		for _, component := range def.Components {
+			metric.Components[component] = 0
 		}
+		execMetrics[def.Name] = metric
 	}
-	return execMetrics, nil
+	return execMetrics
 }
 
-func NewExecMetrics() ExecutionMetrics {
-	return ExecutionMetrics{
-		ComponentsCPUTime: map[string]float64{
-			"vtgate":   0,
-			"vttablet": 0,
-		},
-		ComponentsMemStatsAllocBytes: map[string]float64{
-			"vtgate":   0,
-			"vttablet": 0,
-		},
+// Set stores the given value using the name it is stored under in the metrics table,
+// either "Total<metric>" for the total or "<metric>.<component>" for a component.
+func (em ExecutionMetrics) Set(name string, value float64) {
+	metricName, component, isComponent := strings.Cut(name, ".")
+	if !isComponent {
+		metricName = strings.TrimPrefix(name, totalPrefix)
+	}
+
+	metric, ok := em[metricName]
+	if !ok {
+		metric = Metric{Components: map[string]float64{}}
+	}
+	if isComponent {
+		metric.Components[component] = value
+	} else {
+		metric.Total = value
 	}
+	em[metricName] = metric
 }
 
 func InsertExecutionMetrics(client storage.SQLClient, execUUID string, execMetrics ExecutionMetrics) error {
-	query := "INSERT INTO metrics(exec_uuid, `name`, `value`) VALUES (?, ?, ?), (?, ?, ?)"
-	args := []interface{}{
-		execUUID, "TotalComponentsCPUTime", execMetrics.TotalComponentsCPUTime,
-		execUUID, "TotalComponentsMemStatsAllocBytes", execMetrics.TotalComponentsMemStatsAllocBytes,
+	if len(execMetrics) == 0 {
+		return nil
 	}
-	for k, v := range execMetrics.ComponentsCPUTime {
-		query += ", (?,?,?)"
-		args = append(args, []interface{}{
-			execUUID, "ComponentsCPUTime." + k, v,
-		}...)
-	}
-	for k, v := range execMetrics.ComponentsMemStatsAllocBytes {
-		query += ", (?,?,?)"
-		args = append(args, []interface{}{
-			execUUID, "ComponentsMemStatsAllocBytes." + k, v,
-		}...)
+
+	query := "INSERT INTO metrics(exec_uuid, `name`, `value`) VALUES"
+	var rows [][]interface{}
+	for name, metric := range execMetrics {
+		rows = append(rows, []interface{}{execUUID, totalPrefix + name, metric.Total})
+		for k, v := range metric.Components {
+			rows = append(rows, []interface{}{execUUID, name + "." + k, v})
+		}
 	}
-	_, err := client.Write(query, args...)
-	return err
+	return storage.BulkInsert(context.Background(), client, query, rows)
 }
 
 func GetExecutionMetricsSQL(client storage.SQLClient, execUUID string) (ExecutionMetrics, error) {
 	query := "select `name`, value from metrics where exec_uuid = ?"
 	rows, err := client.Read(query, execUUID)
 	if err != nil {
-		return ExecutionMetrics{}, err
+		return nil, err
 	}
 	defer rows.Close()
 
-	result := NewExecMetrics()
+	result := NewExecMetrics(DefaultDefinitions)
 	for rows.Next() {
 		var name string
 		var value float64
 		err = rows.Scan(&name, &value)
 		if err != nil {
-			return ExecutionMetrics{}, err
-		}
-		switch {
-		case name == "TotalComponentsCPUTime":
-			result.TotalComponentsCPUTime = value
-		case name == "TotalComponentsMemStatsAllocBytes":
-			result.TotalComponentsMemStatsAllocBytes = value
-		case strings.HasPrefix(name, "ComponentsCPUTime."):
-			key := strings.Split(name, ".")[1]
-			result.ComponentsCPUTime[key] = value
-		case strings.HasPrefix(name, "ComponentsMemStatsAllocBytes."):
-			key := strings.Split(name, ".")[1]
-			result.ComponentsMemStatsAllocBytes[key] = value
+			return nil, err
 		}
+		result.Set(name, value)
 	}
 	return result, nil
 }
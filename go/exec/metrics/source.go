@@ -0,0 +1,203 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/vitessio/arewefastyet/go/storage/influxdb"
+	"github.com/vitessio/arewefastyet/go/storage/prometheus"
+)
+
+const (
+	ErrorUnknownSourceType = "unknown metrics source"
+
+	// SourceInfluxDB reads the metrics from InfluxDB using Flux queries.
+	SourceInfluxDB = "influxdb"
+
+	// SourcePrometheus reads the metrics from the Prometheus HTTP API using range queries.
+	SourcePrometheus = "prometheus"
+
+	flagMetricsSource = "metrics-source"
+)
+
+type (
+	// Source fetches the values of the metrics gathered during an execution.
+	Source interface {
+		// Aggregate returns the value of the given Definition for a single component
+		// of an execution, reduced using the Definition's aggregation. AggregationPerQuery
+		// is reduced like AggregationDelta, the division is left to the caller.
+		Aggregate(def Definition, execUUID, component string) (float64, error)
+	}
+
+	// SourceConfig selects and configures the Source of the metrics.
+	SourceConfig struct {
+		// Type is either SourceInfluxDB or SourcePrometheus.
+		Type string
+
+		InfluxDB   *influxdb.Config
+		Prometheus *prometheus.Config
+	}
+
+	influxDBSource struct {
+		client *influxdb.Client
+	}
+
+	// prometheusSource queries Prometheus over the time window of the execution,
+	// the series are selected using their exec_uuid and component labels.
+	prometheusSource struct {
+		client     *prometheus.Client
+		start, end time.Time
+	}
+)
+
+// NewSourceConfig returns an empty SourceConfig that uses SourceInfluxDB.
+func NewSourceConfig() *SourceConfig {
+	return &SourceConfig{
+		Type:       SourceInfluxDB,
+		InfluxDB:   &influxdb.Config{},
+		Prometheus: &prometheus.Config{},
+	}
+}
+
+// IsValid returns true if the configuration of the selected Source is valid.
+func (cfg *SourceConfig) IsValid() bool {
+	if cfg == nil {
+		return false
+	}
+	switch cfg.Type {
+	case SourcePrometheus:
+		return cfg.Prometheus != nil && cfg.Prometheus.IsValid()
+	case SourceInfluxDB, "":
+		return cfg.InfluxDB != nil && cfg.InfluxDB.IsValid()
+	}
+	return false
+}
+
+// NewSource creates the Source selected by the configuration. The start and end of
+// the execution are used by the sources that query a time range, a zero end means the
+// execution ends when the metrics are fetched.
+func (cfg *SourceConfig) NewSource(start, end time.Time) (Source, error) {
+	switch cfg.Type {
+	case SourcePrometheus:
+		client, err := cfg.Prometheus.NewClient()
+		if err != nil {
+			return nil, err
+		}
+		return &prometheusSource{client: client, start: start, end: end}, nil
+	case SourceInfluxDB, "":
+		client, err := cfg.InfluxDB.NewClient()
+		if err != nil {
+			return nil, err
+		}
+		return &influxDBSource{client: client}, nil
+	}
+	return nil, fmt.Errorf("%s: %s", ErrorUnknownSourceType, cfg.Type)
+}
+
+func (cfg *SourceConfig) AddToViper(v *viper.Viper) {
+	_ = v.UnmarshalKey(flagMetricsSource, &cfg.Type)
+	cfg.InfluxDB.AddToViper(v)
+	cfg.Prometheus.AddToViper(v)
+}
+
+// AddToCommand adds SourceConfig, and the configuration of all the sources, to the given cobra.Command.
+func (cfg *SourceConfig) AddToCommand(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&cfg.Type, flagMetricsSource, SourceInfluxDB, fmt.Sprintf("Source of the execution metrics, either %q or %q.", SourceInfluxDB, SourcePrometheus))
+	_ = viper.BindPFlag(flagMetricsSource, cmd.Flags().Lookup(flagMetricsSource))
+
+	cfg.InfluxDB.AddToCommand(cmd)
+	cfg.Prometheus.AddToCommand(cmd)
+}
+
+func (s *influxDBSource) Aggregate(def Definition, execUUID, component string) (float64, error) {
+	format := func(query string) string {
+		return fmt.Sprintf(query, s.client.Config.Database, def.Source, execUUID, component)
+	}
+
+	switch def.Aggregation {
+	case AggregationMax:
+		return getSumFloatValueForQuery(*s.client, format(queryLastCounterValue))
+	case AggregationAvg:
+		return getSumFloatValueForQuery(*s.client, format(queryMeanValue))
+	}
+
+	endValue, err := getSumFloatValueForQuery(*s.client, format(queryLastCounterValue))
+	if err != nil {
+		return 0, err
+	}
+	startValue, err := getSumFloatValueForQuery(*s.client, format(queryFirstCounterValue))
+	if err != nil {
+		return 0, err
+	}
+	return endValue - startValue, nil
+}
+
+func (s *prometheusSource) Aggregate(def Definition, execUUID, component string) (float64, error) {
+	query := fmt.Sprintf(`%s{exec_uuid=%q, component=%q}`, def.Source, execUUID, component)
+	end := s.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+	series, err := s.client.QueryRange(query, s.start, end)
+	if err != nil {
+		return 0, err
+	}
+
+	// like with InfluxDB, each series is reduced on its own and the results are summed
+	var res float64
+	for _, serie := range series {
+		res += aggregateValues(def.Aggregation, serie.Values)
+	}
+	return res, nil
+}
+
+// aggregateValues reduces the values of a single series using the given aggregation.
+func aggregateValues(aggregation string, values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	maxValue, sum := math.Inf(-1), 0.0
+	for _, v := range values {
+		maxValue = math.Max(maxValue, v)
+		sum += v
+	}
+
+	switch aggregation {
+	case AggregationMax:
+		return maxValue
+	case AggregationAvg:
+		return sum / float64(len(values))
+	}
+
+	// the first value of a counter is the lowest positive one
+	minPositive := math.Inf(1)
+	for _, v := range values {
+		if v > 0 {
+			minPositive = math.Min(minPositive, v)
+		}
+	}
+	if math.IsInf(minPositive, 1) {
+		return 0
+	}
+	return maxValue - minPositive
+}
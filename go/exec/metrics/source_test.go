@@ -0,0 +1,44 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestAggregateValues(t *testing.T) {
+	tests := []struct {
+		aggregation string
+		values      []float64
+		want        float64
+	}{
+		{aggregation: AggregationDelta, values: []float64{0, 10, 15, 30}, want: 20},
+		{aggregation: AggregationPerQuery, values: []float64{5, 10}, want: 5},
+		{aggregation: AggregationDelta, values: []float64{0, 0}, want: 0},
+		{aggregation: AggregationMax, values: []float64{3, 7, 2}, want: 7},
+		{aggregation: AggregationAvg, values: []float64{3, 7, 2}, want: 4},
+		{aggregation: AggregationAvg, values: nil, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.aggregation, func(t *testing.T) {
+			c := qt.New(t)
+			c.Assert(aggregateValues(tt.aggregation, tt.values), qt.Equals, tt.want)
+		})
+	}
+}
@@ -26,6 +26,7 @@ import (
 
 	qt "github.com/frankban/quicktest"
 	"github.com/google/uuid"
+	"github.com/spf13/viper"
 )
 
 func Test_createDirFromUUID(t *testing.T) {
@@ -71,3 +72,21 @@ func Test_createDirFromUUID(t *testing.T) {
 		})
 	}
 }
+
+func TestOutputPath(t *testing.T) {
+	c := qt.New(t)
+	v := viper.New()
+	v.Set(flagRootExec, "/tmp/awfy")
+	execUUID := uuid.NewString()
+
+	got, err := OutputPath(v, execUUID, false)
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.Equals, path.Join("/tmp/awfy", execDir, execUUID, stdoutFile))
+
+	got, err = OutputPath(v, execUUID, true)
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.Equals, path.Join("/tmp/awfy", execDir, execUUID, stderrFile))
+
+	_, err = OutputPath(v, "../../etc", false)
+	c.Assert(err, qt.Not(qt.IsNil))
+}
@@ -27,6 +27,9 @@ const (
 	StatusStarted  = "started"
 	StatusFailed   = "failed"
 	StatusFinished = "finished"
+
+	// StatusInvalidated marks a finished execution whose results must not be used anymore.
+	StatusInvalidated = "invalidated"
 )
 
 type BenchmarkStats struct {
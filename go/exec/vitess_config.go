@@ -116,6 +116,12 @@ func getVitessConfigFromMap(value map[string]interface{}, key string, set *strin
 	return nil
 }
 
+// addExtraFlags appends the given flags to the flags of vtgate and vttablet.
+func (vcfg *vitessConfig) addExtraFlags(vtgate, vttablet string) {
+	vcfg.vtgate = strings.TrimSpace(vcfg.vtgate + " " + vtgate)
+	vcfg.vttablet = strings.TrimSpace(vcfg.vttablet + " " + vttablet)
+}
+
 func (vcfg vitessConfig) addToAnsible(ansibleCfg *ansible.Config) {
 	if len(vcfg.vtgate) != 0 {
 		ansibleCfg.AddExtraVar(ansible.KeyExtraFlagsVTGate, vcfg.vtgate)
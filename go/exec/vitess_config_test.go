@@ -131,3 +131,12 @@ func Test_prepareVitessConfiguration(t *testing.T) {
 		})
 	}
 }
+
+func Test_addExtraFlags(t *testing.T) {
+	vcfg := vitessConfig{vtgate: "--toto=1"}
+	vcfg.addExtraFlags("--foo=bar", "--titi=2")
+	require.Equal(t, vitessConfig{vtgate: "--toto=1 --foo=bar", vttablet: "--titi=2"}, vcfg)
+
+	vcfg.addExtraFlags("", "")
+	require.Equal(t, vitessConfig{vtgate: "--toto=1 --foo=bar", vttablet: "--titi=2"}, vcfg)
+}
@@ -87,6 +87,12 @@ const (
 	// 		"--flag1 --flag2"
 	KeyExtraFlagsVTTablet = "extra_vttablet_flags"
 
+	// Microbenchmark related keys
+
+	// KeyMicrobenchPackages corresponding value in the map is the comma-separated list of
+	// the only packages to microbenchmark. It is not set when all the packages are benchmarked.
+	KeyMicrobenchPackages = "microbench_packages"
+
 	// Runtime related keys
 
 	// KeyGoVersion corresponding value in the map is the golang version to use for
@@ -19,6 +19,7 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"sort"
@@ -27,7 +28,9 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/vitessio/arewefastyet/go/exec"
+	"github.com/vitessio/arewefastyet/go/server/api"
 	"github.com/vitessio/arewefastyet/go/tools/git"
 	"github.com/vitessio/arewefastyet/go/tools/github"
 	"github.com/vitessio/arewefastyet/go/tools/macrobench"
@@ -35,98 +38,152 @@ import (
 	"golang.org/x/exp/slices"
 )
 
-type ErrorAPI struct {
-	Error string `json:"error"`
-}
-
-type ExecutionQueue struct {
-	Source   string `json:"source"`
-	GitRef   string `json:"git_ref"`
-	Workload string `json:"workload"`
-	PullNb   int    `json:"pull_nb"`
+func newExecutionQueue(identifier executionIdentifier) api.ExecutionQueue {
+	return api.ExecutionQueue{
+		UUID:     identifier.UUID,
+		Source:   identifier.Source,
+		GitRef:   identifier.GitRef,
+		Workload: identifier.Workload,
+		PullNb:   identifier.PullNb,
+	}
 }
 
-type RecentExecutions struct {
-	UUID          string     `json:"uuid"`
-	Source        string     `json:"source"`
-	GitRef        string     `json:"git_ref"`
-	Status        string     `json:"status"`
-	Workload      string     `json:"workload"`
-	PullNb        int        `json:"pull_nb"`
-	GolangVersion string     `json:"golang_version"`
-	StartedAt     *time.Time `json:"started_at"`
-	FinishedAt    *time.Time `json:"finished_at"`
+func (s *Server) getWorkloadList(c *gin.Context) {
+	c.JSON(http.StatusOK, s.workloads)
 }
 
-type ExecutionMetadatas struct {
-	Workloads []string `json:"workloads"`
-	Sources   []string `json:"sources"`
-	Statuses  []string `json:"statuses"`
+// getExecutionFilter returns the exec.ExecutionFilter set by the optional "source",
+// "workload", "status", "go_version", "git_ref", "pr", "since" and "until" query
+// parameters. The dates are either RFC 3339 timestamps or YYYY-MM-DD days.
+func getExecutionFilter(c *gin.Context) (exec.ExecutionFilter, error) {
+	filter := exec.ExecutionFilter{
+		Source:        c.Query("source"),
+		Workload:      c.Query("workload"),
+		Status:        c.Query("status"),
+		GolangVersion: c.Query("go_version"),
+		GitRefPrefix:  c.Query("git_ref"),
+	}
+	if pr := c.Query("pr"); pr != "" {
+		v, err := strconv.Atoi(pr)
+		if err != nil {
+			return exec.ExecutionFilter{}, fmt.Errorf("invalid pr: %v", err)
+		}
+		filter.PullNB = v
+	}
+	var err error
+	if filter.Since, err = getDateParam(c, "since"); err != nil {
+		return exec.ExecutionFilter{}, err
+	}
+	if filter.Until, err = getDateParam(c, "until"); err != nil {
+		return exec.ExecutionFilter{}, err
+	}
+	return filter, nil
 }
 
-type RecentExecutionsResponse struct {
-	Executions []RecentExecutions `json:"executions"`
-	ExecutionMetadatas
+// getDateParam returns the date of the given query parameter, either a RFC 3339
+// timestamp or a YYYY-MM-DD day, or nil if it is not set.
+func getDateParam(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t, err = time.Parse(time.DateOnly, value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", name, value)
+	}
+	return &t, nil
 }
 
-type ExecutionQueueResponse struct {
-	Executions []ExecutionQueue `json:"executions"`
-	ExecutionMetadatas
+// getPage returns the exec.Page requested through the optional "limit" and "cursor"
+// query parameters.
+func getPage(c *gin.Context) (exec.Page, error) {
+	page := exec.Page{Cursor: c.Query("cursor")}
+	if limit := c.Query("limit"); limit != "" {
+		v, err := strconv.Atoi(limit)
+		if err != nil || v <= 0 {
+			return exec.Page{}, fmt.Errorf("invalid limit: %s", limit)
+		}
+		page.Limit = v
+	}
+	return page, nil
 }
 
-func (s *Server) getWorkloadList(c *gin.Context) {
-	c.JSON(http.StatusOK, s.workloads)
+// listingErrorStatus returns the HTTP status of an error returned when listing
+// executions, an invalid cursor is a bad request.
+func listingErrorStatus(err error) int {
+	if err.Error() == exec.ErrorInvalidCursor {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
 }
 
 func (s *Server) getRecentExecutions(c *gin.Context) {
-	execs, err := exec.GetRecentExecutions(s.dbClient)
+	filter, err := getExecutionFilter(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &ErrorAPI{Error: err.Error()})
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: err.Error()})
 		slog.Error(err)
 		return
 	}
-	response := RecentExecutionsResponse{
-		Executions: make([]RecentExecutions, 0, len(execs)),
+	page, err := getPage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+
+	execs, nextCursor, err := exec.GetRecentExecutions(s.dbClient, filter, page)
+	if err != nil {
+		c.JSON(listingErrorStatus(err), &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+	facets, err := exec.GetExecutionFacets(s.dbClient, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+
+	response := api.RecentExecutionsResponse{
+		Executions: make([]api.RecentExecutions, 0, len(execs)),
+		ExecutionMetadatas: api.ExecutionMetadatas{
+			Workloads: facets.Workloads,
+			Sources:   facets.Sources,
+			Statuses:  facets.Statuses,
+		},
 	}
 	for _, e := range execs {
-		response.Executions = append(response.Executions, RecentExecutions{
-			UUID:          e.RawUUID,
-			Source:        e.Source,
-			GitRef:        e.GitRef,
-			Status:        e.Status,
-			Workload:      e.Workload,
-			PullNb:        e.PullNB,
-			GolangVersion: e.GolangVersion,
-			StartedAt:     e.StartedAt,
-			FinishedAt:    e.FinishedAt,
+		response.Executions = append(response.Executions, api.RecentExecutions{
+			UUID:               e.RawUUID,
+			Source:             e.Source,
+			GitRef:             e.GitRef,
+			Status:             e.Status,
+			Workload:           e.Workload,
+			PullNb:             e.PullNB,
+			GolangVersion:      e.GolangVersion,
+			MicrobenchPackages: e.MicrobenchPackages,
+			StartedAt:          e.StartedAt,
+			FinishedAt:         e.FinishedAt,
 		})
-		if !slices.Contains(response.Workloads, e.Workload) {
-			response.Workloads = append(response.Workloads, e.Workload)
-		}
-		if !slices.Contains(response.Statuses, e.Status) {
-			response.Statuses = append(response.Statuses, e.Status)
-		}
-		if !slices.Contains(response.Sources, e.Source) {
-			response.Sources = append(response.Sources, e.Source)
-		}
+	}
+	if nextCursor != "" {
+		c.Header(api.HeaderNextCursor, nextCursor)
 	}
 	c.JSON(http.StatusOK, response)
 }
 
 func (s *Server) getExecutionsQueue(c *gin.Context) {
-	response := ExecutionQueueResponse{
-		Executions: make([]ExecutionQueue, 0, len(queue)),
+	response := api.ExecutionQueueResponse{
+		Executions: make([]api.ExecutionQueue, 0, len(queue)),
 	}
 	for _, e := range queue {
 		if e.Executing {
 			continue
 		}
-		response.Executions = append(response.Executions, ExecutionQueue{
-			Source:   e.identifier.Source,
-			GitRef:   e.identifier.GitRef,
-			Workload: e.identifier.Workload,
-			PullNb:   e.identifier.PullNb,
-		})
+		response.Executions = append(response.Executions, newExecutionQueue(e.identifier))
 		if !slices.Contains(response.Workloads, e.identifier.Workload) {
 			response.Workloads = append(response.Workloads, e.identifier.Workload)
 		}
@@ -140,22 +197,19 @@ func (s *Server) getExecutionsQueue(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-type VitessGitRefReleases struct {
-	Tags     []*git.Release `json:"tags"`
-	Branches []*git.Release `json:"branches"`
-}
-
 func (s *Server) getLatestVitessGitRef(c *gin.Context) {
-	var response VitessGitRefReleases
+	var response api.VitessGitRefReleases
+	s.vitessPathMu.RLock()
 	allReleases, err := git.GetLatestVitessReleaseCommitHash(s.getVitessPath())
+	s.vitessPathMu.RUnlock()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &ErrorAPI{Error: err.Error()})
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
 		slog.Error(err)
 		return
 	}
 	lastrunDailySHA, err := exec.GetLatestDailyJobForMacrobenchmarks(s.dbClient)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &ErrorAPI{Error: err.Error()})
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
 		slog.Error(err)
 		return
 	}
@@ -165,9 +219,11 @@ func (s *Server) getLatestVitessGitRef(c *gin.Context) {
 	}
 	response.Branches = append(response.Branches, mainRelease)
 	// get all the latest release branches as well
+	s.vitessPathMu.RLock()
 	allReleaseBranches, err := git.GetLatestVitessReleaseBranchCommitHash(s.getVitessPath())
+	s.vitessPathMu.RUnlock()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &ErrorAPI{Error: err.Error()})
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
 		slog.Error(err)
 		return
 	}
@@ -177,25 +233,51 @@ func (s *Server) getLatestVitessGitRef(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-type CompareMacrobench struct {
-	Workload string                               `json:"workload"`
-	Result   macrobench.StatisticalCompareResults `json:"result"`
+// getComparisonMethod returns the macrobench.ComparisonMethod requested through the
+// optional "method", "alpha", "confidence" and "correction" query parameters.
+func getComparisonMethod(c *gin.Context) (macrobench.ComparisonMethod, error) {
+	cfg := macrobench.DefaultStatisticalConfig()
+	if alpha := c.Query("alpha"); alpha != "" {
+		v, err := strconv.ParseFloat(alpha, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid alpha: %v", err)
+		}
+		cfg.Alpha = v
+	}
+	if confidence := c.Query("confidence"); confidence != "" {
+		v, err := strconv.ParseFloat(confidence, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid confidence: %v", err)
+		}
+		cfg.Confidence = v
+	}
+	if correction := c.Query("correction"); correction != "" {
+		cfg.Correction = correction
+	}
+	return macrobench.NewComparisonMethod(c.Query("method"), cfg)
 }
 
 func (s *Server) compareMacroBenchmarks(c *gin.Context) {
 	oldSHA := c.Query("old")
 	newSHA := c.Query("new")
 
-	results, err := macrobench.Compare(s.dbClient, oldSHA, newSHA, s.workloads, macrobench.Gen4Planner)
+	method, err := getComparisonMethod(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+
+	results, err := macrobench.Compare(s.dbClient, oldSHA, newSHA, s.workloads, macrobench.Gen4Planner, method)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &ErrorAPI{Error: err.Error()})
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
 		slog.Error(err)
 		return
 	}
 
-	resultsSlice := make([]CompareMacrobench, 0, len(results))
+	resultsSlice := make([]api.CompareMacrobench, 0, len(results))
 	for workload, res := range results {
-		resultsSlice = append(resultsSlice, CompareMacrobench{
+		resultsSlice = append(resultsSlice, api.CompareMacrobench{
 			Workload: workload,
 			Result:   res,
 		})
@@ -212,28 +294,128 @@ func (s *Server) compareMicrobenchmarks(c *gin.Context) {
 	leftSHA := c.Query("ltag")
 	rightSHA := c.Query("rtag")
 
+	threshold := microbench.DefaultRegressionThreshold
+	if value := c.Query("threshold"); value != "" {
+		var err error
+		threshold, err = strconv.ParseFloat(value, 64)
+		if err != nil || threshold < 0 {
+			c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: "invalid threshold: " + value})
+			return
+		}
+	}
+	method, err := getComparisonMethod(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+
 	// Get the results from the SHAs
 	leftMbd, err := microbench.GetResultsForGitRef(leftSHA, s.dbClient)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &ErrorAPI{Error: err.Error()})
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
 		slog.Error(err)
 		return
 	}
-	leftMbd = leftMbd.ReduceSimpleMedianByName()
 	rightMbd, err := microbench.GetResultsForGitRef(rightSHA, s.dbClient)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &ErrorAPI{Error: err.Error()})
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
 		slog.Error(err)
 		return
 	}
-	rightMbd = rightMbd.ReduceSimpleMedianByName()
 
-	matrix := microbench.MergeDetails(rightMbd, leftMbd)
+	matrix := microbench.CompareDetails(rightMbd, leftMbd, method)
+	matrix.SetRegressions(threshold)
 	c.JSON(http.StatusOK, matrix)
 }
 
-type searchResult struct {
-	Macros map[string]macrobench.StatisticalSingleResult
+// getMicrobenchHistory returns the history of the sub-benchmarks of a microbenchmark over
+// the cron runs, along with their step changes.
+func (s *Server) getMicrobenchHistory(c *gin.Context) {
+	filter := microbench.HistoryFilter{PkgName: c.Query("pkg"), Name: c.Query("name")}
+	if filter.PkgName == "" || filter.Name == "" {
+		err := errors.New("missing argument: pkg and name are required")
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+
+	var err error
+	if filter.Since, err = getDateParam(c, "since"); err != nil {
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+	if filter.Until, err = getDateParam(c, "until"); err != nil {
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+	window := microbench.DefaultStepWindow
+	threshold := microbench.DefaultStepThreshold
+	for _, param := range []struct {
+		name string
+		dest *int
+	}{
+		{name: "last", dest: &filter.Last},
+		{name: "window", dest: &window},
+	} {
+		if value := c.Query(param.name); value != "" {
+			v, err := strconv.Atoi(value)
+			if err != nil || v <= 0 {
+				c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: fmt.Sprintf("invalid %s: %s", param.name, value)})
+				return
+			}
+			*param.dest = v
+		}
+	}
+	if value := c.Query("threshold"); value != "" {
+		threshold, err = strconv.ParseFloat(value, 64)
+		if err != nil || threshold < 0 {
+			c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: "invalid threshold: " + value})
+			return
+		}
+	}
+	method, err := getComparisonMethod(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+
+	results, err := microbench.GetHistoryResults(s.dbClient, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+	histories := microbench.History(results, method)
+	for i := range histories {
+		histories[i].SetStepChanges(method, window, threshold)
+	}
+
+	// the history is still useful without the metadata of the commits
+	var shas []string
+	seen := map[string]bool{}
+	for _, details := range results {
+		if !seen[details.GitRef] {
+			seen[details.GitRef] = true
+			shas = append(shas, details.GitRef)
+		}
+	}
+	s.vitessPathMu.RLock()
+	commits, err := git.GetCommits(s.getVitessPath(), shas)
+	s.vitessPathMu.RUnlock()
+	if err != nil {
+		slog.Error(err)
+	} else {
+		microbench.SetCommits(histories, commits)
+	}
+
+	if histories == nil {
+		histories = []microbench.BenchmarkHistory{}
+	}
+	c.JSON(http.StatusOK, histories)
 }
 
 func (s *Server) searchBenchmark(c *gin.Context) {
@@ -241,12 +423,12 @@ func (s *Server) searchBenchmark(c *gin.Context) {
 
 	results, err := macrobench.Search(s.dbClient, sha, s.workloads, macrobench.Gen4Planner)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &ErrorAPI{Error: err.Error()})
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
 		slog.Error(err)
 		return
 	}
 
-	var res searchResult
+	var res api.SearchResult
 	res.Macros = results
 
 	c.JSON(http.StatusOK, res)
@@ -258,7 +440,7 @@ func (s *Server) queriesCompareMacrobenchmarks(c *gin.Context) {
 	workload := macrobench.Workload(c.Query("workload"))
 
 	if leftGitRef == "" || rightGitRef == "" || workload == "" {
-		c.JSON(http.StatusBadRequest, &ErrorAPI{Error: "The gitref left and right and/or the workload are missing"})
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: "The gitref left and right and/or the workload are missing"})
 		return
 	}
 
@@ -270,13 +452,47 @@ func (s *Server) queriesCompareMacrobenchmarks(c *gin.Context) {
 	c.JSON(http.StatusOK, comparison)
 }
 
+func (s *Server) getMacrobenchIntervals(c *gin.Context) {
+	uuid := c.Query("uuid")
+	if uuid == "" {
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: "missing argument: uuid"})
+		return
+	}
+
+	intervals, err := macrobench.GetExecutionIntervals(s.dbClient, uuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, intervals)
+}
+
+func (s *Server) getMacrobenchLatencyHistogram(c *gin.Context) {
+	uuid := c.Query("uuid")
+	if uuid == "" {
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: "missing argument: uuid"})
+		return
+	}
+
+	histogram, err := macrobench.GetExecutionLatencyHistogram(s.dbClient, uuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, histogram)
+}
+
 func (s *Server) fkQueriesCompareMacrobenchmarks(c *gin.Context) {
 	gitRef := c.Query("gitRef")
 	oldWorkload := macrobench.Workload(c.Query("oldWorkload"))
 	newWorkload := macrobench.Workload(c.Query("newWorkload"))
 
 	if gitRef == "" || oldWorkload == "" || newWorkload == "" {
-		c.JSON(http.StatusBadRequest, &ErrorAPI{Error: "The gitref and the two workloads are incorrect or missing. Please kindly add them."})
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: "The gitref and the two workloads are incorrect or missing. Please kindly add them."})
 		return
 	}
 
@@ -291,13 +507,13 @@ func (s *Server) fkQueriesCompareMacrobenchmarks(c *gin.Context) {
 func (s *Server) queriesCompare(c *gin.Context, oldGitRef, newGitRef string, oldWorkload, newWorkload macrobench.Workload) []macrobench.VTGateQueryPlanComparer {
 	oldPlans, err := macrobench.GetVTGateSelectQueryPlansWithFilter(oldGitRef, oldWorkload, macrobench.Gen4Planner, s.dbClient)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &ErrorAPI{Error: err.Error()})
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
 		slog.Error(err)
 		return nil
 	}
 	newPlans, err := macrobench.GetVTGateSelectQueryPlansWithFilter(newGitRef, newWorkload, macrobench.Gen4Planner, s.dbClient)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &ErrorAPI{Error: err.Error()})
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
 		slog.Error(err)
 		return nil
 	}
@@ -307,7 +523,7 @@ func (s *Server) queriesCompare(c *gin.Context, oldGitRef, newGitRef string, old
 func (s *Server) getPullRequest(c *gin.Context) {
 	prNumbers, err := exec.GetPullRequestList(s.dbClient)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &ErrorAPI{Error: err.Error()})
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
 		slog.Error(err)
 		return
 	}
@@ -315,7 +531,7 @@ func (s *Server) getPullRequest(c *gin.Context) {
 	for _, prNumber := range prNumbers {
 		newPRInfo, err := s.ghApp.GetPullRequestInfo(prNumber)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, &ErrorAPI{Error: err.Error()})
+			c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
 			slog.Error(err)
 			return
 		}
@@ -328,20 +544,20 @@ func (s *Server) getPullRequestInfo(c *gin.Context) {
 	pullNbStr := c.Param("nb")
 	pullNb, err := strconv.Atoi(pullNbStr)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &ErrorAPI{Error: err.Error()})
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
 		slog.Error(err)
 		return
 	}
 	gitPRInfo, err := exec.GetPullRequestInfo(s.dbClient, pullNb)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &ErrorAPI{Error: err.Error()})
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
 		slog.Error(err)
 		return
 	}
 
 	prInfo, err := s.ghApp.GetPullRequestInfo(pullNb)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &ErrorAPI{Error: err.Error()})
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
 		slog.Error(err)
 		return
 	}
@@ -350,11 +566,6 @@ func (s *Server) getPullRequestInfo(c *gin.Context) {
 	c.JSON(http.StatusOK, prInfo)
 }
 
-type dailySummaryResp struct {
-	Name string                                    `json:"name"`
-	Data []macrobench.ShortStatisticalSingleResult `json:"data"`
-}
-
 func (s *Server) getDailySummary(c *gin.Context) {
 	// Query array allows to get multiple values for the same key
 	// For example: /api/daily/summary?workloads=TPCC&workloads=OLTP
@@ -365,20 +576,20 @@ func (s *Server) getDailySummary(c *gin.Context) {
 		for _, workload := range workloads {
 			workload = strings.ToUpper(workload)
 			if !slices.Contains(s.workloads, workload) {
-				c.JSON(http.StatusBadRequest, &ErrorAPI{Error: "Wrong workload specified"})
+				c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: "Wrong workload specified"})
 				return
 			}
 		}
 	}
-	results, err := macrobench.SearchForLast30DaysQPSOnly(s.dbClient, workloads, macrobench.Gen4Planner)
+	results, err := macrobench.SearchForLast30DaysQPSOnly(s.dbClient, workloads, macrobench.Gen4Planner, s.execInitialRuns)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &ErrorAPI{Error: err.Error()})
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
 		slog.Error(err)
 		return
 	}
-	var resp []dailySummaryResp
+	var resp []api.DailySummary
 	for name, result := range results {
-		resp = append(resp, dailySummaryResp{
+		resp = append(resp, api.DailySummary{
 			Name: name,
 			Data: result,
 		})
@@ -394,7 +605,7 @@ func (s *Server) getDaily(c *gin.Context) {
 	workload := c.Query("workload")
 	data, err := macrobench.SearchForLast30Days(s.dbClient, workload, macrobench.Gen4Planner)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &ErrorAPI{Error: err.Error()})
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
 		slog.Error(err)
 		return
 	}
@@ -404,7 +615,7 @@ func (s *Server) getDaily(c *gin.Context) {
 func (s *Server) getStatusStats(c *gin.Context) {
 	stats, err := exec.GetBenchmarkStats(s.dbClient)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &ErrorAPI{Error: err.Error()})
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
 		slog.Error(err)
 		return
 	}
@@ -412,37 +623,33 @@ func (s *Server) getStatusStats(c *gin.Context) {
 }
 
 func (s *Server) requestRun(c *gin.Context) {
-	workload := c.Query("workload")
+	if !s.authorized(c) {
+		return
+	}
+
+	workloads := splitQueryList(c.Query("workload"))
 	sha := c.Query("sha")
-	pswd := c.Query("key")
 	v := c.Query("version")
+	planner := c.DefaultQuery("planner", string(macrobench.Gen4Planner))
 
 	errStrFmt := "missing argument: %s"
-	if workload == "" {
+	if len(workloads) == 0 {
 		errStr := fmt.Sprintf(errStrFmt, "workload")
-		c.JSON(http.StatusBadRequest, &ErrorAPI{Error: errStr})
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: errStr})
 		slog.Error(errStr)
 		return
 	}
 
 	if sha == "" {
 		errStr := fmt.Sprintf(errStrFmt, "sha")
-		c.JSON(http.StatusBadRequest, &ErrorAPI{Error: errStr})
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: errStr})
 		slog.Error(errStr)
 		return
 	}
 
 	if v == "" {
 		errStr := fmt.Sprintf(errStrFmt, "version")
-		c.JSON(http.StatusBadRequest, &ErrorAPI{Error: errStr})
-		slog.Error(errStr)
-		return
-	}
-
-	// check request run key is correct
-	if pswd != s.requestRunKey {
-		errStr := "unauthorized, wrong key"
-		c.JSON(http.StatusUnauthorized, &ErrorAPI{Error: errStr})
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: errStr})
 		slog.Error(errStr)
 		return
 	}
@@ -450,61 +657,74 @@ func (s *Server) requestRun(c *gin.Context) {
 	// get version from URL
 	version, err := strconv.Atoi(v)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &ErrorAPI{Error: err.Error()})
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: err.Error()})
 		slog.Error(err)
 		return
 	}
 	currVersion := git.Version{Major: version}
 
+	// all the workloads are checked before adding anything to the queue
 	configs := s.getConfigFiles()
-	cfg, ok := configs[strings.ToLower(workload)]
-	if !ok {
-		errMsg := "unknown benchmark workload: " + strings.ToUpper(workload)
-		c.JSON(http.StatusBadRequest, &ErrorAPI{Error: errMsg})
-		slog.Error(errMsg)
-		return
+	for _, workload := range workloads {
+		if _, ok := configs[strings.ToLower(workload)]; !ok {
+			errMsg := "unknown benchmark workload: " + strings.ToUpper(workload)
+			c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: errMsg})
+			slog.Error(errMsg)
+			return
+		}
 	}
 
-	// create execution element
-	elem := s.createSimpleExecutionQueueElement(cfg, "custom_run", sha, workload, string(macrobench.Gen4Planner), false, 0, currVersion)
+	response := api.RunRequestResponse{Executions: []api.ExecutionQueue{}}
+	for _, workload := range workloads {
+		// create execution element, the UUID of a microbenchmark run is set here while
+		// addToQueue sets the UUIDs of the macrobenchmark runs it adds, the response lists
+		// the UUIDs that were queued so they can be followed
+		elem := s.createSimpleExecutionQueueElement(configs[strings.ToLower(workload)], "custom_run", sha, workload, planner, false, 0, currVersion)
+		elem.identifier.UUID = uuid.NewString()
+		elem.identifier.VtgateFlags = c.Query("vtgate_flags")
+		elem.identifier.VttabletFlags = c.Query("vttablet_flags")
 
-	// to new element to the queue
-	s.addToQueue(elem)
+		// to new element to the queue
+		for _, identifier := range s.addToQueue(elem) {
+			response.Executions = append(response.Executions, newExecutionQueue(identifier))
+		}
+	}
 
-	c.JSON(http.StatusCreated, "created")
+	// nothing is queued when the runs already exist or are already in the queue
+	status := http.StatusCreated
+	if len(response.Executions) == 0 {
+		status = http.StatusOK
+	}
+	c.JSON(status, response)
 }
 
 func (s *Server) deleteRun(c *gin.Context) {
+	if !s.authorized(c) {
+		return
+	}
+
 	uuid := c.Query("uuid")
 	sha := c.Query("sha")
-	pswd := c.Query("key")
 
 	errStrFmt := "missing argument: %s"
 	if uuid == "" {
 		errStr := fmt.Sprintf(errStrFmt, "uuid")
-		c.JSON(http.StatusBadRequest, &ErrorAPI{Error: errStr})
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: errStr})
 		slog.Error(errStr)
 		return
 	}
 
 	if sha == "" {
 		errStr := fmt.Sprintf(errStrFmt, "sha")
-		c.JSON(http.StatusBadRequest, &ErrorAPI{Error: errStr})
-		slog.Error(errStr)
-		return
-	}
-
-	// check request run key is correct
-	if pswd != s.requestRunKey {
-		errStr := "unauthorized, wrong key"
-		c.JSON(http.StatusUnauthorized, &ErrorAPI{Error: errStr})
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: errStr})
 		slog.Error(errStr)
 		return
 	}
 
 	err := exec.DeleteExecution(s.dbClient, sha, uuid, "custom_run")
+	s.cache.invalidate()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &ErrorAPI{Error: err.Error()})
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
 		slog.Error(err)
 		return
 	}
@@ -516,9 +736,16 @@ func (s *Server) compareBenchmarkFKs(c *gin.Context) {
 	newWorkload := c.Query("newWorkload")
 	oldWorkload := c.Query("oldWorkload")
 
-	results, err := macrobench.CompareFKs(s.dbClient, oldWorkload, newWorkload, sha, macrobench.Gen4Planner)
+	method, err := getComparisonMethod(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+
+	results, err := macrobench.CompareFKs(s.dbClient, oldWorkload, newWorkload, sha, macrobench.Gen4Planner, method)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &ErrorAPI{Error: err.Error()})
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
 		slog.Error(err)
 		return
 	}
@@ -527,12 +754,28 @@ func (s *Server) compareBenchmarkFKs(c *gin.Context) {
 }
 
 func (s *Server) getHistory(c *gin.Context) {
-	results, err := exec.GetHistory(s.dbClient)
+	filter, err := getExecutionFilter(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &ErrorAPI{Error: err.Error()})
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+	page, err := getPage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+
+	results, nextCursor, err := exec.GetHistory(s.dbClient, filter, page, s.execInitialRuns)
+	if err != nil {
+		c.JSON(listingErrorStatus(err), &api.ErrorAPI{Error: err.Error()})
 		slog.Error(err)
 		return
 	}
 
+	if nextCursor != "" {
+		c.Header(api.HeaderNextCursor, nextCursor)
+	}
 	c.JSON(http.StatusOK, results)
 }
@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package api holds the definitions shared by the HTTP API served by the server
+// package and its client: the paths of the endpoints, the headers of the responses
+// and the types of their bodies. It does not depend on the HTTP framework of the
+// server so the client can be used without it.
+package api
+
+// Paths of the endpoints of the HTTP API.
+const (
+	PathWorkloads                  = "/api/workloads"
+	PathRecent                     = "/api/recent"
+	PathQueue                      = "/api/queue"
+	PathVitessRefs                 = "/api/vitess/refs"
+	PathFKCompare                  = "/api/fk/compare"
+	PathFKCompareQueries           = "/api/fk/compare/queries"
+	PathMacrobenchCompare          = "/api/macrobench/compare"
+	PathMacrobenchCompareQueries   = "/api/macrobench/compare/queries"
+	PathMacrobenchIntervals        = "/api/macrobench/intervals"
+	PathMacrobenchLatencyHistogram = "/api/macrobench/latency/histogram"
+	PathMicrobenchCompare          = "/api/microbench/compare"
+	PathMicrobenchHistory          = "/api/microbench/history"
+	PathMicrobenchProfile          = "/api/microbench/profile"
+	PathMicrobenchProfileDiff      = "/api/microbench/profile/diff"
+	PathSearch                     = "/api/search"
+	PathHistory                    = "/api/history"
+	PathPullRequestList            = "/api/pr/list"
+	PathDailySummary               = "/api/daily/summary"
+	PathDaily                      = "/api/daily"
+	PathStatusStats                = "/api/status/stats"
+	PathRunRequest                 = "/api/run/request"
+	PathRunDelete                  = "/api/run/delete"
+	PathRunStatus                  = "/api/run/status"
+	PathRunLogs                    = "/api/run/logs"
+	PathRunCancel                  = "/api/run/cancel"
+	PathRunInvalidate              = "/api/run/invalidate"
+	PathExportMacrobench           = "/api/export/macrobench"
+	PathExportMicrobench           = "/api/export/microbench"
+
+	// PathPullRequestInfo is followed by the number of the pull request.
+	PathPullRequestInfo = "/api/pr/info/"
+)
+
+// Headers of the responses of the HTTP API.
+const (
+	// HeaderNextCursor holds the cursor of the next page of the paginated endpoints,
+	// it is absent on the last page.
+	HeaderNextCursor = "X-Next-Cursor"
+
+	// HeaderLogOffset holds the offset to read the next chunk of logs from.
+	HeaderLogOffset = "X-Log-Offset"
+
+	// HeaderResolvedGitRefs lists the commit used for each git ref query parameter,
+	// formatted as "<param>=<sha>" and separated by commas.
+	HeaderResolvedGitRefs = "X-Resolved-Git-Refs"
+)
+
+// RunStatusQueued is the status of a run waiting in the queue, the status of the
+// runs that already started is their execution status.
+const RunStatusQueued = "queued"
@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"time"
+
+	"github.com/vitessio/arewefastyet/go/tools/git"
+	"github.com/vitessio/arewefastyet/go/tools/macrobench"
+)
+
+type ErrorAPI struct {
+	Error string `json:"error"`
+}
+
+type ExecutionQueue struct {
+	UUID     string `json:"uuid,omitempty"`
+	Source   string `json:"source"`
+	GitRef   string `json:"git_ref"`
+	Workload string `json:"workload"`
+	PullNb   int    `json:"pull_nb"`
+}
+
+type RecentExecutions struct {
+	UUID          string     `json:"uuid"`
+	Source        string     `json:"source"`
+	GitRef        string     `json:"git_ref"`
+	Status        string     `json:"status"`
+	Workload      string     `json:"workload"`
+	PullNb        int        `json:"pull_nb"`
+	GolangVersion string     `json:"golang_version"`
+	StartedAt     *time.Time `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+
+	// MicrobenchPackages lists the only packages benchmarked by a microbenchmark
+	// execution, it is empty if all the packages were benchmarked.
+	MicrobenchPackages []string `json:"microbench_packages,omitempty"`
+}
+
+type ExecutionMetadatas struct {
+	Workloads []string `json:"workloads"`
+	Sources   []string `json:"sources"`
+	Statuses  []string `json:"statuses"`
+}
+
+type RecentExecutionsResponse struct {
+	Executions []RecentExecutions `json:"executions"`
+	ExecutionMetadatas
+}
+
+type ExecutionQueueResponse struct {
+	Executions []ExecutionQueue `json:"executions"`
+	ExecutionMetadatas
+}
+
+type VitessGitRefReleases struct {
+	Tags     []*git.Release `json:"tags"`
+	Branches []*git.Release `json:"branches"`
+}
+
+type CompareMacrobench struct {
+	Workload string                               `json:"workload"`
+	Result   macrobench.StatisticalCompareResults `json:"result"`
+}
+
+type SearchResult struct {
+	Macros map[string]macrobench.StatisticalSingleResult
+}
+
+type DailySummary struct {
+	Name string                                    `json:"name"`
+	Data []macrobench.ShortStatisticalSingleResult `json:"data"`
+}
+
+// RunRequestResponse lists the executions added to the queue by /api/run/request.
+type RunRequestResponse struct {
+	Executions []ExecutionQueue `json:"executions"`
+}
+
+// RunStatus is the status of a run, either queued or known by the database.
+type RunStatus struct {
+	UUID       string     `json:"uuid"`
+	Status     string     `json:"status"`
+	Source     string     `json:"source"`
+	GitRef     string     `json:"git_ref"`
+	Workload   string     `json:"workload"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
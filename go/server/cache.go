@@ -0,0 +1,217 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cacheControl lets clients store the responses but requires them to revalidate
+// with the ETag before using them, the data can change whenever an execution finishes.
+const cacheControl = "public, no-cache"
+
+type (
+	// responseCache keeps the most recently used responses of the API in memory.
+	// The responses are computed from the finished executions, they are all dropped
+	// and the version is bumped every time an execution finishes or is deleted.
+	responseCache struct {
+		mu      sync.Mutex
+		size    int
+		version uint64
+		entries map[string]*list.Element
+		lru     *list.List
+	}
+
+	cachedResponse struct {
+		key         string
+		etag        string
+		contentType string
+		body        []byte
+	}
+
+	// bufferedWriter holds the response written by a handler so it can be cached
+	// before being sent.
+	bufferedWriter struct {
+		gin.ResponseWriter
+		status int
+		body   bytes.Buffer
+	}
+)
+
+// newResponseCache returns a responseCache holding at most size responses, or nil
+// if size is not positive, which disables the cache.
+func newResponseCache(size int) *responseCache {
+	if size <= 0 {
+		return nil
+	}
+	return &responseCache{
+		size:    size,
+		entries: make(map[string]*list.Element, size),
+		lru:     list.New(),
+	}
+}
+
+func (rc *responseCache) get(key string) (*cachedResponse, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	elem, ok := rc.entries[key]
+	if !ok {
+		return nil, false
+	}
+	rc.lru.MoveToFront(elem)
+	return elem.Value.(*cachedResponse), true
+}
+
+// add stores resp unless the data changed since version was read, in which case
+// resp might already be outdated.
+func (rc *responseCache) add(version uint64, resp *cachedResponse) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if version != rc.version {
+		return
+	}
+	if elem, ok := rc.entries[resp.key]; ok {
+		elem.Value = resp
+		rc.lru.MoveToFront(elem)
+		return
+	}
+	rc.entries[resp.key] = rc.lru.PushFront(resp)
+	if rc.lru.Len() > rc.size {
+		oldest := rc.lru.Back()
+		rc.lru.Remove(oldest)
+		delete(rc.entries, oldest.Value.(*cachedResponse).key)
+	}
+}
+
+func (rc *responseCache) currentVersion() uint64 {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.version
+}
+
+// invalidate bumps the data version and drops all the responses. It is a no-op
+// if the cache is disabled.
+func (rc *responseCache) invalidate() {
+	if rc == nil {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.version++
+	rc.entries = make(map[string]*list.Element, rc.size)
+	rc.lru.Init()
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.body.Len() > 0
+}
+
+// cached serves the successful responses of handler from the cache of the server,
+// keyed by the path and query parameters of the request. The responses carry an
+// ETag computed from their body, and a request whose If-None-Match header matches
+// it gets a 304 Not Modified without body.
+func (s *Server) cached(handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.cache == nil {
+			handler(c)
+			return
+		}
+
+		key := c.Request.URL.Path + "?" + c.Request.URL.Query().Encode()
+		if resp, ok := s.cache.get(key); ok {
+			writeCachedResponse(c, resp)
+			return
+		}
+
+		version := s.cache.currentVersion()
+		w := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = w
+		handler(c)
+		c.Writer = w.ResponseWriter
+
+		if w.status != http.StatusOK {
+			c.Writer.WriteHeader(w.status)
+			_, _ = c.Writer.Write(w.body.Bytes())
+			return
+		}
+
+		sum := sha256.Sum256(w.body.Bytes())
+		resp := &cachedResponse{
+			key:         key,
+			etag:        `"` + hex.EncodeToString(sum[:16]) + `"`,
+			contentType: c.Writer.Header().Get("Content-Type"),
+			body:        w.body.Bytes(),
+		}
+		s.cache.add(version, resp)
+		writeCachedResponse(c, resp)
+	}
+}
+
+func writeCachedResponse(c *gin.Context, resp *cachedResponse) {
+	c.Header("ETag", resp.etag)
+	c.Header("Cache-Control", cacheControl)
+	if etagMatches(c.GetHeader("If-None-Match"), resp.etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, resp.contentType, resp.body)
+}
+
+// etagMatches returns true if the value of an If-None-Match header matches etag,
+// using the weak comparison required for this header.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/gin-gonic/gin"
+	"github.com/vitessio/arewefastyet/go/server/api"
+)
+
+func newCacheTestRouter(s *Server, calls *int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/search", s.cached(func(c *gin.Context) {
+		*calls++
+		if c.Query("sha") == "" {
+			c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: "missing sha"})
+			return
+		}
+		c.JSON(http.StatusOK, map[string]string{"sha": c.Query("sha")})
+	}))
+	return router
+}
+
+func cacheTestRequest(router *gin.Engine, url, ifNoneMatch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServer_cached(t *testing.T) {
+	c := qt.New(t)
+	s := &Server{cache: newResponseCache(10)}
+	var calls int
+	router := newCacheTestRouter(s, &calls)
+
+	first := cacheTestRequest(router, "/api/search?sha=abc", "")
+	c.Assert(first.Code, qt.Equals, http.StatusOK)
+	c.Assert(first.Body.String(), qt.Equals, `{"sha":"abc"}`)
+	c.Assert(first.Header().Get("Content-Type"), qt.Equals, "application/json; charset=utf-8")
+	c.Assert(first.Header().Get("Cache-Control"), qt.Equals, cacheControl)
+	etag := first.Header().Get("ETag")
+	c.Assert(etag, qt.Not(qt.Equals), "")
+
+	// served from the cache
+	second := cacheTestRequest(router, "/api/search?sha=abc", "")
+	c.Assert(second.Code, qt.Equals, http.StatusOK)
+	c.Assert(second.Body.String(), qt.Equals, first.Body.String())
+	c.Assert(second.Header().Get("ETag"), qt.Equals, etag)
+	c.Assert(calls, qt.Equals, 1)
+
+	revalidated := cacheTestRequest(router, "/api/search?sha=abc", "W/"+etag)
+	c.Assert(revalidated.Code, qt.Equals, http.StatusNotModified)
+	c.Assert(revalidated.Body.Len(), qt.Equals, 0)
+	c.Assert(calls, qt.Equals, 1)
+
+	other := cacheTestRequest(router, "/api/search?sha=def", etag)
+	c.Assert(other.Code, qt.Equals, http.StatusOK)
+	c.Assert(calls, qt.Equals, 2)
+
+	// errors are not cached
+	for i := 0; i < 2; i++ {
+		failed := cacheTestRequest(router, "/api/search", "")
+		c.Assert(failed.Code, qt.Equals, http.StatusBadRequest)
+		c.Assert(failed.Header().Get("ETag"), qt.Equals, "")
+	}
+	c.Assert(calls, qt.Equals, 4)
+
+	// the response is computed again once the data changed, its ETag is
+	// unchanged since the body is the same
+	s.cache.invalidate()
+	afterInvalidate := cacheTestRequest(router, "/api/search?sha=abc", etag)
+	c.Assert(afterInvalidate.Code, qt.Equals, http.StatusNotModified)
+	c.Assert(calls, qt.Equals, 5)
+}
+
+func TestServer_cachedDisabled(t *testing.T) {
+	c := qt.New(t)
+	s := &Server{cache: newResponseCache(0)}
+	var calls int
+	router := newCacheTestRouter(s, &calls)
+
+	for i := 0; i < 2; i++ {
+		rec := cacheTestRequest(router, "/api/search?sha=abc", "")
+		c.Assert(rec.Code, qt.Equals, http.StatusOK)
+		c.Assert(rec.Header().Get("ETag"), qt.Equals, "")
+	}
+	c.Assert(calls, qt.Equals, 2)
+	s.cache.invalidate()
+}
+
+func TestResponseCache(t *testing.T) {
+	c := qt.New(t)
+	rc := newResponseCache(2)
+
+	rc.add(0, &cachedResponse{key: "a"})
+	rc.add(0, &cachedResponse{key: "b"})
+	_, ok := rc.get("a")
+	c.Assert(ok, qt.IsTrue)
+
+	// b is the least recently used response
+	rc.add(0, &cachedResponse{key: "c"})
+	_, ok = rc.get("b")
+	c.Assert(ok, qt.IsFalse)
+	_, ok = rc.get("a")
+	c.Assert(ok, qt.IsTrue)
+
+	// responses computed before the data changed are not stored
+	version := rc.currentVersion()
+	rc.invalidate()
+	_, ok = rc.get("a")
+	c.Assert(ok, qt.IsFalse)
+	rc.add(version, &cachedResponse{key: "d"})
+	_, ok = rc.get("d")
+	c.Assert(ok, qt.IsFalse)
+}
+
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		ifNoneMatch string
+		want        bool
+	}{
+		{ifNoneMatch: "", want: false},
+		{ifNoneMatch: `"abc"`, want: true},
+		{ifNoneMatch: `W/"abc"`, want: true},
+		{ifNoneMatch: `"def", "abc"`, want: true},
+		{ifNoneMatch: `"def"`, want: false},
+		{ifNoneMatch: "*", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ifNoneMatch, func(t *testing.T) {
+			qt.Assert(t, etagMatches(tt.ifNoneMatch, `"abc"`), qt.Equals, tt.want)
+		})
+	}
+}
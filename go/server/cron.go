@@ -24,8 +24,8 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/robfig/cron/v3"
-	"github.com/vitessio/arewefastyet/go/exec"
 	"github.com/vitessio/arewefastyet/go/tools/git"
+	"github.com/vitessio/arewefastyet/go/tools/macrobench"
 	"golang.org/x/exp/slices"
 )
 
@@ -44,6 +44,13 @@ type (
 		PullBaseRef                                   string
 		Version                                       git.Version
 		UUID                                          string
+
+		// VtgateFlags and VttabletFlags are added to the flags of the configuration.
+		VtgateFlags, VttabletFlags string
+
+		// MicrobenchPackages is the comma-separated list of the only packages benchmarked by
+		// a microbenchmark execution, all the packages are benchmarked if it is empty.
+		MicrobenchPackages string
 	}
 
 	executionQueue map[executionIdentifier]*executionQueueElement
@@ -125,12 +132,10 @@ func (s *Server) removePRFromQueue(element *executionQueueElement) {
 	}
 }
 
-func (s *Server) addToQueue(element *executionQueueElement) {
-	mtx.Lock()
-	defer func() {
-		mtx.Unlock()
-	}()
-
+// addToQueue adds the given element to the queue, possibly several times, and returns
+// the identifiers of the elements that were added. The database is queried before the
+// queue is locked, nothing is added if the queue changed meanwhile.
+func (s *Server) addToQueue(element *executionQueueElement) (added []executionIdentifier) {
 	// Check if the benchmark we are trying to add is part of exclusion rules
 	if len(s.sourceFilter) > 0 && !slices.Contains(s.sourceFilter, element.identifier.Source) {
 		return
@@ -143,34 +148,47 @@ func (s *Server) addToQueue(element *executionQueueElement) {
 	// on how many times we want to execute the same benchmark and on how many
 	// times it already exists in the database.
 	var execElements []*executionQueueElement
+	countInQueue := s.countInQueue(element.identifier)
+	nb, err := s.getNumberOfBenchmarksInDB(element.identifier)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
 	if element.identifier.Workload == "micro" {
+		// a microbenchmark configuration is run once
+		if nb+countInQueue > 0 {
+			slog.Infof("not adding %+v to the queue, already executed or queued", element.identifier)
+			return
+		}
 		execElements = append(execElements, element)
 	} else {
-		nb, err := s.getNumberOfBenchmarksInDB(element.identifier)
+		multiplyFactor, err := s.numberOfRunsToAdd(nb, countInQueue, func() (bool, error) {
+			return s.needsMoreRuns(element.identifier)
+		})
 		if err != nil {
 			slog.Error(err.Error())
 			return
 		}
-
-		countInQueue := 0
-		for identifier := range queue {
-			if identifier.equalWithoutUUID(element.identifier) {
-				countInQueue++
-			}
-		}
-
-		multiplyFactor := exec.MaximumBenchmarkWithSameConfig - nb - countInQueue
 		if multiplyFactor <= 0 {
 			slog.Infof("not adding %+v to the queue, already full", element.identifier)
 			return
 		}
 		for i := 0; i < multiplyFactor; i++ {
 			newElement := *element
+			newElement.Executing = false
 			newElement.identifier.UUID = uuid.NewString()
 			execElements = append(execElements, &newElement)
 		}
 	}
 
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	if countInQueueLocked(element.identifier) != countInQueue {
+		slog.Infof("not adding %+v to the queue, the queue changed meanwhile", element.identifier)
+		return
+	}
+
 	// Add all the elements to the queue
 	for _, execElement := range execElements {
 		// Check if the exact same benchmark is already in the queue
@@ -181,9 +199,65 @@ func (s *Server) addToQueue(element *executionQueueElement) {
 		}
 
 		queue[execElement.identifier] = execElement
+		added = append(added, execElement.identifier)
 		slog.Infof("%+v is added to the queue", execElement.identifier)
 
 		// We sleep here to avoid adding too many similar elements to the queue at the same time.
 		time.Sleep(100 * time.Millisecond)
 	}
+	return
+}
+
+// countInQueue returns the number of elements of the queue with the same configuration
+// as identifier.
+func (s *Server) countInQueue(identifier executionIdentifier) int {
+	mtx.RLock()
+	defer mtx.RUnlock()
+	return countInQueueLocked(identifier)
+}
+
+// countInQueueLocked is like Server.countInQueue, the caller must hold mtx.
+func countInQueueLocked(identifier executionIdentifier) int {
+	count := 0
+	for queued := range queue {
+		if queued.equalWithoutUUID(identifier) {
+			count++
+		}
+	}
+	return count
+}
+
+// numberOfRunsToAdd returns how many runs of a macrobenchmark configuration must be added
+// to the queue, given the number of runs already finished (nbInDB) and waiting in the queue
+// (nbInQueue). The first Server.execInitialRuns runs are added at once. Once they are all done,
+// runs are added one at a time for as long as needsMore returns true and until Server.execMaxRuns
+// runs were executed.
+func (s *Server) numberOfRunsToAdd(nbInDB, nbInQueue int, needsMore func() (bool, error)) (int, error) {
+	if nbInDB+nbInQueue < s.execInitialRuns {
+		return s.execInitialRuns - nbInDB - nbInQueue, nil
+	}
+
+	// We wait for the current batch to be done before looking at the results.
+	if nbInQueue > 0 || nbInDB >= s.execMaxRuns {
+		return 0, nil
+	}
+	more, err := needsMore()
+	if err != nil || !more {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// needsMoreRuns returns true if the confidence range of one of the key metrics of the given
+// configuration is wider than Server.execMaxRange. The runs with extra flags are not part of
+// the execution groups whose ranges are known, only their initial runs are executed.
+func (s *Server) needsMoreRuns(identifier executionIdentifier) (bool, error) {
+	if identifier.VtgateFlags != "" || identifier.VttabletFlags != "" {
+		return false, nil
+	}
+	widest, err := macrobench.GetKeyMetricsWidestRange(s.dbClient, identifier.GitRef, identifier.Workload, macrobench.PlannerVersion(identifier.PlannerVersion))
+	if err != nil {
+		return false, err
+	}
+	return widest.Infinite || widest.Unknown || widest.Value > s.execMaxRange, nil
 }
@@ -20,10 +20,12 @@ package server
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/vitessio/arewefastyet/go/exec"
+	"github.com/vitessio/arewefastyet/go/storage"
 )
 
 func (s *Server) executeSingle(config benchmarkConfig, identifier executionIdentifier, nextIsSame, lastIsSame bool) (err error) {
@@ -33,7 +35,9 @@ func (s *Server) executeSingle(config benchmarkConfig, identifier executionIdent
 			if err != nil {
 				err = fmt.Errorf("%v", err)
 			}
-			if errSuccess := e.Success(); errSuccess != nil {
+			errSuccess := e.Success()
+			s.cache.invalidate()
+			if errSuccess != nil {
 				err = errSuccess
 				return
 			}
@@ -56,12 +60,17 @@ func (s *Server) executeSingle(config benchmarkConfig, identifier executionIdent
 	e.VitessVersion = identifier.Version
 	e.NextBenchmarkIsTheSame = nextIsSame
 	e.RepoDir = s.getVitessPath()
+	e.ExtraVtgateFlags = identifier.VtgateFlags
+	e.ExtraVttabletFlags = identifier.VttabletFlags
+	if identifier.MicrobenchPackages != "" {
+		e.MicrobenchPackages = strings.Split(identifier.MicrobenchPackages, ",")
+	}
 
 	// Check if the previous benchmark is the same and if it is
 	// safe to execute this new benchmark without a preparatory cleanup phase.
 	e.PreviousBenchmarkIsTheSame = lastIsSame
 	if lastIsSame {
-		lastBenchmarkWasClean, err := exec.IsLastExecutionFinished(s.dbClient)
+		lastBenchmarkWasClean, err := exec.IsLastExecutionFinished(storage.Primary(s.dbClient))
 		if err != nil {
 			return err
 		}
@@ -132,6 +141,11 @@ func (s *Server) executeElement(element *executionQueueElement, nextIsSame bool,
 		delete(queue, element.identifier)
 		mtx.Unlock()
 
+		// more runs of the same configuration might be needed if its results are too spread out
+		if element.identifier.Workload != "micro" {
+			s.addToQueue(element)
+		}
+
 		// we will wait for the benchmarks we need to compare it against and notify users if needed
 		s.compareElement(element)
 	}()
@@ -150,7 +164,7 @@ func (s *Server) compareElement(element *executionQueueElement) {
 			if _, ok := seen[comparer]; ok {
 				continue
 			}
-			comparerUUID, err := exec.GetFinishedExecution(s.dbClient, comparer.GitRef, comparer.Source, comparer.Workload, comparer.PlannerVersion, comparer.PullNb)
+			comparerUUID, err := exec.GetFinishedExecution(storage.Primary(s.dbClient), comparer.GitRef, comparer.Source, comparer.Workload, comparer.PlannerVersion, comparer.PullNb, comparer.extraFlags())
 			if err != nil {
 				slog.Error(err)
 				return
@@ -163,17 +177,26 @@ func (s *Server) compareElement(element *executionQueueElement) {
 	}
 }
 
+// extraFlags returns the flags added to the configuration, which are part of the identity
+// of the executions in the database.
+func (ei executionIdentifier) extraFlags() exec.ExtraFlags {
+	return exec.ExtraFlags{Vtgate: ei.VtgateFlags, Vttablet: ei.VttabletFlags}
+}
+
 func (s *Server) getNumberOfBenchmarksInDB(identifier executionIdentifier) (int, error) {
+	// the executions that just finished must be counted, they were written on the primary
+	client := storage.Primary(s.dbClient)
+
 	var nb int
 	var err error
 	if identifier.Workload == "micro" {
 		var exists bool
-		exists, err = exec.Exists(s.dbClient, identifier.GitRef, identifier.Source, identifier.Workload, exec.StatusFinished)
+		exists, err = exec.ExistsMicrobenchmark(client, identifier.GitRef, identifier.Source, identifier.MicrobenchPackages, exec.StatusFinished, identifier.extraFlags())
 		if exists {
 			nb = 1
 		}
 	} else {
-		nb, err = exec.CountMacroBenchmark(s.dbClient, identifier.GitRef, identifier.Source, identifier.Workload, exec.StatusFinished, identifier.PlannerVersion)
+		nb, err = exec.CountMacroBenchmark(client, identifier.GitRef, identifier.Source, identifier.Workload, exec.StatusFinished, identifier.PlannerVersion, identifier.extraFlags())
 	}
 	if err != nil {
 		slog.Error(err)
@@ -22,14 +22,14 @@ import (
 	"strings"
 
 	"github.com/vitessio/arewefastyet/go/exec"
+	"github.com/vitessio/arewefastyet/go/storage"
 	"github.com/vitessio/arewefastyet/go/tools/git"
 	"github.com/vitessio/arewefastyet/go/tools/macrobench"
+	"github.com/vitessio/arewefastyet/go/tools/microbench"
 )
 
 func (s *Server) branchCronHandler() {
 	// update the local clone of vitess from remote
-	s.vitessPathMu.Lock()
-	defer s.vitessPathMu.Unlock()
 	err := s.pullLocalVitess()
 	if err != nil {
 		slog.Error(err.Error())
@@ -59,14 +59,17 @@ func (s *Server) mainBranchCronHandler() ([]*executionQueueElement, error) {
 	configs := s.getConfigFiles()
 	vitessPath := s.getVitessPath()
 
+	s.vitessPathMu.RLock()
 	// getting the latest commit hash from local fork of Vitess
 	ref, err := git.GetCommitHash(vitessPath)
 	if err != nil {
+		s.vitessPathMu.RUnlock()
 		return nil, err
 	}
 
 	// getting the latest release from local fork of Vitess
 	lastRelease, err := git.GetLastReleaseAndCommitHash(vitessPath)
+	s.vitessPathMu.RUnlock()
 	if err != nil {
 		return nil, err
 	}
@@ -74,7 +77,7 @@ func (s *Server) mainBranchCronHandler() ([]*executionQueueElement, error) {
 
 	// We compare main with the previous hash of main and with the latest release
 	for workload, config := range configs {
-		if config.skip {
+		if config.skip || config.pullRequestsOnly {
 			continue
 		}
 		if minVersion := config.v.GetInt(keyMinimumVitessVersion); minVersion > currVersion.Major {
@@ -106,7 +109,9 @@ func (s *Server) releaseBranchesCronHandler() ([]*executionQueueElement, error)
 	configs := s.getConfigFiles()
 	vitesLocalPath := s.getVitessPath()
 
+	s.vitessPathMu.RLock()
 	releases, err := git.GetLatestVitessReleaseBranchCommitHash(vitesLocalPath)
+	s.vitessPathMu.RUnlock()
 	if err != nil {
 		slog.Warn(err.Error())
 		return nil, err
@@ -116,7 +121,9 @@ func (s *Server) releaseBranchesCronHandler() ([]*executionQueueElement, error)
 	for _, release := range releases {
 		ref := release.CommitHash
 		source := exec.SourceReleaseBranch + release.Name
+		s.vitessPathMu.RLock()
 		lastPatchRelease, err := git.GetLastPatchReleaseAndCommitHash(vitesLocalPath, release.Version)
+		s.vitessPathMu.RUnlock()
 		if err != nil && !strings.Contains(err.Error(), "could not find the latest patch release") {
 			slog.Warn(err.Error())
 			continue
@@ -128,7 +135,7 @@ func (s *Server) releaseBranchesCronHandler() ([]*executionQueueElement, error)
 		}
 
 		for workload, config := range configs {
-			if config.skip {
+			if config.skip || config.pullRequestsOnly {
 				continue
 			}
 			if minVersion := config.v.GetInt(keyMinimumVitessVersion); minVersion > currVersion.Major {
@@ -216,7 +223,9 @@ func (s *Server) pullRequestsCronHandler() {
 			if ref == "" || pullNb == 0 {
 				continue
 			}
+			s.vitessPathMu.RLock()
 			currVersion, err := git.GetVersionForCommitSHA(vitesLocalPath, previousGitRef)
+			s.vitessPathMu.RUnlock()
 			if err != nil {
 				slog.Warn(err)
 				continue
@@ -230,14 +239,33 @@ func (s *Server) pullRequestsCronHandler() {
 				}
 
 				if workload == "micro" {
-					elements = append(elements, s.createPullRequestElementWithBaseComparison(config, ref, workload, previousGitRef, "", pullNb, currVersion)...)
+					// listing the affected packages is expensive, it is skipped if the head of the
+					// pull request was already benchmarked
+					done, err := s.pullRequestMicrobenchmarked(pullNb, ref)
+					if err != nil {
+						slog.Warn(err)
+						continue
+					}
+					if done {
+						continue
+					}
+					packages, all, err := s.getPullRequestAffectedPackages(pullNb, previousGitRef, ref)
+					if err != nil {
+						slog.Warn(err)
+						continue
+					}
+					if !all && len(packages) == 0 {
+						slog.Infof("not adding the microbenchmarks of pull request %d, it does not affect any package", pullNb)
+						continue
+					}
+					elements = append(elements, s.createPullRequestElementWithBaseComparison(config, ref, workload, previousGitRef, "", pullNb, currVersion, strings.Join(packages, ","))...)
 				} else {
 					versions := []macrobench.PlannerVersion{macrobench.V3Planner}
 					if labelInfo.useGen4 {
 						versions = []macrobench.PlannerVersion{macrobench.Gen4Planner}
 					}
 					for _, version := range versions {
-						elements = append(elements, s.createPullRequestElementWithBaseComparison(config, ref, workload, previousGitRef, version, pullNb, currVersion)...)
+						elements = append(elements, s.createPullRequestElementWithBaseComparison(config, ref, workload, previousGitRef, version, pullNb, currVersion, "")...)
 					}
 				}
 			}
@@ -249,15 +277,66 @@ func (s *Server) pullRequestsCronHandler() {
 	}
 }
 
-func (s *Server) createPullRequestElementWithBaseComparison(config benchmarkConfig, ref, workload, previousGitRef string, plannerVersion macrobench.PlannerVersion, pullNb int, gitVersion git.Version) []*executionQueueElement {
+// pullRequestMicrobenchmarked returns true if the microbenchmarks of the given head of a pull
+// request are queued or finished, whatever packages they were limited to since these only
+// depend on the pull request.
+func (s *Server) pullRequestMicrobenchmarked(pullNb int, head string) (bool, error) {
+	mtx.RLock()
+	for id := range queue {
+		if id.Workload == "micro" && id.Source == exec.SourcePullRequest && id.PullNb == pullNb && id.GitRef == head {
+			mtx.RUnlock()
+			return true, nil
+		}
+	}
+	mtx.RUnlock()
+	return exec.Exists(storage.Primary(s.dbClient), head, exec.SourcePullRequest, "micro", exec.StatusFinished, exec.ExtraFlags{})
+}
+
+// getPullRequestAffectedPackages fetches the pull request in the local clone of vitess and returns
+// the packages whose microbenchmarks it might affect, see microbench.AffectedPackages. The clone is
+// locked only while the pull request is fetched and checked out in a worktree, the packages are
+// listed in this worktree, at the head of the pull request.
+func (s *Server) getPullRequestAffectedPackages(pullNb int, base, head string) (packages []string, all bool, err error) {
+	vitessPath := s.getVitessPath()
+	s.vitessPathMu.Lock()
+	err = git.FetchPullRequest(vitessPath, pullNb)
+	var worktree string
+	if err == nil {
+		worktree, err = git.AddWorktree(vitessPath, head)
+	}
+	s.vitessPathMu.Unlock()
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() {
+		s.vitessPathMu.Lock()
+		defer s.vitessPathMu.Unlock()
+		if err := git.RemoveWorktree(vitessPath, worktree); err != nil {
+			slog.Warn(err)
+		}
+	}()
+
+	files, err := git.ChangedFiles(worktree, base, head)
+	if err != nil {
+		return nil, false, err
+	}
+	return microbench.AffectedPackages(worktree, files)
+}
+
+// createPullRequestElementWithBaseComparison creates the execution of the pull request and of its
+// base to compare them. The microbenchmarks of both are limited to microbenchPackages if it is not
+// empty, see executionIdentifier.MicrobenchPackages.
+func (s *Server) createPullRequestElementWithBaseComparison(config benchmarkConfig, ref, workload, previousGitRef string, plannerVersion macrobench.PlannerVersion, pullNb int, gitVersion git.Version, microbenchPackages string) []*executionQueueElement {
 	var elements []*executionQueueElement
 
 	newExecutionElement := s.createSimpleExecutionQueueElement(config, exec.SourcePullRequest, ref, workload, string(plannerVersion), true, pullNb, gitVersion)
 	newExecutionElement.identifier.PullBaseRef = previousGitRef
+	newExecutionElement.identifier.MicrobenchPackages = microbenchPackages
 	elements = append(elements, newExecutionElement)
 
 	if previousGitRef != "" {
 		previousElement := s.createSimpleExecutionQueueElement(config, exec.SourcePullRequestBase, previousGitRef, workload, string(plannerVersion), false, pullNb, gitVersion)
+		previousElement.identifier.MicrobenchPackages = microbenchPackages
 		previousElement.compareWith = append(previousElement.compareWith, newExecutionElement.identifier)
 		newExecutionElement.compareWith = append(newExecutionElement.compareWith, previousElement.identifier)
 		elements = append(elements, previousElement)
@@ -267,8 +346,6 @@ func (s *Server) createPullRequestElementWithBaseComparison(config benchmarkConf
 
 func (s *Server) tagsCronHandler() {
 	// update the local clone of vitess from remote
-	s.vitessPathMu.Lock()
-	defer s.vitessPathMu.Unlock()
 	err := s.pullLocalVitess()
 	if err != nil {
 		slog.Error(err.Error())
@@ -277,7 +354,9 @@ func (s *Server) tagsCronHandler() {
 
 	configs := s.getConfigFiles()
 
+	s.vitessPathMu.RLock()
 	releases, err := git.GetLatestVitessReleaseCommitHash(s.getVitessPath())
+	s.vitessPathMu.RUnlock()
 	if err != nil {
 		slog.Error(err)
 		return
@@ -289,7 +368,7 @@ func (s *Server) tagsCronHandler() {
 	for _, release := range releases {
 		source := exec.SourceTag + release.Name
 		for workload, config := range configs {
-			if config.skip {
+			if config.skip || config.pullRequestsOnly {
 				continue
 			}
 			if minVersion := config.v.GetInt(keyMinimumVitessVersion); minVersion > release.Version.Major {
@@ -0,0 +1,106 @@
+/*
+ *
+ * Copyright 2021 The Vitess Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * /
+ */
+
+package server
+
+import (
+	"errors"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+	"github.com/vitessio/arewefastyet/go/exec"
+)
+
+func TestServer_numberOfRunsToAdd(t *testing.T) {
+	needsMore := func() (bool, error) { return true, nil }
+	needsNoMore := func() (bool, error) { return false, nil }
+	fails := func() (bool, error) { return false, errors.New("fail") }
+
+	tests := []struct {
+		name      string
+		nbInDB    int
+		nbInQueue int
+		needsMore func() (bool, error)
+		want      int
+		wantErr   bool
+	}{
+		{name: "Nothing executed yet", needsMore: needsMore, want: 5},
+		{name: "Initial batch partially in the queue", nbInQueue: 2, needsMore: needsMore, want: 3},
+		{name: "Initial batch partially executed", nbInDB: 3, nbInQueue: 1, needsMore: needsMore, want: 1},
+		{name: "Initial batch still running", nbInDB: 4, nbInQueue: 1, needsMore: needsMore, want: 0},
+		{name: "Initial batch done with wide range", nbInDB: 5, needsMore: needsMore, want: 1},
+		{name: "Initial batch done with tight range", nbInDB: 5, needsMore: needsNoMore, want: 0},
+		{name: "Extra run still running", nbInDB: 6, nbInQueue: 1, needsMore: needsMore, want: 0},
+		{name: "Maximum reached", nbInDB: 15, needsMore: needsMore, want: 0},
+		{name: "Error while checking the range", nbInDB: 5, needsMore: fails, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			s := &Server{execInitialRuns: 5, execMaxRuns: 15}
+
+			got, err := s.numberOfRunsToAdd(tt.nbInDB, tt.nbInQueue, tt.needsMore)
+			if tt.wantErr {
+				c.Assert(err, qt.Not(qt.IsNil))
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(got, qt.Equals, tt.want)
+		})
+	}
+}
+
+func TestServer_needsMoreRuns_extraFlags(t *testing.T) {
+	c := qt.New(t)
+	s := &Server{execMaxRange: 1}
+
+	// the runs with extra flags are not grouped, the database is not read for them
+	needsMore, err := s.needsMoreRuns(executionIdentifier{GitRef: "abc", Workload: "OLTP", VtgateFlags: "--foo=bar"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(needsMore, qt.IsFalse)
+}
+
+func TestServer_countInQueue(t *testing.T) {
+	c := qt.New(t)
+	s := &Server{}
+	identifier := executionIdentifier{GitRef: "abc", Source: "cron", Workload: "OLTP", PlannerVersion: "gen4"}
+	other := identifier
+	other.GitRef = "def"
+	queue = executionQueue{}
+	for _, id := range []executionIdentifier{identifier, identifier, other} {
+		id.UUID = uuid.NewString()
+		queue[id] = &executionQueueElement{identifier: id}
+	}
+	c.Cleanup(func() { queue = executionQueue{} })
+
+	c.Assert(s.countInQueue(identifier), qt.Equals, 2)
+	c.Assert(s.countInQueue(other), qt.Equals, 1)
+}
+
+func TestServer_pullRequestMicrobenchmarked(t *testing.T) {
+	c := qt.New(t)
+	s := &Server{}
+	id := executionIdentifier{GitRef: "abc", Source: exec.SourcePullRequest, Workload: "micro", PullNb: 42, MicrobenchPackages: "vitess.io/vitess/go/sqltypes"}
+	queue = executionQueue{id: &executionQueueElement{identifier: id}}
+	c.Cleanup(func() { queue = executionQueue{} })
+
+	done, err := s.pullRequestMicrobenchmarked(42, "abc")
+	c.Assert(err, qt.IsNil)
+	c.Assert(done, qt.IsTrue)
+}
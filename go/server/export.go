@@ -0,0 +1,134 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vitessio/arewefastyet/go/server/api"
+	"github.com/vitessio/arewefastyet/go/tools/export"
+)
+
+// getExportRequest returns the export.Filter and export.Format set by the query
+// parameters of the export endpoints: "sha" and "workload" are comma-separated lists,
+// "source", "since", "until" and "format" are single values. The git refs are resolved
+// to the full SHA of their commit. The format defaults to export.FormatCSV.
+// The returned status is the HTTP status to respond with if err is not nil.
+func (s *Server) getExportRequest(c *gin.Context) (export.Filter, export.Format, int, error) {
+	format := export.FormatCSV
+	if value := c.Query("format"); value != "" {
+		var err error
+		format, err = export.ParseFormat(strings.ToLower(value))
+		if err != nil {
+			return export.Filter{}, "", http.StatusBadRequest, err
+		}
+	}
+
+	cfg := export.Config{
+		GitRefs:   splitQueryList(c.Query("sha")),
+		Workloads: splitQueryList(c.Query("workload")),
+		Source:    c.Query("source"),
+		Since:     c.Query("since"),
+		Until:     c.Query("until"),
+	}
+	for i, ref := range cfg.GitRefs {
+		sha, err := s.resolveGitRef(ref)
+		if err != nil {
+			return export.Filter{}, "", gitRefErrorStatus(err), err
+		}
+		cfg.GitRefs[i] = sha
+	}
+	filter, err := cfg.Filter()
+	if err != nil {
+		return export.Filter{}, "", http.StatusBadRequest, err
+	}
+	return filter, format, http.StatusOK, nil
+}
+
+// splitQueryList splits a comma-separated query parameter, ignoring empty elements.
+func splitQueryList(value string) []string {
+	var list []string
+	for _, element := range strings.Split(value, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			list = append(list, element)
+		}
+	}
+	return list
+}
+
+// exportErrorStatus returns the HTTP status of an error returned when reading the
+// samples to export, a request without any filter is a bad request.
+func exportErrorStatus(err error) int {
+	if err.Error() == export.ErrorEmptyFilter {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// writeExport sets the headers of an export response and writes its body, the
+// response is downloaded as name followed by the extension of format.
+func writeExport(c *gin.Context, name string, format export.Format, write func() error) {
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", "attachment; filename="+name+"."+format.Extension())
+	c.Status(http.StatusOK)
+	if err := write(); err != nil {
+		// the status line may already be sent, the error can only be logged
+		slog.Error(err)
+	}
+}
+
+func (s *Server) exportMacrobenchmarks(c *gin.Context) {
+	filter, format, status, err := s.getExportRequest(c)
+	if err != nil {
+		c.JSON(status, &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+
+	samples, err := export.GetMacroSamples(s.dbClient, filter)
+	if err != nil {
+		c.JSON(exportErrorStatus(err), &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+
+	writeExport(c, "macrobench", format, func() error {
+		return export.WriteMacrobenchmarks(c.Writer, format, samples)
+	})
+}
+
+func (s *Server) exportMicrobenchmarks(c *gin.Context) {
+	filter, format, status, err := s.getExportRequest(c)
+	if err != nil {
+		c.JSON(status, &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+
+	samples, err := export.GetMicroSamples(s.dbClient, filter)
+	if err != nil {
+		c.JSON(exportErrorStatus(err), &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+
+	writeExport(c, "microbench", format, func() error {
+		return export.WriteMicrobenchmarks(c.Writer, format, samples)
+	})
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/gin-gonic/gin"
+	"github.com/vitessio/arewefastyet/go/tools/export"
+	"go.uber.org/zap"
+)
+
+func TestServer_exportMacrobenchmarks_BadRequest(t *testing.T) {
+	SetSLogger(zap.NewNop().Sugar())
+	gin.SetMode(gin.TestMode)
+	s := &Server{}
+	router := gin.New()
+	router.GET("/api/export/macrobench", s.exportMacrobenchmarks)
+
+	tests := []struct {
+		name     string
+		query    string
+		wantBody string
+	}{
+		{name: "unknown format", query: "workload=oltp&format=xml", wantBody: export.ErrorUnknownFormat},
+		{name: "invalid date", query: "since=yesterday", wantBody: export.ErrorInvalidDate},
+		{name: "empty filter", query: "source=cron&format=jsonl", wantBody: export.ErrorEmptyFilter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/export/macrobench?"+tt.query, nil))
+			qt.Assert(t, rec.Code, qt.Equals, http.StatusBadRequest)
+			qt.Assert(t, strings.Contains(rec.Body.String(), tt.wantBody), qt.IsTrue, qt.Commentf("body: %s", rec.Body.String()))
+		})
+	}
+}
+
+func TestSplitQueryList(t *testing.T) {
+	qt.Assert(t, splitQueryList(""), qt.IsNil)
+	qt.Assert(t, splitQueryList("oltp, tpcc,,"), qt.DeepEquals, []string{"oltp", "tpcc"})
+}
@@ -19,9 +19,13 @@
 package server
 
 import (
+	"net/http"
 	"os"
 	"path"
+	"strings"
 
+	"github.com/gin-gonic/gin"
+	"github.com/vitessio/arewefastyet/go/server/api"
 	"github.com/vitessio/arewefastyet/go/tools/git"
 )
 
@@ -47,8 +51,10 @@ func (s *Server) getVitessPath() string {
 	return path.Join(s.localVitessPath, "vitess")
 }
 
-// pullLocalVitess is used to execute
+// pullLocalVitess updates the local clone of vitess to the latest main and tags.
 func (s *Server) pullLocalVitess() error {
+	s.vitessPathMu.Lock()
+	defer s.vitessPathMu.Unlock()
 	_, err := git.ExecCmd(s.getVitessPath(), "git", "fetch", "origin", "--tags")
 	if err != nil {
 		return err
@@ -56,3 +62,53 @@ func (s *Server) pullLocalVitess() error {
 	_, err = git.ExecCmd(s.getVitessPath(), "git", "reset", "--hard", "origin/main")
 	return err
 }
+
+// resolveGitRef returns the full SHA of the commit designated by ref in the local
+// clone of vitess, see git.ResolveRef.
+func (s *Server) resolveGitRef(ref string) (string, error) {
+	s.vitessPathMu.RLock()
+	defer s.vitessPathMu.RUnlock()
+	return git.ResolveRef(s.getVitessPath(), ref)
+}
+
+// gitRefErrorStatus returns the HTTP status of an error returned by resolveGitRef.
+func gitRefErrorStatus(err error) int {
+	switch {
+	case strings.HasPrefix(err.Error(), git.ErrorRefNotFound):
+		return http.StatusNotFound
+	case strings.HasPrefix(err.Error(), git.ErrorRefAmbiguous):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// resolveGitRefs replaces the value of the given query parameters by the full SHA
+// of the commit they designate, so the next handlers can be given a branch, a tag,
+// an abbreviated SHA or a relative ref, and lists the commits in the response headers.
+// Empty parameters are left untouched.
+func (s *Server) resolveGitRefs(params ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// the query is read from the URL directly as c.Query caches the values
+		query := c.Request.URL.Query()
+		var resolved []string
+		for _, param := range params {
+			ref := query.Get(param)
+			if ref == "" {
+				continue
+			}
+			sha, err := s.resolveGitRef(ref)
+			if err != nil {
+				c.AbortWithStatusJSON(gitRefErrorStatus(err), &api.ErrorAPI{Error: param + ": " + err.Error()})
+				slog.Error(err)
+				return
+			}
+			query.Set(param, sha)
+			resolved = append(resolved, param+"="+sha)
+		}
+		c.Request.URL.RawQuery = query.Encode()
+		if len(resolved) > 0 {
+			c.Header(api.HeaderResolvedGitRefs, strings.Join(resolved, ", "))
+		}
+		c.Next()
+	}
+}
@@ -19,10 +19,18 @@
 package server
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
+	"strings"
 	"testing"
 
 	qt "github.com/frankban/quicktest"
+	"github.com/gin-gonic/gin"
+	"github.com/vitessio/arewefastyet/go/server/api"
+	"github.com/vitessio/arewefastyet/go/tools/git"
+	"go.uber.org/zap"
 )
 
 func TestSetupLocalVitess(t *testing.T) {
@@ -101,3 +109,50 @@ func TestPullLocalVitess(t *testing.T) {
 	err = s.pullLocalVitess()
 	qt.Assert(t, err, qt.IsNil)
 }
+
+func TestServer_resolveGitRefs(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	c := qt.New(t)
+	s := &Server{localVitessPath: c.TempDir()}
+	repo := s.getVitessPath()
+	c.Assert(os.Mkdir(repo, 0755), qt.IsNil)
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch=main"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "--allow-empty", "--message=first"},
+	} {
+		_, err := git.ExecCmd(repo, "git", args...)
+		c.Assert(err, qt.IsNil)
+	}
+	head, err := git.GetCommitHash(repo)
+	c.Assert(err, qt.IsNil)
+
+	SetSLogger(zap.NewNop().Sugar())
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/macrobench/compare", s.resolveGitRefs("old", "new"), func(c *gin.Context) {
+		c.String(http.StatusOK, c.Query("old")+" "+c.Query("new"))
+	})
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantBody   string
+		wantHeader string
+	}{
+		{name: "branch and short SHA", query: "old=main&new=" + head[:8], wantStatus: http.StatusOK, wantBody: head + " " + head, wantHeader: "old=" + head + ", new=" + head},
+		{name: "empty parameter", query: "old=main", wantStatus: http.StatusOK, wantBody: head + " ", wantHeader: "old=" + head},
+		{name: "missing ref", query: "old=main&new=release-1.0", wantStatus: http.StatusNotFound, wantBody: "new: " + git.ErrorRefNotFound},
+	}
+	for _, tt := range tests {
+		c.Run(tt.name, func(c *qt.C) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/macrobench/compare?"+tt.query, nil))
+			c.Assert(rec.Code, qt.Equals, tt.wantStatus)
+			c.Assert(strings.Contains(rec.Body.String(), tt.wantBody), qt.IsTrue, qt.Commentf("body: %s", rec.Body.String()))
+			c.Assert(rec.Header().Get(api.HeaderResolvedGitRefs), qt.Equals, tt.wantHeader)
+		})
+	}
+}
@@ -0,0 +1,312 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vitessio/arewefastyet/go/server/api"
+)
+
+// openAPIPath is the path of the OpenAPI document describing the HTTP API.
+const openAPIPath = "/api/openapi.json"
+
+type (
+	openAPIDocument struct {
+		OpenAPI    string                                  `json:"openapi"`
+		Info       openAPIInfo                             `json:"info"`
+		Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+		Components openAPIComponents                       `json:"components"`
+	}
+
+	openAPIInfo struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Version     string `json:"version"`
+	}
+
+	openAPIComponents struct {
+		Schemas map[string]*schema `json:"schemas"`
+	}
+
+	openAPIOperation struct {
+		OperationID string                      `json:"operationId"`
+		Summary     string                      `json:"summary"`
+		Parameters  []openAPIParameter          `json:"parameters,omitempty"`
+		Responses   map[string]*openAPIResponse `json:"responses"`
+	}
+
+	openAPIParameter struct {
+		Name        string  `json:"name"`
+		In          string  `json:"in"`
+		Description string  `json:"description,omitempty"`
+		Required    bool    `json:"required,omitempty"`
+		Schema      *schema `json:"schema"`
+	}
+
+	openAPIResponse struct {
+		Description string                      `json:"description"`
+		Headers     map[string]*openAPIHeader   `json:"headers,omitempty"`
+		Content     map[string]openAPIMediaType `json:"content,omitempty"`
+	}
+
+	openAPIHeader struct {
+		Description string  `json:"description,omitempty"`
+		Schema      *schema `json:"schema"`
+	}
+
+	openAPIMediaType struct {
+		Schema *schema `json:"schema"`
+	}
+
+	// schema is the subset of the OpenAPI 3.0 schema object needed to describe
+	// the responses of the API.
+	schema struct {
+		Ref                  string             `json:"$ref,omitempty"`
+		Type                 string             `json:"type,omitempty"`
+		Format               string             `json:"format,omitempty"`
+		Enum                 []string           `json:"enum,omitempty"`
+		Nullable             bool               `json:"nullable,omitempty"`
+		Items                *schema            `json:"items,omitempty"`
+		Properties           map[string]*schema `json:"properties,omitempty"`
+		AdditionalProperties *schema            `json:"additionalProperties,omitempty"`
+	}
+)
+
+// headerDescriptions documents the response headers listed by the routes.
+var headerDescriptions = map[string]string{
+	"ETag":                    "Entity tag of the response, sent back in If-None-Match to revalidate it.",
+	api.HeaderNextCursor:      "Cursor of the next page, absent on the last page.",
+	api.HeaderLogOffset:       "Offset of the next chunk of logs.",
+	api.HeaderResolvedGitRefs: "Commit used for each git ref parameter, formatted as <param>=<sha> and separated by commas.",
+}
+
+// newOpenAPIDocument returns the OpenAPI 3.0 document describing the given routes,
+// the schemas of the responses are generated from the Go types they are encoded from.
+func newOpenAPIDocument(routes []route) *openAPIDocument {
+	g := &schemaGenerator{schemas: map[string]*schema{}}
+	errorSchema := g.schemaOf(reflect.TypeOf(api.ErrorAPI{}))
+
+	doc := &openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:       "arewefastyet",
+			Description: "HTTP API of arewefastyet, the benchmarking system of vitess.",
+			Version:     "1.0.0",
+		},
+		Paths:      map[string]map[string]*openAPIOperation{},
+		Components: openAPIComponents{Schemas: g.schemas},
+	}
+	for _, r := range routes {
+		op := &openAPIOperation{
+			OperationID: r.name,
+			Summary:     r.summary,
+			Responses: map[string]*openAPIResponse{
+				"default": {
+					Description: "Error",
+					Content:     map[string]openAPIMediaType{"application/json": {Schema: errorSchema}},
+				},
+			},
+		}
+		for _, p := range r.params {
+			op.Parameters = append(op.Parameters, p.openAPI())
+		}
+
+		status := r.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		resp := &openAPIResponse{Description: http.StatusText(status), Content: map[string]openAPIMediaType{}}
+		if r.response != nil {
+			resp.Content["application/json"] = openAPIMediaType{Schema: g.schemaOf(reflect.TypeOf(r.response))}
+		}
+		for _, contentType := range r.contentTypes {
+			resp.Content[contentType] = openAPIMediaType{Schema: &schema{Type: "string"}}
+		}
+		for _, header := range r.headers {
+			if resp.Headers == nil {
+				resp.Headers = map[string]*openAPIHeader{}
+			}
+			resp.Headers[header] = &openAPIHeader{Description: headerDescriptions[header], Schema: &schema{Type: "string"}}
+		}
+		op.Responses[strconv.Itoa(status)] = resp
+
+		p := openAPIPathOf(r.path)
+		if doc.Paths[p] == nil {
+			doc.Paths[p] = map[string]*openAPIOperation{}
+		}
+		doc.Paths[p][strings.ToLower(r.method)] = op
+	}
+	return doc
+}
+
+// OpenAPIDocument returns the JSON encoding of the OpenAPI document served by the
+// HTTP API at /api/openapi.json.
+func OpenAPIDocument() ([]byte, error) {
+	return json.Marshal(newOpenAPIDocument((&Server{}).routes()))
+}
+
+func (p param) openAPI() openAPIParameter {
+	kind := p.kind
+	if kind == "" {
+		kind = "string"
+	}
+	s := &schema{Type: kind, Enum: p.enum}
+	if p.list {
+		s = &schema{Type: "array", Items: s}
+	}
+	return openAPIParameter{
+		Name:        p.name,
+		In:          p.in,
+		Description: p.description,
+		// path parameters are always required
+		Required: p.required || p.in == "path",
+		Schema:   s,
+	}
+}
+
+// openAPIPathOf converts a gin path to an OpenAPI path, e.g. /api/pr/info/:nb
+// becomes /api/pr/info/{nb}.
+func openAPIPathOf(ginPath string) string {
+	parts := strings.Split(ginPath, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// schemaGenerator generates the schemas of Go types following the rules of encoding/json,
+// named struct types are added to schemas and referenced.
+type schemaGenerator struct {
+	schemas map[string]*schema
+}
+
+func (g *schemaGenerator) schemaOf(t reflect.Type) *schema {
+	if t == timeType {
+		return &schema{Type: "string", Format: "date-time"}
+	}
+	if t.Implements(jsonMarshalerType) {
+		// the encoding is unknown
+		return &schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := g.schemaOf(t.Elem())
+		if s.Ref != "" {
+			// OpenAPI 3.0 ignores the siblings of $ref
+			return s
+		}
+		nullable := *s
+		nullable.Nullable = true
+		return &nullable
+	case reflect.Bool:
+		return &schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &schema{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &schema{Type: "string", Format: "byte"}
+		}
+		return &schema{Type: "array", Items: g.schemaOf(t.Elem()), Nullable: true}
+	case reflect.Array:
+		return &schema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem()), Nullable: true}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := schemaName(t)
+		if _, ok := g.schemas[name]; !ok {
+			// the schema is registered before generating it to stop the recursion
+			// of self-referencing types
+			g.schemas[name] = &schema{}
+			*g.schemas[name] = *g.structSchema(t)
+		}
+		return &schema{Ref: "#/components/schemas/" + name}
+	}
+	// interfaces can hold any value
+	return &schema{}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) *schema {
+	s := &schema{Type: "object", Properties: map[string]*schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		fieldType := field.Type
+		if field.Anonymous && name == "" {
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				// the fields of embedded structs are promoted
+				for k, v := range g.structSchema(fieldType).Properties {
+					if _, ok := s.Properties[k]; !ok {
+						s.Properties[k] = v
+					}
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.Contains(opts, "string") {
+			s.Properties[name] = &schema{Type: "string"}
+			continue
+		}
+		s.Properties[name] = g.schemaOf(field.Type)
+	}
+	return s
+}
+
+// schemaName returns the name of the schema of a named type, prefixed by the name
+// of its package to avoid collisions, e.g. macrobench.StatisticalSingleResult.
+func schemaName(t reflect.Type) string {
+	return path.Base(t.PkgPath()) + "." + t.Name()
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/gin-gonic/gin"
+)
+
+func TestServer_registerRoutes(t *testing.T) {
+	c := qt.New(t)
+	gin.SetMode(gin.TestMode)
+	s := &Server{}
+	router := gin.New()
+	s.registerRoutes(router)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, openAPIPath, nil))
+	c.Assert(rec.Code, qt.Equals, http.StatusOK)
+
+	var doc map[string]interface{}
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &doc), qt.IsNil)
+	c.Assert(doc["openapi"], qt.Equals, "3.0.3")
+
+	// every route but the document itself is described, with a unique operationId
+	paths := doc["paths"].(map[string]interface{})
+	operationIDs := map[string]bool{}
+	for _, r := range router.Routes() {
+		if r.Path == openAPIPath {
+			continue
+		}
+		methods, ok := paths[openAPIPathOf(r.Path)].(map[string]interface{})
+		c.Assert(ok, qt.IsTrue, qt.Commentf("missing path %s", r.Path))
+		op, ok := methods[strings.ToLower(r.Method)].(map[string]interface{})
+		c.Assert(ok, qt.IsTrue, qt.Commentf("missing operation %s %s", r.Method, r.Path))
+		id := op["operationId"].(string)
+		c.Assert(operationIDs[id], qt.IsFalse, qt.Commentf("duplicate operationId %s", id))
+		operationIDs[id] = true
+	}
+	c.Assert(operationIDs, qt.HasLen, len(s.routes()))
+
+	// every reference points to a schema of the document
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	var checkRefs func(v interface{})
+	checkRefs = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok {
+				name := strings.TrimPrefix(ref, "#/components/schemas/")
+				c.Assert(schemas[name], qt.IsNotNil, qt.Commentf("dangling reference %s", ref))
+			}
+			for _, child := range v {
+				checkRefs(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				checkRefs(child)
+			}
+		}
+	}
+	checkRefs(doc)
+}
+
+type (
+	testSchemaEmbedded struct {
+		Embedded string `json:"embedded"`
+		Name     string `json:"name"`
+	}
+
+	testSchemaNode struct {
+		testSchemaEmbedded
+		Name     int               `json:"name"`
+		Children []*testSchemaNode `json:"children"`
+		Parent   *testSchemaNode   `json:"parent,omitempty"`
+		Score    *float64          `json:"score"`
+		Tags     map[string]string `json:"tags"`
+		At       time.Time         `json:"at"`
+		ID       int64             `json:"id,string"`
+		Any      interface{}       `json:"any"`
+		NoTag    bool
+		Skipped  string `json:"-"`
+		private  string
+	}
+)
+
+func TestSchemaGenerator(t *testing.T) {
+	c := qt.New(t)
+	g := &schemaGenerator{schemas: map[string]*schema{}}
+	ref := g.schemaOf(reflect.TypeOf([]testSchemaNode{}))
+	c.Assert(ref, qt.DeepEquals, &schema{Type: "array", Nullable: true, Items: &schema{Ref: "#/components/schemas/server.testSchemaNode"}})
+
+	nodeRef := &schema{Ref: "#/components/schemas/server.testSchemaNode"}
+	c.Assert(g.schemas, qt.DeepEquals, map[string]*schema{
+		"server.testSchemaNode": {
+			Type: "object",
+			Properties: map[string]*schema{
+				"embedded": {Type: "string"},
+				"name":     {Type: "integer", Format: "int32"},
+				"children": {Type: "array", Nullable: true, Items: nodeRef},
+				"parent":   nodeRef,
+				"score":    {Type: "number", Format: "double", Nullable: true},
+				"tags":     {Type: "object", Nullable: true, AdditionalProperties: &schema{Type: "string"}},
+				"at":       {Type: "string", Format: "date-time"},
+				"id":       {Type: "string"},
+				"any":      {},
+				"NoTag":    {Type: "boolean"},
+			},
+		},
+	})
+}
+
+func TestOpenAPIPathOf(t *testing.T) {
+	qt.Assert(t, openAPIPathOf("/api/pr/info/:nb"), qt.Equals, "/api/pr/info/{nb}")
+	qt.Assert(t, openAPIPathOf("/api/recent"), qt.Equals, "/api/recent")
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vitessio/arewefastyet/go/server/api"
+	"github.com/vitessio/arewefastyet/go/tools/microbench"
+)
+
+// profileContentType is the content type of the profiles, as written by go test.
+const profileContentType = "application/octet-stream"
+
+// profileRequest is a benchmark and a type of profile, read from the "pkg", "name"
+// and "type" query parameters.
+type profileRequest struct {
+	pkgName, name, profileType string
+}
+
+func getProfileRequest(c *gin.Context) (profileRequest, error) {
+	req := profileRequest{pkgName: c.Query("pkg"), name: c.Query("name"), profileType: c.Query("type")}
+	if req.pkgName == "" || req.name == "" || req.profileType == "" {
+		return profileRequest{}, errors.New("missing argument: pkg, name and type are required")
+	}
+	if req.profileType != microbench.ProfileCPU && req.profileType != microbench.ProfileMem {
+		return profileRequest{}, fmt.Errorf("%s: %s", microbench.ErrorUnknownProfileType, req.profileType)
+	}
+	return req, nil
+}
+
+// profileErrorStatus returns the HTTP status of an error returned when reading or
+// comparing profiles.
+func profileErrorStatus(err error) int {
+	switch {
+	case strings.HasPrefix(err.Error(), microbench.ErrorProfileNotFound):
+		return http.StatusNotFound
+	case strings.HasPrefix(err.Error(), microbench.ErrorUnknownSampleType):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (s *Server) getMicrobenchProfile(c *gin.Context) {
+	sha := c.Query("sha")
+	req, err := getProfileRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+
+	profile, err := microbench.GetProfile(s.dbClient, sha, req.pkgName, req.name, req.profileType)
+	if err != nil {
+		c.JSON(profileErrorStatus(err), &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+microbench.ProfileFileName(req.profileType, req.pkgName, req.name))
+	c.Data(http.StatusOK, profileContentType, profile)
+}
+
+func (s *Server) diffMicrobenchProfiles(c *gin.Context) {
+	leftSHA := c.Query("ltag")
+	rightSHA := c.Query("rtag")
+	req, err := getProfileRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+	sampleType := c.Query("sample")
+	if sampleType == "" {
+		sampleType, _ = microbench.DefaultSampleType(req.profileType)
+	}
+	top := microbench.DefaultProfileDiffTop
+	if v := c.Query("top"); v != "" {
+		top, err = strconv.Atoi(v)
+		if err != nil || top < 0 {
+			c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: "invalid top: " + v})
+			return
+		}
+	}
+
+	leftProfile, err := microbench.GetProfile(s.dbClient, leftSHA, req.pkgName, req.name, req.profileType)
+	if err != nil {
+		c.JSON(profileErrorStatus(err), &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+	rightProfile, err := microbench.GetProfile(s.dbClient, rightSHA, req.pkgName, req.name, req.profileType)
+	if err != nil {
+		c.JSON(profileErrorStatus(err), &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+
+	diff, err := microbench.DiffProfiles(leftProfile, rightProfile, sampleType, top)
+	if err != nil {
+		c.JSON(profileErrorStatus(err), &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
@@ -0,0 +1,434 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vitessio/arewefastyet/go/exec"
+	"github.com/vitessio/arewefastyet/go/server/api"
+	"github.com/vitessio/arewefastyet/go/tools/export"
+	"github.com/vitessio/arewefastyet/go/tools/github"
+	"github.com/vitessio/arewefastyet/go/tools/macrobench"
+	"github.com/vitessio/arewefastyet/go/tools/microbench"
+)
+
+type (
+	// route describes an endpoint of the HTTP API. The routes are used both to register
+	// the handlers and to generate the OpenAPI document. The params are written by hand,
+	// they must be kept in sync with the query parameters read by the handlers.
+	route struct {
+		method string
+
+		// path uses the gin syntax, e.g. /api/pr/info/:nb.
+		path string
+
+		// name is the operationId of the endpoint, it matches the name of the handler.
+		name    string
+		summary string
+		params  []param
+
+		// status is the HTTP status of a successful response.
+		status int
+
+		// response is a value of the type encoded in the body of a successful response,
+		// the body is described by contentTypes instead if it is nil.
+		response     interface{}
+		contentTypes []string
+
+		// headers are the names of the headers set on successful responses.
+		headers []string
+
+		handlers []gin.HandlerFunc
+	}
+
+	// param is a path or query parameter of a route.
+	param struct {
+		name        string
+		in          string
+		description string
+
+		// kind is the OpenAPI type of the parameter, "string" if empty.
+		kind     string
+		enum     []string
+		required bool
+
+		// list parameters are repeated in the query, e.g. ?workloads=OLTP&workloads=TPCC.
+		list bool
+	}
+)
+
+func queryParam(name, description string) param {
+	return param{name: name, in: "query", description: description}
+}
+
+func requiredQueryParam(name, description string) param {
+	return param{name: name, in: "query", description: description, required: true}
+}
+
+// gitRefParam is a query parameter resolved by Server.resolveGitRefs.
+func gitRefParam(name string, required bool) param {
+	return param{
+		name:        name,
+		in:          "query",
+		description: "Git ref: a full or abbreviated SHA, a branch, a tag or a relative ref, resolved against vitess.",
+		required:    required,
+	}
+}
+
+var (
+	executionFilterParams = []param{
+		queryParam("source", "Keep the executions of this source."),
+		queryParam("workload", "Keep the executions of this workload."),
+		queryParam("status", "Keep the executions with this status."),
+		queryParam("go_version", "Keep the executions built with this version of Go."),
+		queryParam("git_ref", "Keep the executions whose git ref starts with this prefix."),
+		{name: "pr", in: "query", kind: "integer", description: "Keep the executions of this pull request."},
+		queryParam("since", "Keep the executions started at or after this date, RFC 3339 or YYYY-MM-DD."),
+		queryParam("until", "Keep the executions started before this date, RFC 3339 or YYYY-MM-DD."),
+	}
+
+	// adminParams are the parameters of the endpoints authorized by Server.authorized.
+	adminParams = []param{
+		queryParam("key", "Key authorizing the request, it can be sent as a bearer token in the Authorization header instead."),
+	}
+
+	pageParams = []param{
+		{name: "limit", in: "query", kind: "integer", description: "Maximum number of elements in the page."},
+		queryParam("cursor", "Cursor of the page, as returned with the previous page."),
+	}
+
+	comparisonParams = []param{
+		{
+			name: "method", in: "query", description: "Statistical test comparing the samples, Mann-Whitney U if empty.",
+			enum: []string{macrobench.MethodMannWhitney, macrobench.MethodWelchTTest, macrobench.MethodBootstrap},
+		},
+		{name: "alpha", in: "query", kind: "number", description: "Significance level of the statistical test."},
+		{name: "confidence", in: "query", kind: "number", description: "Confidence level of the intervals."},
+		{
+			name: "correction", in: "query", description: "Correction of the p-values for multiple comparisons.",
+			enum: []string{macrobench.CorrectionNone, macrobench.CorrectionBenjaminiHochberg, macrobench.CorrectionHolm},
+		},
+	}
+
+	profileParams = []param{
+		requiredQueryParam("pkg", "Package of the microbenchmark."),
+		requiredQueryParam("name", "Name of the microbenchmark."),
+		{
+			name: "type", in: "query", description: "Type of the profile.", required: true,
+			enum: []string{microbench.ProfileCPU, microbench.ProfileMem},
+		},
+	}
+
+	exportParams = []param{
+		queryParam("sha", "Comma-separated list of git refs."),
+		queryParam("workload", "Comma-separated list of workloads, ignored by the microbenchmark export."),
+		queryParam("source", "Keep the executions of this source."),
+		queryParam("since", "Keep the executions started at or after this date, RFC 3339 or YYYY-MM-DD."),
+		queryParam("until", "Keep the executions started before this date, RFC 3339 or YYYY-MM-DD."),
+		{
+			name: "format", in: "query", description: "Format of the export, csv if empty.",
+			enum: []string{string(export.FormatCSV), string(export.FormatJSONL), string(export.FormatBenchstat)},
+		},
+	}
+
+	exportContentTypes = []string{
+		export.FormatCSV.ContentType(),
+		export.FormatJSONL.ContentType(),
+		export.FormatBenchstat.ContentType(),
+	}
+)
+
+func concatParams(params ...[]param) []param {
+	var all []param
+	for _, p := range params {
+		all = append(all, p...)
+	}
+	return all
+}
+
+// routes returns all the endpoints of the HTTP API.
+func (s *Server) routes() []route {
+	return []route{
+		{
+			method: http.MethodGet, path: api.PathWorkloads, name: "getWorkloadList",
+			summary:  "List the workloads benchmarked by the server.",
+			response: []string{},
+			handlers: []gin.HandlerFunc{s.getWorkloadList},
+		},
+		{
+			method: http.MethodGet, path: api.PathRecent, name: "getRecentExecutions",
+			summary:  "List the most recent executions, along with the values available to filter them.",
+			params:   concatParams(executionFilterParams, pageParams),
+			response: api.RecentExecutionsResponse{},
+			headers:  []string{api.HeaderNextCursor},
+			handlers: []gin.HandlerFunc{s.getRecentExecutions},
+		},
+		{
+			method: http.MethodGet, path: api.PathQueue, name: "getExecutionsQueue",
+			summary:  "List the executions waiting in the queue.",
+			response: api.ExecutionQueueResponse{},
+			handlers: []gin.HandlerFunc{s.getExecutionsQueue},
+		},
+		{
+			method: http.MethodGet, path: api.PathVitessRefs, name: "getLatestVitessGitRef",
+			summary:  "List the latest release tags and branches of vitess.",
+			response: api.VitessGitRefReleases{},
+			handlers: []gin.HandlerFunc{s.getLatestVitessGitRef},
+		},
+		{
+			method: http.MethodGet, path: api.PathFKCompare, name: "compareBenchmarkFKs",
+			summary: "Compare the macrobenchmark results of two foreign key workloads on the same git ref.",
+			params: concatParams([]param{
+				gitRefParam("sha", true),
+				requiredQueryParam("oldWorkload", "Workload of the baseline."),
+				requiredQueryParam("newWorkload", "Workload compared to the baseline."),
+			}, comparisonParams),
+			response: macrobench.StatisticalCompareResults{},
+			headers:  []string{api.HeaderResolvedGitRefs, "ETag"},
+			handlers: []gin.HandlerFunc{s.resolveGitRefs("sha"), s.cached(s.compareBenchmarkFKs)},
+		},
+		{
+			method: http.MethodGet, path: api.PathFKCompareQueries, name: "fkQueriesCompareMacrobenchmarks",
+			summary: "Compare the query plans of two foreign key workloads on the same git ref.",
+			params: []param{
+				gitRefParam("gitRef", true),
+				requiredQueryParam("oldWorkload", "Workload of the baseline."),
+				requiredQueryParam("newWorkload", "Workload compared to the baseline."),
+			},
+			response: []macrobench.VTGateQueryPlanComparer{},
+			headers:  []string{api.HeaderResolvedGitRefs, "ETag"},
+			handlers: []gin.HandlerFunc{s.resolveGitRefs("gitRef"), s.cached(s.fkQueriesCompareMacrobenchmarks)},
+		},
+		{
+			method: http.MethodGet, path: api.PathMacrobenchCompare, name: "compareMacroBenchmarks",
+			summary:  "Compare the macrobenchmark results of two git refs, for every workload.",
+			params:   concatParams([]param{gitRefParam("old", true), gitRefParam("new", true)}, comparisonParams),
+			response: []api.CompareMacrobench{},
+			headers:  []string{api.HeaderResolvedGitRefs, "ETag"},
+			handlers: []gin.HandlerFunc{s.resolveGitRefs("old", "new"), s.cached(s.compareMacroBenchmarks)},
+		},
+		{
+			method: http.MethodGet, path: api.PathMicrobenchCompare, name: "compareMicrobenchmarks",
+			summary: "Compare the microbenchmark results of two git refs.",
+			params: concatParams([]param{
+				gitRefParam("ltag", true),
+				gitRefParam("rtag", true),
+				{name: "threshold", in: "query", kind: "number", description: "Percentage beyond which a significant change is reported as a regression, 10 if empty."},
+			}, comparisonParams),
+			response: microbench.ComparisonArray{},
+			headers:  []string{api.HeaderResolvedGitRefs, "ETag"},
+			handlers: []gin.HandlerFunc{s.resolveGitRefs("ltag", "rtag"), s.cached(s.compareMicrobenchmarks)},
+		},
+		{
+			method: http.MethodGet, path: api.PathMicrobenchHistory, name: "getMicrobenchHistory",
+			summary: "Get the median and confidence interval of every metric of a microbenchmark at each git ref benchmarked by the cron runs, with the step changes of these series.",
+			params: concatParams([]param{
+				requiredQueryParam("pkg", "Package of the microbenchmark."),
+				requiredQueryParam("name", "Name of the microbenchmark."),
+				queryParam("since", "Keep the runs started at or after this date, RFC 3339 or YYYY-MM-DD."),
+				queryParam("until", "Keep the runs started before this date, RFC 3339 or YYYY-MM-DD."),
+				{name: "last", in: "query", kind: "integer", description: "Number of most recent runs, at most 1000, 30 if empty or 1000 if since or until is set."},
+				{name: "window", in: "query", kind: "integer", description: "Number of git refs compared on each side of a step change, 3 if empty."},
+				{name: "threshold", in: "query", kind: "number", description: "Percentage beyond which a significant change is reported as a step change, 5 if empty."},
+			}, comparisonParams),
+			response: []microbench.BenchmarkHistory{},
+			handlers: []gin.HandlerFunc{s.getMicrobenchHistory},
+		},
+		{
+			method: http.MethodGet, path: api.PathMicrobenchProfile, name: "getMicrobenchProfile",
+			summary:      "Download the most recent profile of a microbenchmark at a git ref, in the pprof format.",
+			params:       concatParams([]param{gitRefParam("sha", true)}, profileParams),
+			contentTypes: []string{profileContentType},
+			headers:      []string{api.HeaderResolvedGitRefs},
+			handlers:     []gin.HandlerFunc{s.resolveGitRefs("sha"), s.getMicrobenchProfile},
+		},
+		{
+			method: http.MethodGet, path: api.PathMicrobenchProfileDiff, name: "diffMicrobenchProfiles",
+			summary: "Compare the profiles of a microbenchmark at two git refs, function by function.",
+			params: concatParams([]param{gitRefParam("ltag", true), gitRefParam("rtag", true)}, profileParams, []param{
+				queryParam("sample", "Sample type of the profiles compared, cpu or alloc_space depending on the type if empty."),
+				{name: "top", in: "query", kind: "integer", description: "Number of functions returned, the largest changes first, 10 if empty, all if 0."},
+			}),
+			response: microbench.ProfileDiff{},
+			headers:  []string{api.HeaderResolvedGitRefs, "ETag"},
+			handlers: []gin.HandlerFunc{s.resolveGitRefs("ltag", "rtag"), s.cached(s.diffMicrobenchProfiles)},
+		},
+		{
+			method: http.MethodGet, path: api.PathSearch, name: "searchBenchmark",
+			summary:  "Get the macrobenchmark results of a git ref, for every workload.",
+			params:   []param{gitRefParam("sha", true)},
+			response: api.SearchResult{},
+			headers:  []string{api.HeaderResolvedGitRefs, "ETag"},
+			handlers: []gin.HandlerFunc{s.resolveGitRefs("sha"), s.cached(s.searchBenchmark)},
+		},
+		{
+			method: http.MethodGet, path: api.PathHistory, name: "getHistory",
+			summary:  "List the git refs that were fully benchmarked, the most recent first.",
+			params:   concatParams(executionFilterParams, pageParams),
+			response: []*exec.History{},
+			headers:  []string{api.HeaderNextCursor},
+			handlers: []gin.HandlerFunc{s.getHistory},
+		},
+		{
+			method: http.MethodGet, path: api.PathMacrobenchCompareQueries, name: "queriesCompareMacrobenchmarks",
+			summary: "Compare the query plans of a workload on two git refs.",
+			params: []param{
+				gitRefParam("ltag", true),
+				gitRefParam("rtag", true),
+				requiredQueryParam("workload", "Workload of the query plans."),
+			},
+			response: []macrobench.VTGateQueryPlanComparer{},
+			headers:  []string{api.HeaderResolvedGitRefs, "ETag"},
+			handlers: []gin.HandlerFunc{s.resolveGitRefs("ltag", "rtag"), s.cached(s.queriesCompareMacrobenchmarks)},
+		},
+		{
+			method: http.MethodGet, path: api.PathMacrobenchIntervals, name: "getMacrobenchIntervals",
+			summary:  "Get the per-interval reports of a macrobenchmark execution.",
+			params:   []param{requiredQueryParam("uuid", "UUID of the execution.")},
+			response: macrobench.ExecutionIntervals{},
+			handlers: []gin.HandlerFunc{s.getMacrobenchIntervals},
+		},
+		{
+			method: http.MethodGet, path: api.PathMacrobenchLatencyHistogram, name: "getMacrobenchLatencyHistogram",
+			summary:  "Get the latency histogram reported by sysbench for a macrobenchmark execution.",
+			params:   []param{requiredQueryParam("uuid", "UUID of the execution.")},
+			response: macrobench.ExecutionLatencyHistogram{},
+			handlers: []gin.HandlerFunc{s.getMacrobenchLatencyHistogram},
+		},
+		{
+			method: http.MethodGet, path: api.PathPullRequestList, name: "getPullRequest",
+			summary:  "List the pull requests that were benchmarked.",
+			response: []github.PRInfo{},
+			handlers: []gin.HandlerFunc{s.getPullRequest},
+		},
+		{
+			method: http.MethodGet, path: api.PathPullRequestInfo + ":nb", name: "getPullRequestInfo",
+			summary:  "Get a pull request along with the git refs of its benchmarks.",
+			params:   []param{{name: "nb", in: "path", kind: "integer", description: "Number of the pull request.", required: true}},
+			response: github.PRInfo{},
+			handlers: []gin.HandlerFunc{s.getPullRequestInfo},
+		},
+		{
+			method: http.MethodGet, path: api.PathDailySummary, name: "getDailySummary",
+			summary:  "Get the QPS of the daily benchmarks of the last 30 days, for each workload.",
+			params:   []param{{name: "workloads", in: "query", description: "Workloads to summarize, all of them if empty.", list: true}},
+			response: []api.DailySummary{},
+			handlers: []gin.HandlerFunc{s.getDailySummary},
+		},
+		{
+			method: http.MethodGet, path: api.PathDaily, name: "getDaily",
+			summary:  "Get the results of the daily benchmarks of the last 30 days for a workload.",
+			params:   []param{requiredQueryParam("workload", "Workload of the benchmarks.")},
+			response: []macrobench.StatisticalSingleResult{},
+			handlers: []gin.HandlerFunc{s.getDaily},
+		},
+		{
+			method: http.MethodGet, path: api.PathStatusStats, name: "getStatusStats",
+			summary:  "Get statistics about the executions.",
+			response: exec.BenchmarkStats{},
+			handlers: []gin.HandlerFunc{s.getStatusStats},
+		},
+		{
+			method: http.MethodGet, path: api.PathRunRequest, name: "requestRun",
+			summary: "Add custom runs of a commit to the queue, one per workload. The status is 200 if nothing was added, the runs being already executed or queued.",
+			params: concatParams([]param{
+				requiredQueryParam("workload", "Workloads to benchmark, separated by commas."),
+				requiredQueryParam("sha", "Full SHA of the commit to benchmark."),
+				{name: "version", in: "query", kind: "integer", description: "Major version of vitess at the commit.", required: true},
+				queryParam("planner", "Planner version of vtgate, Gen4 if empty."),
+				queryParam("vtgate_flags", "Flags added to the flags of vtgate."),
+				queryParam("vttablet_flags", "Flags added to the flags of vttablet."),
+			}, adminParams),
+			status:   http.StatusCreated,
+			response: api.RunRequestResponse{},
+			handlers: []gin.HandlerFunc{s.requestRun},
+		},
+		{
+			method: http.MethodGet, path: api.PathRunDelete, name: "deleteRun",
+			summary: "Delete a custom run.",
+			params: concatParams([]param{
+				requiredQueryParam("uuid", "UUID of the execution."),
+				requiredQueryParam("sha", "Full SHA of the execution."),
+			}, adminParams),
+			response: "",
+			handlers: []gin.HandlerFunc{s.deleteRun},
+		},
+		{
+			method: http.MethodGet, path: api.PathRunStatus, name: "getRunStatus",
+			summary:  "Get the status of a run, queued or executed.",
+			params:   []param{requiredQueryParam("uuid", "UUID of the run.")},
+			response: api.RunStatus{},
+			handlers: []gin.HandlerFunc{s.getRunStatus},
+		},
+		{
+			method: http.MethodGet, path: api.PathRunLogs, name: "getRunLogs",
+			summary: "Get the logs of a run, starting at an offset.",
+			params: concatParams([]param{
+				requiredQueryParam("uuid", "UUID of the run."),
+				{name: "offset", in: "query", kind: "integer", description: "Offset in bytes to read the logs from, as returned with the previous chunk."},
+				{name: "stream", in: "query", description: "Output to read, stdout if empty.", enum: []string{"stdout", "stderr"}},
+			}, adminParams),
+			contentTypes: []string{"text/plain"},
+			headers:      []string{api.HeaderLogOffset},
+			handlers:     []gin.HandlerFunc{s.getRunLogs},
+		},
+		{
+			method: http.MethodPost, path: api.PathRunCancel, name: "cancelRun",
+			summary:  "Remove a run that is not executing yet from the queue, a run that already started cannot be canceled.",
+			params:   concatParams([]param{requiredQueryParam("uuid", "UUID of the run.")}, adminParams),
+			response: "",
+			handlers: []gin.HandlerFunc{s.cancelRun},
+		},
+		{
+			method: http.MethodPost, path: api.PathRunInvalidate, name: "invalidateRun",
+			summary:  "Invalidate a finished execution, its results are not used anymore.",
+			params:   concatParams([]param{requiredQueryParam("uuid", "UUID of the execution.")}, adminParams),
+			response: "",
+			handlers: []gin.HandlerFunc{s.invalidateRun},
+		},
+		{
+			method: http.MethodGet, path: api.PathExportMacrobench, name: "exportMacrobenchmarks",
+			summary:      "Export the macrobenchmark results of the finished executions, at least one git ref, workload or date is required.",
+			params:       exportParams,
+			contentTypes: exportContentTypes,
+			handlers:     []gin.HandlerFunc{s.exportMacrobenchmarks},
+		},
+		{
+			method: http.MethodGet, path: api.PathExportMicrobench, name: "exportMicrobenchmarks",
+			summary:      "Export the microbenchmark results of the finished executions, at least one git ref or date is required.",
+			params:       exportParams,
+			contentTypes: exportContentTypes,
+			handlers:     []gin.HandlerFunc{s.exportMicrobenchmarks},
+		},
+	}
+}
+
+// registerRoutes adds the endpoints of the HTTP API to router, along with the
+// OpenAPI document describing them.
+func (s *Server) registerRoutes(router gin.IRoutes) {
+	routes := s.routes()
+	for _, r := range routes {
+		router.Handle(r.method, r.path, r.handlers...)
+	}
+
+	doc := newOpenAPIDocument(routes)
+	router.GET(openAPIPath, func(c *gin.Context) {
+		c.JSON(http.StatusOK, doc)
+	})
+}
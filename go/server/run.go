@@ -0,0 +1,270 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vitessio/arewefastyet/go/exec"
+	"github.com/vitessio/arewefastyet/go/server/api"
+)
+
+const (
+	// maxLogChunkSize is the maximum number of bytes returned by /api/run/logs.
+	maxLogChunkSize = 1 << 20
+
+	errorUnauthorized = "unauthorized, wrong key"
+)
+
+// authorized checks that the request holds the key authorizing the administrative
+// endpoints, either as a bearer token or in the "key" query parameter, and responds
+// with 401 otherwise. No request is authorized if the server has no key.
+func (s *Server) authorized(c *gin.Context) bool {
+	key, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !found {
+		key = c.Query("key")
+	}
+	if s.requestRunKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(s.requestRunKey)) != 1 {
+		c.JSON(http.StatusUnauthorized, &api.ErrorAPI{Error: errorUnauthorized})
+		slog.Error(errorUnauthorized)
+		return false
+	}
+	return true
+}
+
+// queuedRun returns the identifier of the run with the given UUID if it is in the queue.
+func queuedRun(runUUID string) (executionIdentifier, *executionQueueElement, bool) {
+	mtx.RLock()
+	defer mtx.RUnlock()
+	for identifier, element := range queue {
+		if identifier.UUID == runUUID {
+			return identifier, element, true
+		}
+	}
+	return executionIdentifier{}, nil, false
+}
+
+// runErrorStatus returns the HTTP status of an error returned by the exec package
+// when looking up or updating an execution.
+func runErrorStatus(err error) int {
+	switch {
+	case strings.HasPrefix(err.Error(), exec.ErrorExecutionNotFound):
+		return http.StatusNotFound
+	case strings.HasPrefix(err.Error(), exec.ErrorExecutionNotFinished):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func (s *Server) getRunStatus(c *gin.Context) {
+	runUUID := c.Query("uuid")
+	if runUUID == "" {
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: "missing argument: uuid"})
+		slog.Error("missing argument: uuid")
+		return
+	}
+
+	identifier, element, found := queuedRun(runUUID)
+	if found && !element.Executing {
+		c.JSON(http.StatusOK, api.RunStatus{
+			UUID:     runUUID,
+			Status:   api.RunStatusQueued,
+			Source:   identifier.Source,
+			GitRef:   identifier.GitRef,
+			Workload: identifier.Workload,
+		})
+		return
+	}
+
+	e, err := exec.GetExecution(s.dbClient, runUUID)
+	if err != nil {
+		// an executing run is not in the database until it is prepared
+		if found && runErrorStatus(err) == http.StatusNotFound {
+			c.JSON(http.StatusOK, api.RunStatus{
+				UUID:     runUUID,
+				Status:   exec.StatusCreated,
+				Source:   identifier.Source,
+				GitRef:   identifier.GitRef,
+				Workload: identifier.Workload,
+			})
+			return
+		}
+		c.JSON(runErrorStatus(err), &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, api.RunStatus{
+		UUID:       runUUID,
+		Status:     e.Status,
+		Source:     e.Source,
+		GitRef:     e.GitRef,
+		Workload:   e.Workload,
+		StartedAt:  e.StartedAt,
+		FinishedAt: e.FinishedAt,
+	})
+}
+
+func (s *Server) getRunLogs(c *gin.Context) {
+	if !s.authorized(c) {
+		return
+	}
+
+	runUUID := c.Query("uuid")
+	if runUUID == "" {
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: "missing argument: uuid"})
+		slog.Error("missing argument: uuid")
+		return
+	}
+	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+	if err != nil || offset < 0 {
+		errStr := "invalid offset: " + c.Query("offset")
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: errStr})
+		slog.Error(errStr)
+		return
+	}
+	stream := c.DefaultQuery("stream", "stdout")
+	if stream != "stdout" && stream != "stderr" {
+		errStr := "invalid stream: " + stream
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: errStr})
+		slog.Error(errStr)
+		return
+	}
+
+	e, err := exec.GetExecution(s.dbClient, runUUID)
+	if err != nil {
+		c.JSON(runErrorStatus(err), &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+	cfg, ok := s.getConfigFiles()[strings.ToLower(e.Workload)]
+	if !ok {
+		errStr := "unknown benchmark workload: " + strings.ToUpper(e.Workload)
+		c.JSON(http.StatusNotFound, &api.ErrorAPI{Error: errStr})
+		slog.Error(errStr)
+		return
+	}
+	logPath, err := exec.OutputPath(cfg.v, runUUID, stream == "stderr")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+
+	chunk, err := readLogChunk(logPath, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+	c.Header(api.HeaderLogOffset, strconv.FormatInt(offset+int64(len(chunk)), 10))
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", chunk)
+}
+
+// readLogChunk reads at most maxLogChunkSize bytes of the file at logPath starting
+// at offset. A file that does not exist yet is read as an empty file.
+func readLogChunk(logPath string, offset int64) ([]byte, error) {
+	file, err := os.Open(logPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return []byte{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(io.LimitReader(file, maxLogChunkSize))
+}
+
+func (s *Server) cancelRun(c *gin.Context) {
+	if !s.authorized(c) {
+		return
+	}
+
+	runUUID := c.Query("uuid")
+	if runUUID == "" {
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: "missing argument: uuid"})
+		slog.Error("missing argument: uuid")
+		return
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	for identifier, element := range queue {
+		if identifier.UUID != runUUID {
+			continue
+		}
+		if element.Executing {
+			errStr := "run is executing: " + runUUID
+			c.JSON(http.StatusConflict, &api.ErrorAPI{Error: errStr})
+			slog.Error(errStr)
+			return
+		}
+		slog.Infof("%+v is removed from the queue", identifier)
+		delete(queue, identifier)
+		c.JSON(http.StatusOK, "canceled")
+		return
+	}
+
+	// a run that left the queue started, it can only be invalidated
+	e, err := exec.GetExecution(s.dbClient, runUUID)
+	if err != nil {
+		status := runErrorStatus(err)
+		if status == http.StatusNotFound {
+			err = errors.New("run not in the queue: " + runUUID)
+		}
+		c.JSON(status, &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+	errStr := fmt.Sprintf("run already started, its status is %s: %s", e.Status, runUUID)
+	c.JSON(http.StatusConflict, &api.ErrorAPI{Error: errStr})
+	slog.Error(errStr)
+}
+
+func (s *Server) invalidateRun(c *gin.Context) {
+	if !s.authorized(c) {
+		return
+	}
+
+	runUUID := c.Query("uuid")
+	if runUUID == "" {
+		c.JSON(http.StatusBadRequest, &api.ErrorAPI{Error: "missing argument: uuid"})
+		slog.Error("missing argument: uuid")
+		return
+	}
+
+	err := exec.InvalidateExecution(s.dbClient, runUUID)
+	s.cache.invalidate()
+	if err != nil {
+		c.JSON(runErrorStatus(err), &api.ErrorAPI{Error: err.Error()})
+		slog.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, "invalidated")
+}
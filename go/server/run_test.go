@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func newRunTestRouter(s *Server) *gin.Engine {
+	SetSLogger(zap.NewNop().Sugar())
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/run/status", s.getRunStatus)
+	router.POST("/api/run/cancel", s.cancelRun)
+	return router
+}
+
+func TestServer_authorized(t *testing.T) {
+	tests := []struct {
+		name       string
+		serverKey  string
+		header     string
+		query      string
+		wantStatus int
+	}{
+		{name: "bearer token", serverKey: "secret", header: "Bearer secret", wantStatus: http.StatusOK},
+		{name: "query parameter", serverKey: "secret", query: "&key=secret", wantStatus: http.StatusOK},
+		{name: "wrong key", serverKey: "secret", header: "Bearer nope", wantStatus: http.StatusUnauthorized},
+		{name: "no key", serverKey: "secret", wantStatus: http.StatusUnauthorized},
+		{name: "server without key", header: "Bearer ", query: "&key=", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queued := executionIdentifier{GitRef: "abc", Source: "custom_run", Workload: "OLTP", UUID: "queued"}
+			queue = executionQueue{queued: &executionQueueElement{identifier: queued}}
+			router := newRunTestRouter(&Server{requestRunKey: tt.serverKey})
+			req := httptest.NewRequest(http.MethodPost, "/api/run/cancel?uuid=queued"+tt.query, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			qt.Assert(t, rec.Code, qt.Equals, tt.wantStatus, qt.Commentf("body: %s", rec.Body.String()))
+		})
+	}
+}
+
+func TestServer_cancelRun(t *testing.T) {
+	c := qt.New(t)
+	queued := executionIdentifier{GitRef: "abc", Source: "custom_run", Workload: "OLTP", UUID: "queued"}
+	executing := executionIdentifier{GitRef: "abc", Source: "custom_run", Workload: "TPCC", UUID: "executing"}
+	queue = executionQueue{
+		queued:    &executionQueueElement{identifier: queued},
+		executing: &executionQueueElement{identifier: executing, Executing: true},
+	}
+	router := newRunTestRouter(&Server{requestRunKey: "secret"})
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "/api/run/status?uuid=queued")
+	c.Assert(rec.Code, qt.Equals, http.StatusOK)
+	c.Assert(rec.Body.String(), qt.Contains, `"status":"queued"`)
+
+	c.Assert(serve(http.MethodPost, "/api/run/cancel?uuid=executing").Code, qt.Equals, http.StatusConflict)
+	c.Assert(serve(http.MethodPost, "/api/run/cancel?uuid=queued").Code, qt.Equals, http.StatusOK)
+	// the runs that are not in the queue anymore are looked up in the database
+	c.Assert(queue, qt.HasLen, 1)
+}
+
+func TestReadLogChunk(t *testing.T) {
+	c := qt.New(t)
+	logPath := filepath.Join(t.TempDir(), "exec-stdout.log")
+
+	chunk, err := readLogChunk(logPath, 0)
+	c.Assert(err, qt.IsNil)
+	c.Assert(chunk, qt.HasLen, 0)
+
+	c.Assert(os.WriteFile(logPath, []byte("line 1\nline 2\n"), 0o644), qt.IsNil)
+	chunk, err = readLogChunk(logPath, int64(len("line 1\n")))
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(chunk), qt.Equals, "line 2\n")
+
+	big := strings.Repeat("a", maxLogChunkSize+1)
+	c.Assert(os.WriteFile(logPath, []byte(big), 0o644), qt.IsNil)
+	chunk, err = readLogChunk(logPath, 0)
+	c.Assert(err, qt.IsNil)
+	c.Assert(chunk, qt.HasLen, maxLogChunkSize)
+}
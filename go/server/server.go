@@ -26,9 +26,13 @@ import (
 	"time"
 
 	"github.com/gin-contrib/cors"
+	"github.com/vitessio/arewefastyet/go/exec"
+	"github.com/vitessio/arewefastyet/go/server/api"
 	"github.com/vitessio/arewefastyet/go/slack"
-	"github.com/vitessio/arewefastyet/go/storage/psdb"
+	"github.com/vitessio/arewefastyet/go/storage"
+	"github.com/vitessio/arewefastyet/go/storage/database"
 	"github.com/vitessio/arewefastyet/go/tools/github"
+	"github.com/vitessio/arewefastyet/go/tools/microbench"
 
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/cobra"
@@ -51,6 +55,10 @@ const (
 	flagFilterBySource                       = "web-source-filter"
 	flagExcludeFilterBySource                = "web-source-exclude-filter"
 	flagRequestRunKey                        = "web-request-run-key"
+	flagExecInitialRuns                      = "web-exec-initial-runs"
+	flagExecMaxRuns                          = "web-exec-max-runs"
+	flagExecMaxRange                         = "web-exec-max-range"
+	flagCacheSize                            = "web-cache-size"
 
 	// keyMinimumVitessVersion is used to define on which minimum Vitess version a given
 	// benchmark should be run. Only the major version is counted. This key/value is located
@@ -62,17 +70,23 @@ type benchmarkConfig struct {
 	file string
 	v    *viper.Viper
 	skip bool
+
+	// pullRequestsOnly restricts the benchmark to the pull requests, the crons of
+	// the branches and of the tags skip it.
+	pullRequestsOnly bool
 }
 
 type Server struct {
 	port   string
 	router *gin.Engine
 
-	vitessPathMu    sync.Mutex
+	// vitessPathMu is held for writing while the local clone of vitess is updated,
+	// and for reading while it is read by git commands.
+	vitessPathMu    sync.RWMutex
 	localVitessPath string
 
-	dbCfg    *psdb.Config
-	dbClient *psdb.Client
+	dbCfg    *database.Config
+	dbClient storage.Client
 
 	// Configuration used to send message to Slack.
 	slackConfig slack.Config
@@ -82,6 +96,14 @@ type Server struct {
 	cronScheduleTags         string
 	cronNbRetry              int
 
+	// execInitialRuns is the number of runs of a macrobenchmark configuration that are
+	// added to the queue at once. Once they are done, more runs are added one by one
+	// as long as the confidence range of the key metrics is wider than execMaxRange,
+	// until the configuration was executed execMaxRuns times.
+	execInitialRuns int
+	execMaxRuns     int
+	execMaxRange    float64
+
 	benchmarkConfigPath string
 
 	prLabelTrigger   string
@@ -100,6 +122,11 @@ type Server struct {
 
 	requestRunKey string
 
+	// cacheSize is the maximum number of responses kept in cache, the cache
+	// is disabled if it is not positive.
+	cacheSize int
+	cache     *responseCache
+
 	// Mode used to run the server.
 	Mode
 }
@@ -120,6 +147,10 @@ func (s *Server) AddToCommand(cmd *cobra.Command) {
 	cmd.Flags().StringSliceVar(&s.sourceFilter, flagFilterBySource, nil, "List of execution source that should be run. By default, all sources are ran.")
 	cmd.Flags().StringSliceVar(&s.excludeSourceFilter, flagExcludeFilterBySource, nil, "List of execution source to not execute. By default, all sources are ran.")
 	cmd.Flags().StringVar(&s.requestRunKey, flagRequestRunKey, "", "Key to authenticate requests for custom benchmark runs.")
+	cmd.Flags().IntVar(&s.execInitialRuns, flagExecInitialRuns, exec.InitialBenchmarkWithSameConfig, "Number of runs of a macrobenchmark configuration that are added to the queue at first.")
+	cmd.Flags().IntVar(&s.execMaxRuns, flagExecMaxRuns, exec.MaximumBenchmarkWithSameConfig, "Maximum number of runs of a macrobenchmark configuration.")
+	cmd.Flags().Float64Var(&s.execMaxRange, flagExecMaxRange, exec.MaximumRangeWithSameConfig, "Width (in percent) of the confidence range of the key metrics above which more runs of a macrobenchmark configuration are added.")
+	cmd.Flags().IntVar(&s.cacheSize, flagCacheSize, 1000, "Maximum number of comparison and search responses kept in cache. The cache is disabled if this is 0.")
 
 	_ = viper.BindPFlag(flagPort, cmd.Flags().Lookup(flagPort))
 	_ = viper.BindPFlag(flagVitessPath, cmd.Flags().Lookup(flagVitessPath))
@@ -133,10 +164,14 @@ func (s *Server) AddToCommand(cmd *cobra.Command) {
 	_ = viper.BindPFlag(flagFilterBySource, cmd.Flags().Lookup(flagFilterBySource))
 	_ = viper.BindPFlag(flagExcludeFilterBySource, cmd.Flags().Lookup(flagExcludeFilterBySource))
 	_ = viper.BindPFlag(flagRequestRunKey, cmd.Flags().Lookup(flagRequestRunKey))
+	_ = viper.BindPFlag(flagExecInitialRuns, cmd.Flags().Lookup(flagExecInitialRuns))
+	_ = viper.BindPFlag(flagExecMaxRuns, cmd.Flags().Lookup(flagExecMaxRuns))
+	_ = viper.BindPFlag(flagExecMaxRange, cmd.Flags().Lookup(flagExecMaxRange))
+	_ = viper.BindPFlag(flagCacheSize, cmd.Flags().Lookup(flagCacheSize))
 
 	s.slackConfig.AddToCommand(cmd)
 	if s.dbCfg == nil {
-		s.dbCfg = &psdb.Config{}
+		s.dbCfg = database.NewConfig()
 	}
 	s.dbCfg.AddToCommand(cmd)
 	if s.ghApp == nil {
@@ -175,9 +210,12 @@ func (s *Server) Init() error {
 	if err := s.createStorages(); err != nil {
 		return err
 	}
+	s.cache = newResponseCache(s.cacheSize)
 
 	s.benchmarkConfig = map[string]benchmarkConfig{
-		// "micro":         {file: path.Join(s.benchmarkConfigPath, "micro.yaml"), v: viper.New(), skip: true},
+		// The microbenchmarks only run on the pull requests, limited to the packages they affect,
+		// running all of them on every commit of the branches and on every tag takes too long.
+		"micro":         {file: path.Join(s.benchmarkConfigPath, "micro.yaml"), v: viper.New(), pullRequestsOnly: true},
 		"oltp":          {file: path.Join(s.benchmarkConfigPath, "oltp.yaml"), v: viper.New()},
 		"oltp-set":      {file: path.Join(s.benchmarkConfigPath, "oltp-set.yaml"), v: viper.New()},
 		"oltp-readonly": {file: path.Join(s.benchmarkConfigPath, "oltp-readonly.yaml"), v: viper.New()},
@@ -201,7 +239,7 @@ func (s *Server) Init() error {
 		}
 		s.workloads = append(s.workloads, strings.ToUpper(workload))
 	}
-	return nil
+	return microbench.LoadUnitDirections(s.benchmarkConfig["micro"].v)
 }
 
 func (s *Server) Run() error {
@@ -225,31 +263,13 @@ func (s *Server) Run() error {
 	s.router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET"},
-		AllowHeaders:     []string{"Origin"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "If-None-Match"},
+		ExposeHeaders:    []string{"Content-Length", "ETag", api.HeaderNextCursor, api.HeaderResolvedGitRefs},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
-	// API
-	s.router.GET("/api/workloads", s.getWorkloadList)
-	s.router.GET("/api/recent", s.getRecentExecutions)
-	s.router.GET("/api/queue", s.getExecutionsQueue)
-	s.router.GET("/api/vitess/refs", s.getLatestVitessGitRef)
-	s.router.GET("/api/fk/compare", s.compareBenchmarkFKs)
-	s.router.GET("/api/fk/compare/queries", s.fkQueriesCompareMacrobenchmarks)
-	s.router.GET("/api/macrobench/compare", s.compareMacroBenchmarks)
-	s.router.GET("/api/microbench/compare", s.compareMicrobenchmarks)
-	s.router.GET("/api/search", s.searchBenchmark)
-	s.router.GET("/api/history", s.getHistory)
-	s.router.GET("/api/macrobench/compare/queries", s.queriesCompareMacrobenchmarks)
-	s.router.GET("/api/pr/list", s.getPullRequest)
-	s.router.GET("/api/pr/info/:nb", s.getPullRequestInfo)
-	s.router.GET("/api/daily/summary", s.getDailySummary)
-	s.router.GET("/api/daily", s.getDaily)
-	s.router.GET("/api/status/stats", s.getStatusStats)
-	s.router.GET("/api/run/request", s.requestRun)
-	s.router.GET("/api/run/delete", s.deleteRun)
+	s.registerRoutes(s.router)
 
 	return s.router.Run(":" + s.port)
 }
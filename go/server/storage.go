@@ -18,10 +18,14 @@
 
 package server
 
+import "github.com/vitessio/arewefastyet/go/storage/migrations"
+
 func (s *Server) createStorages() (err error) {
 	s.dbClient, err = s.dbCfg.NewClient()
 	if err != nil {
 		return
 	}
-	return
+
+	// refuse to run against a schema this binary does not know
+	return migrations.CheckVersion(s.dbClient)
 }
@@ -0,0 +1,118 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package database selects the database in which arewefastyet stores its data.
+package database
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/vitessio/arewefastyet/go/storage"
+	"github.com/vitessio/arewefastyet/go/storage/localmysqld"
+	"github.com/vitessio/arewefastyet/go/storage/mysql"
+	"github.com/vitessio/arewefastyet/go/storage/psdb"
+)
+
+const (
+	ErrorUnknownDriver = "unknown database driver"
+
+	// DriverPlanetScale connects to a PlanetScale database, with separate credentials
+	// for the read and write servers.
+	DriverPlanetScale = "planetscale"
+
+	// DriverMySQL connects to any MySQL server, and optionally to a read replica.
+	DriverMySQL = "mysql"
+
+	// DriverLocalMysqld stores the data in a local directory, using a mysqld process
+	// managed by arewefastyet.
+	DriverLocalMysqld = "local-mysqld"
+
+	flagDatabaseDriver = "db-driver"
+)
+
+// Config selects and configures the database in which arewefastyet stores its data.
+type Config struct {
+	// Driver is either DriverPlanetScale, DriverMySQL or DriverLocalMysqld.
+	Driver string
+
+	PlanetScale *psdb.Config
+	MySQL       *mysql.ConfigDB
+	LocalMysqld *localmysqld.Config
+}
+
+// NewConfig returns an empty Config that uses DriverPlanetScale.
+func NewConfig() *Config {
+	return &Config{
+		Driver:      DriverPlanetScale,
+		PlanetScale: &psdb.Config{},
+		MySQL:       &mysql.ConfigDB{},
+		LocalMysqld: &localmysqld.Config{},
+	}
+}
+
+// IsValid returns true if the configuration of the selected driver is valid.
+func (cfg *Config) IsValid() bool {
+	if cfg == nil {
+		return false
+	}
+	switch cfg.Driver {
+	case DriverPlanetScale, "":
+		return cfg.PlanetScale != nil && cfg.PlanetScale.IsValid()
+	case DriverMySQL:
+		return cfg.MySQL != nil && cfg.MySQL.IsValid()
+	case DriverLocalMysqld:
+		return cfg.LocalMysqld != nil && cfg.LocalMysqld.IsValid()
+	}
+	return false
+}
+
+// NewClient creates a storage.Client using the selected driver.
+func (cfg *Config) NewClient() (client storage.Client, err error) {
+	switch cfg.Driver {
+	case DriverPlanetScale, "":
+		client, err = cfg.PlanetScale.NewClient()
+	case DriverMySQL:
+		client, err = cfg.MySQL.NewClient()
+	case DriverLocalMysqld:
+		client, err = cfg.LocalMysqld.NewClient()
+	default:
+		return nil, fmt.Errorf("%s: %s", ErrorUnknownDriver, cfg.Driver)
+	}
+	if err != nil {
+		// do not return a typed nil pointer
+		return nil, err
+	}
+	return client, nil
+}
+
+func (cfg *Config) AddToViper(v *viper.Viper) {
+	_ = v.UnmarshalKey(flagDatabaseDriver, &cfg.Driver)
+	cfg.PlanetScale.AddToViper(v)
+	cfg.MySQL.AddToViper(v)
+	cfg.LocalMysqld.AddToViper(v)
+}
+
+// AddToCommand adds Config, and the configuration of all the drivers, to the given cobra.Command.
+func (cfg *Config) AddToCommand(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&cfg.Driver, flagDatabaseDriver, DriverPlanetScale, fmt.Sprintf("Driver of the database, either %q, %q or %q.", DriverPlanetScale, DriverMySQL, DriverLocalMysqld))
+	_ = viper.BindPFlag(flagDatabaseDriver, cmd.Flags().Lookup(flagDatabaseDriver))
+
+	cfg.PlanetScale.AddToCommand(cmd)
+	cfg.MySQL.AddToCommand(cmd)
+	cfg.LocalMysqld.AddToCommand(cmd)
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/spf13/viper"
+)
+
+func TestConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		settings  map[string]string
+		wantValid bool
+		wantErr   string
+	}{
+		{name: "PlanetScale by default, missing credentials", settings: map[string]string{}, wantValid: false},
+		{name: "MySQL with a DSN", settings: map[string]string{"db-driver": "mysql", "db-dsn": "user:password@tcp(host:3306)/database"}, wantValid: true},
+		{name: "MySQL without a host", settings: map[string]string{"db-driver": "mysql", "db-user": "user"}, wantValid: false},
+		{name: "Local mysqld without a directory", settings: map[string]string{"db-driver": "local-mysqld"}, wantValid: false},
+		{name: "Unknown driver", settings: map[string]string{"db-driver": "sqlite"}, wantValid: false, wantErr: ErrorUnknownDriver + ": sqlite"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			v := viper.New()
+			for key, value := range tt.settings {
+				v.Set(key, value)
+			}
+
+			cfg := NewConfig()
+			cfg.AddToViper(v)
+			c.Assert(cfg.IsValid(), qt.Equals, tt.wantValid)
+			if tt.wantErr != "" {
+				client, err := cfg.NewClient()
+				c.Assert(err, qt.ErrorMatches, tt.wantErr)
+				c.Assert(client, qt.IsNil)
+			}
+		})
+	}
+}
@@ -60,7 +60,7 @@ func (cfg Config) NewClient() (*Client, error) {
 		if err != nil {
 			return nil, err
 		}
-		cfg.Host = "http://"+cfg.Host
+		cfg.Host = "http://" + cfg.Host
 	}
 
 	client := Client{
@@ -92,8 +92,6 @@ func (cfg *Config) AddToCommand(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&cfg.Password, flagInfluxPassword, "", "Password used to connect to InfluxDB.")
 	cmd.Flags().StringVar(&cfg.Database, flagInfluxDatabase, "", "Name of the database to use in InfluxDB.")
 
-	_ = cmd.MarkFlagRequired(flagInfluxHostname)
-
 	_ = viper.BindPFlag(flagInfluxHostname, cmd.Flags().Lookup(flagInfluxHostname))
 	_ = viper.BindPFlag(flagInfluxPort, cmd.Flags().Lookup(flagInfluxPort))
 	_ = viper.BindPFlag(flagInfluxUsername, cmd.Flags().Lookup(flagInfluxUsername))
@@ -0,0 +1,274 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package localmysqld stores the data of arewefastyet in a directory of the local
+// filesystem, using a mysqld process started by arewefastyet and stopped when the
+// client that started it is closed. It is meant for development on a laptop and
+// for tests on machines where a mysqld binary is installed.
+//
+// It is not an embedded database: the queries of arewefastyet rely on MySQL, and
+// running them in process would require a MySQL compatible engine written in Go,
+// which arewefastyet does not depend on.
+package localmysqld
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/vitessio/arewefastyet/go/storage/mysql"
+)
+
+const (
+	ErrorMissingDirectory = "the directory of the local database is not set"
+	ErrorStartTimeout     = "timed out waiting for the local mysqld to start"
+	ErrorStopTimeout      = "timed out waiting for the local mysqld to stop, it was killed"
+
+	flagDirectory = "db-local-mysqld-dir"
+	flagBinary    = "db-local-mysqld-binary"
+	flagDatabase  = "db-local-mysqld-database"
+
+	dataDir    = "data"
+	socketFile = "mysqld.sock"
+	pidFile    = "mysqld.pid"
+	logFile    = "mysqld.log"
+
+	startTimeout  = 30 * time.Second
+	startInterval = 250 * time.Millisecond
+	stopTimeout   = 30 * time.Second
+)
+
+// Client is a mysql.Client connected to the local database. It stops the mysqld
+// process when it is closed if it started it.
+type Client struct {
+	*mysql.Client
+
+	// process is the mysqld process started by this client, nil if it was
+	// already running, started by another command.
+	process *exec.Cmd
+}
+
+// Config defines where the local database is stored and how to start it.
+type Config struct {
+	// Directory holds the data files, socket and logs of the mysqld process.
+	Directory string
+
+	// Mysqld is the path to the mysqld binary.
+	Mysqld string
+
+	// Database is the name of the database used by arewefastyet.
+	Database string
+}
+
+// IsValid returns true if Config is ready to be used.
+func (cfg *Config) IsValid() bool {
+	return cfg.Directory != ""
+}
+
+// NewClient starts the mysqld process of the local database if it is not running
+// yet, initializing its data directory on the first start, and connects to it.
+// A process started by NewClient is stopped when the Client is closed, a process
+// that was already running is left as it is.
+func (cfg *Config) NewClient() (*Client, error) {
+	if !cfg.IsValid() {
+		return nil, errors.New(ErrorMissingDirectory)
+	}
+	dir, err := filepath.Abs(cfg.Directory)
+	if err != nil {
+		return nil, err
+	}
+
+	var process *exec.Cmd
+	if !isRunning(dir) {
+		process, err = cfg.start(dir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	client, err := cfg.connect(dir)
+	if err != nil {
+		if process != nil {
+			_ = stop(process)
+		}
+		return nil, err
+	}
+	return &Client{Client: client, process: process}, nil
+}
+
+// Close closes the connections to the local database and stops its mysqld process
+// if it was started by this client.
+func (c *Client) Close() error {
+	err := c.Client.Close()
+	if c.process != nil {
+		if stopErr := stop(c.process); stopErr != nil && err == nil {
+			err = stopErr
+		}
+		c.process = nil
+	}
+	return err
+}
+
+// connect creates the database of arewefastyet if it does not exist yet and
+// connects to it.
+func (cfg *Config) connect(dir string) (*mysql.Client, error) {
+	client, err := rootClient(dir, "")
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	_, err = client.Write(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", cfg.database()))
+	if err != nil {
+		return nil, err
+	}
+	return rootClient(dir, cfg.database())
+}
+
+func (cfg *Config) database() string {
+	if cfg.Database == "" {
+		return "arewefastyet"
+	}
+	return cfg.Database
+}
+
+func (cfg *Config) mysqld() string {
+	if cfg.Mysqld == "" {
+		return "mysqld"
+	}
+	return cfg.Mysqld
+}
+
+// rootClient connects to the local database through its socket, as the root user
+// which has no password on a freshly initialized data directory.
+func rootClient(dir, database string) (*mysql.Client, error) {
+	return mysql.New(mysql.ConfigDB{DSN: connectionString(dir, database)})
+}
+
+func connectionString(dir, database string) string {
+	return fmt.Sprintf("root@unix(%s)/%s", filepath.Join(dir, socketFile), database)
+}
+
+func isRunning(dir string) bool {
+	db, err := sql.Open("mysql", connectionString(dir, ""))
+	if err != nil {
+		return false
+	}
+	defer db.Close()
+	return db.Ping() == nil
+}
+
+// start starts the mysqld process of the local database and waits for it to accept
+// connections.
+func (cfg *Config) start(dir string) (*exec.Cmd, error) {
+	data := filepath.Join(dir, dataDir)
+	if _, err := os.Stat(data); os.IsNotExist(err) {
+		err = os.MkdirAll(dir, 0755)
+		if err != nil {
+			return nil, err
+		}
+		out, err := exec.Command(cfg.mysqld(), initializeArgs(dir)...).CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("%s:\n%s", err.Error(), string(out))
+		}
+	}
+
+	command := exec.Command(cfg.mysqld(), startArgs(dir)...)
+	err := command.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(startTimeout)
+	for time.Now().Before(deadline) {
+		if isRunning(dir) {
+			return command, nil
+		}
+		time.Sleep(startInterval)
+	}
+	_ = stop(command)
+	return nil, fmt.Errorf("%s, see %s", ErrorStartTimeout, filepath.Join(dir, logFile))
+}
+
+// stop asks the given mysqld process to shut down and waits for it, the process
+// is killed if it does not exit in time.
+func stop(process *exec.Cmd) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- process.Wait()
+	}()
+
+	err := process.Process.Signal(syscall.SIGTERM)
+	if err != nil {
+		_ = process.Process.Kill()
+		<-done
+		return err
+	}
+	select {
+	case <-done:
+		// mysqld exits with a zero status on SIGTERM, any error was already logged
+		// in its log file
+		return nil
+	case <-time.After(stopTimeout):
+		_ = process.Process.Kill()
+		<-done
+		return errors.New(ErrorStopTimeout)
+	}
+}
+
+func initializeArgs(dir string) []string {
+	return []string{
+		"--no-defaults",
+		"--initialize-insecure",
+		"--datadir=" + filepath.Join(dir, dataDir),
+		"--log-error=" + filepath.Join(dir, logFile),
+	}
+}
+
+func startArgs(dir string) []string {
+	return []string{
+		"--no-defaults",
+		"--datadir=" + filepath.Join(dir, dataDir),
+		"--socket=" + filepath.Join(dir, socketFile),
+		"--pid-file=" + filepath.Join(dir, pidFile),
+		"--log-error=" + filepath.Join(dir, logFile),
+		"--skip-networking",
+		"--mysqlx=OFF",
+	}
+}
+
+func (cfg *Config) AddToViper(v *viper.Viper) {
+	_ = v.UnmarshalKey(flagDirectory, &cfg.Directory)
+	_ = v.UnmarshalKey(flagBinary, &cfg.Mysqld)
+	_ = v.UnmarshalKey(flagDatabase, &cfg.Database)
+}
+
+// AddToCommand adds Config to the given cobra.Command.
+func (cfg *Config) AddToCommand(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&cfg.Directory, flagDirectory, "", "Directory in which the local database stores its files.")
+	cmd.Flags().StringVar(&cfg.Mysqld, flagBinary, "mysqld", "Path to the mysqld binary used to run the local database.")
+	cmd.Flags().StringVar(&cfg.Database, flagDatabase, "arewefastyet", "Name of the database to use in the local database.")
+
+	_ = viper.BindPFlag(flagDirectory, cmd.Flags().Lookup(flagDirectory))
+	_ = viper.BindPFlag(flagBinary, cmd.Flags().Lookup(flagBinary))
+	_ = viper.BindPFlag(flagDatabase, cmd.Flags().Lookup(flagDatabase))
+}
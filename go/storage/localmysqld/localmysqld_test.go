@@ -0,0 +1,54 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package localmysqld
+
+import (
+	"os/exec"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestNewClient(t *testing.T) {
+	c := qt.New(t)
+
+	mysqld, err := exec.LookPath("mysqld")
+	if err != nil {
+		c.Skip("mysqld is not installed")
+	}
+
+	dir := c.TempDir()
+	cfg := Config{Directory: dir, Mysqld: mysqld}
+	client, err := cfg.NewClient()
+	c.Assert(err, qt.IsNil)
+
+	_, err = client.Write("CREATE TABLE t (id INT NOT NULL AUTO_INCREMENT, PRIMARY KEY (id))")
+	c.Assert(err, qt.IsNil)
+	id, err := client.Write("INSERT INTO t VALUES ()")
+	c.Assert(err, qt.IsNil)
+	c.Assert(id, qt.Equals, int64(1))
+
+	// the mysqld process was started by the client, closing it stops the process
+	c.Assert(client.Close(), qt.IsNil)
+	c.Assert(isRunning(dir), qt.IsFalse)
+}
+
+func TestConfig_IsValid(t *testing.T) {
+	c := qt.New(t)
+	c.Assert((&Config{}).IsValid(), qt.IsFalse)
+	c.Assert((&Config{Directory: "/tmp/arewefastyet"}).IsValid(), qt.IsTrue)
+}
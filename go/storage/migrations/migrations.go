@@ -0,0 +1,370 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package migrations holds the versioned schema of the arewefastyet database.
+// Each migration is a SQL file named <version>_<name>.sql embedded in the binary,
+// the versions that were applied are recorded in the schema_migrations table.
+//
+// The statements of the migrations are not idempotent, like a plain CREATE TABLE
+// or ALTER TABLE ... ADD COLUMN: each of them is applied exactly once. The number
+// of statements applied of the migration in progress is recorded after each of
+// them, so a migration that failed halfway resumes at its failed statement.
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vitessio/arewefastyet/go/storage"
+	"github.com/vitessio/arewefastyet/go/storage/mysql"
+)
+
+const (
+	ErrorMalformedFileName  = "malformed migration file name"
+	ErrorDuplicatedVersion  = "migration version is defined more than once"
+	ErrorMissingVersion     = "migration versions must follow each other starting from 1"
+	ErrorSchemaOutdated     = "the database schema is outdated, run 'arewefastyet db migrate'"
+	ErrorSchemaTooRecent    = "the database schema is more recent than this binary"
+	ErrorEmptyMigrationFile = "migration file has no statement"
+
+	migrationsDir = "sql"
+
+	// baselineTable is a table created by the first migration, it exists in the
+	// databases created before the schema was versioned.
+	baselineTable = "execution"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+type (
+	// Migration is a single version of the schema.
+	Migration struct {
+		Version    int
+		Name       string
+		Statements []string
+	}
+
+	// Status tells whether a Migration was applied to the database.
+	Status struct {
+		Version   int
+		Name      string
+		AppliedAt *time.Time
+	}
+)
+
+// All returns the embedded migrations sorted by version.
+func All() ([]Migration, error) {
+	return load(files)
+}
+
+// LatestVersion returns the version of the schema expected by this binary.
+func LatestVersion() (int, error) {
+	all, err := All()
+	if err != nil {
+		return 0, err
+	}
+	if len(all) == 0 {
+		return 0, nil
+	}
+	return all[len(all)-1].Version, nil
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, migrationsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Migration
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		version, name, err := parseFileName(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		content, err := fs.ReadFile(fsys, path.Join(migrationsDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		statements := splitStatements(string(content))
+		if len(statements) == 0 {
+			return nil, fmt.Errorf("%s: %s", ErrorEmptyMigrationFile, entry.Name())
+		}
+		res = append(res, Migration{Version: version, Name: name, Statements: statements})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Version < res[j].Version
+	})
+	for i, m := range res {
+		if i > 0 && res[i-1].Version == m.Version {
+			return nil, fmt.Errorf("%s: %d", ErrorDuplicatedVersion, m.Version)
+		}
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("%s: %d", ErrorMissingVersion, m.Version)
+		}
+	}
+	return res, nil
+}
+
+// parseFileName splits a file name such as 0001_initial.sql into its version and name.
+func parseFileName(fileName string) (int, string, error) {
+	versionStr, name, found := strings.Cut(strings.TrimSuffix(fileName, ".sql"), "_")
+	if !found || name == "" {
+		return 0, "", fmt.Errorf("%s: %s", ErrorMalformedFileName, fileName)
+	}
+	version, err := strconv.Atoi(versionStr)
+	if err != nil || version <= 0 {
+		return 0, "", fmt.Errorf("%s: %s", ErrorMalformedFileName, fileName)
+	}
+	return version, name, nil
+}
+
+// splitStatements removes the comment lines of the given SQL script and splits it
+// into statements separated by semicolons.
+func splitStatements(script string) []string {
+	var b strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	var res []string
+	for _, stmt := range strings.Split(b.String(), ";") {
+		stmt = strings.TrimSpace(stmt)
+		if stmt != "" {
+			res = append(res, stmt)
+		}
+	}
+	return res
+}
+
+// CurrentVersion returns the version of the schema of the database, 0 if no
+// migration was ever applied.
+func CurrentVersion(client storage.SQLClient) (int, error) {
+	applied, err := appliedMigrations(storage.Primary(client))
+	if err != nil {
+		return 0, err
+	}
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+// CheckVersion returns an error if the schema of the database is not the one
+// expected by this binary.
+func CheckVersion(client storage.SQLClient) error {
+	latest, err := LatestVersion()
+	if err != nil {
+		return err
+	}
+	current, err := CurrentVersion(client)
+	if err != nil {
+		return err
+	}
+	if current < latest {
+		return fmt.Errorf("%s: version %d, expected %d", ErrorSchemaOutdated, current, latest)
+	}
+	if current > latest {
+		return fmt.Errorf("%s: version %d, expected %d", ErrorSchemaTooRecent, current, latest)
+	}
+	return nil
+}
+
+// Migrate applies all the migrations that were not yet applied to the database,
+// and returns them.
+func Migrate(client storage.SQLClient) ([]Migration, error) {
+	if client == nil {
+		return nil, errors.New(mysql.ErrorClientConnectionNotInitialized)
+	}
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	// the progress must be read where it is written
+	client = storage.Primary(client)
+
+	for _, query := range []string{
+		"CREATE TABLE IF NOT EXISTS schema_migrations (version INT NOT NULL, name VARCHAR(256) NOT NULL, applied_at DATETIME NOT NULL, PRIMARY KEY (version))",
+		"CREATE TABLE IF NOT EXISTS schema_migrations_progress (version INT NOT NULL, statements INT NOT NULL, PRIMARY KEY (version))",
+	} {
+		if _, err = client.Write(query); err != nil {
+			return nil, err
+		}
+	}
+	applied, err := appliedMigrations(client)
+	if err != nil {
+		return nil, err
+	}
+	progress, err := migrationsProgress(client)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Migration
+	for _, m := range all {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if m.Version == 1 && len(applied) == 0 && progress[m.Version] == 0 {
+			// the first migration describes the tables of the databases created
+			// before the schema was versioned, they are adopted as they are
+			baseline, err := tableExists(client, baselineTable)
+			if err != nil {
+				return res, err
+			}
+			if baseline {
+				progress[m.Version] = len(m.Statements)
+			}
+		}
+		for i := progress[m.Version]; i < len(m.Statements); i++ {
+			if _, err = client.Write(m.Statements[i]); err != nil {
+				return res, fmt.Errorf("migration %d_%s, statement %d: %w", m.Version, m.Name, i+1, err)
+			}
+			_, err = client.Write("REPLACE INTO schema_migrations_progress(version, statements) VALUES(?, ?)", m.Version, i+1)
+			if err != nil {
+				return res, err
+			}
+		}
+		_, err = client.Write("INSERT INTO schema_migrations(version, name, applied_at) VALUES(?, ?, UTC_TIMESTAMP())", m.Version, m.Name)
+		if err != nil {
+			return res, err
+		}
+		_, err = client.Write("DELETE FROM schema_migrations_progress WHERE version = ?", m.Version)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, m)
+	}
+	return res, nil
+}
+
+// GetStatus returns the status of every migration known by this binary or applied
+// to the database, sorted by version.
+func GetStatus(client storage.SQLClient) ([]Status, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(storage.Primary(client))
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Status
+	for _, m := range all {
+		s := Status{Version: m.Version, Name: m.Name}
+		if a, ok := applied[m.Version]; ok {
+			s.AppliedAt = a.AppliedAt
+			delete(applied, m.Version)
+		}
+		res = append(res, s)
+	}
+
+	// migrations applied by a more recent binary
+	for _, a := range applied {
+		res = append(res, a)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Version < res[j].Version
+	})
+	return res, nil
+}
+
+// appliedMigrations returns the migrations recorded in schema_migrations indexed
+// by version, the map is empty if the table does not exist yet.
+func appliedMigrations(client storage.SQLClient) (map[int]Status, error) {
+	if client == nil {
+		return nil, errors.New(mysql.ErrorClientConnectionNotInitialized)
+	}
+	exists, err := tableExists(client, "schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	res := map[int]Status{}
+	if !exists {
+		return res, nil
+	}
+
+	rows, err := client.Read("SELECT version, name, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s Status
+		if err = rows.Scan(&s.Version, &s.Name, &s.AppliedAt); err != nil {
+			return nil, err
+		}
+		res[s.Version] = s
+	}
+	return res, nil
+}
+
+// migrationsProgress returns the number of statements applied of the migrations
+// that failed halfway, indexed by version.
+func migrationsProgress(client storage.SQLClient) (map[int]int, error) {
+	rows, err := client.Read("SELECT version, statements FROM schema_migrations_progress")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := map[int]int{}
+	for rows.Next() {
+		var version, statements int
+		if err = rows.Scan(&version, &statements); err != nil {
+			return nil, err
+		}
+		res[version] = statements
+	}
+	return res, rows.Err()
+}
+
+// tableExists returns true if the given table exists in the database.
+func tableExists(client storage.SQLClient, table string) (bool, error) {
+	rows, err := client.Read("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?", table)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	var count int
+	for rows.Next() {
+		if err = rows.Scan(&count); err != nil {
+			return false, err
+		}
+	}
+	return count > 0, rows.Err()
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestAll(t *testing.T) {
+	c := qt.New(t)
+
+	all, err := All()
+	c.Assert(err, qt.IsNil)
+	c.Assert(all, qt.Not(qt.HasLen), 0)
+	for i, m := range all {
+		c.Assert(m.Version, qt.Equals, i+1)
+		c.Assert(m.Statements, qt.Not(qt.HasLen), 0)
+
+		// the statements are applied exactly once, making some of them idempotent
+		// would only hide a wrongly recorded progress
+		for _, stmt := range m.Statements {
+			c.Assert(strings.Contains(strings.ToUpper(stmt), "IF NOT EXISTS"), qt.IsFalse, qt.Commentf("%04d_%s: %s", m.Version, m.Name, stmt))
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		want    []Migration
+		wantErr string
+	}{
+		{
+			name: "sorted with comments removed",
+			files: fstest.MapFS{
+				"sql/0002_second.sql": {Data: []byte("-- second\nCREATE TABLE b (id INT);\nCREATE TABLE c (id INT);\n")},
+				"sql/0001_first.sql":  {Data: []byte("CREATE TABLE a (\n    id INT\n);")},
+			},
+			want: []Migration{
+				{Version: 1, Name: "first", Statements: []string{"CREATE TABLE a (\n    id INT\n)"}},
+				{Version: 2, Name: "second", Statements: []string{"CREATE TABLE b (id INT)", "CREATE TABLE c (id INT)"}},
+			},
+		},
+		{
+			name:    "malformed file name",
+			files:   fstest.MapFS{"sql/first.sql": {Data: []byte("CREATE TABLE a (id INT);")}},
+			wantErr: ErrorMalformedFileName + ": first.sql",
+		},
+		{
+			name: "missing version",
+			files: fstest.MapFS{
+				"sql/0001_first.sql": {Data: []byte("CREATE TABLE a (id INT);")},
+				"sql/0003_third.sql": {Data: []byte("CREATE TABLE c (id INT);")},
+			},
+			wantErr: ErrorMissingVersion + ": 3",
+		},
+		{
+			name: "duplicated version",
+			files: fstest.MapFS{
+				"sql/0001_first.sql": {Data: []byte("CREATE TABLE a (id INT);")},
+				"sql/0001_other.sql": {Data: []byte("CREATE TABLE b (id INT);")},
+			},
+			wantErr: ErrorDuplicatedVersion + ": 1",
+		},
+		{
+			name:    "empty migration",
+			files:   fstest.MapFS{"sql/0001_first.sql": {Data: []byte("-- nothing\n")}},
+			wantErr: ErrorEmptyMigrationFile + ": 0001_first.sql",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			got, err := load(tt.files)
+			if tt.wantErr != "" {
+				c.Assert(err, qt.ErrorMatches, tt.wantErr)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(got, qt.DeepEquals, tt.want)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+-- Tables used by the executions, macrobenchmarks and microbenchmarks.
+
+CREATE TABLE execution (
+    uuid        VARCHAR(100) NOT NULL,
+    status      VARCHAR(50)  NOT NULL,
+    source      VARCHAR(100) NOT NULL,
+    git_ref     VARCHAR(100) NOT NULL,
+    workload    VARCHAR(100) NOT NULL,
+    pull_nb     INT          NOT NULL DEFAULT 0,
+    go_version  VARCHAR(50)  NOT NULL DEFAULT '',
+    started_at  DATETIME     NULL,
+    finished_at DATETIME     NULL,
+    PRIMARY KEY (uuid),
+    KEY idx_execution_git_ref (git_ref),
+    KEY idx_execution_started_at (started_at),
+    KEY idx_execution_source_status_workload (source, status, workload)
+);
+
+CREATE TABLE macrobenchmark (
+    macrobenchmark_id      INT          NOT NULL AUTO_INCREMENT,
+    exec_uuid              VARCHAR(100) NULL,
+    `commit`               VARCHAR(100) NOT NULL,
+    vtgate_planner_version VARCHAR(50)  NOT NULL,
+    workload               VARCHAR(100) NOT NULL,
+    PRIMARY KEY (macrobenchmark_id),
+    KEY idx_macrobenchmark_exec_uuid (exec_uuid),
+    KEY idx_macrobenchmark_commit (`commit`)
+);
+
+CREATE TABLE macrobenchmark_results (
+    results_id        INT    NOT NULL AUTO_INCREMENT,
+    macrobenchmark_id INT    NOT NULL,
+    queries           INT    NOT NULL,
+    tps               DOUBLE NOT NULL,
+    latency           DOUBLE NOT NULL,
+    errors            DOUBLE NOT NULL,
+    reconnects        DOUBLE NOT NULL,
+    time              INT    NOT NULL,
+    threads           DOUBLE NOT NULL,
+    total_qps         DOUBLE NOT NULL,
+    reads_qps         DOUBLE NOT NULL,
+    writes_qps        DOUBLE NOT NULL,
+    other_qps         DOUBLE NOT NULL,
+    PRIMARY KEY (results_id),
+    KEY idx_macrobenchmark_results_macrobenchmark_id (macrobenchmark_id)
+);
+
+CREATE TABLE query_plans (
+    id                INT          NOT NULL AUTO_INCREMENT,
+    exec_uuid         VARCHAR(100) NOT NULL,
+    macrobenchmark_id INT          NOT NULL,
+    `key`             TEXT         NOT NULL,
+    plan              MEDIUMTEXT   NOT NULL,
+    exec_count        BIGINT       NOT NULL,
+    exec_time         BIGINT       NOT NULL,
+    `rows`            BIGINT       NOT NULL,
+    errors            BIGINT       NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_query_plans_exec_uuid (exec_uuid),
+    KEY idx_query_plans_macrobenchmark_id (macrobenchmark_id)
+);
+
+CREATE TABLE metrics (
+    id        INT          NOT NULL AUTO_INCREMENT,
+    exec_uuid VARCHAR(100) NOT NULL,
+    `name`    VARCHAR(256) NOT NULL,
+    `value`   DOUBLE       NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_metrics_exec_uuid (exec_uuid)
+);
+
+CREATE TABLE microbenchmark (
+    microbenchmark_no INT          NOT NULL AUTO_INCREMENT,
+    exec_uuid         VARCHAR(100) NULL,
+    pkg_name          VARCHAR(256) NOT NULL,
+    name              VARCHAR(256) NOT NULL,
+    git_ref           VARCHAR(100) NOT NULL,
+    PRIMARY KEY (microbenchmark_no),
+    KEY idx_microbenchmark_exec_uuid (exec_uuid),
+    KEY idx_microbenchmark_git_ref (git_ref),
+    KEY idx_microbenchmark_name (name)
+);
+
+CREATE TABLE microbenchmark_details (
+    id                INT          NOT NULL AUTO_INCREMENT,
+    microbenchmark_no INT          NOT NULL,
+    name              VARCHAR(256) NOT NULL,
+    bench_type        VARCHAR(50)  NOT NULL,
+    n                 BIGINT       NOT NULL,
+    ns_per_op         DOUBLE       NOT NULL,
+    mb_per_sec        DOUBLE       NOT NULL,
+    bytes_per_op      DOUBLE       NOT NULL,
+    allocs_per_op     DOUBLE       NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_microbenchmark_details_microbenchmark_no (microbenchmark_no)
+);
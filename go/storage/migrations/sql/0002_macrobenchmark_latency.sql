@@ -0,0 +1,19 @@
+-- Latency percentiles and histogram reported by sysbench for each macrobenchmark.
+
+CREATE TABLE macrobenchmark_latency (
+    macrobenchmark_id INT    NOT NULL,
+    p50               DOUBLE NOT NULL,
+    p95               DOUBLE NOT NULL,
+    p99               DOUBLE NOT NULL,
+    max               DOUBLE NOT NULL,
+    PRIMARY KEY (macrobenchmark_id)
+);
+
+CREATE TABLE macrobenchmark_latency_histogram (
+    id                INT    NOT NULL AUTO_INCREMENT,
+    macrobenchmark_id INT    NOT NULL,
+    value             DOUBLE NOT NULL,
+    count             BIGINT NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_macrobenchmark_latency_histogram_macrobenchmark_id (macrobenchmark_id)
+);
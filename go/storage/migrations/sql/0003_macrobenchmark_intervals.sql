@@ -0,0 +1,18 @@
+-- Per-interval reports printed by sysbench during the run step of a macrobenchmark.
+
+CREATE TABLE macrobenchmark_intervals (
+    id                INT    NOT NULL AUTO_INCREMENT,
+    macrobenchmark_id INT    NOT NULL,
+    time              INT    NOT NULL,
+    total_qps         DOUBLE NOT NULL,
+    reads_qps         DOUBLE NOT NULL,
+    writes_qps        DOUBLE NOT NULL,
+    other_qps         DOUBLE NOT NULL,
+    tps               DOUBLE NOT NULL,
+    latency           DOUBLE NOT NULL,
+    errors            DOUBLE NOT NULL,
+    reconnects        DOUBLE NOT NULL,
+    threads           DOUBLE NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_macrobenchmark_intervals_macrobenchmark_id (macrobenchmark_id)
+);
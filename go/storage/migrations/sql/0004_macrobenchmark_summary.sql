@@ -0,0 +1,17 @@
+-- Statistical summary of the finished executions of a macrobenchmark, for each
+-- git ref, workload, planner and source. The rows are recomputed from the raw
+-- results whenever an execution of the group finishes or is deleted.
+
+CREATE TABLE macrobenchmark_summary (
+    git_ref     VARCHAR(100) NOT NULL,
+    workload    VARCHAR(100) NOT NULL,
+    planner     VARCHAR(50)  NOT NULL,
+    source      VARCHAR(100) NOT NULL,
+    executions  INT          NOT NULL,
+    summary     MEDIUMTEXT   NOT NULL,
+    samples     MEDIUMTEXT   NOT NULL,
+    finished_at DATETIME     NULL,
+    updated_at  DATETIME     NOT NULL,
+    PRIMARY KEY (git_ref, workload, planner, source),
+    KEY idx_macrobenchmark_summary_finished_at (workload, planner, source, finished_at)
+);
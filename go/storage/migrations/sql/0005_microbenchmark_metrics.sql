@@ -0,0 +1,14 @@
+-- Metrics of the microbenchmarks that have no column in microbenchmark_details, such
+-- as the ones reported with testing.B.ReportMetric. A row holds the value of one unit
+-- for the run-th details row of a sub-benchmark, in the order of their ids.
+
+CREATE TABLE microbenchmark_metrics (
+    id                INT          NOT NULL AUTO_INCREMENT,
+    microbenchmark_no INT          NOT NULL,
+    name              VARCHAR(256) NOT NULL,
+    run               INT          NOT NULL,
+    unit              VARCHAR(100) NOT NULL,
+    value             DOUBLE       NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_microbenchmark_metrics_microbenchmark_no (microbenchmark_no)
+);
@@ -0,0 +1,18 @@
+-- Configuration of the microbenchmark results, as printed by go test: the goos,
+-- goarch, cpu and pkg lines of each run, and for each result the GOMAXPROCS
+-- suffix of its name and the "/key=value" parts of its sub-benchmark name.
+--
+-- The GOMAXPROCS of the results stored so far was not recorded, and a trailing
+-- "-N" of their name may as well be part of a sub-benchmark name, their name is
+-- left as it is and their procs is 0. They are matched with the newer results
+-- when these are read, see microbench.DetailsArray.
+
+ALTER TABLE microbenchmark
+    ADD COLUMN goos   VARCHAR(50)  NOT NULL DEFAULT '',
+    ADD COLUMN goarch VARCHAR(50)  NOT NULL DEFAULT '',
+    ADD COLUMN cpu    VARCHAR(256) NOT NULL DEFAULT '',
+    ADD COLUMN pkg    VARCHAR(256) NOT NULL DEFAULT '';
+
+ALTER TABLE microbenchmark_details
+    ADD COLUMN procs       INT           NOT NULL DEFAULT 0 AFTER name,
+    ADD COLUMN name_config VARCHAR(1024) NOT NULL DEFAULT '' AFTER procs;
@@ -0,0 +1,6 @@
+-- Packages benchmarked by a microbenchmark execution, separated by commas, when it
+-- is limited to some packages like the ones affected by a pull request. It is NULL
+-- when all the packages are benchmarked.
+
+ALTER TABLE execution
+    ADD COLUMN microbench_packages TEXT NULL;
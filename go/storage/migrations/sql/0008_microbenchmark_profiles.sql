@@ -0,0 +1,11 @@
+-- Profiles of the microbenchmarks, as written by go test -cpuprofile and -memprofile,
+-- at most one per type for each microbenchmark row.
+
+CREATE TABLE microbenchmark_profiles (
+    id                INT         NOT NULL AUTO_INCREMENT,
+    microbenchmark_no INT         NOT NULL,
+    profile_type      VARCHAR(10) NOT NULL,
+    profile           MEDIUMBLOB  NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY idx_microbenchmark_profiles_microbenchmark_no_type (microbenchmark_no, profile_type)
+);
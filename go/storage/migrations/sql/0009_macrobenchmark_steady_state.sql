@@ -0,0 +1,10 @@
+-- Steady state check of the macrobenchmarks, evaluated on their per-interval reports
+-- when the results are stored. There is no row when there were not enough intervals
+-- to run the check.
+
+CREATE TABLE macrobenchmark_steady_state (
+    macrobenchmark_id INT        NOT NULL,
+    steady            TINYINT(1) NOT NULL,
+    drift             DOUBLE     NOT NULL,
+    PRIMARY KEY (macrobenchmark_id)
+);
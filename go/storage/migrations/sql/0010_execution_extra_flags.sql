@@ -0,0 +1,6 @@
+-- Flags added to the flags of vtgate and vttablet by an execution requested with
+-- them. They are NULL when the flags of the configuration are used as they are.
+
+ALTER TABLE execution
+    ADD COLUMN vtgate_flags TEXT NULL,
+    ADD COLUMN vttablet_flags TEXT NULL;
@@ -0,0 +1,26 @@
+-- The metrics of the microbenchmarks reference the details row they were measured
+-- with, instead of its position among the details rows of its sub-benchmark.
+--
+-- The existing metrics are matched to the run-th details row of their sub-benchmark,
+-- in the order of their ids, the ones left without a details row are dropped.
+
+ALTER TABLE microbenchmark_metrics
+    ADD COLUMN microbenchmark_details_id INT NULL AFTER id;
+
+UPDATE microbenchmark_metrics AS mm
+JOIN (
+    SELECT id, microbenchmark_no, name, ROW_NUMBER() OVER (PARTITION BY microbenchmark_no, name ORDER BY id) - 1 AS run
+    FROM microbenchmark_details
+) AS md ON md.microbenchmark_no = mm.microbenchmark_no AND md.name = mm.name AND md.run = mm.run
+SET mm.microbenchmark_details_id = md.id;
+
+DELETE FROM microbenchmark_metrics
+WHERE microbenchmark_details_id IS NULL;
+
+ALTER TABLE microbenchmark_metrics
+    MODIFY COLUMN microbenchmark_details_id INT NOT NULL,
+    DROP INDEX idx_microbenchmark_metrics_microbenchmark_no,
+    DROP COLUMN microbenchmark_no,
+    DROP COLUMN name,
+    DROP COLUMN run,
+    ADD KEY idx_microbenchmark_metrics_microbenchmark_details_id (microbenchmark_details_id);
@@ -19,8 +19,7 @@
 package mysql
 
 import (
-	"database/sql"
-	"fmt"
+	gomysql "github.com/go-sql-driver/mysql"
 )
 
 type ConfigDB struct {
@@ -28,19 +27,54 @@ type ConfigDB struct {
 	User     string
 	Password string
 	Database string
+
+	// DSN is a data source name, as defined by github.com/go-sql-driver/mysql.
+	// When set, it is used instead of Host, User, Password and Database.
+	DSN string
+
+	// ReadHost is the hostname of a read replica. If set, the queries sent
+	// through Client.Read are executed on it.
+	ReadHost string
+
+	// TLS is the TLS mode of the connections: "true", "false", "skip-verify" or "preferred".
+	// If empty, the mode of the DSN is used.
+	TLS string
 }
 
 func (cfg ConfigDB) NewClient() (*Client, error) {
-	var err error
-	client := &Client{}
-	client.db, err = sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=true", cfg.User, cfg.Password, cfg.Host, cfg.Database))
-	if err != nil {
-		return nil, err
-	}
-	return client, nil
+	return New(cfg)
 }
 
 func (cfg ConfigDB) IsValid() bool {
+	if cfg.DSN != "" {
+		return true
+	}
 	return !(cfg.Database == "" || cfg.User == "" || cfg.Host == "")
 }
 
+// connectionString returns the data source name used to connect to the given host,
+// an empty host means the host of the ConfigDB.
+func (cfg ConfigDB) connectionString(host string) (string, error) {
+	dc := gomysql.NewConfig()
+	if cfg.DSN != "" {
+		var err error
+		dc, err = gomysql.ParseDSN(cfg.DSN)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		dc.User = cfg.User
+		dc.Passwd = cfg.Password
+		dc.Net = "tcp"
+		dc.Addr = cfg.Host
+		dc.DBName = cfg.Database
+	}
+	if host != "" {
+		dc.Addr = host
+	}
+	if cfg.TLS != "" {
+		dc.TLSConfig = cfg.TLS
+	}
+	dc.ParseTime = true
+	return dc.FormatDSN(), nil
+}
@@ -34,6 +34,7 @@ func TestConfigDB_IsValid(t *testing.T) {
 		{name: "Invalid ConfigDB, missing host", config: ConfigDB{User: "user", Password: "password", Database: "database"}, wantValid: false},
 		{name: "Invalid ConfigDB, missing user", config: ConfigDB{Host: "host", Password: "password", Database: "database"}, wantValid: false},
 		{name: "Invalid ConfigDB, missing database", config: ConfigDB{Host: "host", User: "user", Password: "password"}, wantValid: false},
+		{name: "Valid ConfigDB, DSN only", config: ConfigDB{DSN: "user:password@tcp(host:3306)/database"}, wantValid: true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -43,3 +44,25 @@ func TestConfigDB_IsValid(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigDB_connectionString(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ConfigDB
+		host   string
+		want   string
+	}{
+		{name: "From fields", config: ConfigDB{Host: "host:3306", User: "user", Password: "password", Database: "database"}, want: "user:password@tcp(host:3306)/database?parseTime=true"},
+		{name: "From fields with TLS", config: ConfigDB{Host: "host:3306", User: "user", Database: "database", TLS: "skip-verify"}, want: "user@tcp(host:3306)/database?parseTime=true&tls=skip-verify"},
+		{name: "From DSN", config: ConfigDB{DSN: "user:password@tcp(host:3306)/database?tls=true", Host: "ignored"}, want: "user:password@tcp(host:3306)/database?parseTime=true&tls=true"},
+		{name: "Read replica", config: ConfigDB{DSN: "user:password@tcp(host:3306)/database"}, host: "replica:3306", want: "user:password@tcp(replica:3306)/database?parseTime=true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			got, err := tt.config.connectionString(tt.host)
+			c.Assert(err, qt.IsNil)
+			c.Assert(got, qt.Equals, tt.want)
+		})
+	}
+}
@@ -28,6 +28,9 @@ const (
 	flagDatabaseHost     = "db-host"
 	flagDatabasePassword = "db-password"
 	flagDatabaseUser     = "db-user"
+	flagDatabaseDSN      = "db-dsn"
+	flagDatabaseReadHost = "db-read-host"
+	flagDatabaseTLS      = "db-tls"
 )
 
 func (cfg *ConfigDB) AddToViper(v *viper.Viper) {
@@ -35,6 +38,9 @@ func (cfg *ConfigDB) AddToViper(v *viper.Viper) {
 	_ = v.UnmarshalKey(flagDatabaseHost, &cfg.Host)
 	_ = v.UnmarshalKey(flagDatabasePassword, &cfg.Password)
 	_ = v.UnmarshalKey(flagDatabaseUser, &cfg.User)
+	_ = v.UnmarshalKey(flagDatabaseDSN, &cfg.DSN)
+	_ = v.UnmarshalKey(flagDatabaseReadHost, &cfg.ReadHost)
+	_ = v.UnmarshalKey(flagDatabaseTLS, &cfg.TLS)
 }
 
 func (cfg *ConfigDB) AddToCommand(cmd *cobra.Command) {
@@ -42,9 +48,15 @@ func (cfg *ConfigDB) AddToCommand(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&cfg.Host, flagDatabaseHost, "", "Hostname of the database")
 	cmd.Flags().StringVar(&cfg.Password, flagDatabasePassword, "", "Password to authenticate the database.")
 	cmd.Flags().StringVar(&cfg.User, flagDatabaseUser, "", "User used to connect to the database")
+	cmd.Flags().StringVar(&cfg.DSN, flagDatabaseDSN, "", "Data source name of the database, if set it is used instead of the host, user, password and database flags.")
+	cmd.Flags().StringVar(&cfg.ReadHost, flagDatabaseReadHost, "", "Hostname of a read replica of the database, used for read queries.")
+	cmd.Flags().StringVar(&cfg.TLS, flagDatabaseTLS, "", "TLS mode of the connections to the database: true, false, skip-verify or preferred.")
 
 	_ = viper.BindPFlag(flagDatabaseName, cmd.Flags().Lookup(flagDatabaseName))
 	_ = viper.BindPFlag(flagDatabaseHost, cmd.Flags().Lookup(flagDatabaseHost))
 	_ = viper.BindPFlag(flagDatabasePassword, cmd.Flags().Lookup(flagDatabasePassword))
 	_ = viper.BindPFlag(flagDatabaseUser, cmd.Flags().Lookup(flagDatabaseUser))
+	_ = viper.BindPFlag(flagDatabaseDSN, cmd.Flags().Lookup(flagDatabaseDSN))
+	_ = viper.BindPFlag(flagDatabaseReadHost, cmd.Flags().Lookup(flagDatabaseReadHost))
+	_ = viper.BindPFlag(flagDatabaseTLS, cmd.Flags().Lookup(flagDatabaseTLS))
 }
@@ -18,61 +18,41 @@ package mysql
 
 import (
 	"database/sql"
-	"errors"
-	"fmt"
 
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/vitessio/arewefastyet/go/storage"
 )
 
 const (
-	ErrorClientConnectionNotInitialized = "the client connection to the database is not initialized"
+	ErrorClientConnectionNotInitialized = storage.ErrorClientConnectionNotInitialized
 )
 
+// Client is a storage.Client connected to a MySQL server.
 type Client struct {
-	db *sql.DB
+	*storage.DB
 }
 
 // New creates a new Client based on the given ConfigDB.
-func New(config ConfigDB) (client *Client, err error) {
-	client = &Client{}
-	client.db, err = sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=true", config.User, config.Password, config.Host, config.Database))
+func New(config ConfigDB) (*Client, error) {
+	dsn, err := config.connectionString("")
 	if err != nil {
 		return nil, err
 	}
-	return client, nil
-}
-
-func (c *Client) Close() error {
-	if c.db == nil {
-		return errors.New(ErrorClientConnectionNotInitialized)
-	}
-	return c.db.Close()
-}
-
-func (c *Client) Write(query string, args ...interface{}) (int64, error) {
-	if c.db == nil {
-		return 0, errors.New(ErrorClientConnectionNotInitialized)
-	}
-	stms, err := c.db.Prepare(query)
-	if err != nil {
-		return 0, err
-	}
-	defer stms.Close()
-
-	res, err := stms.Exec(args...)
+	db, err := sql.Open("mysql", dsn)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return res.LastInsertId()
-}
 
-func (c *Client) Read(query string, args ...interface{}) (*sql.Rows, error) {
-	if c.db == nil {
-		return nil, errors.New(ErrorClientConnectionNotInitialized)
-	}
-	rows, err := c.db.Query(query, args...)
-	if err != nil {
-		return nil, err
+	var readDB *sql.DB
+	if config.ReadHost != "" {
+		readDSN, err := config.connectionString(config.ReadHost)
+		if err != nil {
+			return nil, err
+		}
+		readDB, err = sql.Open("mysql", readDSN)
+		if err != nil {
+			return nil, err
+		}
 	}
-	return rows, nil
+	return &Client{DB: storage.NewDB(db, readDB)}, nil
 }
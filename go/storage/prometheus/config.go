@@ -0,0 +1,98 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	flagPrometheusAddress  = "prometheus-address"
+	flagPrometheusUsername = "prometheus-username"
+	flagPrometheusPassword = "prometheus-password"
+	flagPrometheusStep     = "prometheus-step"
+
+	defaultStep = 15 * time.Second
+)
+
+// Config defines the required configuration used to query
+// the HTTP API of a Prometheus server.
+type Config struct {
+	Address  string
+	User     string
+	Password string
+
+	// Step is the resolution of the range queries.
+	Step time.Duration
+}
+
+func (cfg Config) NewClient() (*Client, error) {
+	if !cfg.IsValid() {
+		return nil, errors.New(ErrorInvalidConfiguration)
+	}
+
+	if cfg.Step <= 0 {
+		cfg.Step = defaultStep
+	}
+
+	// Add the prefix http:// to the address if it is not already present
+	if matched, err := regexp.Match(`http(s?)://.+`, []byte(cfg.Address)); !matched || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		cfg.Address = "http://" + cfg.Address
+	}
+	cfg.Address = strings.TrimSuffix(cfg.Address, "/")
+
+	client := Client{
+		Config: &cfg,
+		http:   &http.Client{Timeout: time.Minute},
+	}
+	return &client, nil
+}
+
+// IsValid return true if Config is ready to be used, and false otherwise.
+func (cfg Config) IsValid() bool {
+	return cfg.Address != ""
+}
+
+func (cfg *Config) AddToViper(v *viper.Viper) {
+	_ = v.UnmarshalKey(flagPrometheusAddress, &cfg.Address)
+	_ = v.UnmarshalKey(flagPrometheusUsername, &cfg.User)
+	_ = v.UnmarshalKey(flagPrometheusPassword, &cfg.Password)
+	cfg.Step = v.GetDuration(flagPrometheusStep)
+}
+
+// AddToCommand adds Config to the given cobra.Command.
+func (cfg *Config) AddToCommand(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&cfg.Address, flagPrometheusAddress, "", "Address of the Prometheus HTTP API.")
+	cmd.Flags().StringVar(&cfg.User, flagPrometheusUsername, "", "Username used to connect to Prometheus.")
+	cmd.Flags().StringVar(&cfg.Password, flagPrometheusPassword, "", "Password used to connect to Prometheus.")
+	cmd.Flags().DurationVar(&cfg.Step, flagPrometheusStep, defaultStep, "Resolution of the range queries sent to Prometheus.")
+
+	_ = viper.BindPFlag(flagPrometheusAddress, cmd.Flags().Lookup(flagPrometheusAddress))
+	_ = viper.BindPFlag(flagPrometheusUsername, cmd.Flags().Lookup(flagPrometheusUsername))
+	_ = viper.BindPFlag(flagPrometheusPassword, cmd.Flags().Lookup(flagPrometheusPassword))
+	_ = viper.BindPFlag(flagPrometheusStep, cmd.Flags().Lookup(flagPrometheusStep))
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	ErrorInvalidConfiguration = "invalid configuration"
+)
+
+type (
+	// Client used to query the HTTP API of a Prometheus server.
+	Client struct {
+		http   *http.Client
+		Config *Config
+	}
+
+	// Series is a single time series returned by a range query.
+	Series struct {
+		// Labels of the series.
+		Labels map[string]string
+
+		// Values of the series, ordered by time.
+		Values []float64
+	}
+
+	queryRangeResponse struct {
+		Status    string `json:"status"`
+		ErrorType string `json:"errorType"`
+		Error     string `json:"error"`
+		Data      struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Metric map[string]string `json:"metric"`
+				Values [][2]interface{}  `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+)
+
+// QueryRange evaluates the given PromQL query over the time range [start, end]
+// and returns the resulting series.
+func (c *Client) QueryRange(query string, start, end time.Time) ([]Series, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", strconv.FormatInt(start.Unix(), 10))
+	params.Set("end", strconv.FormatInt(end.Unix(), 10))
+	params.Set("step", strconv.FormatFloat(c.Config.Step.Seconds(), 'f', -1, 64))
+
+	request, err := http.NewRequest(http.MethodGet, c.Config.Address+"/api/v1/query_range?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.Config.User != "" {
+		request.SetBasicAuth(c.Config.User, c.Config.Password)
+	}
+
+	response, err := c.http.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("Query error: %s\n", err.Error())
+	}
+	defer response.Body.Close()
+
+	var res queryRangeResponse
+	err = json.NewDecoder(response.Body).Decode(&res)
+	if err != nil {
+		return nil, fmt.Errorf("Query error: %s\n", err.Error())
+	}
+	if res.Status != "success" {
+		return nil, fmt.Errorf("Query error: %s: %s\n", res.ErrorType, res.Error)
+	}
+
+	series := make([]Series, 0, len(res.Data.Result))
+	for _, r := range res.Data.Result {
+		s := Series{Labels: r.Metric, Values: make([]float64, 0, len(r.Values))}
+		for _, sample := range r.Values {
+			// each sample is a [<unix time>, "<value>"] pair
+			str, ok := sample[1].(string)
+			if !ok {
+				return nil, fmt.Errorf("Query error: unexpected sample value %v\n", sample[1])
+			}
+			value, err := strconv.ParseFloat(str, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Query error: %s\n", err.Error())
+			}
+			s.Values = append(s.Values, value)
+		}
+		series = append(series, s)
+	}
+	return series, nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestClient_QueryRange(t *testing.T) {
+	c := qt.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, qt.Equals, "/api/v1/query_range")
+		c.Check(r.URL.Query().Get("query"), qt.Equals, `go_goroutines{component="vtgate"}`)
+		c.Check(r.URL.Query().Get("start"), qt.Equals, "100")
+		c.Check(r.URL.Query().Get("end"), qt.Equals, "200")
+		c.Check(r.URL.Query().Get("step"), qt.Equals, "15")
+		_, _ = w.Write([]byte(`{"status": "success", "data": {"resultType": "matrix", "result": [
+			{"metric": {"instance": "a"}, "values": [[100, "1"], [115, "2.5"]]},
+			{"metric": {"instance": "b"}, "values": [[100, "3"]]}
+		]}}`))
+	}))
+	defer server.Close()
+
+	client, err := Config{Address: server.URL}.NewClient()
+	c.Assert(err, qt.IsNil)
+
+	series, err := client.QueryRange(`go_goroutines{component="vtgate"}`, time.Unix(100, 0), time.Unix(200, 0))
+	c.Assert(err, qt.IsNil)
+	c.Assert(series, qt.DeepEquals, []Series{
+		{Labels: map[string]string{"instance": "a"}, Values: []float64{1, 2.5}},
+		{Labels: map[string]string{"instance": "b"}, Values: []float64{3}},
+	})
+}
+
+func TestClient_QueryRangeError(t *testing.T) {
+	c := qt.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"status": "error", "errorType": "bad_data", "error": "parse error"}`))
+	}))
+	defer server.Close()
+
+	client, err := Config{Address: server.URL}.NewClient()
+	c.Assert(err, qt.IsNil)
+
+	_, err = client.QueryRange(`go_goroutines{`, time.Unix(100, 0), time.Unix(200, 0))
+	c.Assert(err, qt.ErrorMatches, "Query error: bad_data: parse error\n")
+}
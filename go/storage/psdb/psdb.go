@@ -20,12 +20,12 @@ package psdb
 
 import (
 	"database/sql"
-	"errors"
 	"fmt"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/vitessio/arewefastyet/go/storage"
 )
 
 const (
@@ -37,8 +37,6 @@ const (
 	flagPsdbHost          = "planetscale-db-host"
 	flagPsdbDatabase      = "planetscale-db-database"
 
-	errorClientConnectionNotInitialized = "the client connection to the database is not initialized"
-
 	// These two values are used to configure our connection pools.
 	// The values are subject to change, but are based off the recommendations on:
 	// https://github.com/go-sql-driver/mysql#important-settings
@@ -61,9 +59,8 @@ type (
 	}
 
 	Client struct {
-		config  *Config
-		writeDB *sql.DB
-		readDB  *sql.DB
+		*storage.DB
+		config *Config
 	}
 )
 
@@ -86,8 +83,8 @@ func (cfg *Config) AddToViper(v *viper.Viper) {
 	_ = v.UnmarshalKey(flagPsdbUserWrite, &cfg.authWrite.username)
 
 	// Read authentication
-	_ = v.UnmarshalKey(flagPsdbPasswordWrite, &cfg.authRead.password)
-	_ = v.UnmarshalKey(flagPsdbUserWrite, &cfg.authRead.username)
+	_ = v.UnmarshalKey(flagPsdbPasswordRead, &cfg.authRead.password)
+	_ = v.UnmarshalKey(flagPsdbUserRead, &cfg.authRead.username)
 }
 
 func (cfg *Config) AddToCommand(cmd *cobra.Command) {
@@ -132,9 +129,8 @@ func (cfg *Config) NewClient() (*Client, error) {
 	setConnDefault(readdb)
 
 	return &Client{
-		config:  cfg,
-		writeDB: writedb,
-		readDB:  readdb,
+		DB:     storage.NewDB(writedb, readdb),
+		config: cfg,
 	}, nil
 }
 
@@ -143,42 +139,3 @@ func setConnDefault(db *sql.DB) {
 	db.SetMaxOpenConns(maxOpenedConns)
 	db.SetMaxIdleConns(maxOpenedConns)
 }
-
-func (c *Client) Close() error {
-	if c.writeDB == nil || c.readDB == nil {
-		return errors.New(errorClientConnectionNotInitialized)
-	}
-	err := c.writeDB.Close()
-	if err != nil {
-		return err
-	}
-	return c.readDB.Close()
-}
-
-func (c *Client) Write(query string, args ...interface{}) (int64, error) {
-	if c.writeDB == nil {
-		return 0, errors.New(errorClientConnectionNotInitialized)
-	}
-	stms, err := c.writeDB.Prepare(query)
-	if err != nil {
-		return 0, err
-	}
-	defer stms.Close()
-
-	res, err := stms.Exec(args...)
-	if err != nil {
-		return 0, err
-	}
-	return res.LastInsertId()
-}
-
-func (c *Client) Read(query string, args ...interface{}) (*sql.Rows, error) {
-	if c.readDB == nil {
-		return nil, errors.New(errorClientConnectionNotInitialized)
-	}
-	rows, err := c.readDB.Query(query, args...)
-	if err != nil {
-		return nil, err
-	}
-	return rows, nil
-}
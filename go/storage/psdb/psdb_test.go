@@ -0,0 +1,48 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package psdb
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/spf13/viper"
+)
+
+func TestConfig_AddToViper(t *testing.T) {
+	c := qt.New(t)
+
+	v := viper.New()
+	v.Set(flagPsdbOrg, "org")
+	v.Set(flagPsdbHost, "host")
+	v.Set(flagPsdbDatabase, "database")
+	v.Set(flagPsdbUserWrite, "writer")
+	v.Set(flagPsdbPasswordWrite, "write-password")
+	v.Set(flagPsdbUserRead, "reader")
+	v.Set(flagPsdbPasswordRead, "read-password")
+
+	var cfg Config
+	cfg.AddToViper(v)
+	c.Assert(cfg, qt.Equals, Config{
+		organisation: "org",
+		database:     "database",
+		hostname:     "host",
+		authWrite:    auth{username: "writer", password: "write-password"},
+		authRead:     auth{username: "reader", password: "read-password"},
+	})
+	c.Assert(cfg.IsValid(), qt.IsTrue)
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	ErrorClientConnectionNotInitialized = "the client connection to the database is not initialized"
+	ErrorBulkInsertRowLength            = "all the rows of a bulk insert must have the same number of values"
+
+	// maxBulkInsertRows is the maximum number of rows inserted by a single statement.
+	maxBulkInsertRows = 1000
+
+	// maxPlaceholders is the maximum number of placeholders MySQL accepts in a statement.
+	maxPlaceholders = 65535
+)
+
+type (
+	// DB is a Client using connection pools to a database. The read queries are
+	// sent to a separate pool when the database has a read replica.
+	DB struct {
+		write *sql.DB
+		read  *sql.DB
+	}
+
+	// tx is a SQLClient running all its queries in a transaction.
+	tx struct {
+		tx *sql.Tx
+	}
+)
+
+// NewDB creates a DB writing to the write pool and reading from the read pool,
+// a nil read pool means the reads are sent to the write pool.
+func NewDB(write, read *sql.DB) *DB {
+	if read == nil {
+		read = write
+	}
+	return &DB{write: write, read: read}
+}
+
+// Primary returns a DB sending its reads to the write pool, for the reads that must
+// see the writes made just before, which a read replica might not have received yet.
+func (db *DB) Primary() SQLClient {
+	if db == nil || db.read == db.write {
+		return db
+	}
+	return &DB{write: db.write, read: db.write}
+}
+
+func (db *DB) isReady() bool {
+	return db != nil && db.write != nil && db.read != nil
+}
+
+func (db *DB) Close() error {
+	if !db.isReady() {
+		return errors.New(ErrorClientConnectionNotInitialized)
+	}
+	if db.read != db.write {
+		if err := db.read.Close(); err != nil {
+			return err
+		}
+	}
+	return db.write.Close()
+}
+
+func (db *DB) Write(query string, args ...interface{}) (int64, error) {
+	return db.WriteContext(context.Background(), query, args...)
+}
+
+func (db *DB) Read(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.ReadContext(context.Background(), query, args...)
+}
+
+func (db *DB) WriteContext(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	if !db.isReady() {
+		return 0, errors.New(ErrorClientConnectionNotInitialized)
+	}
+	res, err := db.write.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (db *DB) ReadContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if !db.isReady() {
+		return nil, errors.New(ErrorClientConnectionNotInitialized)
+	}
+	return db.read.QueryContext(ctx, query, args...)
+}
+
+func (db *DB) WithTx(ctx context.Context, fn func(tx SQLClient) error) (err error) {
+	if !db.isReady() {
+		return errors.New(ErrorClientConnectionNotInitialized)
+	}
+	sqlTx, err := db.write.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = sqlTx.Rollback()
+			panic(p)
+		}
+	}()
+
+	err = fn(&tx{tx: sqlTx})
+	if err != nil {
+		if rbErr := sqlTx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w, rollback: %s", err, rbErr.Error())
+		}
+		return err
+	}
+	return sqlTx.Commit()
+}
+
+func (t *tx) Write(query string, args ...interface{}) (int64, error) {
+	return t.WriteContext(context.Background(), query, args...)
+}
+
+func (t *tx) Read(query string, args ...interface{}) (*sql.Rows, error) {
+	return t.ReadContext(context.Background(), query, args...)
+}
+
+func (t *tx) WriteContext(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	res, err := t.tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (t *tx) ReadContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return t.tx.QueryContext(ctx, query, args...)
+}
+
+func (t *tx) WithTx(_ context.Context, fn func(tx SQLClient) error) error {
+	return fn(t)
+}
+
+// BulkInsert inserts the given rows using as few statements as possible. The query
+// is an INSERT statement ending with the VALUES keyword, for instance:
+//
+//	INSERT INTO metrics(exec_uuid, `name`, `value`) VALUES
+//
+// Each row holds one value per column. The statements are run in a single
+// transaction, so either all the rows are inserted or none of them are.
+func BulkInsert(ctx context.Context, client SQLClient, query string, rows [][]interface{}) error {
+	if client == nil {
+		return errors.New(ErrorClientConnectionNotInitialized)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	columns := len(rows[0])
+	for _, row := range rows {
+		if len(row) != columns || columns == 0 {
+			return errors.New(ErrorBulkInsertRowLength)
+		}
+	}
+
+	batchSize := min(maxBulkInsertRows, maxPlaceholders/columns)
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", columns), ", ") + ")"
+	return client.WithTx(ctx, func(tx SQLClient) error {
+		for start := 0; start < len(rows); start += batchSize {
+			batch := rows[start:min(start+batchSize, len(rows))]
+
+			values := make([]string, 0, len(batch))
+			args := make([]interface{}, 0, len(batch)*columns)
+			for _, row := range batch {
+				values = append(values, placeholders)
+				args = append(args, row...)
+			}
+			_, err := tx.WriteContext(ctx, query+" "+strings.Join(values, ", "), args...)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
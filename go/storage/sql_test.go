@@ -0,0 +1,151 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+type (
+	statement struct {
+		query string
+		args  []interface{}
+	}
+
+	// recorder is a SQLClient recording the statements it writes, they are
+	// only kept if the transaction they belong to is committed.
+	recorder struct {
+		committed []statement
+		pending   []statement
+		failAt    int
+	}
+)
+
+func (r *recorder) Write(query string, args ...interface{}) (int64, error) {
+	return r.WriteContext(context.Background(), query, args...)
+}
+
+func (r *recorder) Read(query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *recorder) WriteContext(_ context.Context, query string, args ...interface{}) (int64, error) {
+	if r.failAt > 0 && len(r.pending)+1 == r.failAt {
+		return 0, errors.New("write failed")
+	}
+	r.pending = append(r.pending, statement{query: query, args: args})
+	return 0, nil
+}
+
+func (r *recorder) ReadContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return r.Read(query, args...)
+}
+
+func (r *recorder) WithTx(_ context.Context, fn func(tx SQLClient) error) error {
+	r.pending = nil
+	err := fn(r)
+	if err == nil {
+		r.committed = append(r.committed, r.pending...)
+	}
+	r.pending = nil
+	return err
+}
+
+func TestBulkInsert(t *testing.T) {
+	const query = "INSERT INTO t(a, b) VALUES"
+	rows := func(n int) [][]interface{} {
+		var res [][]interface{}
+		for i := 0; i < n; i++ {
+			res = append(res, []interface{}{i, "b"})
+		}
+		return res
+	}
+
+	tests := []struct {
+		name          string
+		rows          [][]interface{}
+		failAt        int
+		wantQueries   []string
+		wantArgsCount []int
+		wantErr       string
+	}{
+		{name: "no rows", rows: nil},
+		{
+			name:          "single statement",
+			rows:          rows(2),
+			wantQueries:   []string{query + " (?, ?), (?, ?)"},
+			wantArgsCount: []int{4},
+		},
+		{
+			name:          "several statements",
+			rows:          rows(maxBulkInsertRows + 1),
+			wantQueries:   []string{"", query + " (?, ?)"},
+			wantArgsCount: []int{2 * maxBulkInsertRows, 2},
+		},
+		{
+			name:    "rows of different length",
+			rows:    [][]interface{}{{1, "b"}, {2}},
+			wantErr: ErrorBulkInsertRowLength,
+		},
+		{
+			name:    "nothing is inserted on failure",
+			rows:    rows(maxBulkInsertRows + 1),
+			failAt:  2,
+			wantErr: "write failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			r := &recorder{failAt: tt.failAt}
+
+			err := BulkInsert(context.Background(), r, query, tt.rows)
+			if tt.wantErr != "" {
+				c.Assert(err, qt.ErrorMatches, tt.wantErr)
+				c.Assert(r.committed, qt.HasLen, 0)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(r.committed, qt.HasLen, len(tt.wantQueries))
+			for i, stmt := range r.committed {
+				if tt.wantQueries[i] != "" {
+					c.Assert(stmt.query, qt.Equals, tt.wantQueries[i])
+				}
+				c.Assert(stmt.args, qt.HasLen, tt.wantArgsCount[i])
+			}
+		})
+	}
+}
+
+func TestPrimary(t *testing.T) {
+	c := qt.New(t)
+	write, read := &sql.DB{}, &sql.DB{}
+
+	primary, ok := Primary(NewDB(write, read)).(*DB)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(primary.write, qt.Equals, write)
+	c.Assert(primary.read, qt.Equals, write)
+
+	// clients without a replica are used as they are
+	r := &recorder{}
+	c.Assert(Primary(r), qt.Equals, SQLClient(r))
+}
@@ -18,9 +18,45 @@
 
 package storage
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+)
 
 type SQLClient interface {
 	Write(query string, args ...interface{}) (int64, error)
 	Read(query string, args ...interface{}) (*sql.Rows, error)
+
+	// WriteContext is like Write, the query is cancelled if ctx is done.
+	WriteContext(ctx context.Context, query string, args ...interface{}) (int64, error)
+
+	// ReadContext is like Read, the query is cancelled if ctx is done.
+	ReadContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+
+	// WithTx calls fn with a SQLClient running all its queries in a single transaction.
+	// The transaction is committed if fn returns nil, and rolled back otherwise.
+	// Calling WithTx on the SQLClient given to fn reuses the same transaction.
+	WithTx(ctx context.Context, fn func(tx SQLClient) error) error
+}
+
+// Client is a SQLClient holding connections that must be closed once done.
+type Client interface {
+	SQLClient
+	Close() error
+}
+
+// primaryReader is implemented by the clients that may read from a replica.
+type primaryReader interface {
+	Primary() SQLClient
+}
+
+// Primary returns a SQLClient reading from the primary server of the given client,
+// for the reads that must see the writes made just before: a read replica might not
+// have received them yet. The client is returned as is if it does not read from a
+// replica, like a transaction.
+func Primary(client SQLClient) SQLClient {
+	if p, ok := client.(primaryReader); ok {
+		return p.Primary()
+	}
+	return client
 }
@@ -0,0 +1,222 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package admin administers the queue of an API server: it lists the queue, requests
+// custom runs, follows their status and logs, and cancels or invalidates them.
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vitessio/arewefastyet/go/client"
+	"github.com/vitessio/arewefastyet/go/exec"
+	"github.com/vitessio/arewefastyet/go/server/api"
+)
+
+const (
+	ErrorRunFailed = "run failed"
+)
+
+// adminAPI is the part of client.Client used to administer the server.
+type adminAPI interface {
+	Queue(ctx context.Context) (*api.ExecutionQueueResponse, error)
+	RequestRun(ctx context.Context, req client.RunRequest) ([]api.ExecutionQueue, error)
+	RunStatus(ctx context.Context, runUUID string) (*api.RunStatus, error)
+	RunLogs(ctx context.Context, runUUID string, stderr bool, offset int64) ([]byte, int64, error)
+	CancelRun(ctx context.Context, runUUID string) error
+	InvalidateRun(ctx context.Context, execUUID string) error
+}
+
+// Queue writes the runs waiting in the queue of the server to w, the run being
+// executed is not part of the queue anymore.
+func Queue(ctx context.Context, cfg Config, w io.Writer) error {
+	c, err := cfg.newClient(false)
+	if err != nil {
+		return err
+	}
+	return writeQueue(ctx, c, w)
+}
+
+func writeQueue(ctx context.Context, c adminAPI, w io.Writer) error {
+	resp, err := c.Queue(ctx)
+	if err != nil {
+		return err
+	}
+	if len(resp.Executions) == 0 {
+		_, err = fmt.Fprintln(w, "The queue is empty.")
+		return err
+	}
+	return writeExecutions(w, resp.Executions)
+}
+
+func writeExecutions(w io.Writer, executions []api.ExecutionQueue) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "UUID\tSOURCE\tGIT REF\tWORKLOAD\tPR")
+	for _, e := range executions {
+		pr := "-"
+		if e.PullNb > 0 {
+			pr = fmt.Sprintf("#%d", e.PullNb)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.UUID, e.Source, e.GitRef, e.Workload, pr)
+	}
+	return tw.Flush()
+}
+
+// Request adds custom runs of sha to the queue of the server and writes the queued
+// runs to w, which are then followed until they end if cfg.Follow is true.
+func Request(ctx context.Context, cfg RequestConfig, sha string, w io.Writer) error {
+	if len(cfg.Workloads) == 0 {
+		return errors.New(ErrorMissingWorkloads)
+	}
+	if cfg.VitessVersion == 0 {
+		return errors.New(ErrorMissingVitessVersion)
+	}
+	c, err := cfg.newClient(true)
+	if err != nil {
+		return err
+	}
+	return request(ctx, c, cfg, sha, w)
+}
+
+func request(ctx context.Context, c adminAPI, cfg RequestConfig, sha string, w io.Writer) error {
+	queued, err := c.RequestRun(ctx, client.RunRequest{
+		SHA:           sha,
+		Workloads:     cfg.Workloads,
+		Version:       cfg.VitessVersion,
+		Planner:       cfg.Planner,
+		VtgateFlags:   cfg.VtgateFlags,
+		VttabletFlags: cfg.VttabletFlags,
+	})
+	if err != nil {
+		return err
+	}
+	if len(queued) == 0 {
+		_, err = fmt.Fprintln(w, "No run was queued: the runs are already in the queue or have enough results.")
+		return err
+	}
+	if err := writeExecutions(w, queued); err != nil {
+		return err
+	}
+	if !cfg.Follow {
+		return nil
+	}
+
+	// the runs are executed one after the other, so they are followed in order
+	for _, e := range queued {
+		if err := follow(ctx, c, e.UUID, cfg.PollInterval, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Follow writes the status changes and the logs of the run with the given UUID to w
+// until it ends. An error is returned if the run fails.
+func Follow(ctx context.Context, cfg Config, runUUID string, w io.Writer) error {
+	c, err := cfg.newClient(true)
+	if err != nil {
+		return err
+	}
+	return follow(ctx, c, runUUID, cfg.PollInterval, w)
+}
+
+func follow(ctx context.Context, c adminAPI, runUUID string, interval time.Duration, w io.Writer) error {
+	var (
+		lastStatus string
+		offset     int64
+	)
+	for {
+		status, err := c.RunStatus(ctx, runUUID)
+		if err != nil {
+			return err
+		}
+		if status.Status != lastStatus {
+			fmt.Fprintf(w, "%s: %s (%s on %s)\n", runUUID, status.Status, status.Workload, status.GitRef)
+			lastStatus = status.Status
+		}
+
+		// the logs exist once the execution started
+		if status.Status != api.RunStatusQueued && status.Status != exec.StatusCreated {
+			offset, err = copyLogs(ctx, c, runUUID, offset, w)
+			if err != nil {
+				return err
+			}
+		}
+
+		switch status.Status {
+		case exec.StatusFinished, exec.StatusInvalidated:
+			return nil
+		case exec.StatusFailed:
+			return fmt.Errorf("%s: %s", ErrorRunFailed, runUUID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// copyLogs writes the logs of the run starting at offset to w, and returns the offset
+// of the logs that are not written yet.
+func copyLogs(ctx context.Context, c adminAPI, runUUID string, offset int64, w io.Writer) (int64, error) {
+	for {
+		logs, next, err := c.RunLogs(ctx, runUUID, false, offset)
+		if err != nil {
+			return offset, err
+		}
+		if len(logs) == 0 {
+			return offset, nil
+		}
+		if _, err := w.Write(logs); err != nil {
+			return offset, err
+		}
+		offset = next
+	}
+}
+
+// Cancel removes the run with the given UUID from the queue of the server.
+func Cancel(ctx context.Context, cfg Config, runUUID string, w io.Writer) error {
+	c, err := cfg.newClient(true)
+	if err != nil {
+		return err
+	}
+	if err := c.CancelRun(ctx, runUUID); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s: canceled\n", runUUID)
+	return err
+}
+
+// Invalidate marks the finished execution with the given UUID as invalidated, its
+// results are not used by the server anymore.
+func Invalidate(ctx context.Context, cfg Config, execUUID string, w io.Writer) error {
+	c, err := cfg.newClient(true)
+	if err != nil {
+		return err
+	}
+	if err := c.InvalidateRun(ctx, execUUID); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s: %s\n", execUUID, exec.StatusInvalidated)
+	return err
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admin
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vitessio/arewefastyet/go/client"
+	"github.com/vitessio/arewefastyet/go/exec"
+	"github.com/vitessio/arewefastyet/go/server/api"
+)
+
+// fakeAPI replays a sequence of statuses and appends one chunk of logs per status
+// that is not queued.
+type fakeAPI struct {
+	queue    []api.ExecutionQueue
+	statuses []string
+	logs     []string
+
+	requests []client.RunRequest
+	written  int
+}
+
+func (f *fakeAPI) Queue(context.Context) (*api.ExecutionQueueResponse, error) {
+	return &api.ExecutionQueueResponse{Executions: f.queue}, nil
+}
+
+func (f *fakeAPI) RequestRun(_ context.Context, req client.RunRequest) ([]api.ExecutionQueue, error) {
+	f.requests = append(f.requests, req)
+	return f.queue, nil
+}
+
+func (f *fakeAPI) RunStatus(_ context.Context, runUUID string) (*api.RunStatus, error) {
+	status := f.statuses[0]
+	if len(f.statuses) > 1 {
+		f.statuses = f.statuses[1:]
+	}
+	if status != api.RunStatusQueued && status != exec.StatusCreated && f.written < len(f.logs) {
+		f.written++
+	}
+	return &api.RunStatus{UUID: runUUID, Status: status, GitRef: "abc", Workload: "OLTP"}, nil
+}
+
+func (f *fakeAPI) RunLogs(_ context.Context, _ string, _ bool, offset int64) ([]byte, int64, error) {
+	var all string
+	for _, chunk := range f.logs[:f.written] {
+		all += chunk
+	}
+	return []byte(all[offset:]), int64(len(all)), nil
+}
+
+func (f *fakeAPI) CancelRun(context.Context, string) error { return nil }
+
+func (f *fakeAPI) InvalidateRun(context.Context, string) error { return nil }
+
+func TestFollow(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		logs     []string
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "finished",
+			statuses: []string{api.RunStatusQueued, api.RunStatusQueued, exec.StatusStarted, exec.StatusStarted, exec.StatusFinished},
+			logs:     []string{"preparing\n", "running\n", "done\n"},
+			want:     "u1: queued (OLTP on abc)\nu1: started (OLTP on abc)\npreparing\nrunning\nu1: finished (OLTP on abc)\ndone\n",
+		},
+		{
+			name:     "failed",
+			statuses: []string{exec.StatusStarted, exec.StatusFailed},
+			logs:     []string{"preparing\n", "error\n"},
+			want:     "u1: started (OLTP on abc)\npreparing\nu1: failed (OLTP on abc)\nerror\n",
+			wantErr:  "run failed: u1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			var out bytes.Buffer
+			err := follow(context.Background(), &fakeAPI{statuses: tt.statuses, logs: tt.logs}, "u1", 0, &out)
+			if tt.wantErr != "" {
+				c.Assert(err, qt.ErrorMatches, tt.wantErr)
+			} else {
+				c.Assert(err, qt.IsNil)
+			}
+			c.Assert(out.String(), qt.Equals, tt.want)
+		})
+	}
+}
+
+func TestRequest(t *testing.T) {
+	c := qt.New(t)
+	fake := &fakeAPI{
+		queue:    []api.ExecutionQueue{{UUID: "u1", Source: "custom_run", GitRef: "abc", Workload: "OLTP"}},
+		statuses: []string{exec.StatusFinished},
+	}
+	cfg := RequestConfig{Workloads: []string{"OLTP"}, VitessVersion: 20, VtgateFlags: "--foo=bar", Follow: true}
+
+	var out bytes.Buffer
+	c.Assert(request(context.Background(), fake, cfg, "abc", &out), qt.IsNil)
+	c.Assert(fake.requests, qt.DeepEquals, []client.RunRequest{{SHA: "abc", Workloads: []string{"OLTP"}, Version: 20, VtgateFlags: "--foo=bar"}})
+	c.Assert(out.String(), qt.Equals, `UUID  SOURCE      GIT REF  WORKLOAD  PR
+u1    custom_run  abc      OLTP      -
+u1: finished (OLTP on abc)
+`)
+
+	c.Assert(Request(context.Background(), RequestConfig{VitessVersion: 20}, "abc", &out), qt.ErrorMatches, ErrorMissingWorkloads)
+	c.Assert(Request(context.Background(), RequestConfig{Workloads: []string{"OLTP"}, VitessVersion: 20}, "abc", &out), qt.ErrorMatches, ErrorMissingKey)
+}
+
+func TestWriteQueue(t *testing.T) {
+	c := qt.New(t)
+	var out bytes.Buffer
+	c.Assert(writeQueue(context.Background(), &fakeAPI{}, &out), qt.IsNil)
+	c.Assert(out.String(), qt.Equals, "The queue is empty.\n")
+
+	out.Reset()
+	fake := &fakeAPI{queue: []api.ExecutionQueue{{UUID: "u1", Source: "cron_pr", GitRef: "abc", Workload: "TPCC", PullNb: 42}}}
+	c.Assert(writeQueue(context.Background(), fake, &out), qt.IsNil)
+	c.Assert(out.String(), qt.Equals, "UUID  SOURCE   GIT REF  WORKLOAD  PR\nu1    cron_pr  abc      TPCC      #42\n")
+}
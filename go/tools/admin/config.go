@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admin
+
+import (
+	"errors"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/vitessio/arewefastyet/go/client"
+	"github.com/vitessio/arewefastyet/go/tools/compare"
+)
+
+const (
+	ErrorMissingKey           = "missing key: set --web-request-run-key or the web-request-run-key entry of the secrets file"
+	ErrorMissingWorkloads     = "missing workloads: set --run-workload"
+	ErrorMissingVitessVersion = "missing vitess version: set --run-vitess-version"
+
+	flagServerURL = "admin-server-url"
+
+	// flagKey has the name of the flag of the server so the same secrets file can be used.
+	flagKey = "web-request-run-key"
+
+	flagWorkloads     = "run-workload"
+	flagVitessVersion = "run-vitess-version"
+	flagPlanner       = "run-planner"
+	flagVtgateFlags   = "run-vtgate-flags"
+	flagVttabletFlags = "run-vttablet-flags"
+	flagFollow        = "run-follow"
+	flagPollInterval  = "run-poll-interval"
+)
+
+// Config defines the API server administered by the queue and run commands.
+type Config struct {
+	ServerURL string
+
+	// Key authorizes the administrative requests, it is the key of the server.
+	Key string
+
+	// PollInterval is the time between two requests when following a run.
+	PollInterval time.Duration
+}
+
+// AddToCommand adds Config to the given cobra.Command.
+func (cfg *Config) AddToCommand(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&cfg.ServerURL, flagServerURL, compare.DefaultServerURL, "URL of the API server.")
+	cmd.Flags().StringVar(&cfg.Key, flagKey, "", "Key authorizing the administrative requests, the one configured on the server.")
+	cmd.Flags().DurationVar(&cfg.PollInterval, flagPollInterval, 10*time.Second, "Time between two requests when following a run.")
+
+	_ = viper.BindPFlag(flagServerURL, cmd.Flags().Lookup(flagServerURL))
+	_ = viper.BindPFlag(flagKey, cmd.Flags().Lookup(flagKey))
+	_ = viper.BindPFlag(flagPollInterval, cmd.Flags().Lookup(flagPollInterval))
+}
+
+// newClient returns a client of the API server sending the key of cfg, an error is
+// returned if the key is required and missing.
+func (cfg Config) newClient(keyRequired bool) (*client.Client, error) {
+	if keyRequired && cfg.Key == "" {
+		return nil, errors.New(ErrorMissingKey)
+	}
+	return client.New(cfg.ServerURL, nil).WithKey(cfg.Key), nil
+}
+
+// RequestConfig defines the runs added to the queue by the run request command.
+type RequestConfig struct {
+	Config
+
+	Workloads     []string
+	VitessVersion int
+	Planner       string
+	VtgateFlags   string
+	VttabletFlags string
+
+	// Follow follows the queued runs until they end.
+	Follow bool
+}
+
+// AddToCommand adds RequestConfig to the given cobra.Command.
+func (cfg *RequestConfig) AddToCommand(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&cfg.Workloads, flagWorkloads, nil, "Workloads to benchmark, one run is queued per workload.")
+	cmd.Flags().IntVar(&cfg.VitessVersion, flagVitessVersion, 0, "Major version of vitess at the benchmarked commit.")
+	cmd.Flags().StringVar(&cfg.Planner, flagPlanner, "", "Planner version of vtgate, Gen4 if empty.")
+	cmd.Flags().StringVar(&cfg.VtgateFlags, flagVtgateFlags, "", "Flags added to the flags of vtgate.")
+	cmd.Flags().StringVar(&cfg.VttabletFlags, flagVttabletFlags, "", "Flags added to the flags of vttablet.")
+	cmd.Flags().BoolVar(&cfg.Follow, flagFollow, false, "Follow the status and the logs of the queued runs until they end.")
+
+	_ = viper.BindPFlag(flagWorkloads, cmd.Flags().Lookup(flagWorkloads))
+	_ = viper.BindPFlag(flagVitessVersion, cmd.Flags().Lookup(flagVitessVersion))
+	_ = viper.BindPFlag(flagPlanner, cmd.Flags().Lookup(flagPlanner))
+	_ = viper.BindPFlag(flagVtgateFlags, cmd.Flags().Lookup(flagVtgateFlags))
+	_ = viper.BindPFlag(flagVttabletFlags, cmd.Flags().Lookup(flagVttabletFlags))
+	_ = viper.BindPFlag(flagFollow, cmd.Flags().Lookup(flagFollow))
+
+	cfg.Config.AddToCommand(cmd)
+}
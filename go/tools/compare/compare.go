@@ -0,0 +1,325 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package compare prints the comparison of the benchmark results of two git refs in
+// the terminal, and reports the regressions larger than a threshold.
+package compare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/vitessio/arewefastyet/go/client"
+	"github.com/vitessio/arewefastyet/go/server/api"
+	"github.com/vitessio/arewefastyet/go/tools/macrobench"
+	"github.com/vitessio/arewefastyet/go/tools/microbench"
+)
+
+const (
+	ErrorRegression = "regressions exceed the threshold"
+)
+
+type (
+	results = macrobench.StatisticalCompareResults
+	result  = macrobench.StatisticalResult
+)
+
+// macroMetric is a row of the comparison of a macrobenchmark workload.
+type macroMetric struct {
+	name           string
+	higherIsBetter bool
+	result         func(r results) result
+}
+
+var macroMetrics = []macroMetric{
+	{name: "total_qps", higherIsBetter: true, result: func(r results) result { return r.TotalQPS }},
+	{name: "reads_qps", higherIsBetter: true, result: func(r results) result { return r.ReadsQPS }},
+	{name: "writes_qps", higherIsBetter: true, result: func(r results) result { return r.WritesQPS }},
+	{name: "other_qps", higherIsBetter: true, result: func(r results) result { return r.OtherQPS }},
+	{name: "tps", higherIsBetter: true, result: func(r results) result { return r.TPS }},
+	{name: "latency", result: func(r results) result { return r.Latency }},
+	{name: "latency_p50", result: func(r results) result { return r.LatencyP50 }},
+	{name: "latency_p95", result: func(r results) result { return r.LatencyP95 }},
+	{name: "latency_p99", result: func(r results) result { return r.LatencyP99 }},
+	{name: "latency_max", result: func(r results) result { return r.LatencyMax }},
+	{name: "errors", result: func(r results) result { return r.Errors }},
+	{name: "cpu_time", result: func(r results) result { return r.TotalComponentsCPUTime }},
+	{name: "mem_alloc_bytes", result: func(r results) result { return r.TotalComponentsMemStatsAllocBytes }},
+}
+
+// change is the relative difference of a metric between the two git refs,
+// delta is positive if the metric improved.
+type change struct {
+	delta       float64
+	significant bool
+}
+
+func (c change) color() string {
+	switch {
+	case !c.significant:
+		return colorDim
+	case c.delta < 0:
+		return colorRed
+	case c.delta > 0:
+		return colorGreen
+	}
+	return ""
+}
+
+// Run compares oldRef and newRef and writes the comparison to w. An error is returned
+// if a significant regression exceeds cfg.MaxRegression.
+func Run(ctx context.Context, cfg Config, oldRef, newRef string, w io.Writer) error {
+	colored, err := useColor(cfg.Color, w)
+	if err != nil {
+		return err
+	}
+
+	var src source
+	if cfg.DatabaseConfig.IsValid() {
+		dbClient, err := cfg.DatabaseConfig.NewClient()
+		if err != nil {
+			return err
+		}
+		defer dbClient.Close()
+		src = dbSource{client: dbClient}
+	} else {
+		serverURL := cfg.ServerURL
+		if serverURL == "" {
+			serverURL = DefaultServerURL
+		}
+		src = apiSource{client: client.New(serverURL, nil)}
+	}
+	return run(ctx, src, cfg, oldRef, newRef, w, colored)
+}
+
+func run(ctx context.Context, src source, cfg Config, oldRef, newRef string, w io.Writer, colored bool) error {
+	var regressions []string
+	cmp := client.Comparison{Method: cfg.Method, Alpha: cfg.Alpha, Confidence: cfg.Confidence, Correction: cfg.Correction}
+	if cfg.Micro {
+		comparisons, err := src.compareMicrobenchmarks(ctx, oldRef, newRef, cmp)
+		if err != nil {
+			return err
+		}
+		regressions, err = writeMicrobenchmarks(w, comparisons, cfg.MaxRegression, colored)
+		if err != nil {
+			return err
+		}
+	} else {
+		comparisons, err := src.compareMacrobenchmarks(ctx, oldRef, newRef, cfg.Workloads, cmp)
+		if err != nil {
+			return err
+		}
+		regressions, err = writeMacrobenchmarks(w, comparisons, cfg.MaxRegression, colored)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(regressions) > 0 {
+		return fmt.Errorf("%s of %s%%: %s", ErrorRegression, formatFloat(cfg.MaxRegression), strings.Join(regressions, ", "))
+	}
+	return nil
+}
+
+// writeMacrobenchmarks writes a table per workload and returns the regressions
+// exceeding maxRegression, named <workload>/<metric>.
+func writeMacrobenchmarks(w io.Writer, comparisons []api.CompareMacrobench, maxRegression float64, colored bool) ([]string, error) {
+	var regressions []string
+	for i, comparison := range comparisons {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s\n", comparison.Workload); err != nil {
+			return nil, err
+		}
+
+		t := &table{header: []string{"metric", "old", "new", "delta", "old range", "new range", "p-value"}}
+		for _, metric := range macroMetrics {
+			res := metric.result(comparison.Result)
+			if res.N1 == 0 && res.N2 == 0 {
+				continue
+			}
+			c := change{delta: res.Delta, significant: !res.Insignificant}
+			if !metric.higherIsBetter {
+				c.delta = -c.delta
+			}
+			if maxRegression >= 0 && res.Regressed(maxRegression, metric.higherIsBetter) {
+				regressions = append(regressions, comparison.Workload+"/"+metric.name)
+			}
+			t.addRow(
+				cell{text: metric.name},
+				cell{text: formatFloat(res.Old.Center)},
+				cell{text: formatFloat(res.New.Center)},
+				cell{text: formatDelta(res.Delta), color: c.color()},
+				cell{text: formatRange(res.Old.Range)},
+				cell{text: formatRange(res.New.Range)},
+				cell{text: strconv.FormatFloat(res.AdjustedP, 'f', 3, 64), color: c.color()},
+			)
+		}
+		if len(t.rows) == 0 {
+			if _, err := io.WriteString(w, "no results\n"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := t.write(w, colored); err != nil {
+			return nil, err
+		}
+	}
+	return regressions, nil
+}
+
+// writeMicrobenchmarks writes a table of the microbenchmarks, followed by a table of the
+// metrics reported with testing.B.ReportMetric if any, and returns the significant regressions
+// exceeding maxRegression, named <package>/<benchmark>/<unit>.
+func writeMicrobenchmarks(w io.Writer, comparisons microbench.ComparisonArray, maxRegression float64, colored bool) ([]string, error) {
+	t := &table{header: []string{"benchmark", "old ns/op", "new ns/op", "delta", "old B/op", "new B/op", "delta", "old allocs/op", "new allocs/op", "delta"}}
+	metrics := &table{header: []string{"benchmark metric", "old", "new", "delta", "p-value"}}
+	var regressions []string
+	for _, comparison := range comparisons {
+		if comparison.Name == "" {
+			continue
+		}
+		name := comparison.PkgName + "/" + comparison.FullName()
+		if maxRegression >= 0 {
+			for _, unit := range comparison.RegressedUnits(maxRegression) {
+				regressions = append(regressions, name+"/"+unit)
+			}
+		}
+		row := []cell{{text: name}}
+		for _, unit := range []struct {
+			name     string
+			old, new float64
+		}{
+			{name: "ns/op", old: comparison.Left.NSPerOp, new: comparison.Right.NSPerOp},
+			{name: "B/op", old: comparison.Left.BytesPerOp, new: comparison.Right.BytesPerOp},
+			{name: "allocs/op", old: comparison.Left.AllocsPerOp, new: comparison.Right.AllocsPerOp},
+		} {
+			cells := microCells(comparison, unit.name, unit.old, unit.new)
+			row = append(row, cells[:3]...)
+		}
+		t.addRow(row...)
+
+		for _, unit := range comparison.Right.Units() {
+			cells := microCells(comparison, unit, comparison.Left.Metrics[unit], comparison.Right.Metrics[unit])
+			metrics.addRow(append([]cell{{text: name + " " + unit}}, cells...)...)
+		}
+	}
+	if len(t.rows) == 0 {
+		_, err := io.WriteString(w, "no results\n")
+		return nil, err
+	}
+	if err := t.write(w, colored); err != nil {
+		return nil, err
+	}
+	if len(metrics.rows) == 0 {
+		return regressions, nil
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return nil, err
+	}
+	return regressions, metrics.write(w, colored)
+}
+
+// microCells returns the old, new, delta and p-value cells of a unit of a microbenchmark
+// comparison. The medians old and new are used when the unit was not compared
+// statistically, such as when one side is missing, the change is then not significant.
+func microCells(comparison microbench.Comparison, unit string, old, new float64) []cell {
+	res, ok := comparison.Statistics[unit]
+	if !ok {
+		c := change{}
+		return []cell{
+			{text: formatOptionalFloat(old)},
+			{text: formatOptionalFloat(new)},
+			{text: formatDelta(0), color: c.color()},
+			{text: "-"},
+		}
+	}
+
+	// the delta is the increase of the value, which is an improvement only if higher is better
+	c := change{delta: res.Delta, significant: !res.Insignificant}
+	if !microbench.HigherIsBetter(unit) {
+		c.delta = -c.delta
+	}
+	return []cell{
+		{text: formatOptionalFloat(res.Old.Center)},
+		{text: formatOptionalFloat(res.New.Center)},
+		{text: formatDelta(res.Delta), color: c.color()},
+		{text: strconv.FormatFloat(res.AdjustedP, 'f', 3, 64), color: c.color()},
+	}
+}
+
+// useColor returns true if the output written to w must be colored.
+func useColor(mode string, w io.Writer) (bool, error) {
+	switch mode {
+	case ColorAlways:
+		return true, nil
+	case ColorNever:
+		return false, nil
+	case ColorAuto, "":
+		if _, ok := os.LookupEnv("NO_COLOR"); ok {
+			return false, nil
+		}
+		f, ok := w.(*os.File)
+		if !ok {
+			return false, nil
+		}
+		info, err := f.Stat()
+		if err != nil {
+			return false, nil
+		}
+		return info.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, errors.New(ErrorInvalidColor + ": " + mode)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 2, 64)
+}
+
+// formatOptionalFloat formats f, or returns "-" if it is 0, which is the value of
+// the benchmarks missing on one side of a comparison.
+func formatOptionalFloat(f float64) string {
+	if f == 0 {
+		return "-"
+	}
+	return formatFloat(f)
+}
+
+func formatDelta(delta float64) string {
+	if delta == 0 {
+		// do not print negative zeros
+		delta = 0
+	}
+	return fmt.Sprintf("%+.2f%%", delta)
+}
+
+func formatRange(r macrobench.Range) string {
+	switch {
+	case r.Infinite:
+		return "∞"
+	case r.Unknown:
+		return "?"
+	}
+	return fmt.Sprintf("±%.2f%%", r.Value)
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compare
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vitessio/arewefastyet/go/client"
+	"github.com/vitessio/arewefastyet/go/server/api"
+	"github.com/vitessio/arewefastyet/go/tools/macrobench"
+	"github.com/vitessio/arewefastyet/go/tools/microbench"
+)
+
+type fakeSource struct {
+	macro []api.CompareMacrobench
+	micro microbench.ComparisonArray
+}
+
+func (s fakeSource) compareMacrobenchmarks(context.Context, string, string, []string, client.Comparison) ([]api.CompareMacrobench, error) {
+	return s.macro, nil
+}
+
+func (s fakeSource) compareMicrobenchmarks(context.Context, string, string, client.Comparison) (microbench.ComparisonArray, error) {
+	return s.micro, nil
+}
+
+func summary(center, rangeValue float64) macrobench.StatisticalSummary {
+	return macrobench.StatisticalSummary{Center: center, Range: macrobench.Range{Value: rangeValue}}
+}
+
+func testMacroSource() fakeSource {
+	return fakeSource{macro: []api.CompareMacrobench{
+		{
+			Workload: "OLTP",
+			Result: macrobench.StatisticalCompareResults{
+				// significant regression of the QPS
+				TotalQPS: macrobench.StatisticalResult{Delta: -8, AdjustedP: 0.001, N1: 10, N2: 10, Old: summary(1000, 1.5), New: summary(920, 2)},
+				// significant improvement of the latency
+				Latency: macrobench.StatisticalResult{Delta: -10, AdjustedP: 0.002, N1: 10, N2: 10, Old: summary(10, 1), New: summary(9, 1)},
+				// insignificant regression of the errors
+				Errors: macrobench.StatisticalResult{Insignificant: true, Delta: 50, AdjustedP: 0.4, N1: 10, N2: 10, Old: summary(2, 0), New: macrobench.StatisticalSummary{Center: 3, Range: macrobench.Range{Infinite: true}}},
+			},
+		},
+		{Workload: "TPCC"},
+	}}
+}
+
+func TestRun_Macrobenchmarks(t *testing.T) {
+	c := qt.New(t)
+	var buf bytes.Buffer
+	err := run(context.Background(), testMacroSource(), Config{MaxRegression: -1}, "old", "new", &buf, false)
+	c.Assert(err, qt.IsNil)
+	c.Assert(buf.String(), qt.Equals, `OLTP
+metric         old     new    delta  old range  new range  p-value
+total_qps  1000.00  920.00   -8.00%     ±1.50%     ±2.00%    0.001
+latency      10.00    9.00  -10.00%     ±1.00%     ±1.00%    0.002
+errors        2.00    3.00  +50.00%     ±0.00%          ∞    0.400
+
+TPCC
+no results
+`)
+}
+
+func TestRun_MaxRegression(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxRegression float64
+		wantErr       string
+	}{
+		{name: "disabled", maxRegression: -1},
+		{name: "larger threshold", maxRegression: 10},
+		{name: "any regression", maxRegression: 0, wantErr: ErrorRegression + " of 0.00%: OLTP/total_qps"},
+		{name: "exceeded", maxRegression: 5, wantErr: ErrorRegression + " of 5.00%: OLTP/total_qps"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := run(context.Background(), testMacroSource(), Config{MaxRegression: tt.maxRegression}, "old", "new", &buf, false)
+			if tt.wantErr == "" {
+				qt.Assert(t, err, qt.IsNil)
+				return
+			}
+			qt.Assert(t, err, qt.ErrorMatches, tt.wantErr)
+		})
+	}
+}
+
+func TestRun_Microbenchmarks(t *testing.T) {
+	c := qt.New(t)
+	src := fakeSource{micro: microbench.ComparisonArray{
+		{
+			BenchmarkId: microbench.BenchmarkId{PkgName: "sqltypes", Name: "BenchmarkParse", SubBenchmarkName: "BenchmarkParse/small"},
+			Left:        microbench.Result{NSPerOp: 100, BytesPerOp: 64, AllocsPerOp: 2, Metrics: map[string]float64{"rows/op": 10, "plans/s": 50}},
+			Right:       microbench.Result{NSPerOp: 125, BytesPerOp: 64, AllocsPerOp: 2, Metrics: map[string]float64{"rows/op": 10, "plans/s": 40}},
+			Diff:        microbench.Result{NSPerOp: -20, Metrics: map[string]float64{"rows/op": 0, "plans/s": -25}},
+			Statistics: map[string]macrobench.StatisticalResult{
+				"ns/op":     {Delta: 25, AdjustedP: 0.001, Old: summary(100, 1), New: summary(125, 1)},
+				"B/op":      {Insignificant: true, AdjustedP: 1, Old: summary(64, 0), New: summary(64, 0)},
+				"allocs/op": {Delta: 50, AdjustedP: 0.002, Old: summary(2, 0), New: summary(3, 0)},
+				"rows/op":   {Delta: 10, Insignificant: true, AdjustedP: 0.2, Old: summary(10, 5), New: summary(11, 5)},
+				"plans/s":   {Delta: -20, AdjustedP: 0.01, Old: summary(50, 2), New: summary(40, 2)},
+			},
+		},
+		{
+			BenchmarkId: microbench.BenchmarkId{PkgName: "sqltypes", Name: "BenchmarkNew", SubBenchmarkName: "BenchmarkNew"},
+			Right:       microbench.Result{NSPerOp: 10},
+		},
+	}}
+
+	var buf bytes.Buffer
+	err := run(context.Background(), src, Config{Micro: true, MaxRegression: 15}, "old", "new", &buf, true)
+	c.Assert(err, qt.ErrorMatches, ErrorRegression+" of 15.00%: sqltypes/BenchmarkParse/small/allocs/op, sqltypes/BenchmarkParse/small/ns/op, sqltypes/BenchmarkParse/small/plans/s")
+	c.Assert(buf.String(), qt.Equals, "benchmark                      old ns/op  new ns/op    delta  old B/op  new B/op   delta  old allocs/op  new allocs/op    delta\n"+
+		"sqltypes/BenchmarkParse/small     100.00     125.00  "+colorRed+"+25.00%"+colorReset+"     64.00     64.00  "+colorDim+"+0.00%"+colorReset+"           2.00           3.00  "+colorRed+"+50.00%"+colorReset+"\n"+
+		"sqltypes/BenchmarkNew                  -      10.00   "+colorDim+"+0.00%"+colorReset+"         -         -  "+colorDim+"+0.00%"+colorReset+"              -              -   "+colorDim+"+0.00%"+colorReset+"\n"+
+		"\n"+
+		"benchmark metric                         old    new    delta  p-value\n"+
+		"sqltypes/BenchmarkParse/small plans/s  50.00  40.00  "+colorRed+"-20.00%"+colorReset+"    "+colorRed+"0.010"+colorReset+"\n"+
+		"sqltypes/BenchmarkParse/small rows/op  10.00  11.00  "+colorDim+"+10.00%"+colorReset+"    "+colorDim+"0.200"+colorReset+"\n")
+}
+
+func TestUseColor(t *testing.T) {
+	c := qt.New(t)
+	colored, err := useColor(ColorAlways, &bytes.Buffer{})
+	c.Assert(err, qt.IsNil)
+	c.Assert(colored, qt.IsTrue)
+
+	colored, err = useColor(ColorAuto, &bytes.Buffer{})
+	c.Assert(err, qt.IsNil)
+	c.Assert(colored, qt.IsFalse)
+
+	_, err = useColor("rainbow", &bytes.Buffer{})
+	c.Assert(err, qt.ErrorMatches, ErrorInvalidColor+": rainbow")
+}
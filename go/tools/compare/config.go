@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compare
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/vitessio/arewefastyet/go/storage/database"
+)
+
+const (
+	ErrorInvalidColor = "invalid color mode"
+
+	// DefaultServerURL is the URL of the production API server.
+	DefaultServerURL = "https://benchmark.vitess.io"
+
+	// ColorAuto colors the output if it is a terminal and NO_COLOR is not set.
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+
+	flagWorkloads     = "workload"
+	flagMicro         = "micro"
+	flagServerURL     = "compare-server-url"
+	flagMethod        = "compare-method"
+	flagAlpha         = "compare-alpha"
+	flagConfidence    = "compare-confidence"
+	flagCorrection    = "compare-correction"
+	flagMaxRegression = "compare-max-regression"
+	flagColor         = "compare-color"
+)
+
+// Config defines how two git refs are compared and how the comparison is printed.
+type Config struct {
+	// Workloads restricts the macrobenchmarks that are compared, all the workloads
+	// are compared if it is empty.
+	Workloads []string
+
+	// Micro compares the microbenchmarks instead of the macrobenchmarks.
+	Micro bool
+
+	// ServerURL is the API server queried when DatabaseConfig is not valid.
+	ServerURL string
+
+	// Method, Alpha, Confidence and Correction configure the statistical comparison
+	// of the macrobenchmarks, the zero values use the defaults.
+	Method     string
+	Alpha      float64
+	Confidence float64
+	Correction string
+
+	// MaxRegression is the percentage above which a significant regression makes the
+	// comparison fail, a negative value never fails.
+	MaxRegression float64
+
+	// Color is either ColorAuto, ColorAlways or ColorNever.
+	Color string
+
+	DatabaseConfig *database.Config
+}
+
+// AddToCommand adds Config to the given cobra.Command.
+func (cfg *Config) AddToCommand(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&cfg.Workloads, flagWorkloads, nil, "Workloads to compare, all of them if empty.")
+	cmd.Flags().BoolVar(&cfg.Micro, flagMicro, false, "Compare the microbenchmarks instead of the macrobenchmarks.")
+	cmd.Flags().StringVar(&cfg.ServerURL, flagServerURL, DefaultServerURL, "URL of the API server, used when no database is configured.")
+	cmd.Flags().StringVar(&cfg.Method, flagMethod, "", "Statistical test comparing the macrobenchmarks: mann-whitney (default), welch-t-test or bootstrap.")
+	cmd.Flags().Float64Var(&cfg.Alpha, flagAlpha, 0, "Significance level of the statistical test, the default of the method if 0.")
+	cmd.Flags().Float64Var(&cfg.Confidence, flagConfidence, 0, "Confidence level of the ranges, the default of the method if 0.")
+	cmd.Flags().StringVar(&cfg.Correction, flagCorrection, "", "Correction of the p-values for multiple comparisons: none (default), benjamini-hochberg or holm.")
+	cmd.Flags().Float64Var(&cfg.MaxRegression, flagMaxRegression, -1, "Exit with an error if a significant regression is larger than this percentage, negative to never fail.")
+	cmd.Flags().StringVar(&cfg.Color, flagColor, ColorAuto, fmt.Sprintf("Color the significant changes: %q, %q or %q.", ColorAuto, ColorAlways, ColorNever))
+
+	_ = viper.BindPFlag(flagWorkloads, cmd.Flags().Lookup(flagWorkloads))
+	_ = viper.BindPFlag(flagMicro, cmd.Flags().Lookup(flagMicro))
+	_ = viper.BindPFlag(flagServerURL, cmd.Flags().Lookup(flagServerURL))
+	_ = viper.BindPFlag(flagMethod, cmd.Flags().Lookup(flagMethod))
+	_ = viper.BindPFlag(flagAlpha, cmd.Flags().Lookup(flagAlpha))
+	_ = viper.BindPFlag(flagConfidence, cmd.Flags().Lookup(flagConfidence))
+	_ = viper.BindPFlag(flagCorrection, cmd.Flags().Lookup(flagCorrection))
+	_ = viper.BindPFlag(flagMaxRegression, cmd.Flags().Lookup(flagMaxRegression))
+	_ = viper.BindPFlag(flagColor, cmd.Flags().Lookup(flagColor))
+
+	if cfg.DatabaseConfig == nil {
+		cfg.DatabaseConfig = database.NewConfig()
+	}
+	cfg.DatabaseConfig.AddToCommand(cmd)
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compare
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/vitessio/arewefastyet/go/client"
+	"github.com/vitessio/arewefastyet/go/server/api"
+	"github.com/vitessio/arewefastyet/go/storage"
+	"github.com/vitessio/arewefastyet/go/tools/macrobench"
+	"github.com/vitessio/arewefastyet/go/tools/microbench"
+)
+
+// source provides the comparisons of two git refs.
+type source interface {
+	compareMacrobenchmarks(ctx context.Context, oldRef, newRef string, workloads []string, cmp client.Comparison) ([]api.CompareMacrobench, error)
+	compareMicrobenchmarks(ctx context.Context, oldRef, newRef string, cmp client.Comparison) (microbench.ComparisonArray, error)
+}
+
+// apiSource queries the API server, which resolves branches, tags and abbreviated SHAs.
+type apiSource struct {
+	client *client.Client
+}
+
+func (s apiSource) compareMacrobenchmarks(ctx context.Context, oldRef, newRef string, workloads []string, cmp client.Comparison) ([]api.CompareMacrobench, error) {
+	results, err := s.client.CompareMacrobenchmarks(ctx, oldRef, newRef, cmp)
+	if err != nil {
+		return nil, err
+	}
+	if len(workloads) == 0 {
+		return results, nil
+	}
+	filtered := results[:0]
+	for _, result := range results {
+		for _, workload := range workloads {
+			if strings.EqualFold(result.Workload, workload) {
+				filtered = append(filtered, result)
+				break
+			}
+		}
+	}
+	return filtered, nil
+}
+
+func (s apiSource) compareMicrobenchmarks(ctx context.Context, oldRef, newRef string, cmp client.Comparison) (microbench.ComparisonArray, error) {
+	return s.client.CompareMicrobenchmarks(ctx, oldRef, newRef, cmp)
+}
+
+// dbSource reads the database directly, the git refs must be full SHAs.
+type dbSource struct {
+	client storage.SQLClient
+}
+
+// comparisonMethod returns the macrobench.ComparisonMethod matching cmp, the zero
+// values of cmp keep the defaults.
+func comparisonMethod(cmp client.Comparison) (macrobench.ComparisonMethod, error) {
+	cfg := macrobench.DefaultStatisticalConfig()
+	if cmp.Alpha != 0 {
+		cfg.Alpha = cmp.Alpha
+	}
+	if cmp.Confidence != 0 {
+		cfg.Confidence = cmp.Confidence
+	}
+	if cmp.Correction != "" {
+		cfg.Correction = cmp.Correction
+	}
+	return macrobench.NewComparisonMethod(cmp.Method, cfg)
+}
+
+func (s dbSource) compareMacrobenchmarks(_ context.Context, oldRef, newRef string, workloads []string, cmp client.Comparison) ([]api.CompareMacrobench, error) {
+	method, err := comparisonMethod(cmp)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(workloads) == 0 {
+		workloads, err = macrobench.GetWorkloadsForGitRefs(s.client, macrobench.Gen4Planner, oldRef, newRef)
+		if err != nil {
+			return nil, err
+		}
+	}
+	upper := make([]string, 0, len(workloads))
+	for _, workload := range workloads {
+		upper = append(upper, strings.ToUpper(workload))
+	}
+
+	results, err := macrobench.Compare(s.client, oldRef, newRef, upper, macrobench.Gen4Planner, method)
+	if err != nil {
+		return nil, err
+	}
+	comparisons := make([]api.CompareMacrobench, 0, len(results))
+	for workload, result := range results {
+		comparisons = append(comparisons, api.CompareMacrobench{Workload: workload, Result: result})
+	}
+	sort.Slice(comparisons, func(i, j int) bool {
+		return comparisons[i].Workload < comparisons[j].Workload
+	})
+	return comparisons, nil
+}
+
+func (s dbSource) compareMicrobenchmarks(_ context.Context, oldRef, newRef string, cmp client.Comparison) (microbench.ComparisonArray, error) {
+	method, err := comparisonMethod(cmp)
+	if err != nil {
+		return nil, err
+	}
+	return microbench.Compare(s.client, newRef, oldRef, method)
+}
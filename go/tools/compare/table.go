@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compare
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	colorReset = "\x1b[0m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorDim   = "\x1b[2m"
+)
+
+type (
+	// cell is a value of a table, color is an ANSI escape sequence or empty.
+	cell struct {
+		text  string
+		color string
+	}
+
+	// table aligns its cells in columns, the first column is aligned to the left
+	// and the others to the right. The colors are not counted in the width of the cells.
+	table struct {
+		header []string
+		rows   [][]cell
+	}
+)
+
+func (t *table) addRow(cells ...cell) {
+	t.rows = append(t.rows, cells)
+}
+
+func (t *table) write(w io.Writer, colored bool) error {
+	widths := make([]int, len(t.header))
+	for i, h := range t.header {
+		widths[i] = utf8.RuneCountInString(h)
+	}
+	for _, row := range t.rows {
+		for i, c := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(c.text))
+		}
+	}
+
+	var b strings.Builder
+	line := func(cells []cell) {
+		for i, c := range cells {
+			if i > 0 {
+				b.WriteString("  ")
+			}
+			padding := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(c.text))
+			if i > 0 {
+				b.WriteString(padding)
+			}
+			if colored && c.color != "" {
+				b.WriteString(c.color + c.text + colorReset)
+			} else {
+				b.WriteString(c.text)
+			}
+			if i == 0 && len(cells) > 1 {
+				b.WriteString(padding)
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	header := make([]cell, 0, len(t.header))
+	for _, h := range t.header {
+		header = append(header, cell{text: h})
+	}
+	line(header)
+	for _, row := range t.rows {
+		line(row)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
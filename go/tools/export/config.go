@@ -0,0 +1,172 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/vitessio/arewefastyet/go/storage"
+	"github.com/vitessio/arewefastyet/go/storage/database"
+)
+
+const (
+	ErrorInvalidDate = "invalid date, expected RFC 3339 or YYYY-MM-DD"
+
+	flagGitRefs   = "export-git-ref"
+	flagWorkloads = "export-workload"
+	flagSource    = "export-source"
+	flagSince     = "export-since"
+	flagUntil     = "export-until"
+	flagFormat    = "export-format"
+	flagOutput    = "export-output"
+)
+
+// Config defines which results are exported, and where.
+type Config struct {
+	GitRefs   []string
+	Workloads []string
+	Source    string
+	Since     string
+	Until     string
+	Format    string
+
+	// Output is the file the export is written to, the standard output is used if it is empty.
+	Output string
+
+	DatabaseConfig *database.Config
+}
+
+// AddToCommand adds Config to the given cobra.Command.
+func (cfg *Config) AddToCommand(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&cfg.GitRefs, flagGitRefs, nil, "Git refs (full SHAs) of the executions to export.")
+	cmd.Flags().StringSliceVar(&cfg.Workloads, flagWorkloads, nil, "Workloads of the macrobenchmarks to export.")
+	cmd.Flags().StringVar(&cfg.Source, flagSource, "", "Source of the executions to export, all sources are exported if empty.")
+	cmd.Flags().StringVar(&cfg.Since, flagSince, "", "Export the executions started at or after this date (RFC 3339 or YYYY-MM-DD).")
+	cmd.Flags().StringVar(&cfg.Until, flagUntil, "", "Export the executions started before this date (RFC 3339 or YYYY-MM-DD).")
+	cmd.Flags().StringVar(&cfg.Format, flagFormat, string(FormatCSV), "Format of the export: csv, jsonl or benchstat.")
+	cmd.Flags().StringVar(&cfg.Output, flagOutput, "", "File to write the export to, defaults to the standard output.")
+
+	_ = viper.BindPFlag(flagGitRefs, cmd.Flags().Lookup(flagGitRefs))
+	_ = viper.BindPFlag(flagWorkloads, cmd.Flags().Lookup(flagWorkloads))
+	_ = viper.BindPFlag(flagSource, cmd.Flags().Lookup(flagSource))
+	_ = viper.BindPFlag(flagSince, cmd.Flags().Lookup(flagSince))
+	_ = viper.BindPFlag(flagUntil, cmd.Flags().Lookup(flagUntil))
+	_ = viper.BindPFlag(flagFormat, cmd.Flags().Lookup(flagFormat))
+	_ = viper.BindPFlag(flagOutput, cmd.Flags().Lookup(flagOutput))
+
+	if cfg.DatabaseConfig == nil {
+		cfg.DatabaseConfig = database.NewConfig()
+	}
+	cfg.DatabaseConfig.AddToCommand(cmd)
+}
+
+// ParseDate parses either a RFC 3339 timestamp or a YYYY-MM-DD day, an empty string
+// returns a nil date.
+func ParseDate(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t, err = time.Parse(time.DateOnly, s)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", ErrorInvalidDate, s)
+	}
+	return &t, nil
+}
+
+// Filter returns the Filter described by the configuration.
+func (cfg Config) Filter() (Filter, error) {
+	since, err := ParseDate(cfg.Since)
+	if err != nil {
+		return Filter{}, err
+	}
+	until, err := ParseDate(cfg.Until)
+	if err != nil {
+		return Filter{}, err
+	}
+	return Filter{
+		GitRefs:   cfg.GitRefs,
+		Workloads: cfg.Workloads,
+		Source:    cfg.Source,
+		Since:     since,
+		Until:     until,
+	}, nil
+}
+
+// Macrobenchmarks exports the macrobenchmark results selected by cfg.
+func Macrobenchmarks(cfg Config) error {
+	return run(cfg, func(client storage.SQLClient, filter Filter, format Format, w io.Writer) error {
+		samples, err := GetMacroSamples(client, filter)
+		if err != nil {
+			return err
+		}
+		return WriteMacrobenchmarks(w, format, samples)
+	})
+}
+
+// Microbenchmarks exports the microbenchmark results selected by cfg.
+func Microbenchmarks(cfg Config) error {
+	return run(cfg, func(client storage.SQLClient, filter Filter, format Format, w io.Writer) error {
+		samples, err := GetMicroSamples(client, filter)
+		if err != nil {
+			return err
+		}
+		return WriteMicrobenchmarks(w, format, samples)
+	})
+}
+
+func run(cfg Config, export func(client storage.SQLClient, filter Filter, format Format, w io.Writer) error) (err error) {
+	format, err := ParseFormat(strings.ToLower(cfg.Format))
+	if err != nil {
+		return err
+	}
+	filter, err := cfg.Filter()
+	if err != nil {
+		return err
+	}
+	if cfg.DatabaseConfig == nil {
+		cfg.DatabaseConfig = database.NewConfig()
+	}
+	client, err := cfg.DatabaseConfig.NewClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var w io.Writer = os.Stdout
+	if cfg.Output != "" {
+		f, err := os.Create(cfg.Output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if errClose := f.Close(); err == nil {
+				err = errClose
+			}
+		}()
+		w = f
+	}
+	return export(client, filter, format, w)
+}
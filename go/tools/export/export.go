@@ -0,0 +1,362 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package export dumps the raw results of the executions, one row per execution for
+// the macrobenchmarks and one row per benchmark line for the microbenchmarks, so they
+// can be analyzed outside of arewefastyet.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ErrorUnknownFormat = "unknown export format"
+
+	// FormatCSV writes a header line followed by one line per sample.
+	FormatCSV = Format("csv")
+
+	// FormatJSONL writes one JSON object per line, as described by JSON Lines.
+	FormatJSONL = Format("jsonl")
+
+	// FormatBenchstat writes the Go benchmark text format consumed by benchstat, the git ref
+	// of the samples is the "commit" configuration key, e.g. `benchstat -col commit export.txt`.
+	FormatBenchstat = Format("benchstat")
+)
+
+// Formats lists all the supported formats.
+var Formats = []Format{FormatCSV, FormatJSONL, FormatBenchstat}
+
+type (
+	// Format is the encoding of an export.
+	Format string
+
+	// MacroSample holds the results of a single macrobenchmark execution.
+	MacroSample struct {
+		ExecUUID   string     `json:"exec_uuid"`
+		GitRef     string     `json:"git_ref"`
+		Source     string     `json:"source"`
+		Workload   string     `json:"workload"`
+		Planner    string     `json:"planner"`
+		StartedAt  *time.Time `json:"started_at"`
+		TPS        float64    `json:"tps"`
+		Latency    float64    `json:"latency"`
+		Errors     float64    `json:"errors"`
+		Reconnects float64    `json:"reconnects"`
+		Time       int        `json:"time"`
+		Threads    float64    `json:"threads"`
+		TotalQPS   float64    `json:"total_qps"`
+		ReadsQPS   float64    `json:"reads_qps"`
+		WritesQPS  float64    `json:"writes_qps"`
+		OtherQPS   float64    `json:"other_qps"`
+
+		// The latency percentiles are nil if sysbench did not report them.
+		LatencyP50 *float64 `json:"latency_p50"`
+		LatencyP95 *float64 `json:"latency_p95"`
+		LatencyP99 *float64 `json:"latency_p99"`
+		LatencyMax *float64 `json:"latency_max"`
+
+		// Metrics maps the name of the metrics, as stored in the metrics table, to their value.
+		Metrics map[string]float64 `json:"metrics"`
+	}
+
+	// MicroSample holds a single result line of a microbenchmark execution.
+	MicroSample struct {
+		ExecUUID    string     `json:"exec_uuid"`
+		GitRef      string     `json:"git_ref"`
+		Source      string     `json:"source"`
+		StartedAt   *time.Time `json:"started_at"`
+		PkgName     string     `json:"pkg_name"`
+		Name        string     `json:"name"`
+		Benchmark   string     `json:"benchmark"`
+		Procs       int        `json:"procs"`
+		GOOS        string     `json:"goos"`
+		GOARCH      string     `json:"goarch"`
+		CPU         string     `json:"cpu"`
+		N           int64      `json:"n"`
+		NSPerOp     float64    `json:"ns_per_op"`
+		MBPerSec    float64    `json:"mb_per_sec"`
+		BytesPerOp  float64    `json:"bytes_per_op"`
+		AllocsPerOp float64    `json:"allocs_per_op"`
+
+		// Metrics maps the units of the metrics reported with testing.B.ReportMetric to their value.
+		Metrics map[string]float64 `json:"metrics"`
+	}
+
+	// withMetrics is implemented by the samples whose metrics are written as extra columns.
+	withMetrics interface {
+		metrics() map[string]float64
+	}
+)
+
+func (s MacroSample) metrics() map[string]float64 { return s.Metrics }
+
+func (s MicroSample) metrics() map[string]float64 { return s.Metrics }
+
+// ParseFormat returns the Format named s.
+func ParseFormat(s string) (Format, error) {
+	for _, format := range Formats {
+		if string(format) == s {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("%s: %s", ErrorUnknownFormat, s)
+}
+
+// ContentType returns the media type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSONL:
+		return "application/x-ndjson"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// Extension returns the file extension of the format.
+func (f Format) Extension() string {
+	switch f {
+	case FormatBenchstat:
+		return "txt"
+	default:
+		return string(f)
+	}
+}
+
+// WriteMacrobenchmarks writes samples to w using the given format.
+func WriteMacrobenchmarks(w io.Writer, format Format, samples []MacroSample) error {
+	switch format {
+	case FormatCSV:
+		return writeMacroCSV(w, samples)
+	case FormatJSONL:
+		return writeJSONL(w, samples)
+	case FormatBenchstat:
+		return writeMacroBenchstat(w, samples)
+	}
+	return fmt.Errorf("%s: %s", ErrorUnknownFormat, format)
+}
+
+// WriteMicrobenchmarks writes samples to w using the given format.
+func WriteMicrobenchmarks(w io.Writer, format Format, samples []MicroSample) error {
+	switch format {
+	case FormatCSV:
+		return writeMicroCSV(w, samples)
+	case FormatJSONL:
+		return writeJSONL(w, samples)
+	case FormatBenchstat:
+		return writeMicroBenchstat(w, samples)
+	}
+	return fmt.Errorf("%s: %s", ErrorUnknownFormat, format)
+}
+
+func writeJSONL[T any](w io.Writer, samples []T) error {
+	enc := json.NewEncoder(w)
+	for _, sample := range samples {
+		if err := enc.Encode(sample); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// metricNames returns the sorted names of all the metrics of the samples.
+func metricNames[T withMetrics](samples []T) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, sample := range samples {
+		for name := range sample.metrics() {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func formatOptionalFloat(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return formatFloat(*f)
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func writeMacroCSV(w io.Writer, samples []MacroSample) error {
+	metrics := metricNames(samples)
+	cw := csv.NewWriter(w)
+	header := []string{
+		"exec_uuid", "git_ref", "source", "workload", "planner", "started_at",
+		"tps", "latency", "errors", "reconnects", "time", "threads",
+		"total_qps", "reads_qps", "writes_qps", "other_qps",
+		"latency_p50", "latency_p95", "latency_p99", "latency_max",
+	}
+	if err := cw.Write(append(header, metrics...)); err != nil {
+		return err
+	}
+	for _, s := range samples {
+		record := []string{
+			s.ExecUUID, s.GitRef, s.Source, s.Workload, s.Planner, formatTime(s.StartedAt),
+			formatFloat(s.TPS), formatFloat(s.Latency), formatFloat(s.Errors), formatFloat(s.Reconnects), strconv.Itoa(s.Time), formatFloat(s.Threads),
+			formatFloat(s.TotalQPS), formatFloat(s.ReadsQPS), formatFloat(s.WritesQPS), formatFloat(s.OtherQPS),
+			formatOptionalFloat(s.LatencyP50), formatOptionalFloat(s.LatencyP95), formatOptionalFloat(s.LatencyP99), formatOptionalFloat(s.LatencyMax),
+		}
+		record = appendMetrics(record, metrics, s.Metrics)
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// appendMetrics appends the values of the given metrics to record, the missing ones are empty.
+func appendMetrics(record, names []string, metrics map[string]float64) []string {
+	for _, name := range names {
+		value, ok := metrics[name]
+		if !ok {
+			record = append(record, "")
+			continue
+		}
+		record = append(record, formatFloat(value))
+	}
+	return record
+}
+
+func writeMicroCSV(w io.Writer, samples []MicroSample) error {
+	metrics := metricNames(samples)
+	cw := csv.NewWriter(w)
+	header := []string{
+		"exec_uuid", "git_ref", "source", "started_at", "pkg_name", "name", "benchmark", "procs", "goos", "goarch", "cpu",
+		"n", "ns_per_op", "mb_per_sec", "bytes_per_op", "allocs_per_op",
+	}
+	if err := cw.Write(append(header, metrics...)); err != nil {
+		return err
+	}
+	for _, s := range samples {
+		record := []string{
+			s.ExecUUID, s.GitRef, s.Source, formatTime(s.StartedAt), s.PkgName, s.Name, s.Benchmark, strconv.Itoa(s.Procs), s.GOOS, s.GOARCH, s.CPU,
+			strconv.FormatInt(s.N, 10), formatFloat(s.NSPerOp), formatFloat(s.MBPerSec), formatFloat(s.BytesPerOp), formatFloat(s.AllocsPerOp),
+		}
+		record = appendMetrics(record, metrics, s.Metrics)
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// benchstatWriter writes results in the Go benchmark text format, the configuration
+// lines are only written when their value changes, and not before they have a value.
+type benchstatWriter struct {
+	w      io.Writer
+	config map[string]string
+	err    error
+}
+
+func (bw *benchstatWriter) setConfig(keys []string, values ...string) {
+	for i, key := range keys {
+		value := values[i]
+		if current, ok := bw.config[key]; (ok && current == value) || (!ok && value == "") {
+			continue
+		}
+		bw.config[key] = value
+		bw.printf("%s: %s\n", key, value)
+	}
+}
+
+func (bw *benchstatWriter) printf(format string, args ...interface{}) {
+	if bw.err != nil {
+		return
+	}
+	_, bw.err = fmt.Fprintf(bw.w, format, args...)
+}
+
+// benchmarkName turns s into a valid benchmark name, without spaces.
+func benchmarkName(s string) string {
+	return "Benchmark" + strings.Join(strings.Fields(s), "_")
+}
+
+func writeMacroBenchstat(w io.Writer, samples []MacroSample) error {
+	bw := &benchstatWriter{w: w, config: map[string]string{}}
+	keys := []string{"commit", "source", "planner"}
+	for _, s := range samples {
+		bw.setConfig(keys, s.GitRef, s.Source, s.Planner)
+		bw.printf("%s 1 %s total-qps %s reads-qps %s writes-qps %s other-qps %s tps %s latency-ms %s errors %s reconnects",
+			benchmarkName(s.Workload), formatFloat(s.TotalQPS), formatFloat(s.ReadsQPS), formatFloat(s.WritesQPS), formatFloat(s.OtherQPS),
+			formatFloat(s.TPS), formatFloat(s.Latency), formatFloat(s.Errors), formatFloat(s.Reconnects))
+		for _, p := range []struct {
+			unit  string
+			value *float64
+		}{
+			{"latency-p50-ms", s.LatencyP50}, {"latency-p95-ms", s.LatencyP95}, {"latency-p99-ms", s.LatencyP99}, {"latency-max-ms", s.LatencyMax},
+		} {
+			if p.value != nil {
+				bw.printf(" %s %s", formatFloat(*p.value), p.unit)
+			}
+		}
+		for _, name := range metricNames([]MacroSample{s}) {
+			bw.printf(" %s %s", formatFloat(s.Metrics[name]), strings.Join(strings.Fields(name), "_"))
+		}
+		bw.printf("\n")
+	}
+	return bw.err
+}
+
+func writeMicroBenchstat(w io.Writer, samples []MicroSample) error {
+	bw := &benchstatWriter{w: w, config: map[string]string{}}
+	keys := []string{"commit", "source", "goos", "goarch", "cpu", "pkg"}
+	for _, s := range samples {
+		bw.setConfig(keys, s.GitRef, s.Source, s.GOOS, s.GOARCH, s.CPU, s.PkgName)
+		name := s.Benchmark
+		if s.Procs > 1 {
+			name += "-" + strconv.Itoa(s.Procs)
+		}
+		bw.printf("%s %d %s ns/op", name, s.N, formatFloat(s.NSPerOp))
+		if s.MBPerSec != 0 {
+			bw.printf(" %s MB/s", formatFloat(s.MBPerSec))
+		}
+		if s.BytesPerOp != 0 || s.AllocsPerOp != 0 {
+			bw.printf(" %s B/op %s allocs/op", formatFloat(s.BytesPerOp), formatFloat(s.AllocsPerOp))
+		}
+		for _, unit := range metricNames([]MicroSample{s}) {
+			bw.printf(" %s %s", formatFloat(s.Metrics[unit]), unit)
+		}
+		bw.printf("\n")
+	}
+	return bw.err
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func testMacroSamples() []MacroSample {
+	startedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	return []MacroSample{
+		{
+			ExecUUID: "uuid-1", GitRef: "abc", Source: "cron", Workload: "OLTP", Planner: "Gen4", StartedAt: &startedAt,
+			TPS: 100, Latency: 2.5, TotalQPS: 2000, ReadsQPS: 1400, WritesQPS: 400, OtherQPS: 200, Time: 60, Threads: 16,
+			LatencyP95: floatPtr(4.1),
+			Metrics:    map[string]float64{"vtgate_cpu": 1.5},
+		},
+		{
+			ExecUUID: "uuid-2", GitRef: "abc", Source: "cron", Workload: "TPCC", Planner: "Gen4",
+			TPS: 50, Latency: 7, TotalQPS: 1000, Errors: 1, Time: 60, Threads: 16,
+			Metrics: map[string]float64{"vttablet_mem": 200},
+		},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, format := range Formats {
+		got, err := ParseFormat(string(format))
+		qt.Assert(t, err, qt.IsNil)
+		qt.Assert(t, got, qt.Equals, format)
+	}
+	_, err := ParseFormat("xml")
+	qt.Assert(t, err, qt.ErrorMatches, ErrorUnknownFormat+": xml")
+}
+
+func TestWriteMacrobenchmarks(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		want   string
+	}{
+		{name: "csv", format: FormatCSV, want: `exec_uuid,git_ref,source,workload,planner,started_at,tps,latency,errors,reconnects,time,threads,total_qps,reads_qps,writes_qps,other_qps,latency_p50,latency_p95,latency_p99,latency_max,vtgate_cpu,vttablet_mem
+uuid-1,abc,cron,OLTP,Gen4,2024-03-01T12:00:00Z,100,2.5,0,0,60,16,2000,1400,400,200,,4.1,,,1.5,
+uuid-2,abc,cron,TPCC,Gen4,,50,7,1,0,60,16,1000,0,0,0,,,,,,200
+`},
+		{name: "jsonl", format: FormatJSONL, want: `{"exec_uuid":"uuid-1","git_ref":"abc","source":"cron","workload":"OLTP","planner":"Gen4","started_at":"2024-03-01T12:00:00Z","tps":100,"latency":2.5,"errors":0,"reconnects":0,"time":60,"threads":16,"total_qps":2000,"reads_qps":1400,"writes_qps":400,"other_qps":200,"latency_p50":null,"latency_p95":4.1,"latency_p99":null,"latency_max":null,"metrics":{"vtgate_cpu":1.5}}
+{"exec_uuid":"uuid-2","git_ref":"abc","source":"cron","workload":"TPCC","planner":"Gen4","started_at":null,"tps":50,"latency":7,"errors":1,"reconnects":0,"time":60,"threads":16,"total_qps":1000,"reads_qps":0,"writes_qps":0,"other_qps":0,"latency_p50":null,"latency_p95":null,"latency_p99":null,"latency_max":null,"metrics":{"vttablet_mem":200}}
+`},
+		{name: "benchstat", format: FormatBenchstat, want: `commit: abc
+source: cron
+planner: Gen4
+BenchmarkOLTP 1 2000 total-qps 1400 reads-qps 400 writes-qps 200 other-qps 100 tps 2.5 latency-ms 0 errors 0 reconnects 4.1 latency-p95-ms 1.5 vtgate_cpu
+BenchmarkTPCC 1 1000 total-qps 0 reads-qps 0 writes-qps 0 other-qps 50 tps 7 latency-ms 1 errors 0 reconnects 200 vttablet_mem
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := WriteMacrobenchmarks(&buf, tt.format, testMacroSamples())
+			qt.Assert(t, err, qt.IsNil)
+			qt.Assert(t, buf.String(), qt.Equals, tt.want)
+		})
+	}
+}
+
+func TestWriteMicrobenchmarks(t *testing.T) {
+	samples := []MicroSample{
+		{ExecUUID: "uuid-1", GitRef: "abc", Source: "cron", PkgName: "vitess.io/vitess/go/sqltypes", Name: "BenchmarkParse", Benchmark: "BenchmarkParse", Procs: 16, GOOS: "linux", GOARCH: "amd64", N: 1000, NSPerOp: 12.5, BytesPerOp: 8, AllocsPerOp: 1, Metrics: map[string]float64{"rows/op": 3, "plans/s": 40}},
+		{ExecUUID: "uuid-1", GitRef: "abc", Source: "cron", PkgName: "vitess.io/vitess/go/sqltypes", Name: "BenchmarkCopy", Benchmark: "BenchmarkCopy/size=1", Procs: 1, GOOS: "linux", GOARCH: "amd64", N: 200, NSPerOp: 300, MBPerSec: 42},
+		{ExecUUID: "uuid-2", GitRef: "def", Source: "cron", PkgName: "vitess.io/vitess/go/sqltypes", Name: "BenchmarkParse", Benchmark: "BenchmarkParse", Procs: 16, GOOS: "linux", GOARCH: "arm64", N: 1000, NSPerOp: 11},
+	}
+
+	var buf bytes.Buffer
+	err := WriteMicrobenchmarks(&buf, FormatBenchstat, samples)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, buf.String(), qt.Equals, `commit: abc
+source: cron
+goos: linux
+goarch: amd64
+pkg: vitess.io/vitess/go/sqltypes
+BenchmarkParse-16 1000 12.5 ns/op 8 B/op 1 allocs/op 40 plans/s 3 rows/op
+BenchmarkCopy/size=1 200 300 ns/op 42 MB/s
+commit: def
+goarch: arm64
+BenchmarkParse-16 1000 11 ns/op
+`)
+
+	buf.Reset()
+	err = WriteMicrobenchmarks(&buf, FormatCSV, samples[:2])
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, buf.String(), qt.Equals, `exec_uuid,git_ref,source,started_at,pkg_name,name,benchmark,procs,goos,goarch,cpu,n,ns_per_op,mb_per_sec,bytes_per_op,allocs_per_op,plans/s,rows/op
+uuid-1,abc,cron,,vitess.io/vitess/go/sqltypes,BenchmarkParse,BenchmarkParse,16,linux,amd64,,1000,12.5,0,8,1,40,3
+uuid-1,abc,cron,,vitess.io/vitess/go/sqltypes,BenchmarkCopy,BenchmarkCopy/size=1,1,linux,amd64,,200,300,42,0,0,,
+`)
+
+	buf.Reset()
+	err = WriteMicrobenchmarks(&buf, FormatJSONL, samples[:1])
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, buf.String(), qt.Equals, `{"exec_uuid":"uuid-1","git_ref":"abc","source":"cron","started_at":null,"pkg_name":"vitess.io/vitess/go/sqltypes","name":"BenchmarkParse","benchmark":"BenchmarkParse","procs":16,"goos":"linux","goarch":"amd64","cpu":"","n":1000,"ns_per_op":12.5,"mb_per_sec":0,"bytes_per_op":8,"allocs_per_op":1,"metrics":{"plans/s":40,"rows/op":3}}
+`)
+}
+
+func TestFilter_where(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		filter        Filter
+		withWorkloads bool
+		wantWhere     string
+		wantArgs      []interface{}
+	}{
+		{name: "empty", wantWhere: "e.status = 'finished'"},
+		{
+			name:          "all",
+			filter:        Filter{GitRefs: []string{"abc", "def"}, Workloads: []string{"oltp"}, Source: "cron", Since: &since},
+			withWorkloads: true,
+			wantWhere:     "e.status = 'finished' AND e.git_ref IN (?, ?) AND info.workload IN (?) AND e.source = ? AND e.started_at >= ?",
+			wantArgs:      []interface{}{"abc", "def", "OLTP", "cron", since},
+		},
+		{
+			name:      "workloads ignored",
+			filter:    Filter{Workloads: []string{"oltp"}, Until: &since},
+			wantWhere: "e.status = 'finished' AND e.started_at < ?",
+			wantArgs:  []interface{}{since},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := tt.filter.where(tt.withWorkloads)
+			qt.Assert(t, where, qt.Equals, tt.wantWhere)
+			qt.Assert(t, args, qt.DeepEquals, tt.wantArgs)
+		})
+	}
+}
+
+func TestGetMacroSamples_EmptyFilter(t *testing.T) {
+	_, err := GetMacroSamples(nil, Filter{Source: "cron"})
+	qt.Assert(t, err, qt.ErrorMatches, ErrorEmptyFilter)
+	_, err = GetMicroSamples(nil, Filter{})
+	qt.Assert(t, err, qt.ErrorMatches, ErrorEmptyFilter)
+}
+
+func TestParseDate(t *testing.T) {
+	got, err := ParseDate("")
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, got, qt.IsNil)
+
+	got, err = ParseDate("2024-03-01")
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, got.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)), qt.IsTrue)
+
+	got, err = ParseDate("2024-03-01T10:00:00+02:00")
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, got.Equal(time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)), qt.IsTrue)
+
+	_, err = ParseDate("yesterday")
+	qt.Assert(t, strings.HasPrefix(err.Error(), ErrorInvalidDate), qt.IsTrue)
+}
@@ -0,0 +1,273 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/vitessio/arewefastyet/go/storage"
+	"github.com/vitessio/arewefastyet/go/storage/mysql"
+)
+
+const ErrorEmptyFilter = "at least one git ref, workload or date must be given"
+
+// Filter selects the finished executions to export.
+type Filter struct {
+	GitRefs []string
+
+	// Workloads are ignored when exporting microbenchmarks.
+	Workloads []string
+
+	// Source keeps the executions of the given source if it is not empty.
+	Source string
+
+	// Since and Until restrict the date at which the executions started.
+	Since *time.Time
+	Until *time.Time
+}
+
+func (f Filter) isEmpty() bool {
+	return len(f.GitRefs) == 0 && len(f.Workloads) == 0 && f.Since == nil && f.Until == nil
+}
+
+// where returns the SQL conditions corresponding to the filter, the execution table
+// is aliased as e, and the macrobenchmark table as info if withWorkloads is true.
+func (f Filter) where(withWorkloads bool) (string, []interface{}) {
+	conditions := []string{"e.status = 'finished'"}
+	var args []interface{}
+	in := func(column string, values []string) {
+		conditions = append(conditions, column+" IN (?"+strings.Repeat(", ?", len(values)-1)+")")
+		for _, value := range values {
+			args = append(args, value)
+		}
+	}
+
+	if len(f.GitRefs) > 0 {
+		in("e.git_ref", f.GitRefs)
+	}
+	if withWorkloads && len(f.Workloads) > 0 {
+		workloads := make([]string, 0, len(f.Workloads))
+		for _, workload := range f.Workloads {
+			workloads = append(workloads, strings.ToUpper(workload))
+		}
+		in("info.workload", workloads)
+	}
+	if f.Source != "" {
+		conditions = append(conditions, "e.source = ?")
+		args = append(args, f.Source)
+	}
+	if f.Since != nil {
+		conditions = append(conditions, "e.started_at >= ?")
+		args = append(args, f.Since.UTC())
+	}
+	if f.Until != nil {
+		conditions = append(conditions, "e.started_at < ?")
+		args = append(args, f.Until.UTC())
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// GetMacroSamples returns the results of the finished macrobenchmark executions matching
+// filter, sorted by start date.
+func GetMacroSamples(client storage.SQLClient, filter Filter) ([]MacroSample, error) {
+	if filter.isEmpty() {
+		return nil, errors.New(ErrorEmptyFilter)
+	}
+	if client == nil {
+		return nil, errors.New(mysql.ErrorClientConnectionNotInitialized)
+	}
+	where, args := filter.where(true)
+
+	query := `
+        SELECT
+            e.uuid, e.git_ref, e.source, info.workload, info.vtgate_planner_version, e.started_at,
+            results.tps, results.latency, results.errors, results.reconnects, results.time, results.threads,
+            results.total_qps, results.reads_qps, results.writes_qps, results.other_qps,
+            lat.p50, lat.p95, lat.p99, lat.max
+        FROM
+            execution AS e
+        JOIN
+            macrobenchmark AS info ON e.uuid = info.exec_uuid
+        JOIN
+            macrobenchmark_results AS results ON info.macrobenchmark_id = results.macrobenchmark_id
+        LEFT JOIN
+            macrobenchmark_latency AS lat ON info.macrobenchmark_id = lat.macrobenchmark_id
+        WHERE ` + where + `
+        ORDER BY
+            e.started_at, e.uuid
+    `
+	rows, err := client.Read(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []MacroSample
+	index := map[string]int{}
+	for rows.Next() {
+		var (
+			s                  MacroSample
+			p50, p95, p99, max sql.NullFloat64
+		)
+		err = rows.Scan(
+			&s.ExecUUID, &s.GitRef, &s.Source, &s.Workload, &s.Planner, &s.StartedAt,
+			&s.TPS, &s.Latency, &s.Errors, &s.Reconnects, &s.Time, &s.Threads,
+			&s.TotalQPS, &s.ReadsQPS, &s.WritesQPS, &s.OtherQPS,
+			&p50, &p95, &p99, &max,
+		)
+		if err != nil {
+			return nil, err
+		}
+		s.LatencyP50, s.LatencyP95, s.LatencyP99, s.LatencyMax = nullFloat(p50), nullFloat(p95), nullFloat(p99), nullFloat(max)
+		s.Metrics = map[string]float64{}
+		index[s.ExecUUID] = len(samples)
+		samples = append(samples, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		return samples, nil
+	}
+
+	query = `
+        SELECT m.exec_uuid, m.name, m.value
+        FROM
+            execution AS e
+        JOIN
+            macrobenchmark AS info ON e.uuid = info.exec_uuid
+        JOIN
+            metrics AS m ON e.uuid = m.exec_uuid
+        WHERE ` + where
+	metricRows, err := client.Read(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer metricRows.Close()
+	for metricRows.Next() {
+		var (
+			execUUID, name string
+			value          float64
+		)
+		if err = metricRows.Scan(&execUUID, &name, &value); err != nil {
+			return nil, err
+		}
+		if i, ok := index[execUUID]; ok {
+			samples[i].Metrics[name] = value
+		}
+	}
+	return samples, metricRows.Err()
+}
+
+// GetMicroSamples returns the result lines of the finished microbenchmark executions
+// matching filter, sorted by start date.
+func GetMicroSamples(client storage.SQLClient, filter Filter) ([]MicroSample, error) {
+	if filter.isEmpty() {
+		return nil, errors.New(ErrorEmptyFilter)
+	}
+	if client == nil {
+		return nil, errors.New(mysql.ErrorClientConnectionNotInitialized)
+	}
+	where, args := filter.where(false)
+
+	query := `
+        SELECT
+            md.id, e.uuid, e.git_ref, e.source, e.started_at, m.pkg_name, m.name, md.name,
+            md.procs, m.goos, m.goarch, m.cpu, md.n, md.ns_per_op, md.mb_per_sec, md.bytes_per_op, md.allocs_per_op
+        FROM
+            execution AS e
+        JOIN
+            microbenchmark AS m ON e.uuid = m.exec_uuid
+        JOIN
+            microbenchmark_details AS md ON m.microbenchmark_no = md.microbenchmark_no
+        WHERE ` + where + `
+        ORDER BY
+            e.started_at, e.uuid, md.id
+    `
+	rows, err := client.Read(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []MicroSample
+	index := map[int64]int{}
+	for rows.Next() {
+		var (
+			s         MicroSample
+			detailsID int64
+		)
+		err = rows.Scan(
+			&detailsID, &s.ExecUUID, &s.GitRef, &s.Source, &s.StartedAt, &s.PkgName, &s.Name, &s.Benchmark,
+			&s.Procs, &s.GOOS, &s.GOARCH, &s.CPU,
+			&s.N, &s.NSPerOp, &s.MBPerSec, &s.BytesPerOp, &s.AllocsPerOp,
+		)
+		if err != nil {
+			return nil, err
+		}
+		s.Metrics = map[string]float64{}
+		index[detailsID] = len(samples)
+		samples = append(samples, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		return samples, nil
+	}
+
+	query = `
+        SELECT mm.microbenchmark_details_id, mm.unit, mm.value
+        FROM
+            execution AS e
+        JOIN
+            microbenchmark AS m ON e.uuid = m.exec_uuid
+        JOIN
+            microbenchmark_details AS md ON m.microbenchmark_no = md.microbenchmark_no
+        JOIN
+            microbenchmark_metrics AS mm ON md.id = mm.microbenchmark_details_id
+        WHERE ` + where
+	metricRows, err := client.Read(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer metricRows.Close()
+	for metricRows.Next() {
+		var (
+			detailsID int64
+			unit      string
+			value     float64
+		)
+		if err = metricRows.Scan(&detailsID, &unit, &value); err != nil {
+			return nil, err
+		}
+		if i, ok := index[detailsID]; ok {
+			samples[i].Metrics[unit] = value
+		}
+	}
+	return samples, metricRows.Err()
+}
+
+func nullFloat(f sql.NullFloat64) *float64 {
+	if !f.Valid {
+		return nil
+	}
+	return &f.Float64
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"strings"
+	"time"
+)
+
+// Commit holds the metadata of a commit.
+type Commit struct {
+	SHA     string    `json:"sha"`
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
+	Subject string    `json:"subject"`
+}
+
+// GetCommits returns the metadata of the given commits of the repository located in
+// repoDir, by full SHA. The commits that are not found in the repository are ignored.
+func GetCommits(repoDir string, shas []string) (map[string]*Commit, error) {
+	commits := make(map[string]*Commit, len(shas))
+	if len(shas) == 0 {
+		return commits, nil
+	}
+	args := append([]string{"log", "--no-walk=unsorted", "--ignore-missing", "--format=%H%x1f%an%x1f%aI%x1f%s"}, shas...)
+	out, err := ExecCmd(repoDir, "git", append(args, "--")...)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.SplitN(line, "\x1f", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		date, err := time.Parse(time.RFC3339, fields[2])
+		if err != nil {
+			return nil, err
+		}
+		commits[fields[0]] = &Commit{SHA: fields[0], Author: fields[1], Date: date, Subject: fields[3]}
+	}
+	return commits, nil
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestGetCommits(t *testing.T) {
+	c := qt.New(t)
+	if _, err := exec.LookPath("git"); err != nil {
+		c.Skip("git is not installed")
+	}
+
+	dir := c.TempDir()
+	gitOutput(c, dir, "init", "--quiet", "--initial-branch=main")
+	gitOutput(c, dir, "commit", "--quiet", "--allow-empty", "--message=first", "--date=2024-07-01T10:00:00Z")
+	first := gitOutput(c, dir, "rev-parse", "HEAD")
+	gitOutput(c, dir, "commit", "--quiet", "--allow-empty", "--message=second: with a colon", "--date=2024-07-02T10:00:00Z")
+	second := gitOutput(c, dir, "rev-parse", "HEAD")
+	missing := "0123456789abcdef0123456789abcdef01234567"
+
+	commits, err := GetCommits(dir, []string{second, missing, first})
+	c.Assert(err, qt.IsNil)
+	c.Assert(commits, qt.HasLen, 2)
+	c.Assert(commits[first].SHA, qt.Equals, first)
+	c.Assert(commits[first].Author, qt.Equals, "test")
+	c.Assert(commits[first].Subject, qt.Equals, "first")
+	c.Assert(commits[first].Date.Equal(time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)), qt.IsTrue)
+	c.Assert(commits[second].Subject, qt.Equals, "second: with a colon")
+
+	commits, err = GetCommits(dir, nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(commits, qt.HasLen, 0)
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// FetchPullRequest fetches the head commit of the given pull request from the origin
+// remote of the repository located in repoDir.
+func FetchPullRequest(repoDir string, pullNb int) error {
+	_, err := ExecCmd(repoDir, "git", "fetch", "origin", "refs/pull/"+strconv.Itoa(pullNb)+"/head")
+	return err
+}
+
+// ChangedFiles returns the path, relative to the root of the repository, of the files
+// changed by head since it diverged from base, the way a pull request's diff is computed.
+// Renamed files are listed under both their old and new path.
+func ChangedFiles(repoDir, base, head string) ([]string, error) {
+	out, err := ExecCmd(repoDir, "git", "diff", "--name-only", "--no-renames", base+"..."+head, "--")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// AddWorktree checks ref out in a new worktree of the repository located in repoDir,
+// and returns its path. The worktree must be removed with RemoveWorktree.
+func AddWorktree(repoDir, ref string) (string, error) {
+	dir, err := os.MkdirTemp("", "vitess-worktree-")
+	if err != nil {
+		return "", err
+	}
+	_, err = ExecCmd(repoDir, "git", "worktree", "add", "--detach", "--quiet", dir, ref)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// RemoveWorktree removes the worktree located in dir from the repository located in repoDir.
+func RemoveWorktree(repoDir, dir string) error {
+	_, err := ExecCmd(repoDir, "git", "worktree", "remove", "--force", dir)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		_, _ = ExecCmd(repoDir, "git", "worktree", "prune")
+	}
+	return err
+}
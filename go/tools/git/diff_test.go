@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestChangedFiles(t *testing.T) {
+	c := qt.New(t)
+	if _, err := exec.LookPath("git"); err != nil {
+		c.Skip("git is not installed")
+	}
+
+	dir := c.TempDir()
+	write := func(name string) {
+		path := filepath.Join(dir, name)
+		c.Assert(os.MkdirAll(filepath.Dir(path), 0755), qt.IsNil)
+		c.Assert(os.WriteFile(path, []byte(name+gitOutput(c, dir, "rev-parse", "--verify", "--quiet", "HEAD")), 0644), qt.IsNil)
+	}
+	gitOutput(c, dir, "init", "--quiet", "--initial-branch=main")
+	gitOutput(c, dir, "commit", "--quiet", "--allow-empty", "--message=first")
+	write("go/vt/sqlparser/parser.go")
+	write("go/vt/vtgate/engine/join.go")
+	gitOutput(c, dir, "add", ".")
+	gitOutput(c, dir, "commit", "--quiet", "--message=second")
+
+	gitOutput(c, dir, "checkout", "--quiet", "-b", "pr")
+	write("go/vt/sqlparser/parser.go")
+	gitOutput(c, dir, "mv", "go/vt/vtgate/engine/join.go", "go/vt/vtgate/engine/hash_join.go")
+	gitOutput(c, dir, "commit", "--quiet", "--all", "--message=pull request")
+	head := gitOutput(c, dir, "rev-parse", "HEAD")
+
+	// changes made on the base branch after the pull request was opened are not listed
+	gitOutput(c, dir, "checkout", "--quiet", "main")
+	write("go/vt/vttablet/tabletserver.go")
+	gitOutput(c, dir, "add", ".")
+	gitOutput(c, dir, "commit", "--quiet", "--message=third")
+	base := gitOutput(c, dir, "rev-parse", "HEAD")
+
+	files, err := ChangedFiles(dir, base, head)
+	c.Assert(err, qt.IsNil)
+	c.Assert(files, qt.DeepEquals, []string{"go/vt/sqlparser/parser.go", "go/vt/vtgate/engine/hash_join.go", "go/vt/vtgate/engine/join.go"})
+
+	files, err = ChangedFiles(dir, base, base)
+	c.Assert(err, qt.IsNil)
+	c.Assert(files, qt.HasLen, 0)
+}
+
+func TestWorktree(t *testing.T) {
+	c := qt.New(t)
+	if _, err := exec.LookPath("git"); err != nil {
+		c.Skip("git is not installed")
+	}
+
+	dir := c.TempDir()
+	gitOutput(c, dir, "init", "--quiet", "--initial-branch=main")
+	c.Assert(os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module first\n"), 0644), qt.IsNil)
+	gitOutput(c, dir, "add", ".")
+	gitOutput(c, dir, "commit", "--quiet", "--message=first")
+	first := gitOutput(c, dir, "rev-parse", "HEAD")
+	c.Assert(os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module second\n"), 0644), qt.IsNil)
+	gitOutput(c, dir, "commit", "--quiet", "--all", "--message=second")
+
+	worktree, err := AddWorktree(dir, first)
+	c.Assert(err, qt.IsNil)
+	content, err := os.ReadFile(filepath.Join(worktree, "go.mod"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(content), qt.Equals, "module first\n")
+
+	// the checkout of the repository is left as it is
+	content, err = os.ReadFile(filepath.Join(dir, "go.mod"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(content), qt.Equals, "module second\n")
+
+	c.Assert(RemoveWorktree(dir, worktree), qt.IsNil)
+	_, err = os.Stat(worktree)
+	c.Assert(os.IsNotExist(err), qt.IsTrue)
+	c.Assert(gitOutput(c, dir, "worktree", "list", "--porcelain"), qt.Not(qt.Contains), worktree)
+}
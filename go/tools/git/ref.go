@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+const (
+	ErrorRefNotFound  = "git ref not found"
+	ErrorRefAmbiguous = "git ref is ambiguous"
+)
+
+var (
+	// regex pattern accepts full SHA-1 commit hashes
+	regexPatternFullSHA = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+	// regex pattern accepts abbreviated SHA-1 commit hashes, git requires at least 4 characters
+	regexPatternShortSHA = regexp.MustCompile(`^[0-9a-f]{4,39}$`)
+)
+
+// ResolveRef returns the full SHA of the commit designated by ref in the repository
+// located in repoDir. ref can be anything understood by git rev-parse such as a branch,
+// a tag, an abbreviated SHA or HEAD~3. Branches that only exist on the origin remote,
+// like the release branches, can be given without their "origin/" prefix.
+// A full SHA is returned untouched, even if the commit is not in the repository.
+func ResolveRef(repoDir, ref string) (string, error) {
+	if regexPatternFullSHA.MatchString(ref) {
+		return ref, nil
+	}
+	if ref == "" || strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("%s: %q", ErrorRefNotFound, ref)
+	}
+
+	if regexPatternShortSHA.MatchString(ref) {
+		commits, err := commitsWithPrefix(repoDir, ref)
+		if err != nil {
+			return "", err
+		}
+		if len(commits) == 1 {
+			return commits[0], nil
+		}
+		if len(commits) > 1 {
+			for i, commit := range commits {
+				commits[i] = ShortenSHA(commit)
+			}
+			return "", fmt.Errorf("%s: %s matches the commits %s", ErrorRefAmbiguous, ref, strings.Join(commits, ", "))
+		}
+		// no commit has this prefix, but a branch or a tag might be named like this
+	}
+
+	for _, candidate := range []string{ref, "origin/" + ref} {
+		sha, found, err := revParseCommit(repoDir, candidate)
+		if err != nil {
+			return "", err
+		}
+		if found {
+			return sha, nil
+		}
+	}
+	return "", fmt.Errorf("%s: %s", ErrorRefNotFound, ref)
+}
+
+// commitsWithPrefix returns the full SHA of all the commits starting with the given prefix.
+func commitsWithPrefix(repoDir, prefix string) ([]string, error) {
+	out, err := ExecCmd(repoDir, "git", "rev-parse", "--disambiguate="+prefix)
+	if err != nil {
+		return nil, err
+	}
+	var commits []string
+	for _, object := range strings.Fields(string(out)) {
+		objectType, err := ExecCmd(repoDir, "git", "cat-file", "-t", object)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(string(objectType)) == "commit" {
+			commits = append(commits, object)
+		}
+	}
+	return commits, nil
+}
+
+// revParseCommit returns the full SHA of the commit designated by rev, found is false
+// if rev does not designate any commit.
+func revParseCommit(repoDir, rev string) (sha string, found bool, err error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", "--end-of-options", rev+"^{commit}")
+	cmd.Dir = repoDir
+	out, err := cmd.Output()
+	if err != nil {
+		// git rev-parse --verify --quiet exits with 1 and without any message when rev is invalid,
+		// ExecCmd is not used as it does not keep the exit code
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return "", false, nil
+		}
+		if exitErr != nil {
+			return "", false, fmt.Errorf("%s:\nstderr: %s", err.Error(), exitErr.Stderr)
+		}
+		return "", false, err
+	}
+	return strings.TrimSpace(string(out)), true, nil
+}
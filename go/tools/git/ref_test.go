@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func gitOutput(c *qt.C, dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(cmd.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+	out, err := cmd.Output()
+	c.Assert(err, qt.IsNil, qt.Commentf("git %s", strings.Join(args, " ")))
+	return strings.TrimSpace(string(out))
+}
+
+func TestResolveRef(t *testing.T) {
+	c := qt.New(t)
+	if _, err := exec.LookPath("git"); err != nil {
+		c.Skip("git is not installed")
+	}
+
+	dir := c.TempDir()
+	gitOutput(c, dir, "init", "--quiet", "--initial-branch=main")
+	gitOutput(c, dir, "commit", "--quiet", "--allow-empty", "--message=first")
+	first := gitOutput(c, dir, "rev-parse", "HEAD")
+	gitOutput(c, dir, "commit", "--quiet", "--allow-empty", "--message=second")
+	second := gitOutput(c, dir, "rev-parse", "HEAD")
+	gitOutput(c, dir, "tag", "--annotate", "--message=release", "v19.0.3", first)
+	gitOutput(c, dir, "update-ref", "refs/remotes/origin/release-19.0", first)
+
+	// create dangling commits until two of them share the same 4 characters prefix
+	emptyTree := gitOutput(c, dir, "hash-object", "-t", "tree", "/dev/null")
+	commitsByPrefix := map[string]string{}
+	var ambiguous string
+	for i := 0; ambiguous == ""; i++ {
+		commit := gitOutput(c, dir, "commit-tree", emptyTree, "-m", fmt.Sprintf("dangling %d", i))
+		if _, ok := commitsByPrefix[commit[:4]]; ok {
+			ambiguous = commit[:4]
+		}
+		commitsByPrefix[commit[:4]] = commit
+	}
+
+	tests := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{name: "branch", ref: "main", want: second},
+		{name: "remote branch", ref: "release-19.0", want: first},
+		{name: "annotated tag", ref: "v19.0.3", want: first},
+		{name: "relative ref", ref: "HEAD~1", want: first},
+		{name: "short SHA", ref: second[:10], want: second},
+		{name: "full SHA not in the repository", ref: strings.Repeat("a", 40), want: strings.Repeat("a", 40)},
+		{name: "missing ref", ref: "release-1.0", wantErr: ErrorRefNotFound + ": release-1.0"},
+		{name: "option", ref: "--all", wantErr: ErrorRefNotFound + `: "--all"`},
+		{name: "ambiguous short SHA", ref: ambiguous, wantErr: ErrorRefAmbiguous + ": " + ambiguous + " matches the commits .*"},
+	}
+	for _, tt := range tests {
+		c.Run(tt.name, func(c *qt.C) {
+			got, err := ResolveRef(dir, tt.ref)
+			if tt.wantErr != "" {
+				c.Assert(err, qt.ErrorMatches, tt.wantErr)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(got, qt.Equals, tt.want)
+		})
+	}
+}
@@ -22,9 +22,9 @@ import (
 	"errors"
 	"strings"
 
+	"github.com/vitessio/arewefastyet/go/exec/metrics"
 	"github.com/vitessio/arewefastyet/go/storage"
-	"github.com/vitessio/arewefastyet/go/storage/influxdb"
-	"github.com/vitessio/arewefastyet/go/storage/psdb"
+	"github.com/vitessio/arewefastyet/go/storage/database"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -41,14 +41,14 @@ type Config struct {
 	WorkloadPath string
 
 	// DatabaseConfig points to the configuration used to create
-	// a *psdb.Client. If no configuration, results and reports will
+	// a storage.Client. If no configuration, results and reports will
 	// not be saved to a database, though the program won't fail.
-	DatabaseConfig *psdb.Config
+	DatabaseConfig *database.Config
 
-	// MetricsDatabaseConfig points to the required configuration to create
-	// a *influxdb.Client. If no configuration is provided results will not be
+	// MetricsSourceConfig points to the required configuration to create
+	// a metrics.Source. If no configuration is provided results will not be
 	// saved to the database and the program will not fail.
-	MetricsDatabaseConfig *influxdb.Config
+	MetricsSourceConfig *metrics.SourceConfig
 
 	// M contains all metadata used to parameter sysbench execution.
 	// This key value map stores the value of each CLI parameters.
@@ -85,6 +85,10 @@ type Config struct {
 
 	// vtgateWebPorts lists web endpoint of each VTGate
 	vtgateWebPorts []string
+
+	// MetricsDefinitions lists the metrics to gather once the benchmark is done.
+	// If nil, they are loaded from the configuration file.
+	MetricsDefinitions []metrics.Definition
 }
 
 const (
@@ -103,7 +107,7 @@ const (
 // the given *cobra.Command.
 func (mabcfg *Config) AddToCommand(cmd *cobra.Command) {
 	mabcfg.DatabaseConfig.AddToCommand(cmd)
-	mabcfg.MetricsDatabaseConfig.AddToCommand(cmd)
+	mabcfg.MetricsSourceConfig.AddToCommand(cmd)
 
 	cmd.Flags().StringVar(&mabcfg.WorkloadPath, flagSysbenchPath, "", "Path to the workload used by sysbench.")
 	cmd.Flags().StringVar(&mabcfg.SysbenchExec, flagSysbenchExecutable, "", "Path to the sysbench binary.")
@@ -0,0 +1,203 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package macrobench
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	ErrorUnknownCorrection = "unknown multiple-comparison correction"
+
+	// CorrectionNone does not adjust the p-values, each comparison is
+	// considered on its own.
+	CorrectionNone = "none"
+
+	// CorrectionBenjaminiHochberg controls the false discovery rate of the
+	// whole family of comparisons.
+	CorrectionBenjaminiHochberg = "benjamini-hochberg"
+
+	// CorrectionHolm controls the family-wise error rate of the whole
+	// family of comparisons.
+	CorrectionHolm = "holm"
+)
+
+// Corrections lists the name of all the available multiple-comparison corrections.
+var Corrections = []string{CorrectionNone, CorrectionBenjaminiHochberg, CorrectionHolm}
+
+func isValidCorrection(correction string) bool {
+	for _, c := range Corrections {
+		if c == correction {
+			return true
+		}
+	}
+	return correction == ""
+}
+
+// forEachResult calls fn on every StatisticalResult of scr, in a deterministic order.
+// The results that do not compare any value are skipped as they are not part of the
+// family of comparisons.
+func (scr *StatisticalCompareResults) forEachResult(fn func(sr *StatisticalResult)) {
+	apply := func(sr *StatisticalResult) {
+		if sr.N1 == 0 || sr.N2 == 0 {
+			return
+		}
+		fn(sr)
+	}
+	applyMap := func(m map[string]StatisticalResult) {
+		names := make([]string, 0, len(m))
+		for name := range m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			sr := m[name]
+			apply(&sr)
+			m[name] = sr
+		}
+	}
+
+	apply(&scr.TotalQPS)
+	apply(&scr.ReadsQPS)
+	apply(&scr.WritesQPS)
+	apply(&scr.OtherQPS)
+	apply(&scr.TPS)
+	apply(&scr.Latency)
+	apply(&scr.LatencyP50)
+	apply(&scr.LatencyP95)
+	apply(&scr.LatencyP99)
+	apply(&scr.LatencyMax)
+	apply(&scr.Errors)
+
+	// the components CPU time and memory allocation fields are copies of their
+	// entry in Metrics, they are not visited twice
+	metricNames := make([]string, 0, len(scr.Metrics))
+	for name := range scr.Metrics {
+		metricNames = append(metricNames, name)
+	}
+	sort.Strings(metricNames)
+	for _, name := range metricNames {
+		mr := scr.Metrics[name]
+		apply(&mr.Total)
+		applyMap(mr.Components)
+		scr.Metrics[name] = mr
+	}
+}
+
+// adjustPValues sets the AdjustedP of every StatisticalResult in the given family of
+// comparisons using the given correction. When the correction is not CorrectionNone,
+// the Insignificant flag is computed using the adjusted p-values.
+func adjustPValues(family []*StatisticalCompareResults, cfg StatisticalConfig) {
+	var ps []float64
+	for _, scr := range family {
+		scr.forEachResult(func(sr *StatisticalResult) {
+			ps = append(ps, sr.P)
+		})
+	}
+
+	adjusted := correct(ps, cfg.Correction)
+	i := 0
+	for _, scr := range family {
+		scr.forEachResult(func(sr *StatisticalResult) {
+			sr.setAdjustedP(adjusted[i], cfg)
+			i++
+		})
+		scr.setComponentsFields()
+	}
+}
+
+// AdjustPValues sets the AdjustedP of every StatisticalResult of the given family of
+// comparisons using the correction of cfg, like the results of a macrobenchmark
+// comparison. The results that do not compare any value are skipped.
+func AdjustPValues(family []*StatisticalResult, cfg StatisticalConfig) {
+	var compared []*StatisticalResult
+	var ps []float64
+	for _, sr := range family {
+		if sr.N1 == 0 || sr.N2 == 0 {
+			continue
+		}
+		compared = append(compared, sr)
+		ps = append(ps, sr.P)
+	}
+
+	adjusted := correct(ps, cfg.Correction)
+	for i, sr := range compared {
+		sr.setAdjustedP(adjusted[i], cfg)
+	}
+}
+
+// setAdjustedP sets the adjusted p-value of sr. When the correction is not CorrectionNone,
+// the Insignificant flag is computed using the adjusted p-value.
+func (sr *StatisticalResult) setAdjustedP(p float64, cfg StatisticalConfig) {
+	sr.AdjustedP = p
+	if cfg.Correction != "" && cfg.Correction != CorrectionNone {
+		sr.Insignificant = sr.AdjustedP > cfg.Alpha
+	}
+}
+
+// correct returns the p-values ps adjusted with the given correction.
+func correct(ps []float64, correction string) []float64 {
+	switch correction {
+	case CorrectionBenjaminiHochberg:
+		return benjaminiHochberg(ps)
+	case CorrectionHolm:
+		return holm(ps)
+	}
+	return ps
+}
+
+// sortedIndexes returns the indexes of ps sorted by ascending p-value.
+func sortedIndexes(ps []float64) []int {
+	idx := make([]int, len(ps))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		return ps[idx[i]] < ps[idx[j]]
+	})
+	return idx
+}
+
+// benjaminiHochberg returns the Benjamini-Hochberg adjusted p-values of ps.
+func benjaminiHochberg(ps []float64) []float64 {
+	n := len(ps)
+	adjusted := make([]float64, n)
+	idx := sortedIndexes(ps)
+	prev := 1.0
+	for rank := n; rank >= 1; rank-- {
+		i := idx[rank-1]
+		v := math.Min(prev, ps[i]*float64(n)/float64(rank))
+		adjusted[i] = v
+		prev = v
+	}
+	return adjusted
+}
+
+// holm returns the Holm-Bonferroni adjusted p-values of ps.
+func holm(ps []float64) []float64 {
+	n := len(ps)
+	adjusted := make([]float64, n)
+	idx := sortedIndexes(ps)
+	prev := 0.0
+	for rank, i := range idx {
+		v := math.Min(1, math.Max(prev, ps[i]*float64(n-rank)))
+		adjusted[i] = v
+		prev = v
+	}
+	return adjusted
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package macrobench
+
+import (
+	"math"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vitessio/arewefastyet/go/exec/metrics"
+)
+
+func TestAdjustPValues(t *testing.T) {
+	newFamily := func() []*StatisticalCompareResults {
+		return []*StatisticalCompareResults{
+			{
+				TotalQPS:          StatisticalResult{P: 0.01, N1: 5, N2: 5},
+				TPS:               StatisticalResult{P: 0.04, N1: 5, N2: 5},
+				ComponentsCPUTime: map[string]StatisticalResult{},
+				Metrics: map[string]MetricResult{
+					metrics.CPUTimeMetric: {
+						Components: map[string]StatisticalResult{
+							"vtgate":   {P: 0.03, N1: 5, N2: 5},
+							"vttablet": {Insignificant: true},
+						},
+					},
+				},
+			},
+			{
+				Latency: StatisticalResult{P: 0.005, N1: 5, N2: 5},
+			},
+		}
+	}
+
+	tests := []struct {
+		correction        string
+		want              []float64
+		wantInsignificant []bool
+	}{
+		{correction: CorrectionNone, want: []float64{0.01, 0.04, 0.03, 0.005}, wantInsignificant: []bool{false, false, false, false}},
+		{correction: CorrectionBenjaminiHochberg, want: []float64{0.02, 0.04, 0.04, 0.02}, wantInsignificant: []bool{false, false, false, false}},
+		{correction: CorrectionHolm, want: []float64{0.03, 0.06, 0.06, 0.02}, wantInsignificant: []bool{false, true, true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.correction, func(t *testing.T) {
+			c := qt.New(t)
+			family := newFamily()
+			adjustPValues(family, StatisticalConfig{Alpha: 0.05, Confidence: 0.95, Correction: tt.correction})
+
+			got := []StatisticalResult{family[0].TotalQPS, family[0].TPS, family[0].ComponentsCPUTime["vtgate"], family[1].Latency}
+			for i, sr := range got {
+				c.Assert(math.Abs(sr.AdjustedP-tt.want[i]) < 1e-9, qt.IsTrue, qt.Commentf("got %f, want %f", sr.AdjustedP, tt.want[i]))
+				c.Assert(sr.Insignificant, qt.Equals, tt.wantInsignificant[i])
+			}
+
+			// results without any value are left untouched
+			c.Assert(family[0].ComponentsCPUTime["vttablet"], qt.DeepEquals, StatisticalResult{Insignificant: true})
+		})
+	}
+}
+
+func TestAdjustPValues_flat(t *testing.T) {
+	c := qt.New(t)
+	family := []*StatisticalResult{
+		{P: 0.01, N1: 5, N2: 5},
+		{P: 0.5, N1: 0, N2: 5},
+		{P: 0.04, N1: 5, N2: 5},
+	}
+	AdjustPValues(family, StatisticalConfig{Alpha: 0.05, Confidence: 0.95, Correction: CorrectionHolm})
+
+	c.Assert(math.Abs(family[0].AdjustedP-0.02) < 1e-9, qt.IsTrue, qt.Commentf("got %f", family[0].AdjustedP))
+	c.Assert(family[0].Insignificant, qt.IsFalse)
+	c.Assert(*family[1], qt.DeepEquals, StatisticalResult{P: 0.5, N2: 5})
+	c.Assert(math.Abs(family[2].AdjustedP-0.04) < 1e-9, qt.IsTrue, qt.Commentf("got %f", family[2].AdjustedP))
+	c.Assert(family[2].Insignificant, qt.IsFalse)
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package macrobench
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/vitessio/arewefastyet/go/storage"
+	"github.com/vitessio/arewefastyet/go/storage/mysql"
+)
+
+const (
+	// MaxSteadyStateDrift is the maximum difference, in percent, between the mean total QPS
+	// of the first and last thirds of the intervals of a run for it to be considered steady.
+	MaxSteadyStateDrift = 10.0
+
+	// minIntervalsForSteadyState is the minimum number of intervals needed to check
+	// whether a run reached steady state.
+	minIntervalsForSteadyState = 3
+)
+
+type (
+	// Interval is the report sysbench prints after each report-interval of the run step.
+	Interval struct {
+		Time       int     `json:"time"`
+		TotalQPS   float64 `json:"total_qps"`
+		ReadsQPS   float64 `json:"reads_qps"`
+		WritesQPS  float64 `json:"writes_qps"`
+		OtherQPS   float64 `json:"other_qps"`
+		TPS        float64 `json:"tps"`
+		Latency    float64 `json:"latency"`
+		Errors     float64 `json:"errors"`
+		Reconnects float64 `json:"reconnects"`
+		Threads    float64 `json:"threads"`
+	}
+
+	// SteadyState tells whether the throughput of a run was stable after warm-up.
+	SteadyState struct {
+		// Checked is false if there were not enough intervals to run the check.
+		Checked bool `json:"checked"`
+		Steady  bool `json:"steady"`
+
+		// Drift is the difference, in percent, between the mean total QPS of the
+		// first and last thirds of the intervals. It is 0 when the first third has
+		// no throughput, such a run is not steady.
+		Drift float64 `json:"drift"`
+	}
+
+	// ExecutionIntervals is the time series of a single execution.
+	ExecutionIntervals struct {
+		ExecUUID    string      `json:"exec_uuid"`
+		Intervals   []Interval  `json:"intervals"`
+		SteadyState SteadyState `json:"steady_state"`
+	}
+)
+
+// GetExecutionIntervals returns the per-interval reports of the given execution
+// along with its steady state check.
+func GetExecutionIntervals(client storage.SQLClient, execUUID string) (ExecutionIntervals, error) {
+	query := `
+        SELECT
+            i.time, i.total_qps, i.reads_qps, i.writes_qps, i.other_qps,
+            i.tps, i.latency, i.errors, i.reconnects, i.threads
+        FROM
+            macrobenchmark_intervals AS i
+        JOIN
+            macrobenchmark AS info ON info.macrobenchmark_id = i.macrobenchmark_id
+        WHERE
+            info.exec_uuid = ?
+        ORDER BY
+            i.time ASC
+    `
+
+	rows, err := client.Read(query, execUUID)
+	if err != nil {
+		return ExecutionIntervals{}, err
+	}
+	defer rows.Close()
+
+	res := ExecutionIntervals{ExecUUID: execUUID, Intervals: []Interval{}}
+	for rows.Next() {
+		var i Interval
+		err = rows.Scan(&i.Time, &i.TotalQPS, &i.ReadsQPS, &i.WritesQPS, &i.OtherQPS, &i.TPS, &i.Latency, &i.Errors, &i.Reconnects, &i.Threads)
+		if err != nil {
+			return ExecutionIntervals{}, err
+		}
+		res.Intervals = append(res.Intervals, i)
+	}
+	if err := rows.Err(); err != nil {
+		return ExecutionIntervals{}, err
+	}
+
+	ss, found, err := getSteadyState(client, execUUID)
+	if err != nil {
+		return ExecutionIntervals{}, err
+	}
+	if !found {
+		// the check was not stored with the results of older executions
+		ss = checkSteadyState(res.Intervals)
+	}
+	res.SteadyState = ss
+	return res, nil
+}
+
+// getSteadyState returns the steady state check stored with the results of the
+// given execution.
+func getSteadyState(client storage.SQLClient, execUUID string) (ss SteadyState, found bool, err error) {
+	rows, err := client.Read("SELECT s.steady, s.drift FROM macrobenchmark_steady_state AS s JOIN macrobenchmark AS info ON info.macrobenchmark_id = s.macrobenchmark_id WHERE info.exec_uuid = ?", execUUID)
+	if err != nil {
+		return SteadyState{}, false, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return SteadyState{}, false, rows.Err()
+	}
+	ss.Checked = true
+	if err := rows.Scan(&ss.Steady, &ss.Drift); err != nil {
+		return SteadyState{}, false, err
+	}
+	return ss, true, nil
+}
+
+// checkSteadyState compares the mean total QPS of the first and last thirds of
+// the given intervals, if they differ by more than MaxSteadyStateDrift percent
+// the throughput did not reach a steady state.
+func checkSteadyState(intervals []Interval) SteadyState {
+	if len(intervals) < minIntervalsForSteadyState {
+		return SteadyState{}
+	}
+
+	third := len(intervals) / 3
+	meanQPS := func(intervals []Interval) float64 {
+		var sum float64
+		for _, i := range intervals {
+			sum += i.TotalQPS
+		}
+		return sum / float64(len(intervals))
+	}
+	first := meanQPS(intervals[:third])
+	last := meanQPS(intervals[len(intervals)-third:])
+
+	// a run without any throughput at first never warmed up, its drift is unknown
+	ss := SteadyState{Checked: true}
+	if first == 0 {
+		return ss
+	}
+	ss.Drift = (last/first - 1.0) * 100.0
+	ss.Steady = math.Abs(ss.Drift) <= MaxSteadyStateDrift
+	return ss
+}
+
+func (mbr sysbenchResult) toInterval() Interval {
+	return Interval{
+		Time:       mbr.Time,
+		TotalQPS:   mbr.QPS.Total,
+		ReadsQPS:   mbr.QPS.Reads,
+		WritesQPS:  mbr.QPS.Writes,
+		OtherQPS:   mbr.QPS.Other,
+		TPS:        mbr.TPS,
+		Latency:    mbr.Latency,
+		Errors:     mbr.Errors,
+		Reconnects: mbr.Reconnects,
+		Threads:    mbr.Threads,
+	}
+}
+
+// insertSteadyStateToMySQL checks whether the given per-interval reports reached a
+// steady state and stores the result of the check, if there were enough of them.
+func (mrs sysbenchResultArray) insertSteadyStateToMySQL(macrobenchmarkID int, client storage.SQLClient) error {
+	intervals := make([]Interval, 0, len(mrs))
+	for _, mr := range mrs {
+		intervals = append(intervals, mr.toInterval())
+	}
+	ss := checkSteadyState(intervals)
+	if !ss.Checked {
+		return nil
+	}
+	_, err := client.Write("INSERT INTO macrobenchmark_steady_state(macrobenchmark_id, steady, drift) VALUES(?, ?, ?)", macrobenchmarkID, ss.Steady, ss.Drift)
+	return err
+}
+
+// insertIntervalsToMySQL inserts all the given per-interval reports at once.
+func (mrs sysbenchResultArray) insertIntervalsToMySQL(macrobenchmarkID int, client storage.SQLClient) error {
+	if client == nil {
+		return errors.New(mysql.ErrorClientConnectionNotInitialized)
+	}
+
+	query := "INSERT INTO macrobenchmark_intervals(macrobenchmark_id, time, total_qps, reads_qps, writes_qps, other_qps, tps, latency, errors, reconnects, threads) VALUES"
+	rows := make([][]interface{}, 0, len(mrs))
+	for _, mr := range mrs {
+		i := mr.toInterval()
+		rows = append(rows, []interface{}{macrobenchmarkID, i.Time, i.TotalQPS, i.ReadsQPS, i.WritesQPS, i.OtherQPS, i.TPS, i.Latency, i.Errors, i.Reconnects, i.Threads})
+	}
+	return storage.BulkInsert(context.Background(), client, query, rows)
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package macrobench
+
+import (
+	"github.com/vitessio/arewefastyet/go/storage"
+)
+
+// ExecutionLatencyHistogram is the latency histogram reported by sysbench for a single
+// execution, the buckets are sorted by latency.
+type ExecutionLatencyHistogram struct {
+	ExecUUID string          `json:"exec_uuid"`
+	Buckets  []LatencyBucket `json:"buckets"`
+}
+
+// GetExecutionLatencyHistogram returns the latency histogram of the given execution.
+func GetExecutionLatencyHistogram(client storage.SQLClient, execUUID string) (ExecutionLatencyHistogram, error) {
+	query := `
+        SELECT
+            h.value, SUM(h.count)
+        FROM
+            macrobenchmark_latency_histogram AS h
+        JOIN
+            macrobenchmark AS info ON info.macrobenchmark_id = h.macrobenchmark_id
+        WHERE
+            info.exec_uuid = ?
+        GROUP BY
+            h.value
+        ORDER BY
+            h.value ASC
+    `
+
+	rows, err := client.Read(query, execUUID)
+	if err != nil {
+		return ExecutionLatencyHistogram{}, err
+	}
+	defer rows.Close()
+
+	res := ExecutionLatencyHistogram{ExecUUID: execUUID, Buckets: []LatencyBucket{}}
+	for rows.Next() {
+		var b LatencyBucket
+		if err := rows.Scan(&b.Value, &b.Count); err != nil {
+			return ExecutionLatencyHistogram{}, err
+		}
+		res.Buckets = append(res.Buckets, b)
+	}
+	return res, rows.Err()
+}
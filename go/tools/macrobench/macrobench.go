@@ -19,16 +19,19 @@
 package macrobench
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
+	"github.com/spf13/viper"
 	"github.com/vitessio/arewefastyet/go/exec/metrics"
-	"github.com/vitessio/arewefastyet/go/storage/influxdb"
-	"github.com/vitessio/arewefastyet/go/storage/psdb"
+	"github.com/vitessio/arewefastyet/go/storage"
+	"github.com/vitessio/arewefastyet/go/storage/database"
 )
 
 type PlannerVersion string
@@ -96,10 +99,12 @@ func Run(mabcfg Config) error {
 	if err != nil {
 		return err
 	}
-	defer sqlClient.Close()
+	if sqlClient != nil {
+		defer sqlClient.Close()
+	}
 
-	// get metrics database client
-	metricsClient, err := createMetricsDatabaseClient(mabcfg.MetricsDatabaseConfig)
+	// get metrics source, the execution window starts now and ends when the metrics are fetched
+	metricsSource, err := createMetricsSource(mabcfg.MetricsSourceConfig, time.Now())
 	if err != nil {
 		return err
 	}
@@ -114,6 +119,12 @@ func Run(mabcfg Config) error {
 	}
 
 	// Prepare
+	if mabcfg.MetricsDefinitions == nil {
+		mabcfg.MetricsDefinitions, err = metrics.LoadDefinitions(viper.GetViper())
+		if err != nil {
+			return err
+		}
+	}
 	if mabcfg.WorkingDirectory == "" {
 		mabcfg.WorkingDirectory, _ = os.Getwd()
 	}
@@ -137,30 +148,52 @@ func Run(mabcfg Config) error {
 		}
 	}
 
-	err = handleResults(mabcfg, resStr, sqlClient, metricsClient, macrobenchID)
+	err = handleResults(mabcfg, resStr, sqlClient, metricsSource, macrobenchID)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func handleResults(mabcfg Config, resStr []byte, sqlClient *psdb.Client, metricsClient *influxdb.Client, macrobenchID int) error {
-	sysbenchResults, err := handleSysBenchResults(resStr, sqlClient, macrobenchID)
+func handleResults(mabcfg Config, resStr []byte, sqlClient storage.SQLClient, metricsSource metrics.Source, macrobenchID int) error {
+	sysbenchResults, err := parseSysBenchResults(resStr)
 	if err != nil {
 		return err
 	}
-	err = handleMetricsResults(metricsClient, sqlClient, mabcfg.execUUID, sysbenchResults.Queries)
-	if err != nil {
-		return err
+
+	// gather all the results before storing them
+	var execMetrics metrics.ExecutionMetrics
+	if metricsSource != nil {
+		execMetrics, err = metrics.GetExecutionMetrics(metricsSource, mabcfg.execUUID, sysbenchResults[0].Queries, mabcfg.MetricsDefinitions)
+		if err != nil {
+			return err
+		}
 	}
-	err = handleVTGateResults(mabcfg.vtgateWebPorts, sqlClient, mabcfg.execUUID, macrobenchID)
+	plans, err := getVTGatesQueryPlans(mabcfg.vtgateWebPorts)
 	if err != nil {
 		return err
 	}
-	return nil
+	if sqlClient == nil {
+		return nil
+	}
+
+	// the results of the execution are either all stored or not at all
+	return sqlClient.WithTx(context.Background(), func(tx storage.SQLClient) error {
+		err := insertSysBenchResults(sysbenchResults, tx, macrobenchID)
+		if err != nil {
+			return err
+		}
+		if execMetrics != nil {
+			err = metrics.InsertExecutionMetrics(tx, mabcfg.execUUID, execMetrics)
+			if err != nil {
+				return err
+			}
+		}
+		return insertVTGateQueryMapToMySQL(tx, mabcfg.execUUID, plans, macrobenchID)
+	})
 }
 
-func createSQLClient(dbConfig *psdb.Config) (client *psdb.Client, err error) {
+func createSQLClient(dbConfig *database.Config) (client storage.Client, err error) {
 	if dbConfig != nil && dbConfig.IsValid() {
 		client, err = dbConfig.NewClient()
 		if err != nil {
@@ -170,9 +203,9 @@ func createSQLClient(dbConfig *psdb.Config) (client *psdb.Client, err error) {
 	return
 }
 
-func createMetricsDatabaseClient(dbConfig *influxdb.Config) (client *influxdb.Client, err error) {
-	if dbConfig != nil && dbConfig.IsValid() {
-		client, err = dbConfig.NewClient()
+func createMetricsSource(sourceConfig *metrics.SourceConfig, start time.Time) (source metrics.Source, err error) {
+	if sourceConfig.IsValid() {
+		source, err = sourceConfig.NewSource(start, time.Time{})
 		if err != nil {
 			return
 		}
@@ -180,43 +213,29 @@ func createMetricsDatabaseClient(dbConfig *influxdb.Config) (client *influxdb.Cl
 	return
 }
 
-func handleVTGateResults(ports []string, sqlClient *psdb.Client, execUUID string, macrobenchID int) error {
-	plans, err := getVTGatesQueryPlans(ports)
+// parseSysBenchResults parses the output of sysbench, the first element is the final
+// aggregate and the following ones are the reports printed after each report-interval.
+func parseSysBenchResults(resStr []byte) ([]sysbenchResult, error) {
+	var results []sysbenchResult
+	err := json.Unmarshal(resStr, &results)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("unmarshal results: %+v\n", err)
+	}
+	if len(results) == 0 {
+		return nil, errors.New(ErrorNoSysBenchResult)
 	}
-	return insertVTGateQueryMapToMySQL(sqlClient, execUUID, plans, macrobenchID)
+	return results, nil
 }
 
-func handleMetricsResults(client *influxdb.Client, sqlClient *psdb.Client, execUUID string, queries int) error {
-	execMetrics, err := metrics.GetExecutionMetrics(*client, execUUID, queries)
+func insertSysBenchResults(results []sysbenchResult, sqlClient storage.SQLClient, macrobenchID int) error {
+	err := results[0].insertToMySQL(macrobenchID, sqlClient)
 	if err != nil {
 		return err
 	}
-	err = metrics.InsertExecutionMetrics(sqlClient, execUUID, execMetrics)
+	intervals := sysbenchResultArray(results[1:])
+	err = intervals.insertIntervalsToMySQL(macrobenchID, sqlClient)
 	if err != nil {
 		return err
 	}
-	return nil
-}
-
-func handleSysBenchResults(resStr []byte, sqlClient *psdb.Client, macrobenchID int) (sysbenchResult, error) {
-	// Parse results
-	var results []sysbenchResult
-	err := json.Unmarshal(resStr, &results)
-	if err != nil {
-		return sysbenchResult{}, fmt.Errorf("unmarshal results: %+v\n", err)
-	}
-	if len(results) == 0 {
-		return sysbenchResult{}, errors.New(ErrorNoSysBenchResult)
-	}
-
-	// Save results
-	if sqlClient != nil {
-		err = results[0].insertToMySQL(macrobenchID, sqlClient)
-		if err != nil {
-			return sysbenchResult{}, err
-		}
-	}
-	return results[0], nil
+	return intervals.insertSteadyStateToMySQL(macrobenchID, sqlClient)
 }
@@ -51,3 +51,65 @@ func TestBuildSysbenchArgString(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSysBenchResults(t *testing.T) {
+	tts := []struct {
+		name string
+		in   string
+		want sysbenchResult
+	}{
+		{
+			name: "without latency percentiles",
+			in:   `[{"queries": 100, "qps": {"total": 10.5}, "tps": 2, "latency": 3.5}]`,
+			want: sysbenchResult{Queries: 100, QPS: sysbenchQPS{Total: 10.5}, TPS: 2, Latency: 3.5},
+		},
+		{
+			name: "with latency percentiles and histogram",
+			in: `[{"queries": 100, "latency": 3.5, "latency_percentiles": {"p50": 2.1, "p95": 4.2, "p99": 8.4, "max": 20},
+				"latency_histogram": [{"value": 2.03, "count": 40}, {"value": 4.1, "count": 9}]}]`,
+			want: sysbenchResult{
+				Queries:            100,
+				Latency:            3.5,
+				LatencyPercentiles: &sysbenchLatencyPercentiles{P50: 2.1, P95: 4.2, P99: 8.4, Max: 20},
+				LatencyHistogram:   []LatencyBucket{{Value: 2.03, Count: 40}, {Value: 4.1, Count: 9}},
+			},
+		},
+	}
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			got, err := parseSysBenchResults([]byte(tt.in))
+			c.Assert(err, qt.IsNil)
+			c.Assert(got, qt.HasLen, 1)
+			c.Assert(got[0], qt.DeepEquals, tt.want)
+		})
+	}
+}
+
+func TestCheckSteadyState(t *testing.T) {
+	intervalsWithQPS := func(qps ...float64) []Interval {
+		var res []Interval
+		for i, v := range qps {
+			res = append(res, Interval{Time: (i + 1) * 10, TotalQPS: v})
+		}
+		return res
+	}
+
+	tts := []struct {
+		name      string
+		intervals []Interval
+		want      SteadyState
+	}{
+		{name: "not enough intervals", intervals: intervalsWithQPS(100, 100), want: SteadyState{}},
+		{name: "steady", intervals: intervalsWithQPS(100, 100, 98, 102, 99, 101), want: SteadyState{Checked: true, Steady: true, Drift: 0}},
+		{name: "no throughput at first", intervals: intervalsWithQPS(0, 0, 0, 100, 100, 100), want: SteadyState{Checked: true, Steady: false}},
+		{name: "no throughput at all", intervals: intervalsWithQPS(0, 0, 0), want: SteadyState{Checked: true, Steady: false}},
+		{name: "still warming up", intervals: intervalsWithQPS(50, 70, 90, 100, 100, 100), want: SteadyState{Checked: true, Steady: false, Drift: 66.66666666666667}},
+	}
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			c.Assert(checkSteadyState(tt.intervals), qt.DeepEquals, tt.want)
+		})
+	}
+}
@@ -0,0 +1,296 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package macrobench
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/aclements/go-moremath/stats"
+	"golang.org/x/perf/benchmath"
+)
+
+const (
+	ErrorUnknownComparisonMethod = "unknown comparison method"
+	ErrorInvalidAlpha            = "alpha must be between 0 and 1"
+	ErrorInvalidConfidence       = "confidence must be between 0 and 1"
+
+	MethodMannWhitney = "mann-whitney"
+	MethodWelchTTest  = "welch-t-test"
+	MethodBootstrap   = "bootstrap"
+
+	// bootstrapResamples is the number of resamples drawn by the bootstrap method.
+	bootstrapResamples = 10000
+
+	// bootstrapSeed makes the bootstrap method deterministic, so the same samples
+	// always lead to the same result.
+	bootstrapSeed = 1
+)
+
+type (
+	// ComparisonMethod summarizes samples and compares two of them.
+	ComparisonMethod interface {
+		// Name returns the name of the method, as accepted by NewComparisonMethod.
+		Name() string
+
+		// Summary returns the center of the given sample and its confidence interval.
+		Summary(values []float64) StatisticalSummary
+
+		// Compare tests whether the old and new samples differ significantly.
+		Compare(old, new []float64) StatisticalResult
+
+		// Config returns the StatisticalConfig used by the method.
+		Config() StatisticalConfig
+	}
+
+	// StatisticalConfig holds the thresholds used by a ComparisonMethod.
+	StatisticalConfig struct {
+		// Alpha is the level below which a p-value is considered significant.
+		Alpha float64
+
+		// Confidence is the desired confidence of the summaries' intervals.
+		Confidence float64
+
+		// Correction is the multiple-comparison correction applied to the p-values
+		// of a whole comparison. It defaults to CorrectionNone.
+		Correction string
+	}
+
+	// mannWhitney summarizes samples using their median and compares them
+	// using the Mann-Whitney U test. It does not assume anything about the
+	// distribution of the samples.
+	mannWhitney struct {
+		cfg StatisticalConfig
+	}
+
+	// welchTTest summarizes samples using their mean and compares them using
+	// Welch's t-test. It assumes the samples are normally distributed.
+	welchTTest struct {
+		cfg StatisticalConfig
+	}
+
+	// bootstrap summarizes samples using their median and compares them using
+	// a bootstrap confidence interval on the difference of the medians.
+	bootstrap struct {
+		cfg StatisticalConfig
+	}
+)
+
+// ComparisonMethods lists the name of all the available ComparisonMethod.
+var ComparisonMethods = []string{MethodMannWhitney, MethodWelchTTest, MethodBootstrap}
+
+// DefaultStatisticalConfig returns the StatisticalConfig used when none is provided.
+func DefaultStatisticalConfig() StatisticalConfig {
+	return StatisticalConfig{
+		Alpha:      defaultThresholds.CompareAlpha,
+		Confidence: defaultConfidence,
+		Correction: CorrectionNone,
+	}
+}
+
+// IsValid returns an error if the given StatisticalConfig cannot be used.
+func (cfg StatisticalConfig) IsValid() error {
+	if cfg.Alpha <= 0 || cfg.Alpha >= 1 {
+		return errors.New(ErrorInvalidAlpha)
+	}
+	if cfg.Confidence <= 0 || cfg.Confidence >= 1 {
+		return errors.New(ErrorInvalidConfidence)
+	}
+	if !isValidCorrection(cfg.Correction) {
+		return fmt.Errorf("%s: %s", ErrorUnknownCorrection, cfg.Correction)
+	}
+	return nil
+}
+
+// NewComparisonMethod returns the ComparisonMethod matching the given name, configured
+// with cfg. An empty name returns the default method: Mann-Whitney.
+func NewComparisonMethod(name string, cfg StatisticalConfig) (ComparisonMethod, error) {
+	if err := cfg.IsValid(); err != nil {
+		return nil, err
+	}
+	switch name {
+	case "", MethodMannWhitney:
+		return mannWhitney{cfg: cfg}, nil
+	case MethodWelchTTest:
+		return welchTTest{cfg: cfg}, nil
+	case MethodBootstrap:
+		return bootstrap{cfg: cfg}, nil
+	}
+	return nil, fmt.Errorf("%s: %s", ErrorUnknownComparisonMethod, name)
+}
+
+func (m mannWhitney) Config() StatisticalConfig {
+	return m.cfg
+}
+
+func (m mannWhitney) Name() string {
+	return MethodMannWhitney
+}
+
+func (m mannWhitney) sample(values []float64) *benchmath.Sample {
+	return benchmath.NewSample(values, &benchmath.Thresholds{CompareAlpha: m.cfg.Alpha})
+}
+
+func (m mannWhitney) Summary(values []float64) StatisticalSummary {
+	return toStatisticalSummary(benchmath.AssumeNothing.Summary(m.sample(values), m.cfg.Confidence))
+}
+
+func (m mannWhitney) Compare(old, new []float64) StatisticalResult {
+	c := benchmath.AssumeNothing.Compare(m.sample(old), m.sample(new))
+	return newStatisticalResult(m.Summary(old), m.Summary(new), c.P, c.N1, c.N2, m.cfg.Alpha)
+}
+
+func (w welchTTest) Config() StatisticalConfig {
+	return w.cfg
+}
+
+func (w welchTTest) Name() string {
+	return MethodWelchTTest
+}
+
+func (w welchTTest) Summary(values []float64) StatisticalSummary {
+	mean, lo, hi := stats.MeanCI(values, w.cfg.Confidence)
+	return toStatisticalSummary(benchmath.Summary{Center: mean, Lo: lo, Hi: hi, Confidence: w.cfg.Confidence})
+}
+
+func (w welchTTest) Compare(old, new []float64) StatisticalResult {
+	p := 1.0
+	t, err := stats.TwoSampleWelchTTest(stats.Sample{Xs: old}, stats.Sample{Xs: new}, stats.LocationDiffers)
+	if err == nil {
+		p = t.P
+	}
+	return newStatisticalResult(w.Summary(old), w.Summary(new), p, len(old), len(new), w.cfg.Alpha)
+}
+
+func (b bootstrap) Config() StatisticalConfig {
+	return b.cfg
+}
+
+func (b bootstrap) Name() string {
+	return MethodBootstrap
+}
+
+func (b bootstrap) Summary(values []float64) StatisticalSummary {
+	// resampling a single value always gives the same median, its interval is unknown
+	// like with benchmath
+	if len(values) < 2 {
+		center := math.NaN()
+		if len(values) == 1 {
+			center = values[0]
+		}
+		return toStatisticalSummary(benchmath.Summary{Center: center, Lo: math.Inf(-1), Hi: math.Inf(1), Confidence: b.cfg.Confidence})
+	}
+	rnd := rand.New(rand.NewSource(bootstrapSeed))
+	medians := make([]float64, bootstrapResamples)
+	for i := range medians {
+		medians[i] = median(resample(rnd, values))
+	}
+	lo, hi := percentileInterval(medians, b.cfg.Confidence)
+	return toStatisticalSummary(benchmath.Summary{Center: median(values), Lo: lo, Hi: hi, Confidence: b.cfg.Confidence})
+}
+
+func (b bootstrap) Compare(old, new []float64) StatisticalResult {
+	p := 1.0
+	if len(old) > 0 && len(new) > 0 {
+		rnd := rand.New(rand.NewSource(bootstrapSeed))
+		var belowOrZero, aboveOrZero int
+		for i := 0; i < bootstrapResamples; i++ {
+			diff := median(resample(rnd, new)) - median(resample(rnd, old))
+			if diff <= 0 {
+				belowOrZero++
+			}
+			if diff >= 0 {
+				aboveOrZero++
+			}
+		}
+		// Two-sided p-value: how often the bootstrapped difference of the medians
+		// lands on the other side of zero.
+		p = math.Min(1, 2*float64(min(belowOrZero, aboveOrZero))/bootstrapResamples)
+	}
+	return newStatisticalResult(b.Summary(old), b.Summary(new), p, len(old), len(new), b.cfg.Alpha)
+}
+
+func toStatisticalSummary(s benchmath.Summary) StatisticalSummary {
+	return StatisticalSummary{
+		Center:     s.Center,
+		Confidence: s.Confidence,
+		Range:      getRangeFromSummary(s),
+	}
+}
+
+// newStatisticalResult builds a StatisticalResult out of the summaries of the old and new
+// samples and of the p-value of their comparison.
+func newStatisticalResult(old, new StatisticalSummary, p float64, n1, n2 int, alpha float64) StatisticalResult {
+	if math.IsNaN(old.Center) {
+		old.Center = 0.0
+	}
+	if math.IsNaN(new.Center) {
+		new.Center = 0.0
+	}
+
+	sr := StatisticalResult{
+		Old:       old,
+		New:       new,
+		P:         p,
+		AdjustedP: p,
+		N1:        n1,
+		N2:        n2,
+	}
+	if p > alpha {
+		sr.Insignificant = true
+	}
+
+	switch {
+	case sr.Old.Center == sr.New.Center || sr.Old.Center == 0:
+		sr.Delta = 0
+	default:
+		sr.Delta = ((sr.New.Center / sr.Old.Center) - 1.0) * 100.0
+	}
+	return sr
+}
+
+func resample(rnd *rand.Rand, values []float64) []float64 {
+	res := make([]float64, len(values))
+	for i := range res {
+		res[i] = values[rnd.Intn(len(values))]
+	}
+	return res
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// percentileInterval returns the bounds of the central interval of the given values
+// that contains the given proportion (confidence) of them.
+func percentileInterval(values []float64, confidence float64) (lo, hi float64) {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	tail := (1 - confidence) / 2
+	loIdx := int(math.Floor(tail * float64(len(sorted)-1)))
+	hiIdx := int(math.Ceil((1 - tail) * float64(len(sorted)-1)))
+	return sorted[loIdx], sorted[hiIdx]
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package macrobench
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestComparisonMethods(t *testing.T) {
+	old := []float64{100, 101, 99, 100.5, 99.5, 100.2, 99.8, 100.1, 99.9, 100.3}
+	faster := []float64{110, 111, 109, 110.5, 109.5, 110.2, 109.8, 110.1, 109.9, 110.3}
+
+	for _, name := range ComparisonMethods {
+		t.Run(name, func(t *testing.T) {
+			c := qt.New(t)
+			method, err := NewComparisonMethod(name, DefaultStatisticalConfig())
+			c.Assert(err, qt.IsNil)
+			c.Assert(method.Name(), qt.Equals, name)
+
+			res := method.Compare(old, faster)
+			c.Assert(res.Insignificant, qt.IsFalse)
+			c.Assert(res.Delta > 9 && res.Delta < 11, qt.IsTrue, qt.Commentf("delta: %f", res.Delta))
+			c.Assert(res.N1, qt.Equals, len(old))
+			c.Assert(res.N2, qt.Equals, len(faster))
+
+			res = method.Compare(old, old)
+			c.Assert(res.Insignificant, qt.IsTrue)
+			c.Assert(res.Delta, qt.Equals, 0.0)
+
+			// the interval of a single value is unknown, not empty
+			summary := method.Summary([]float64{100})
+			c.Assert(summary.Center, qt.Equals, 100.0)
+			c.Assert(summary.Range.Infinite || summary.Range.Unknown, qt.IsTrue, qt.Commentf("range: %+v", summary.Range))
+		})
+	}
+}
+
+func TestNewComparisonMethod(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		cfg     StatisticalConfig
+		want    string
+		wantErr string
+	}{
+		{name: "default method", cfg: DefaultStatisticalConfig(), want: MethodMannWhitney},
+		{name: "welch", method: MethodWelchTTest, cfg: DefaultStatisticalConfig(), want: MethodWelchTTest},
+		{name: "unknown method", method: "coin-flip", cfg: DefaultStatisticalConfig(), wantErr: ErrorUnknownComparisonMethod + ": coin-flip"},
+		{name: "invalid alpha", cfg: StatisticalConfig{Alpha: 1.5, Confidence: 0.95}, wantErr: ErrorInvalidAlpha},
+		{name: "invalid confidence", cfg: StatisticalConfig{Alpha: 0.05}, wantErr: ErrorInvalidConfidence},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			got, err := NewComparisonMethod(tt.method, tt.cfg)
+			if tt.wantErr != "" {
+				c.Assert(err, qt.ErrorMatches, tt.wantErr)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(got.Name(), qt.Equals, tt.want)
+		})
+	}
+}
+
+func TestStatisticalResult_Regressed(t *testing.T) {
+	tests := []struct {
+		name           string
+		result         StatisticalResult
+		higherIsBetter bool
+		want           bool
+	}{
+		{name: "increase of a cost", result: StatisticalResult{Delta: 12}, want: true},
+		{name: "decrease of a cost", result: StatisticalResult{Delta: -12}},
+		{name: "decrease of a rate", result: StatisticalResult{Delta: -12}, higherIsBetter: true, want: true},
+		{name: "increase of a rate", result: StatisticalResult{Delta: 12}, higherIsBetter: true},
+		{name: "below the threshold", result: StatisticalResult{Delta: 8}},
+		{name: "insignificant", result: StatisticalResult{Delta: 12, Insignificant: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			c.Assert(tt.result.Regressed(10, tt.higherIsBetter), qt.Equals, tt.want)
+		})
+	}
+}
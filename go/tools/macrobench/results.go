@@ -38,6 +38,21 @@ type (
 		Other  float64 `json:"other"`
 	}
 
+	// sysbenchLatencyPercentiles holds the latency percentiles (in milliseconds) reported by sysbench.
+	sysbenchLatencyPercentiles struct {
+		P50 float64 `json:"p50"`
+		P95 float64 `json:"p95"`
+		P99 float64 `json:"p99"`
+		Max float64 `json:"max"`
+	}
+
+	// LatencyBucket is a single bucket of the latency histogram reported by sysbench,
+	// Value is the upper bound of the bucket in milliseconds.
+	LatencyBucket struct {
+		Value float64 `json:"value"`
+		Count int     `json:"count"`
+	}
+
 	// sysbenchResult is the full representation of the results we get after executing sysbench once.
 	sysbenchResult struct {
 		ID         int
@@ -49,6 +64,10 @@ type (
 		Reconnects float64     `json:"reconnects"`
 		Time       int         `json:"time"`
 		Threads    float64     `json:"threads"`
+
+		// LatencyPercentiles is nil if sysbench did not report them.
+		LatencyPercentiles *sysbenchLatencyPercentiles `json:"latency_percentiles"`
+		LatencyHistogram   []LatencyBucket             `json:"latency_histogram"`
 	}
 
 	sysbenchResultArray []sysbenchResult
@@ -60,23 +79,32 @@ type (
 		other  []float64
 	}
 
-	metricsAsSlice struct {
-		totalComponentsCPUTime []float64
-		componentsCPUTime      map[string][]float64
+	// metricAsSlice contains all the values of a single metric, for all its components.
+	metricAsSlice struct {
+		total      []float64
+		components map[string][]float64
+	}
+
+	// metricsAsSlice maps the name of a metric to all its values.
+	metricsAsSlice map[string]metricAsSlice
 
-		totalComponentsMemStatsAllocBytes []float64
-		componentsMemStatsAllocBytes      map[string][]float64
+	latencyPercentilesAsSlice struct {
+		p50 []float64
+		p95 []float64
+		p99 []float64
+		max []float64
 	}
 
 	executionGroupResultsAsSlice struct {
 		qps qpsAsSlice
 
-		tps        []float64
-		latency    []float64
-		errors     []float64
-		reconnects []float64
-		time       []int
-		threads    []float64
+		tps                []float64
+		latency            []float64
+		latencyPercentiles latencyPercentilesAsSlice
+		errors             []float64
+		reconnects         []float64
+		time               []int
+		threads            []float64
 
 		metrics metricsAsSlice
 	}
@@ -118,6 +146,7 @@ func (br executionGroupResults) toStatisticalSingleResult() StatisticalSingleRes
 		GitRef:                       br.GitRef,
 		ComponentsCPUTime:            map[string]StatisticalSummary{},
 		ComponentsMemStatsAllocBytes: map[string]StatisticalSummary{},
+		Metrics:                      map[string]MetricSummary{},
 	}
 
 	resultSlice := br.asSlice()
@@ -129,42 +158,45 @@ func (br executionGroupResults) toStatisticalSingleResult() StatisticalSingleRes
 
 	ssr.TPS, _ = getSummary(resultSlice.tps)
 	ssr.Latency, _ = getSummary(resultSlice.latency)
+	ssr.LatencyP50, _ = getSummary(resultSlice.latencyPercentiles.p50)
+	ssr.LatencyP95, _ = getSummary(resultSlice.latencyPercentiles.p95)
+	ssr.LatencyP99, _ = getSummary(resultSlice.latencyPercentiles.p99)
+	ssr.LatencyMax, _ = getSummary(resultSlice.latencyPercentiles.max)
 	ssr.Errors, _ = getSummary(resultSlice.errors)
 
-	ssr.TotalComponentsCPUTime, _ = getSummary(resultSlice.metrics.totalComponentsCPUTime)
-	for name, value := range resultSlice.metrics.componentsCPUTime {
-		ssr.ComponentsCPUTime[name], _ = getSummary(value)
+	for metricName, metric := range resultSlice.metrics {
+		ms := MetricSummary{Components: make(map[string]StatisticalSummary, len(metric.components))}
+		ms.Total, _ = getSummary(metric.total)
+		for name, value := range metric.components {
+			ms.Components[name], _ = getSummary(value)
+		}
+		ssr.Metrics[metricName] = ms
 	}
 
-	ssr.TotalComponentsMemStatsAllocBytes, _ = getSummary(resultSlice.metrics.totalComponentsMemStatsAllocBytes)
-	for name, value := range resultSlice.metrics.componentsMemStatsAllocBytes {
-		ssr.ComponentsMemStatsAllocBytes[name], _ = getSummary(value)
+	if cpu, ok := ssr.Metrics[metrics.CPUTimeMetric]; ok {
+		ssr.TotalComponentsCPUTime = cpu.Total
+		ssr.ComponentsCPUTime = cpu.Components
+	}
+	if mem, ok := ssr.Metrics[metrics.MemStatsAllocBytesMetric]; ok {
+		ssr.TotalComponentsMemStatsAllocBytes = mem.Total
+		ssr.ComponentsMemStatsAllocBytes = mem.Components
 	}
 	return ssr
 }
 
-func (br executionGroupResults) toShortStatisticalSingleResult() ShortStatisticalSingleResult {
-	var sssr ShortStatisticalSingleResult
-
-	resultSlice := br.asSlice()
-
-	sssr.TotalQPS, _ = getSummary(resultSlice.qps.total)
-	return sssr
-}
-
 func metricsToSlice(metrics metrics.ExecutionMetricsArray) metricsAsSlice {
-	var s metricsAsSlice
-	s.componentsCPUTime = make(map[string][]float64)
-	s.componentsMemStatsAllocBytes = make(map[string][]float64)
+	s := metricsAsSlice{}
 	for _, metricRow := range metrics {
-		s.totalComponentsCPUTime = append(s.totalComponentsCPUTime, metricRow.TotalComponentsCPUTime)
-		for name, value := range metricRow.ComponentsCPUTime {
-			s.componentsCPUTime[name] = append(s.componentsCPUTime[name], value)
-		}
-
-		s.totalComponentsMemStatsAllocBytes = append(s.totalComponentsMemStatsAllocBytes, metricRow.TotalComponentsMemStatsAllocBytes)
-		for name, value := range metricRow.ComponentsMemStatsAllocBytes {
-			s.componentsMemStatsAllocBytes[name] = append(s.componentsMemStatsAllocBytes[name], value)
+		for metricName, metric := range metricRow {
+			ms, ok := s[metricName]
+			if !ok {
+				ms.components = make(map[string][]float64)
+			}
+			ms.total = append(ms.total, metric.Total)
+			for name, value := range metric.Components {
+				ms.components[name] = append(ms.components[name], value)
+			}
+			s[metricName] = ms
 		}
 	}
 	return s
@@ -179,6 +211,12 @@ func (mrs sysbenchResultArray) resultsArrayToSlice() executionGroupResultsAsSlic
 		ras.qps.other = append(ras.qps.other, mr.QPS.Other)
 		ras.tps = append(ras.tps, mr.TPS)
 		ras.latency = append(ras.latency, mr.Latency)
+		if mr.LatencyPercentiles != nil {
+			ras.latencyPercentiles.p50 = append(ras.latencyPercentiles.p50, mr.LatencyPercentiles.P50)
+			ras.latencyPercentiles.p95 = append(ras.latencyPercentiles.p95, mr.LatencyPercentiles.P95)
+			ras.latencyPercentiles.p99 = append(ras.latencyPercentiles.p99, mr.LatencyPercentiles.P99)
+			ras.latencyPercentiles.max = append(ras.latencyPercentiles.max, mr.LatencyPercentiles.Max)
+		}
 		ras.errors = append(ras.errors, mr.Errors)
 		ras.reconnects = append(ras.reconnects, mr.Reconnects)
 		ras.time = append(ras.time, mr.Time)
@@ -190,13 +228,17 @@ func (mrs sysbenchResultArray) resultsArrayToSlice() executionGroupResultsAsSlic
 	sort.Float64s(ras.qps.other)
 	sort.Float64s(ras.tps)
 	sort.Float64s(ras.latency)
+	sort.Float64s(ras.latencyPercentiles.p50)
+	sort.Float64s(ras.latencyPercentiles.p95)
+	sort.Float64s(ras.latencyPercentiles.p99)
+	sort.Float64s(ras.latencyPercentiles.max)
 	sort.Float64s(ras.reconnects)
 	sort.Ints(ras.time)
 	sort.Float64s(ras.threads)
 	return ras
 }
 
-func Compare(client storage.SQLClient, old, new string, workloads []string, planner PlannerVersion) (map[string]StatisticalCompareResults, error) {
+func Compare(client storage.SQLClient, old, new string, workloads []string, planner PlannerVersion, method ComparisonMethod) (map[string]StatisticalCompareResults, error) {
 	results := make(map[string]StatisticalCompareResults, len(workloads))
 	mu := sync.Mutex{}
 	wg := sync.WaitGroup{}
@@ -207,13 +249,13 @@ func Compare(client storage.SQLClient, old, new string, workloads []string, plan
 		go func() {
 			defer wg.Done()
 
-			var oldResult, newResult executionGroupResults
-			oldResult, err = getExecutionGroupResults(workload, old, planner, client)
+			var oldResult, newResult summarizedResults
+			oldResult, err = getSummarizedExecutionGroupResults(workload, old, planner, client)
 			if err != nil {
 				return
 			}
 
-			newResult, err = getExecutionGroupResults(workload, new, planner, client)
+			newResult, err = getSummarizedExecutionGroupResults(workload, new, planner, client)
 			if err != nil {
 				return
 			}
@@ -237,7 +279,7 @@ func Compare(client storage.SQLClient, old, new string, workloads []string, plan
 			oldResultsAsSlice := oldResult.asSlice()
 			newResultsAsSlice := newResult.asSlice()
 
-			scr := performAnalysis(oldResultsAsSlice, newResultsAsSlice)
+			scr := performAnalysis(oldResultsAsSlice, newResultsAsSlice, method)
 
 			mu.Lock()
 			defer mu.Unlock()
@@ -245,16 +287,33 @@ func Compare(client storage.SQLClient, old, new string, workloads []string, plan
 		}()
 	}
 	wg.Wait()
-	return results, err
+	if err != nil {
+		return nil, err
+	}
+
+	// all the workloads are part of the same family of comparisons
+	names := make([]string, 0, len(results))
+	family := make([]*StatisticalCompareResults, 0, len(results))
+	for _, workload := range workloads {
+		if scr, ok := results[workload]; ok {
+			names = append(names, workload)
+			family = append(family, &scr)
+		}
+	}
+	adjustPValues(family, method.Config())
+	for i, workload := range names {
+		results[workload] = *family[i]
+	}
+	return results, nil
 }
 
-func CompareFKs(client storage.SQLClient, oldWorkload, newWorkload string, sha string, planner PlannerVersion) (StatisticalCompareResults, error) {
-	oldResult, err := getExecutionGroupResults(oldWorkload, sha, planner, client)
+func CompareFKs(client storage.SQLClient, oldWorkload, newWorkload string, sha string, planner PlannerVersion, method ComparisonMethod) (StatisticalCompareResults, error) {
+	oldResult, err := getSummarizedExecutionGroupResults(oldWorkload, sha, planner, client)
 	if err != nil {
 		return StatisticalCompareResults{}, err
 	}
 
-	newResult, err := getExecutionGroupResults(newWorkload, sha, planner, client)
+	newResult, err := getSummarizedExecutionGroupResults(newWorkload, sha, planner, client)
 	if err != nil {
 		return StatisticalCompareResults{}, err
 	}
@@ -275,7 +334,8 @@ func CompareFKs(client storage.SQLClient, oldWorkload, newWorkload string, sha s
 	oldResultsAsSlice := oldResult.asSlice()
 	newResultsAsSlice := newResult.asSlice()
 
-	scr := performAnalysis(oldResultsAsSlice, newResultsAsSlice)
+	scr := performAnalysis(oldResultsAsSlice, newResultsAsSlice, method)
+	adjustPValues([]*StatisticalCompareResults{&scr}, method.Config())
 
 	return scr, nil
 }
@@ -283,7 +343,7 @@ func CompareFKs(client storage.SQLClient, oldWorkload, newWorkload string, sha s
 func Search(client storage.SQLClient, sha string, workloads []string, planner PlannerVersion) (map[string]StatisticalSingleResult, error) {
 	results := make(map[string]StatisticalSingleResult, len(workloads))
 	for _, workload := range workloads {
-		result, err := getExecutionGroupResults(workload, sha, planner, client)
+		result, err := getSummarizedExecutionGroupResults(workload, sha, planner, client)
 		if err != nil {
 			return nil, err
 		}
@@ -300,38 +360,66 @@ func Search(client storage.SQLClient, sha string, workloads []string, planner Pl
 			}
 			continue
 		}
-		results[workload] = result.toStatisticalSingleResult()
+		results[workload] = result.statisticalSingleResult()
 	}
 	return results, nil
 }
 
+// GetKeyMetricsWidestRange returns the widest confidence range among the key metrics
+// (total QPS, TPS and latency) of all the finished executions of the given git ref,
+// workload and planner. The range is infinite if there are not enough results to
+// compute a confidence interval.
+func GetKeyMetricsWidestRange(client storage.SQLClient, gitRef, workload string, planner PlannerVersion) (Range, error) {
+	result, err := getSummarizedExecutionGroupResults(workload, gitRef, planner, client)
+	if err != nil {
+		return Range{}, err
+	}
+	if len(result.Results) == 0 {
+		return Range{Infinite: true}, nil
+	}
+
+	ssr := result.statisticalSingleResult()
+	var widest Range
+	for _, summary := range []StatisticalSummary{ssr.TotalQPS, ssr.TPS, ssr.Latency} {
+		r := summary.Range
+		if r.Infinite || r.Unknown {
+			return r, nil
+		}
+		if r.Value > widest.Value {
+			widest = r
+		}
+	}
+	return widest, nil
+}
+
 func SearchForLast30Days(client storage.SQLClient, workload string, planner PlannerVersion) ([]StatisticalSingleResult, error) {
-	var ssrs []StatisticalSingleResult
-	results, err := getExecutionGroupResultsFromLast30Days(workload, planner, client)
+	summaries, err := getSummariesFromLast30Days(workload, planner, client)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, result := range results {
-		ssrs = append(ssrs, result.toStatisticalSingleResult())
+	var ssrs []StatisticalSingleResult
+	for _, s := range summaries {
+		ssrs = append(ssrs, s.summary)
 	}
 	return ssrs, nil
 }
 
-func SearchForLast30DaysQPSOnly(client storage.SQLClient, workloads []string, planner PlannerVersion) (map[string][]ShortStatisticalSingleResult, error) {
+// SearchForLast30DaysQPSOnly returns the total QPS of the git refs benchmarked in the last
+// 30 days by workload, the git refs with less than minExecutions executions are skipped.
+func SearchForLast30DaysQPSOnly(client storage.SQLClient, workloads []string, planner PlannerVersion, minExecutions int) (map[string][]ShortStatisticalSingleResult, error) {
 	results := make(map[string][]ShortStatisticalSingleResult)
 	for _, workload := range workloads {
-		workloadResult, err := getSummaryLast30Days(workload, planner, client)
+		summaries, err := getSummariesFromLast30Days(workload, planner, client)
 		if err != nil {
 			return nil, err
 		}
-
-		for _, result := range workloadResult {
+		for _, s := range summaries {
 			// If we do not have a decent number of results in the set of benchmark, let's skip the result.
-			if len(result.Results) < 6 {
+			if s.executions < minExecutions {
 				continue
 			}
-			results[workload] = append(results[workload], result.toShortStatisticalSingleResult())
+			results[workload] = append(results[workload], ShortStatisticalSingleResult{TotalQPS: s.summary.TotalQPS})
 		}
 	}
 	return results, nil
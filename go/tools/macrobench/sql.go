@@ -17,6 +17,7 @@ limitations under the License.
 package macrobench
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"strings"
@@ -26,8 +27,37 @@ import (
 	"github.com/vitessio/arewefastyet/go/storage/mysql"
 )
 
+// nullLatencyPercentiles is used to scan the latency percentiles of a result,
+// they are NULL for the results that were stored without percentiles.
+type nullLatencyPercentiles struct {
+	p50, p95, p99, max sql.NullFloat64
+}
+
+func (nlp nullLatencyPercentiles) toLatencyPercentiles() *sysbenchLatencyPercentiles {
+	if !nlp.p50.Valid || !nlp.p95.Valid || !nlp.p99.Valid || !nlp.max.Valid {
+		return nil
+	}
+	return &sysbenchLatencyPercentiles{
+		P50: nlp.p50.Float64,
+		P95: nlp.p95.Float64,
+		P99: nlp.p99.Float64,
+		Max: nlp.max.Float64,
+	}
+}
+
+// defaultFlagsCondition matches the executions of the execution table "e" that ran with
+// the flags of their configuration. The executions run with extra flags are not part of
+// the execution groups, their results would not be comparable.
+const defaultFlagsCondition = "e.vtgate_flags IS NULL AND e.vttablet_flags IS NULL"
+
 // getExecutionGroupResults the results of an execution group
 func getExecutionGroupResults(workload string, ref string, planner PlannerVersion, client storage.SQLClient) (executionGroupResults, error) {
+	return getExecutionGroupResultsFromSource(workload, ref, planner, "", client)
+}
+
+// getExecutionGroupResultsFromSource the results of an execution group, only keeping the
+// executions of the given source, or all of them if source is empty
+func getExecutionGroupResultsFromSource(workload string, ref string, planner PlannerVersion, source string, client storage.SQLClient) (executionGroupResults, error) {
 	query := `
         SELECT 
             IFNULL(e.uuid, '') AS exec_uuid, 
@@ -41,6 +71,10 @@ func getExecutionGroupResults(workload string, ref string, planner PlannerVersio
             results.reads_qps, 
             results.writes_qps, 
             results.other_qps, 
+            lat.p50, 
+            lat.p95, 
+            lat.p99, 
+            lat.max, 
             m.name AS metric_name, 
             m.value AS metric_value
         FROM 
@@ -49,18 +83,22 @@ func getExecutionGroupResults(workload string, ref string, planner PlannerVersio
             macrobenchmark AS info ON e.uuid = info.exec_uuid
         JOIN 
             macrobenchmark_results AS results ON info.macrobenchmark_id = results.macrobenchmark_id
+        LEFT JOIN 
+            macrobenchmark_latency AS lat ON info.macrobenchmark_id = lat.macrobenchmark_id
         LEFT JOIN 
             metrics AS m ON e.uuid = m.exec_uuid
         WHERE 
             e.status = 'finished'
+            AND ` + defaultFlagsCondition + `
             AND e.git_ref = ? 
             AND info.vtgate_planner_version = ? 
             AND info.workload = ?
+            AND (? = '' OR e.source = ?)
         ORDER BY 
             e.uuid, m.name
     `
 
-	rows, err := client.Read(query, ref, planner, strings.ToUpper(workload))
+	rows, err := client.Read(query, ref, planner, strings.ToUpper(workload), source, source)
 	if err != nil {
 		return executionGroupResults{}, err
 	}
@@ -74,17 +112,20 @@ func getExecutionGroupResults(workload string, ref string, planner PlannerVersio
 		var (
 			execUUID    string
 			sr          sysbenchResult
+			latency     nullLatencyPercentiles
 			metricName  sql.NullString
 			metricValue sql.NullFloat64
 		)
 
 		err := rows.Scan(
 			&execUUID, &sr.TPS, &sr.Latency, &sr.Errors, &sr.Reconnects, &sr.Time, &sr.Threads, &sr.QPS.Total,
-			&sr.QPS.Reads, &sr.QPS.Writes, &sr.QPS.Other, &metricName, &metricValue,
+			&sr.QPS.Reads, &sr.QPS.Writes, &sr.QPS.Other, &latency.p50, &latency.p95, &latency.p99, &latency.max,
+			&metricName, &metricValue,
 		)
 		if err != nil {
 			return executionGroupResults{}, err
 		}
+		sr.LatencyPercentiles = latency.toLatencyPercentiles()
 
 		// If execUUID is different it means we are looking at another set of results
 		// we then add the current execRes to our results, and start again with a new executionResults
@@ -94,11 +135,8 @@ func getExecutionGroupResults(workload string, ref string, planner PlannerVersio
 				results.Metrics = append(results.Metrics, execRes.Metrics)
 			}
 			execRes = &executionResults{
-				Result: sr,
-				Metrics: metrics.ExecutionMetrics{
-					ComponentsCPUTime:            make(map[string]float64),
-					ComponentsMemStatsAllocBytes: make(map[string]float64),
-				},
+				Result:  sr,
+				Metrics: metrics.ExecutionMetrics{},
 			}
 			currentExecUUID = execUUID
 		}
@@ -107,16 +145,7 @@ func getExecutionGroupResults(workload string, ref string, planner PlannerVersio
 		// here we just all the metrics value to the executionResults, later when we are done consuming
 		// all the metrics for our current execUUID we will create a new executionResults
 		if metricName.Valid {
-			switch {
-			case metricName.String == "TotalComponentsCPUTime":
-				execRes.Metrics.TotalComponentsCPUTime = metricValue.Float64
-			case metricName.String == "TotalComponentsMemStatsAllocBytes":
-				execRes.Metrics.TotalComponentsMemStatsAllocBytes = metricValue.Float64
-			case strings.HasPrefix(metricName.String, "ComponentsCPUTime."):
-				execRes.Metrics.ComponentsCPUTime[strings.Split(metricName.String, ".")[1]] = metricValue.Float64
-			case strings.HasPrefix(metricName.String, "ComponentsMemStatsAllocBytes."):
-				execRes.Metrics.ComponentsMemStatsAllocBytes[strings.Split(metricName.String, ".")[1]] = metricValue.Float64
-			}
+			execRes.Metrics.Set(metricName.String, metricValue.Float64)
 		}
 	}
 
@@ -129,200 +158,80 @@ func getExecutionGroupResults(workload string, ref string, planner PlannerVersio
 	return results, nil
 }
 
-func getExecutionGroupResultsFromLast30Days(workload string, planner PlannerVersion, client storage.SQLClient) ([]executionGroupResults, error) {
-	query := `
-        SELECT 
-            IFNULL(e.uuid, '') AS exec_uuid, 
-            e.git_ref,
-            results.tps, 
-            results.latency, 
-            results.errors, 
-            results.reconnects, 
-            results.time, 
-            results.threads, 
-            results.total_qps, 
-            results.reads_qps, 
-            results.writes_qps, 
-            results.other_qps, 
-            m.name AS metric_name, 
-            m.value AS metric_value
-        FROM 
-            execution AS e
-        JOIN 
-            macrobenchmark AS info ON e.uuid = info.exec_uuid
-        JOIN 
-            macrobenchmark_results AS results ON info.macrobenchmark_id = results.macrobenchmark_id
-        LEFT JOIN 
-            metrics AS m ON e.uuid = m.exec_uuid
-        WHERE 
-            e.finished_at BETWEEN DATE(NOW()) - INTERVAL 30 DAY AND DATE(NOW() + INTERVAL 1 DAY)
-            AND e.source = 'cron'
-            AND e.status = 'finished'
-            AND info.vtgate_planner_version = ? 
-            AND info.workload = ?
-        ORDER BY 
-            e.finished_at ASC, e.uuid, m.name
-    `
+// insertToMySQL inserts the given sysbenchResult to MySQL.
+func (mbr *sysbenchResult) insertToMySQL(macrobenchmarkID int, client storage.SQLClient) error {
+	if client == nil {
+		return errors.New(mysql.ErrorClientConnectionNotInitialized)
+	}
 
-	rows, err := client.Read(query, planner, strings.ToUpper(workload))
+	// insert sysbenchResult
+	queryResult := "INSERT INTO macrobenchmark_results(macrobenchmark_id, queries, tps, latency, errors, reconnects, time, threads, total_qps, reads_qps, writes_qps, other_qps) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	_, err := client.Write(queryResult, macrobenchmarkID, mbr.Queries, mbr.TPS, mbr.Latency, mbr.Errors, mbr.Reconnects, mbr.Time, mbr.Threads, mbr.QPS.Total, mbr.QPS.Reads, mbr.QPS.Writes, mbr.QPS.Other)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
-
-	var allResults []executionGroupResults
-	var results executionGroupResults
-	var execRes *executionResults
-	var currentExecUUID string
-	var currentGitRef string
-
-	for rows.Next() {
-		var (
-			execUUID    string
-			gitRef      string
-			sr          sysbenchResult
-			metricName  sql.NullString
-			metricValue sql.NullFloat64
-		)
 
-		err := rows.Scan(
-			&execUUID, &gitRef, &sr.TPS, &sr.Latency, &sr.Errors, &sr.Reconnects, &sr.Time, &sr.Threads, &sr.QPS.Total,
-			&sr.QPS.Reads, &sr.QPS.Writes, &sr.QPS.Other, &metricName, &metricValue,
-		)
+	// insert the latency percentiles, if sysbench reported them
+	if mbr.LatencyPercentiles != nil {
+		queryLatency := "INSERT INTO macrobenchmark_latency(macrobenchmark_id, p50, p95, p99, max) VALUES(?, ?, ?, ?, ?)"
+		lp := mbr.LatencyPercentiles
+		_, err = client.Write(queryLatency, macrobenchmarkID, lp.P50, lp.P95, lp.P99, lp.Max)
 		if err != nil {
-			return nil, err
-		}
-
-		// If gitRef is different it means we are looking at another group of results
-		if currentGitRef != gitRef {
-			if execRes != nil {
-				results.Results = append(results.Results, execRes.Result)
-				results.Metrics = append(results.Metrics, execRes.Metrics)
-				execRes = nil
-			}
-			if len(results.Results) > 0 {
-				allResults = append(allResults, results)
-			}
-			results = executionGroupResults{GitRef: gitRef}
-			currentGitRef = gitRef
-		}
-
-		// If execUUID is different it means we are looking at another set of results
-		if currentExecUUID != execUUID {
-			if execRes != nil {
-				results.Results = append(results.Results, execRes.Result)
-				results.Metrics = append(results.Metrics, execRes.Metrics)
-			}
-			execRes = &executionResults{
-				Result: sr,
-				Metrics: metrics.ExecutionMetrics{
-					ComponentsCPUTime:            make(map[string]float64),
-					ComponentsMemStatsAllocBytes: make(map[string]float64),
-				},
-			}
-			currentExecUUID = execUUID
-		}
-
-		// Add the metrics values to the current executionResults
-		if metricName.Valid {
-			switch {
-			case metricName.String == "TotalComponentsCPUTime":
-				execRes.Metrics.TotalComponentsCPUTime = metricValue.Float64
-			case metricName.String == "TotalComponentsMemStatsAllocBytes":
-				execRes.Metrics.TotalComponentsMemStatsAllocBytes = metricValue.Float64
-			case strings.HasPrefix(metricName.String, "ComponentsCPUTime."):
-				execRes.Metrics.ComponentsCPUTime[strings.Split(metricName.String, ".")[1]] = metricValue.Float64
-			case strings.HasPrefix(metricName.String, "ComponentsMemStatsAllocBytes."):
-				execRes.Metrics.ComponentsMemStatsAllocBytes[strings.Split(metricName.String, ".")[1]] = metricValue.Float64
-			}
+			return err
 		}
 	}
 
-	// Add the last set of results
-	if execRes != nil {
-		results.Results = append(results.Results, execRes.Result)
-		results.Metrics = append(results.Metrics, execRes.Metrics)
+	// insert the whole latency histogram at once
+	queryHistogram := "INSERT INTO macrobenchmark_latency_histogram(macrobenchmark_id, value, count) VALUES"
+	rows := make([][]interface{}, 0, len(mbr.LatencyHistogram))
+	for _, bucket := range mbr.LatencyHistogram {
+		rows = append(rows, []interface{}{macrobenchmarkID, bucket.Value, bucket.Count})
 	}
-	if len(results.Results) > 0 {
-		allResults = append(allResults, results)
-	}
-
-	return allResults, nil
+	return storage.BulkInsert(context.Background(), client, queryHistogram, rows)
 }
 
-func getSummaryLast30Days(workload string, planner PlannerVersion, client storage.SQLClient) ([]executionGroupResults, error) {
+// GetWorkloadsForGitRefs returns the sorted workloads of the finished macrobenchmarks
+// executed on any of the given git refs and planner.
+func GetWorkloadsForGitRefs(client storage.SQLClient, planner PlannerVersion, gitRefs ...string) ([]string, error) {
+	if client == nil {
+		return nil, errors.New(mysql.ErrorClientConnectionNotInitialized)
+	}
+	if len(gitRefs) == 0 {
+		return nil, nil
+	}
 	query := `
-        SELECT 
-            e.git_ref, 
-            results.total_qps 
-        FROM 
+        SELECT DISTINCT
+            info.workload
+        FROM
             execution AS e
-        JOIN 
+        JOIN
             macrobenchmark AS info ON e.uuid = info.exec_uuid
-        JOIN 
-            macrobenchmark_results AS results ON info.macrobenchmark_id = results.macrobenchmark_id
-        WHERE 
-            e.finished_at BETWEEN DATE(NOW()) - INTERVAL 30 DAY AND DATE(NOW() + INTERVAL 1 DAY) 
-            AND e.status = "finished" 
-            AND e.source = "cron" 
-            AND info.vtgate_planner_version = ? 
-            AND info.workload = ? 
-        ORDER BY 
-            e.finished_at ASC
+        WHERE
+            e.status = 'finished'
+            AND ` + defaultFlagsCondition + `
+            AND info.vtgate_planner_version = ?
+            AND e.git_ref IN (?` + strings.Repeat(", ?", len(gitRefs)-1) + `)
+        ORDER BY
+            info.workload
     `
+	args := []interface{}{planner}
+	for _, gitRef := range gitRefs {
+		args = append(args, gitRef)
+	}
 
-	rows, err := client.Read(query, planner, strings.ToUpper(workload))
+	rows, err := client.Read(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var allResults []executionGroupResults
-	var results *executionGroupResults
-	var currentGitRef string
-
+	var workloads []string
 	for rows.Next() {
-		var (
-			gitRef string
-			sr     sysbenchResult
-		)
-
-		if err := rows.Scan(&gitRef, &sr.QPS.Total); err != nil {
+		var workload string
+		if err := rows.Scan(&workload); err != nil {
 			return nil, err
 		}
-
-		if currentGitRef != gitRef {
-			if results != nil {
-				allResults = append(allResults, *results)
-			}
-			results = &executionGroupResults{
-				GitRef:  gitRef,
-				Results: []sysbenchResult{sr},
-			}
-			currentGitRef = gitRef
-		} else if results != nil {
-			results.Results = append(results.Results, sr)
-		}
-	}
-
-	if results != nil {
-		allResults = append(allResults, *results)
-	}
-
-	return allResults, nil
-}
-
-// insertToMySQL inserts the given sysbenchResult to MySQL.
-func (mbr *sysbenchResult) insertToMySQL(macrobenchmarkID int, client storage.SQLClient) error {
-	if client == nil {
-		return errors.New(mysql.ErrorClientConnectionNotInitialized)
-	}
-
-	// insert sysbenchResult
-	queryResult := "INSERT INTO macrobenchmark_results(macrobenchmark_id, queries, tps, latency, errors, reconnects, time, threads, total_qps, reads_qps, writes_qps, other_qps) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
-	_, err := client.Write(queryResult, macrobenchmarkID, mbr.Queries, mbr.TPS, mbr.Latency, mbr.Errors, mbr.Reconnects, mbr.Time, mbr.Threads, mbr.QPS.Total, mbr.QPS.Reads, mbr.QPS.Writes, mbr.QPS.Other)
-	if err != nil {
-		return err
+		workloads = append(workloads, workload)
 	}
-	return nil
+	return workloads, rows.Err()
 }
@@ -20,6 +20,7 @@ import (
 	"math"
 
 	"github.com/aclements/go-moremath/mathx"
+	"github.com/vitessio/arewefastyet/go/exec/metrics"
 	"golang.org/x/perf/benchmath"
 )
 
@@ -40,6 +41,7 @@ type (
 		Insignificant bool               `json:"insignificant"`
 		Delta         float64            `json:"delta"`
 		P             float64            `json:"p"`
+		AdjustedP     float64            `json:"adjusted_p"`
 		N1            int                `json:"n1"`
 		N2            int                `json:"n2"`
 		Old           StatisticalSummary `json:"old"`
@@ -59,34 +61,62 @@ type (
 		WritesQPS StatisticalSummary `json:"writes_qps"`
 		OtherQPS  StatisticalSummary `json:"other_qps"`
 
-		TPS     StatisticalSummary `json:"tps"`
-		Latency StatisticalSummary `json:"latency"`
-		Errors  StatisticalSummary `json:"errors"`
+		TPS        StatisticalSummary `json:"tps"`
+		Latency    StatisticalSummary `json:"latency"`
+		LatencyP50 StatisticalSummary `json:"latency_p50"`
+		LatencyP95 StatisticalSummary `json:"latency_p95"`
+		LatencyP99 StatisticalSummary `json:"latency_p99"`
+		LatencyMax StatisticalSummary `json:"latency_max"`
+		Errors     StatisticalSummary `json:"errors"`
 
 		TotalComponentsCPUTime StatisticalSummary            `json:"total_components_cpu_time"`
 		ComponentsCPUTime      map[string]StatisticalSummary `json:"components_cpu_time"`
 
 		TotalComponentsMemStatsAllocBytes StatisticalSummary            `json:"total_components_mem_stats_alloc_bytes"`
 		ComponentsMemStatsAllocBytes      map[string]StatisticalSummary `json:"components_mem_stats_alloc_bytes"`
+
+		// Metrics contains the summary of every metric gathered during the executions,
+		// including the components CPU time and memory allocation above.
+		Metrics map[string]MetricSummary `json:"metrics"`
+	}
+
+	// MetricSummary is the statistical summary of a single metric, for all its components.
+	MetricSummary struct {
+		Total      StatisticalSummary            `json:"total"`
+		Components map[string]StatisticalSummary `json:"components"`
 	}
 
 	// StatisticalCompareResults is the full representation of the results
-	// obtained by comparing two samples using the Mann Whitney U Test.
+	// obtained by comparing two samples using a ComparisonMethod.
 	StatisticalCompareResults struct {
 		TotalQPS  StatisticalResult `json:"total_qps"`
 		ReadsQPS  StatisticalResult `json:"reads_qps"`
 		WritesQPS StatisticalResult `json:"writes_qps"`
 		OtherQPS  StatisticalResult `json:"other_qps"`
 
-		TPS     StatisticalResult `json:"tps"`
-		Latency StatisticalResult `json:"latency"`
-		Errors  StatisticalResult `json:"errors"`
+		TPS        StatisticalResult `json:"tps"`
+		Latency    StatisticalResult `json:"latency"`
+		LatencyP50 StatisticalResult `json:"latency_p50"`
+		LatencyP95 StatisticalResult `json:"latency_p95"`
+		LatencyP99 StatisticalResult `json:"latency_p99"`
+		LatencyMax StatisticalResult `json:"latency_max"`
+		Errors     StatisticalResult `json:"errors"`
 
 		TotalComponentsCPUTime StatisticalResult            `json:"total_components_cpu_time"`
 		ComponentsCPUTime      map[string]StatisticalResult `json:"components_cpu_time"`
 
 		TotalComponentsMemStatsAllocBytes StatisticalResult            `json:"total_components_mem_stats_alloc_bytes"`
 		ComponentsMemStatsAllocBytes      map[string]StatisticalResult `json:"components_mem_stats_alloc_bytes"`
+
+		// Metrics contains the comparison of every metric gathered during the executions,
+		// including the components CPU time and memory allocation above.
+		Metrics map[string]MetricResult `json:"metrics"`
+	}
+
+	// MetricResult is the comparison of a single metric, for all its components.
+	MetricResult struct {
+		Total      StatisticalResult            `json:"total"`
+		Components map[string]StatisticalResult `json:"components"`
 	}
 )
 
@@ -99,6 +129,17 @@ var (
 	defaultConfidence = 0.95
 )
 
+// Regressed returns true if the change from Old to New is significant and makes
+// the metric worse by more than threshold percent. The metric is worse when it
+// increases, unless higherIsBetter.
+func (sr StatisticalResult) Regressed(threshold float64, higherIsBetter bool) bool {
+	regression := sr.Delta
+	if higherIsBetter {
+		regression = -regression
+	}
+	return !sr.Insignificant && regression > threshold
+}
+
 func getRangeFromSummary(s benchmath.Summary) Range {
 	if math.IsInf(s.Lo, 0) || math.IsInf(s.Hi, 0) {
 		return Range{Infinite: true}
@@ -125,6 +166,10 @@ func getRangeFromSummary(s benchmath.Summary) Range {
 
 func getSummary(values []float64) (StatisticalSummary, *benchmath.Sample) {
 	sample := benchmath.NewSample(values, &defaultThresholds)
+	if len(values) == 0 {
+		// the median of an empty sample is NaN, which cannot be encoded to JSON
+		return StatisticalSummary{Range: Range{Infinite: true}}, sample
+	}
 	summary := benchmath.AssumeNothing.Summary(sample, defaultConfidence)
 	return StatisticalSummary{
 		Center:     summary.Center,
@@ -133,41 +178,7 @@ func getSummary(values []float64) (StatisticalSummary, *benchmath.Sample) {
 	}, sample
 }
 
-func compare(old, new []float64) StatisticalResult {
-	var sr StatisticalResult
-
-	ssOld, s1 := getSummary(old)
-	ssNew, s2 := getSummary(new)
-
-	if math.IsNaN(ssOld.Center) {
-		ssOld.Center = 0.0
-	}
-	if math.IsNaN(ssNew.Center) {
-		ssNew.Center = 0.0
-	}
-
-	sr.Old = ssOld
-	sr.New = ssNew
-
-	c := benchmath.AssumeNothing.Compare(s1, s2)
-	sr.P = c.P
-	sr.N1 = c.N1
-	sr.N2 = c.N2
-
-	if c.P > c.Alpha {
-		sr.Insignificant = true
-	}
-
-	switch {
-	case sr.Old.Center == sr.New.Center || sr.Old.Center == 0:
-		sr.Delta = 0
-	default:
-		sr.Delta = ((sr.New.Center / sr.Old.Center) - 1.0) * 100.0
-	}
-	return sr
-}
-
-func performAnalysis(old, new executionGroupResultsAsSlice) StatisticalCompareResults {
+func performAnalysis(old, new executionGroupResultsAsSlice, method ComparisonMethod) StatisticalCompareResults {
 	scr := StatisticalCompareResults{
 		ComponentsCPUTime: map[string]StatisticalResult{
 			"vtgate":   {Insignificant: true},
@@ -177,25 +188,56 @@ func performAnalysis(old, new executionGroupResultsAsSlice) StatisticalCompareRe
 			"vtgate":   {Insignificant: true},
 			"vttablet": {Insignificant: true},
 		},
-	}
-
-	scr.TotalQPS = compare(old.qps.total, new.qps.total)
-	scr.ReadsQPS = compare(old.qps.reads, new.qps.reads)
-	scr.WritesQPS = compare(old.qps.writes, new.qps.writes)
-	scr.OtherQPS = compare(old.qps.other, new.qps.other)
-
-	scr.TPS = compare(old.tps, new.tps)
-	scr.Latency = compare(old.latency, new.latency)
-	scr.Errors = compare(old.errors, new.errors)
-
-	scr.TotalComponentsCPUTime = compare(old.metrics.totalComponentsCPUTime, new.metrics.totalComponentsCPUTime)
-	for name, values := range old.metrics.componentsCPUTime {
-		scr.ComponentsCPUTime[name] = compare(values, new.metrics.componentsCPUTime[name])
-	}
+		Metrics: map[string]MetricResult{},
+	}
+
+	scr.TotalQPS = method.Compare(old.qps.total, new.qps.total)
+	scr.ReadsQPS = method.Compare(old.qps.reads, new.qps.reads)
+	scr.WritesQPS = method.Compare(old.qps.writes, new.qps.writes)
+	scr.OtherQPS = method.Compare(old.qps.other, new.qps.other)
+
+	scr.TPS = method.Compare(old.tps, new.tps)
+	scr.Latency = method.Compare(old.latency, new.latency)
+	scr.LatencyP50 = method.Compare(old.latencyPercentiles.p50, new.latencyPercentiles.p50)
+	scr.LatencyP95 = method.Compare(old.latencyPercentiles.p95, new.latencyPercentiles.p95)
+	scr.LatencyP99 = method.Compare(old.latencyPercentiles.p99, new.latencyPercentiles.p99)
+	scr.LatencyMax = method.Compare(old.latencyPercentiles.max, new.latencyPercentiles.max)
+	scr.Errors = method.Compare(old.errors, new.errors)
+
+	for metricName, metric := range old.metrics {
+		newMetric := new.metrics[metricName]
+		mr := MetricResult{
+			Total:      method.Compare(metric.total, newMetric.total),
+			Components: make(map[string]StatisticalResult, len(metric.components)),
+		}
+		for name, values := range metric.components {
+			mr.Components[name] = method.Compare(values, newMetric.components[name])
+		}
+		scr.Metrics[metricName] = mr
+	}
+	scr.setComponentsFields()
+	return scr
+}
 
-	scr.TotalComponentsMemStatsAllocBytes = compare(old.metrics.totalComponentsMemStatsAllocBytes, new.metrics.totalComponentsMemStatsAllocBytes)
-	for name, values := range old.metrics.componentsMemStatsAllocBytes {
-		scr.ComponentsMemStatsAllocBytes[name] = compare(values, new.metrics.componentsMemStatsAllocBytes[name])
+// setComponentsFields sets the components CPU time and memory allocation fields
+// using their corresponding entry in Metrics.
+func (scr *StatisticalCompareResults) setComponentsFields() {
+	if cpu, ok := scr.Metrics[metrics.CPUTimeMetric]; ok {
+		scr.TotalComponentsCPUTime = cpu.Total
+		if scr.ComponentsCPUTime == nil {
+			scr.ComponentsCPUTime = make(map[string]StatisticalResult, len(cpu.Components))
+		}
+		for name, result := range cpu.Components {
+			scr.ComponentsCPUTime[name] = result
+		}
+	}
+	if mem, ok := scr.Metrics[metrics.MemStatsAllocBytesMetric]; ok {
+		scr.TotalComponentsMemStatsAllocBytes = mem.Total
+		if scr.ComponentsMemStatsAllocBytes == nil {
+			scr.ComponentsMemStatsAllocBytes = make(map[string]StatisticalResult, len(mem.Components))
+		}
+		for name, result := range mem.Components {
+			scr.ComponentsMemStatsAllocBytes[name] = result
+		}
 	}
-	return scr
 }
@@ -0,0 +1,351 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package macrobench
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/vitessio/arewefastyet/go/storage"
+	"github.com/vitessio/arewefastyet/go/storage/mysql"
+)
+
+type (
+	// SummaryGroup identifies a row of the macrobenchmark_summary table: the finished
+	// executions of a git ref with the same workload, planner and source.
+	SummaryGroup struct {
+		GitRef   string
+		Workload string
+		Planner  PlannerVersion
+		Source   string
+	}
+
+	// groupSummary is a row of the macrobenchmark_summary table.
+	groupSummary struct {
+		executions int
+		summary    StatisticalSingleResult
+		samples    executionGroupResults
+	}
+
+	// summarizedResults holds the results of an execution group and their statistical
+	// summary, summary is nil when it was not precomputed.
+	summarizedResults struct {
+		executionGroupResults
+		summary *StatisticalSingleResult
+	}
+)
+
+func (sr summarizedResults) statisticalSingleResult() StatisticalSingleResult {
+	if sr.summary != nil {
+		return *sr.summary
+	}
+	return sr.toStatisticalSingleResult()
+}
+
+// GetSummaryGroups returns the groups the given executions belong to. Executions that
+// are not macrobenchmarks do not belong to any group.
+func GetSummaryGroups(client storage.SQLClient, execUUIDs ...string) ([]SummaryGroup, error) {
+	if client == nil {
+		return nil, errors.New(mysql.ErrorClientConnectionNotInitialized)
+	}
+	if len(execUUIDs) == 0 {
+		return nil, nil
+	}
+
+	args := make([]interface{}, 0, len(execUUIDs))
+	for _, execUUID := range execUUIDs {
+		args = append(args, execUUID)
+	}
+	query := "SELECT DISTINCT e.git_ref, info.workload, info.vtgate_planner_version, e.source FROM execution AS e " +
+		"JOIN macrobenchmark AS info ON e.uuid = info.exec_uuid " +
+		"WHERE " + defaultFlagsCondition + " AND e.uuid IN (?" + strings.Repeat(", ?", len(execUUIDs)-1) + ")"
+	rows, err := client.Read(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanSummaryGroups(rows)
+}
+
+func scanSummaryGroups(rows *sql.Rows) ([]SummaryGroup, error) {
+	defer rows.Close()
+
+	var groups []SummaryGroup
+	for rows.Next() {
+		var group SummaryGroup
+		err := rows.Scan(&group.GitRef, &group.Workload, &group.Planner, &group.Source)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
+
+// RefreshSummaries recomputes the summary of the given groups from the results of their
+// finished executions. The summary of a group without any finished execution is removed.
+func RefreshSummaries(client storage.SQLClient, groups []SummaryGroup) error {
+	if client == nil {
+		return errors.New(mysql.ErrorClientConnectionNotInitialized)
+	}
+	for _, group := range groups {
+		err := refreshSummary(client, group)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RefreshAllSummaries recomputes the summary of every group having finished executions or
+// an existing summary, and returns the number of groups that were refreshed.
+func RefreshAllSummaries(client storage.SQLClient) (int, error) {
+	if client == nil {
+		return 0, errors.New(mysql.ErrorClientConnectionNotInitialized)
+	}
+	query := `
+        SELECT DISTINCT e.git_ref, info.workload, info.vtgate_planner_version, e.source
+        FROM execution AS e
+        JOIN macrobenchmark AS info ON e.uuid = info.exec_uuid
+        WHERE e.status = 'finished' AND ` + defaultFlagsCondition + `
+        UNION
+        SELECT git_ref, workload, planner, source FROM macrobenchmark_summary
+    `
+	rows, err := client.Read(query)
+	if err != nil {
+		return 0, err
+	}
+	groups, err := scanSummaryGroups(rows)
+	if err != nil {
+		return 0, err
+	}
+	return len(groups), RefreshSummaries(client, groups)
+}
+
+// refreshSummary recomputes the summary of a group in a transaction: the results are
+// read from the primary, which holds the executions that just finished.
+func refreshSummary(client storage.SQLClient, group SummaryGroup) error {
+	workload := strings.ToUpper(group.Workload)
+	return client.WithTx(context.Background(), func(tx storage.SQLClient) error {
+		results, err := getExecutionGroupResultsFromSource(workload, group.GitRef, group.Planner, group.Source, tx)
+		if err != nil {
+			return err
+		}
+
+		var finishedAt sql.NullTime
+		if len(results.Results) > 0 {
+			rows, err := tx.Read(`
+                SELECT MAX(e.finished_at) FROM execution AS e
+                JOIN macrobenchmark AS info ON e.uuid = info.exec_uuid
+                WHERE e.status = 'finished' AND `+defaultFlagsCondition+` AND e.git_ref = ? AND e.source = ? AND info.workload = ? AND info.vtgate_planner_version = ?`,
+				group.GitRef, group.Source, workload, group.Planner)
+			if err != nil {
+				return err
+			}
+			for rows.Next() {
+				err = rows.Scan(&finishedAt)
+			}
+			rows.Close()
+			if err != nil {
+				return err
+			}
+		}
+
+		summary, err := json.Marshal(results.toStatisticalSingleResult())
+		if err != nil {
+			return err
+		}
+		samples, err := json.Marshal(results)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Write("DELETE FROM macrobenchmark_summary WHERE git_ref = ? AND workload = ? AND planner = ? AND source = ?",
+			group.GitRef, workload, group.Planner, group.Source)
+		if err != nil || len(results.Results) == 0 {
+			return err
+		}
+		_, err = tx.Write("INSERT INTO macrobenchmark_summary(git_ref, workload, planner, source, executions, summary, samples, finished_at, updated_at) VALUES(?, ?, ?, ?, ?, ?, ?, ?, UTC_TIMESTAMP())",
+			group.GitRef, workload, group.Planner, group.Source, len(results.Results), string(summary), string(samples), finishedAt)
+		return err
+	})
+}
+
+// getSummarizedExecutionGroupResults returns the results of an execution group, all sources
+// included, using the macrobenchmark_summary table. The results are read from the raw
+// tables if the group was never summarized.
+func getSummarizedExecutionGroupResults(workload string, ref string, planner PlannerVersion, client storage.SQLClient) (summarizedResults, error) {
+	rows, err := client.Read("SELECT executions, summary, samples FROM macrobenchmark_summary WHERE git_ref = ? AND workload = ? AND planner = ?",
+		ref, strings.ToUpper(workload), planner)
+	if err != nil {
+		return summarizedResults{}, err
+	}
+	summaries, err := scanGroupSummaries(rows, true)
+	if err != nil {
+		return summarizedResults{}, err
+	}
+
+	switch len(summaries) {
+	case 0:
+		results, err := getExecutionGroupResults(workload, ref, planner, client)
+		return summarizedResults{executionGroupResults: results}, err
+	case 1:
+		return summarizedResults{executionGroupResults: summaries[0].samples, summary: &summaries[0].summary}, nil
+	}
+
+	// the group was executed from several sources, their samples are merged
+	results := executionGroupResults{GitRef: ref}
+	for _, s := range summaries {
+		results.Results = append(results.Results, s.samples.Results...)
+		results.Metrics = append(results.Metrics, s.samples.Metrics...)
+	}
+	return summarizedResults{executionGroupResults: results}, nil
+}
+
+// getSummariesFromLast30Days returns the summaries of the git refs benchmarked by the cron
+// during the last 30 days, sorted by the date of their last execution. The git refs that
+// were not summarized yet are read from the raw tables.
+func getSummariesFromLast30Days(workload string, planner PlannerVersion, client storage.SQLClient) ([]groupSummary, error) {
+	refs, err := getRefsFromLast30Days(workload, planner, client)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+        SELECT git_ref, executions, summary
+        FROM macrobenchmark_summary
+        WHERE
+            finished_at BETWEEN DATE(NOW()) - INTERVAL 30 DAY AND DATE(NOW() + INTERVAL 1 DAY)
+            AND source = 'cron'
+            AND planner = ?
+            AND workload = ?
+    `
+	rows, err := client.Read(query, planner, strings.ToUpper(workload))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := map[string]groupSummary{}
+	for rows.Next() {
+		var (
+			ref     string
+			gs      groupSummary
+			summary string
+		)
+		if err = rows.Scan(&ref, &gs.executions, &summary); err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal([]byte(summary), &gs.summary); err != nil {
+			return nil, err
+		}
+		summaries[ref] = gs
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return mergeSummaries(refs, summaries, func(ref string) (executionGroupResults, error) {
+		return getExecutionGroupResultsFromSource(workload, ref, planner, "cron", client)
+	})
+}
+
+// mergeSummaries returns the summaries of the given git refs in the same order, the git
+// refs without a summary are summarized from the results returned by readResults.
+func mergeSummaries(refs []string, summaries map[string]groupSummary, readResults func(ref string) (executionGroupResults, error)) ([]groupSummary, error) {
+	var res []groupSummary
+	for _, ref := range refs {
+		if gs, ok := summaries[ref]; ok {
+			res = append(res, gs)
+			continue
+		}
+		results, err := readResults(ref)
+		if err != nil {
+			return nil, err
+		}
+		if len(results.Results) == 0 {
+			continue
+		}
+		res = append(res, groupSummary{executions: len(results.Results), summary: results.toStatisticalSingleResult()})
+	}
+	return res, nil
+}
+
+// getRefsFromLast30Days returns the git refs benchmarked by the cron during the last
+// 30 days, sorted by the date of their last execution.
+func getRefsFromLast30Days(workload string, planner PlannerVersion, client storage.SQLClient) ([]string, error) {
+	query := `
+        SELECT e.git_ref
+        FROM execution AS e
+        JOIN macrobenchmark AS info ON e.uuid = info.exec_uuid
+        WHERE
+            e.finished_at BETWEEN DATE(NOW()) - INTERVAL 30 DAY AND DATE(NOW() + INTERVAL 1 DAY)
+            AND e.source = 'cron'
+            AND e.status = 'finished'
+            AND ` + defaultFlagsCondition + `
+            AND info.vtgate_planner_version = ?
+            AND info.workload = ?
+        GROUP BY e.git_ref
+        ORDER BY MAX(e.finished_at) ASC
+    `
+	rows, err := client.Read(query, planner, strings.ToUpper(workload))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []string
+	for rows.Next() {
+		var ref string
+		if err = rows.Scan(&ref); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+func scanGroupSummaries(rows *sql.Rows, withSamples bool) ([]groupSummary, error) {
+	defer rows.Close()
+
+	var res []groupSummary
+	for rows.Next() {
+		var (
+			gs               groupSummary
+			summary, samples string
+		)
+		dest := []interface{}{&gs.executions, &summary}
+		if withSamples {
+			dest = append(dest, &samples)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(summary), &gs.summary); err != nil {
+			return nil, err
+		}
+		if withSamples {
+			if err := json.Unmarshal([]byte(samples), &gs.samples); err != nil {
+				return nil, err
+			}
+		}
+		res = append(res, gs)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package macrobench
+
+import (
+	"encoding/json"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vitessio/arewefastyet/go/exec/metrics"
+)
+
+func TestSummaryEncoding(t *testing.T) {
+	newResult := func(qps float64, percentiles *sysbenchLatencyPercentiles) sysbenchResult {
+		return sysbenchResult{QPS: sysbenchQPS{Total: qps, Reads: qps / 2}, TPS: qps / 10, Latency: 1000 / qps, LatencyPercentiles: percentiles}
+	}
+	newMetrics := func(vtgate, vttablet float64) metrics.ExecutionMetrics {
+		return metrics.ExecutionMetrics{
+			metrics.CPUTimeMetric: {Total: vtgate + vttablet, Components: map[string]float64{"vtgate": vtgate, "vttablet": vttablet}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		results executionGroupResults
+	}{
+		{name: "no results", results: executionGroupResults{GitRef: "abc"}},
+		{
+			name: "without latency percentiles",
+			results: executionGroupResults{
+				GitRef:  "abc",
+				Results: sysbenchResultArray{newResult(1000, nil), newResult(1010, nil), newResult(990, nil)},
+				Metrics: metrics.ExecutionMetricsArray{newMetrics(1, 2), newMetrics(1.1, 2.1), newMetrics(0.9, 1.9)},
+			},
+		},
+		{
+			name: "with latency percentiles",
+			results: executionGroupResults{
+				GitRef: "abc",
+				Results: sysbenchResultArray{
+					newResult(1000, &sysbenchLatencyPercentiles{P50: 1, P95: 2, P99: 3, Max: 4}),
+					newResult(1010, &sysbenchLatencyPercentiles{P50: 1.1, P95: 2.1, P99: 3.1, Max: 4.1}),
+				},
+				Metrics: metrics.ExecutionMetricsArray{newMetrics(1, 2), newMetrics(1.1, 2.1)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			want := tt.results.toStatisticalSingleResult()
+
+			summary, err := json.Marshal(want)
+			c.Assert(err, qt.IsNil)
+			var gotSummary StatisticalSingleResult
+			c.Assert(json.Unmarshal(summary, &gotSummary), qt.IsNil)
+			c.Assert(gotSummary, qt.DeepEquals, want)
+
+			// the summary computed from the stored samples is the stored summary
+			samples, err := json.Marshal(tt.results)
+			c.Assert(err, qt.IsNil)
+			var gotSamples executionGroupResults
+			c.Assert(json.Unmarshal(samples, &gotSamples), qt.IsNil)
+			c.Assert(gotSamples.toStatisticalSingleResult(), qt.DeepEquals, want)
+		})
+	}
+}
+
+func TestSummarizedResults(t *testing.T) {
+	c := qt.New(t)
+	results := executionGroupResults{
+		GitRef:  "abc",
+		Results: sysbenchResultArray{{QPS: sysbenchQPS{Total: 1000}}, {QPS: sysbenchQPS{Total: 1010}}},
+	}
+
+	computed := summarizedResults{executionGroupResults: results}
+	c.Assert(computed.statisticalSingleResult(), qt.DeepEquals, results.toStatisticalSingleResult())
+
+	stored := StatisticalSingleResult{GitRef: "abc", TotalQPS: StatisticalSummary{Center: 42}}
+	precomputed := summarizedResults{executionGroupResults: results, summary: &stored}
+	c.Assert(precomputed.statisticalSingleResult(), qt.DeepEquals, stored)
+}
+
+func TestMergeSummaries(t *testing.T) {
+	c := qt.New(t)
+	raw := map[string]executionGroupResults{
+		"b": {GitRef: "b", Results: sysbenchResultArray{{QPS: sysbenchQPS{Total: 1000}}, {QPS: sysbenchQPS{Total: 1010}}}},
+	}
+	readResults := func(ref string) (executionGroupResults, error) {
+		return raw[ref], nil
+	}
+	summaries := map[string]groupSummary{
+		"a": {executions: 6, summary: StatisticalSingleResult{GitRef: "a"}},
+		"c": {executions: 3, summary: StatisticalSingleResult{GitRef: "c"}},
+	}
+
+	// "b" was not summarized yet, it is read from the raw results in its place, and
+	// "d" has no result at all
+	got, err := mergeSummaries([]string{"a", "b", "c", "d"}, summaries, readResults)
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.HasLen, 3)
+	c.Assert(got[0].summary.GitRef, qt.Equals, "a")
+	c.Assert(got[1].executions, qt.Equals, 2)
+	c.Assert(got[1].summary, qt.DeepEquals, raw["b"].toStatisticalSingleResult())
+	c.Assert(got[2].summary.GitRef, qt.Equals, "c")
+}
@@ -20,6 +20,7 @@ package macrobench
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -282,13 +283,11 @@ func insertVTGateQueryMapToMySQL(client storage.SQLClient, execUUID string, resu
 		return errors.New(mysql.ErrorClientConnectionNotInitialized)
 	}
 
-	query := "INSERT INTO query_plans(`exec_uuid`, `macrobenchmark_id`, `key`, `plan`, `exec_count`, `exec_time`, `rows`, `errors`) VALUES(?, ?, ?, ?, ?, ?, ?, ?)"
+	query := "INSERT INTO query_plans(`exec_uuid`, `macrobenchmark_id`, `key`, `plan`, `exec_count`, `exec_time`, `rows`, `errors`) VALUES"
+	rows := make([][]interface{}, 0, len(result))
 	for key, value := range result {
 		normalizeVTGateQueryPlan(&value)
-		_, err := client.Write(query, execUUID, macrobenchmarkID, key, fmt.Sprintf("%v", value.Instructions), value.ExecCount, value.ExecTime, value.RowsReturned, value.Errors)
-		if err != nil {
-			return err
-		}
+		rows = append(rows, []interface{}{execUUID, macrobenchmarkID, key, fmt.Sprintf("%v", value.Instructions), value.ExecCount, value.ExecTime, value.RowsReturned, value.Errors})
 	}
-	return nil
+	return storage.BulkInsert(context.Background(), client, query, rows)
 }
@@ -0,0 +1,180 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package microbench
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// goListPackage is the part of the output of go list -json used to find the packages
+// affected by a change.
+type goListPackage struct {
+	ImportPath   string
+	Dir          string
+	Imports      []string
+	TestImports  []string
+	XTestImports []string
+}
+
+// AffectedPackages returns the import paths of the packages of the module located in
+// repoDir whose benchmarks might be affected by a change of the given files, relative
+// to repoDir. These are the packages containing a changed file, and the packages that
+// depend on them, directly or transitively, including through their tests. A change
+// of a test file, or of a file in a testdata directory, only affects its own package.
+// If go.mod or go.sum changed, any package might be affected and all is true.
+//
+// The dependencies are read from the files currently checked out in repoDir.
+func AffectedPackages(repoDir string, files []string) (pkgs []string, all bool, err error) {
+	for _, file := range files {
+		switch filepath.Base(file) {
+		case "go.mod", "go.sum", "go.work", "go.work.sum":
+			return nil, true, nil
+		}
+	}
+
+	command := exec.Command("go", "list", "-e", "-json=ImportPath,Dir,Imports,TestImports,XTestImports", "./...")
+	command.Dir = repoDir
+	out, err := command.Output()
+	if err != nil {
+		return nil, false, err
+	}
+	list, err := parseGoList(bytes.NewReader(out))
+	if err != nil {
+		return nil, false, err
+	}
+	absRepoDir, err := filepath.Abs(repoDir)
+	if err != nil {
+		return nil, false, err
+	}
+	return affectedPackages(list, absRepoDir, files), false, nil
+}
+
+func parseGoList(r io.Reader) ([]goListPackage, error) {
+	var list []goListPackage
+	decoder := json.NewDecoder(r)
+	for {
+		var pkg goListPackage
+		err := decoder.Decode(&pkg)
+		if errors.Is(err, io.EOF) {
+			return list, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, pkg)
+	}
+}
+
+func affectedPackages(list []goListPackage, repoDir string, files []string) []string {
+	packagesByDir := make(map[string]string, len(list))
+	importedBy := map[string][]string{}
+	for _, pkg := range list {
+		packagesByDir[pkg.Dir] = pkg.ImportPath
+		for _, imp := range pkg.Imports {
+			importedBy[imp] = append(importedBy[imp], pkg.ImportPath)
+		}
+	}
+
+	// changed holds the packages whose non-test code changed, and their dependents
+	changed := map[string]bool{}
+	affected := map[string]bool{}
+	var toVisit []string
+	for _, file := range files {
+		pkg, found := packageOfFile(packagesByDir, repoDir, file)
+		if !found {
+			continue
+		}
+		affected[pkg] = true
+		if !isTestFile(file) && !changed[pkg] {
+			changed[pkg] = true
+			toVisit = append(toVisit, pkg)
+		}
+	}
+	for len(toVisit) > 0 {
+		pkg := toVisit[0]
+		toVisit = toVisit[1:]
+		for _, dependent := range importedBy[pkg] {
+			if !changed[dependent] {
+				changed[dependent] = true
+				toVisit = append(toVisit, dependent)
+			}
+		}
+	}
+
+	for _, pkg := range list {
+		if changed[pkg.ImportPath] || importsAny(changed, pkg.TestImports) || importsAny(changed, pkg.XTestImports) {
+			affected[pkg.ImportPath] = true
+		}
+	}
+	pkgs := make([]string, 0, len(affected))
+	for pkg := range affected {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	return pkgs
+}
+
+// packageOfFile returns the package of the closest directory containing the file,
+// files outside of any package, like the documentation, are not found. The files of
+// a deleted directory are not found either, they belonged to a deleted package whose
+// former dependents were changed to stop importing it, rather than to a parent package.
+func packageOfFile(packagesByDir map[string]string, repoDir, file string) (string, bool) {
+	dir := filepath.Dir(filepath.Join(repoDir, file))
+	if _, err := os.Stat(dir); err != nil {
+		return "", false
+	}
+	for strings.HasPrefix(dir, repoDir) {
+		if pkg, ok := packagesByDir[dir]; ok {
+			return pkg, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return "", false
+}
+
+func isTestFile(file string) bool {
+	if strings.HasSuffix(file, "_test.go") {
+		return true
+	}
+	for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(file)), "/") {
+		if dir == "testdata" {
+			return true
+		}
+	}
+	return false
+}
+
+func importsAny(pkgs map[string]bool, imports []string) bool {
+	for _, imp := range imports {
+		if pkgs[imp] {
+			return true
+		}
+	}
+	return false
+}
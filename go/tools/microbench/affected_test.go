@@ -0,0 +1,100 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package microbench
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestAffectedPackages(t *testing.T) {
+	c := qt.New(t)
+
+	// sqltypes <- sqlparser <- planbuilder <- vtgate, engine is only used by the tests of
+	// planbuilder and the external tests of vtgate, and stats is not used by any of them
+	dir := c.TempDir()
+	files := map[string]string{
+		"go.mod":                                       "module vitess.io/vitess\n\ngo 1.21\n",
+		"go/sqltypes/value.go":                         "package sqltypes\n",
+		"go/sqltypes/testdata/values.json":             "[]\n",
+		"go/vt/sqlparser/ast.go":                       "package sqlparser\n\nimport _ \"vitess.io/vitess/go/sqltypes\"\n",
+		"go/vt/sqlparser/keywords.txt":                 "select\n",
+		"go/vt/vtgate/engine/join.go":                  "package engine\n",
+		"go/vt/vtgate/planbuilder/plan.go":             "package planbuilder\n\nimport _ \"vitess.io/vitess/go/vt/sqlparser\"\n",
+		"go/vt/vtgate/planbuilder/plan_test.go":        "package planbuilder\n\nimport _ \"vitess.io/vitess/go/vt/vtgate/engine\"\n",
+		"go/vt/vtgate/vtgate.go":                       "package vtgate\n\nimport _ \"vitess.io/vitess/go/vt/vtgate/planbuilder\"\n",
+		"go/vt/vtgate/vtgate_test.go":                  "package vtgate_test\n\nimport _ \"vitess.io/vitess/go/vt/vtgate/engine\"\n",
+		"go/stats/counter.go":                          "package stats\n",
+		"go/stats/counter_test.go":                     "package stats\n",
+		"go/vt/vtgate/planbuilder/testdata/plans.json": "{}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		c.Assert(os.MkdirAll(filepath.Dir(path), 0755), qt.IsNil)
+		c.Assert(os.WriteFile(path, []byte(content), 0644), qt.IsNil)
+	}
+
+	tests := []struct {
+		name    string
+		files   []string
+		want    []string
+		wantAll bool
+	}{
+		{name: "no change", want: []string{}},
+		{name: "documentation", files: []string{"README.md", "doc/design.md"}, want: []string{}},
+		{name: "dependencies", files: []string{"go/stats/counter.go", "go.sum"}, wantAll: true},
+		{name: "leaf package", files: []string{"go/stats/counter.go"}, want: []string{"vitess.io/vitess/go/stats"}},
+		{
+			name:  "transitive dependents",
+			files: []string{"go/sqltypes/value.go"},
+			want:  []string{"vitess.io/vitess/go/sqltypes", "vitess.io/vitess/go/vt/sqlparser", "vitess.io/vitess/go/vt/vtgate", "vitess.io/vitess/go/vt/vtgate/planbuilder"},
+		},
+		{
+			name:  "non-Go file",
+			files: []string{"go/vt/sqlparser/keywords.txt"},
+			want:  []string{"vitess.io/vitess/go/vt/sqlparser", "vitess.io/vitess/go/vt/vtgate", "vitess.io/vitess/go/vt/vtgate/planbuilder"},
+		},
+		{
+			name:  "imported by tests",
+			files: []string{"go/vt/vtgate/engine/join.go"},
+			want:  []string{"vitess.io/vitess/go/vt/vtgate", "vitess.io/vitess/go/vt/vtgate/engine", "vitess.io/vitess/go/vt/vtgate/planbuilder"},
+		},
+		{name: "test file", files: []string{"go/vt/vtgate/planbuilder/plan_test.go"}, want: []string{"vitess.io/vitess/go/vt/vtgate/planbuilder"}},
+		{name: "testdata", files: []string{"go/sqltypes/testdata/values.json", "go/vt/vtgate/planbuilder/testdata/plans.json"}, want: []string{"vitess.io/vitess/go/sqltypes", "vitess.io/vitess/go/vt/vtgate/planbuilder"}},
+		{name: "deleted package", files: []string{"go/vt/vtgate/evalengine/eval.go", "go/vt/vtgate/evalengine/testdata/eval.json"}, want: []string{}},
+		{
+			name:  "deleted file",
+			files: []string{"go/vt/sqlparser/parser.go"},
+			want:  []string{"vitess.io/vitess/go/vt/sqlparser", "vitess.io/vitess/go/vt/vtgate", "vitess.io/vitess/go/vt/vtgate/planbuilder"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			got, all, err := AffectedPackages(dir, tt.files)
+			c.Assert(err, qt.IsNil)
+			c.Assert(all, qt.Equals, tt.wantAll)
+			if tt.wantAll {
+				return
+			}
+			c.Assert(got, qt.DeepEquals, tt.want)
+		})
+	}
+}
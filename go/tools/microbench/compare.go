@@ -20,13 +20,20 @@ package microbench
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/vitessio/arewefastyet/go/storage"
+	"github.com/vitessio/arewefastyet/go/tools/macrobench"
 )
 
-// Compare takes in 3 arguments, the database, and 2 SHAs. It reads from the database, the microbenchmark
-// results for the 2 SHAs and compares them. The result is a comparison array.
-func Compare(client storage.SQLClient, right string, left string) (ComparisonArray, error) {
+// DefaultRegressionThreshold is the percentage beyond which a significant change
+// of a microbenchmark is reported as a regression.
+const DefaultRegressionThreshold = 10.0
+
+// Compare takes in 4 arguments, the database, 2 SHAs and the method used to compare their samples.
+// It reads from the database, the microbenchmark results for the 2 SHAs and compares them.
+// The result is a comparison array.
+func Compare(client storage.SQLClient, right string, left string, method macrobench.ComparisonMethod) (ComparisonArray, error) {
 	// compare micro benchmarks
 	SHAs := []string{right, left}
 	micros := map[string]DetailsArray{}
@@ -35,36 +42,124 @@ func Compare(client storage.SQLClient, right string, left string) (ComparisonArr
 		if err != nil {
 			return nil, err
 		}
-		micros[sha] = micro.ReduceSimpleMedianByName()
+		micros[sha] = micro
 	}
-	microsMatrix := MergeDetails(micros[right], micros[left])
 	// The result of the merge will be sorted by the package name and then the benchmark name
-	return microsMatrix, nil
+	return CompareDetails(micros[right], micros[left], method), nil
+}
+
+// CompareDetails merges the medians of the given DetailsArray into a ComparisonArray, like
+// MergeDetails, and compares all the samples of each benchmark and unit using method.
+// The p-values are adjusted with the correction of the method over all the comparisons.
+func CompareDetails(rightMbd, leftMbd DetailsArray, method macrobench.ComparisonMethod) ComparisonArray {
+	matchUnknownConfigurations(rightMbd, leftMbd)
+	rightSamples := rightMbd.samples()
+	leftSamples := leftMbd.samples()
+	microsMatrix := MergeDetails(rightMbd.ReduceSimpleMedianByName(), leftMbd.ReduceSimpleMedianByName())
+
+	// the results are adjusted all together before being set in the comparisons
+	units := make([][]string, len(microsMatrix))
+	results := make([][]macrobench.StatisticalResult, len(microsMatrix))
+	var family []*macrobench.StatisticalResult
+	for i, micro := range microsMatrix {
+		right, left := rightSamples[micro.BenchmarkId], leftSamples[micro.BenchmarkId]
+		units[i] = sampleUnits(right, left)
+		results[i] = make([]macrobench.StatisticalResult, len(units[i]))
+		for j, unit := range units[i] {
+			results[i][j] = method.Compare(left[unit], right[unit])
+			family = append(family, &results[i][j])
+		}
+	}
+	macrobench.AdjustPValues(family, method.Config())
+
+	for i := range microsMatrix {
+		if len(units[i]) == 0 {
+			continue
+		}
+		microsMatrix[i].Statistics = make(map[string]macrobench.StatisticalResult, len(units[i]))
+		for j, unit := range units[i] {
+			microsMatrix[i].Statistics[unit] = results[i][j]
+		}
+	}
+	return microsMatrix
+}
+
+// samples returns the values of every unit measured by each benchmark.
+func (mbd DetailsArray) samples() map[BenchmarkId]map[string][]float64 {
+	samples := map[BenchmarkId]map[string][]float64{}
+	for _, details := range mbd {
+		values := samples[details.BenchmarkId]
+		if values == nil {
+			values = map[string][]float64{}
+			samples[details.BenchmarkId] = values
+		}
+		for unit, value := range details.Result.values() {
+			values[unit] = append(values[unit], value)
+		}
+	}
+	return samples
+}
+
+// sampleUnits returns the sorted units measured on both sides of a comparison, the
+// units whose values are all zeros, like B/op without testing.B.ReportAllocs, are
+// skipped.
+func sampleUnits(right, left map[string][]float64) []string {
+	var units []string
+	for unit, rightValues := range right {
+		leftValues, ok := left[unit]
+		if !ok || (allZeros(rightValues) && allZeros(leftValues)) {
+			continue
+		}
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+	return units
+}
+
+func allZeros(values []float64) bool {
+	for _, v := range values {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// RegressedUnits returns the sorted units of the statistically significant changes
+// of the comparison that regressed by more than threshold percent.
+func (micro Comparison) RegressedUnits(threshold float64) []string {
+	var units []string
+	for unit, res := range micro.Statistics {
+		if res.Regressed(threshold, HigherIsBetter(unit)) {
+			units = append(units, unit)
+		}
+	}
+	sort.Strings(units)
+	return units
+}
+
+// SetRegressions sets the Regressions of every comparison, see RegressedUnits.
+func (microsMatrix ComparisonArray) SetRegressions(threshold float64) {
+	for i := range microsMatrix {
+		microsMatrix[i].Regressions = microsMatrix[i].RegressedUnits(threshold)
+	}
 }
 
 // Regression returns a string containing the reason of the regression of the given ComparisonArray,
-// if no regression was evaluated, the reason will be an empty string.
+// if no regression was evaluated, the reason will be an empty string. Only the statistically
+// significant changes that regressed by more than threshold percent are reported.
 // The format of a single benchmark regression's reason is like this:
 //
-// "- {pkg name}/{benchmark name} decreased by {decrease percentage}%\n"
-//
-func (microsMatrix ComparisonArray) Regression() (reason string) {
+// "- {pkg name}/{benchmark name}: metric: {unit}, regressed by {regression percentage}% (p={p-value})\n"
+func (microsMatrix ComparisonArray) Regression(threshold float64) (reason string) {
 	for _, micro := range microsMatrix {
-		m := []struct{
-			value float64
-			name string
-		}{
-			{name: "total operation", value: micro.Diff.Ops},
-			{name: "nanosecond per operation", value: micro.Diff.NSPerOp},
-			{name: "bytes per operation", value: micro.Diff.BytesPerOp},
-			{name: "MB per second", value: micro.Diff.MBPerSec},
-			{name: "allocations per operation", value: micro.Diff.AllocsPerOp},
-		}
-
-		for _, s := range m {
-			if s.value < -10 {
-				reason += fmt.Sprintf("- %s/%s: metric: %s, decreased by %.2f%%\n", micro.PkgName, micro.SubBenchmarkName, s.name, -1*s.value)
+		for _, unit := range micro.RegressedUnits(threshold) {
+			res := micro.Statistics[unit]
+			regression := res.Delta
+			if HigherIsBetter(unit) {
+				regression = -res.Delta
 			}
+			reason += fmt.Sprintf("- %s/%s: metric: %s, regressed by %.2f%% (p=%.3f)\n", micro.PkgName, micro.FullName(), unit, regression, res.AdjustedP)
 		}
 	}
 	return
@@ -22,40 +22,111 @@ import (
 	"testing"
 
 	qt "github.com/frankban/quicktest"
+	"github.com/vitessio/arewefastyet/go/tools/macrobench"
 )
 
 func TestMicroBenchmarkComparisonArray_Regression(t *testing.T) {
 	tests := []struct {
 		name         string
 		microsMatrix ComparisonArray
+		threshold    float64
 		wantReason   string
 	}{
-		{name: "No regression", microsMatrix: ComparisonArray{
+		{name: "No regression", threshold: DefaultRegressionThreshold, microsMatrix: ComparisonArray{
 			{BenchmarkId: BenchmarkId{PkgName: "pkg1", Name: "bench3", SubBenchmarkName: "bench3-pkg1"}},
-			{BenchmarkId: BenchmarkId{PkgName: "pkg1", Name: "bench1", SubBenchmarkName: "bench1-pkg1"}},
-			{BenchmarkId: BenchmarkId{PkgName: "pkg1", Name: "bench2", SubBenchmarkName: "bench2-pkg1"}},
-			{BenchmarkId: BenchmarkId{PkgName: "pkg2", Name: "bench2", SubBenchmarkName: "bench2-pkg2"}},
-			{BenchmarkId: BenchmarkId{PkgName: "pkg2", Name: "bench1", SubBenchmarkName: "bench1-pkg2"}},
-			{BenchmarkId: BenchmarkId{PkgName: "pkg3", Name: "bench1", SubBenchmarkName: "bench1-pkg3"}},
+			{BenchmarkId: BenchmarkId{PkgName: "pkg1", Name: "bench1", SubBenchmarkName: "bench1-pkg1"}, Statistics: map[string]macrobench.StatisticalResult{
+				"ns/op": {Delta: -50, AdjustedP: 0.001},
+				"MB/s":  {Delta: 50, AdjustedP: 0.001},
+			}},
 		}, wantReason: ""},
 
-		{name: "Few regressions", microsMatrix: ComparisonArray{
-			{BenchmarkId: BenchmarkId{PkgName: "pkg1", Name: "bench3", SubBenchmarkName: "bench3-pkg1"}, Diff: Result{NSPerOp: -50}},
-			{BenchmarkId: BenchmarkId{PkgName: "pkg1", Name: "bench1", SubBenchmarkName: "bench1-pkg1"}, Diff: Result{NSPerOp: -11}},
-			{BenchmarkId: BenchmarkId{PkgName: "pkg1", Name: "bench2", SubBenchmarkName: "bench2-pkg1"}, Diff: Result{NSPerOp: -75}},
-		}, wantReason: "- pkg1/bench3-pkg1: metric: nanosecond per operation, decreased by 50.00%\n- pkg1/bench1-pkg1: metric: nanosecond per operation, decreased by 11.00%\n- pkg1/bench2-pkg1: metric: nanosecond per operation, decreased by 75.00%\n"},
-
-		{name: "Close call regressions", microsMatrix: ComparisonArray{
-			{BenchmarkId: BenchmarkId{PkgName: "pkg1", Name: "bench3", SubBenchmarkName: "bench3-pkg1"}, Diff: Result{NSPerOp: -10}},
-			{BenchmarkId: BenchmarkId{PkgName: "pkg1", Name: "bench1", SubBenchmarkName: "bench1-pkg1"}, Diff: Result{NSPerOp: -9.99}},
-			{BenchmarkId: BenchmarkId{PkgName: "pkg1", Name: "bench2", SubBenchmarkName: "bench2-pkg1"}, Diff: Result{NSPerOp: -10.01}},
-		}, wantReason: "- pkg1/bench2-pkg1: metric: nanosecond per operation, decreased by 10.01%\n"},
+		{name: "Few regressions", threshold: DefaultRegressionThreshold, microsMatrix: ComparisonArray{
+			{BenchmarkId: BenchmarkId{PkgName: "pkg1", Name: "bench3", SubBenchmarkName: "bench3-pkg1", Procs: 8}, Statistics: map[string]macrobench.StatisticalResult{
+				"ns/op":     {Delta: 50, AdjustedP: 0.001},
+				"allocs/op": {Delta: 11, AdjustedP: 0.002},
+			}},
+			{BenchmarkId: BenchmarkId{PkgName: "pkg1", Name: "bench1", SubBenchmarkName: "bench1-pkg1"}, Statistics: map[string]macrobench.StatisticalResult{
+				"MB/s":    {Delta: -75, AdjustedP: 0.01},
+				"rows/op": {Delta: 20, AdjustedP: 0.04},
+			}},
+		}, wantReason: "- pkg1/bench3-pkg1-8: metric: allocs/op, regressed by 11.00% (p=0.002)\n- pkg1/bench3-pkg1-8: metric: ns/op, regressed by 50.00% (p=0.001)\n" +
+			"- pkg1/bench1-pkg1: metric: MB/s, regressed by 75.00% (p=0.010)\n- pkg1/bench1-pkg1: metric: rows/op, regressed by 20.00% (p=0.040)\n"},
+
+		{name: "Insignificant regressions", threshold: DefaultRegressionThreshold, microsMatrix: ComparisonArray{
+			{BenchmarkId: BenchmarkId{PkgName: "pkg1", Name: "bench3", SubBenchmarkName: "bench3-pkg1"}, Statistics: map[string]macrobench.StatisticalResult{
+				"ns/op": {Delta: 50, AdjustedP: 0.3, Insignificant: true},
+			}},
+		}, wantReason: ""},
+
+		{name: "Close call regressions", threshold: DefaultRegressionThreshold, microsMatrix: ComparisonArray{
+			{BenchmarkId: BenchmarkId{PkgName: "pkg1", Name: "bench3", SubBenchmarkName: "bench3-pkg1"}, Statistics: map[string]macrobench.StatisticalResult{"ns/op": {Delta: 10}}},
+			{BenchmarkId: BenchmarkId{PkgName: "pkg1", Name: "bench1", SubBenchmarkName: "bench1-pkg1"}, Statistics: map[string]macrobench.StatisticalResult{"ns/op": {Delta: 9.99}}},
+			{BenchmarkId: BenchmarkId{PkgName: "pkg1", Name: "bench2", SubBenchmarkName: "bench2-pkg1"}, Statistics: map[string]macrobench.StatisticalResult{"ns/op": {Delta: 10.01}}},
+		}, wantReason: "- pkg1/bench2-pkg1: metric: ns/op, regressed by 10.01% (p=0.000)\n"},
+
+		{name: "Custom threshold", threshold: 60, microsMatrix: ComparisonArray{
+			{BenchmarkId: BenchmarkId{PkgName: "pkg1", Name: "bench3", SubBenchmarkName: "bench3-pkg1"}, Statistics: map[string]macrobench.StatisticalResult{
+				"ns/op": {Delta: 50, AdjustedP: 0.001},
+				"B/op":  {Delta: 70, AdjustedP: 0.001},
+			}},
+		}, wantReason: "- pkg1/bench3-pkg1: metric: B/op, regressed by 70.00% (p=0.001)\n"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c := qt.New(t)
-			reason := tt.microsMatrix.Regression()
-			c.Assert(reason, qt.Contains, tt.wantReason)
+			reason := tt.microsMatrix.Regression(tt.threshold)
+			c.Assert(reason, qt.Equals, tt.wantReason)
 		})
 	}
 }
+
+func TestCompareDetails(t *testing.T) {
+	c := qt.New(t)
+	id := BenchmarkId{PkgName: "pkg1", Name: "bench1", SubBenchmarkName: "bench1", Procs: 8}
+	stable := BenchmarkId{PkgName: "pkg1", Name: "bench2", SubBenchmarkName: "bench2", Procs: 8}
+	added := BenchmarkId{PkgName: "pkg1", Name: "bench3", SubBenchmarkName: "bench3", Procs: 8}
+
+	var right, left DetailsArray
+	for i := 0; i < 10; i++ {
+		left = append(left, *NewDetails(id, "old", "", Result{NSPerOp: float64(100 + i), AllocsPerOp: 1, Metrics: map[string]float64{"rows/op": 10}}))
+		right = append(right, *NewDetails(id, "new", "", Result{NSPerOp: float64(120 + i), AllocsPerOp: 1, Metrics: map[string]float64{"rows/op": 10}}))
+		left = append(left, *NewDetails(stable, "old", "", Result{NSPerOp: float64(50 + i%2)}))
+		right = append(right, *NewDetails(stable, "new", "", Result{NSPerOp: float64(51 - i%2)}))
+	}
+	right = append(right, *NewDetails(added, "new", "", Result{NSPerOp: 1}))
+
+	method, err := macrobench.NewComparisonMethod(macrobench.MethodMannWhitney, macrobench.DefaultStatisticalConfig())
+	c.Assert(err, qt.IsNil)
+	got := CompareDetails(right, left, method)
+	c.Assert(got, qt.HasLen, 3)
+
+	c.Assert(got[0].BenchmarkId, qt.Equals, id)
+	c.Assert(got[0].Right.NSPerOp, qt.Equals, 124.5)
+	c.Assert(got[0].Left.NSPerOp, qt.Equals, 104.5)
+	c.Assert(got[0].Statistics, qt.HasLen, 3)
+	ns := got[0].Statistics["ns/op"]
+	c.Assert(ns.Insignificant, qt.IsFalse)
+	c.Assert(ns.N1, qt.Equals, 10)
+	c.Assert(ns.N2, qt.Equals, 10)
+	c.Assert(ns.P < 0.001, qt.IsTrue, qt.Commentf("p-value %f", ns.P))
+	c.Assert(ns.Old.Center, qt.Equals, 104.5)
+	c.Assert(ns.New.Center, qt.Equals, 124.5)
+	c.Assert(got[0].Statistics["allocs/op"].Insignificant, qt.IsTrue)
+	c.Assert(got[0].Statistics["rows/op"].Insignificant, qt.IsTrue)
+
+	c.Assert(got[1].BenchmarkId, qt.Equals, stable)
+	c.Assert(got[1].Statistics, qt.HasLen, 1)
+	c.Assert(got[1].Statistics["ns/op"].Insignificant, qt.IsTrue)
+
+	// a benchmark without samples on the left side is not compared
+	c.Assert(got[2].BenchmarkId, qt.Equals, added)
+	c.Assert(got[2].Statistics, qt.IsNil)
+
+	c.Assert(got.Regression(DefaultRegressionThreshold), qt.Matches, `- pkg1/bench1-8: metric: ns/op, regressed by 19\.14% \(p=0\.000\)\n`)
+
+	got.SetRegressions(DefaultRegressionThreshold)
+	c.Assert(got[0].Regressions, qt.DeepEquals, []string{"ns/op"})
+	c.Assert(got[1].Regressions, qt.IsNil)
+	got.SetRegressions(20)
+	c.Assert(got[0].Regressions, qt.IsNil)
+}
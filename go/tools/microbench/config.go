@@ -17,14 +17,47 @@ limitations under the License.
 package microbench
 
 import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"github.com/vitessio/arewefastyet/go/storage/psdb"
+	"github.com/vitessio/arewefastyet/go/storage/database"
 )
 
 const (
-	flagExecUUID   = "microbench-exec-uuid"
-	flagRunProfile = "microbench-run-profile"
+	ErrorInvalidPattern        = "invalid pattern"
+	ErrorOverrideMissingName   = "microbenchmark override is missing a name"
+	ErrorOverrideInvalidCount  = "microbenchmark override count cannot be negative"
+	ErrorOverrideInvalidParams = "microbenchmark override does not change any parameter"
+	ErrorUnitDirectionConflict = "unit listed as both higher and lower is better"
+
+	// KeyOverrides is the configuration key under which the per-benchmark overrides are listed.
+	KeyOverrides = "microbench-overrides"
+
+	// KeyHigherIsBetter and KeyLowerIsBetter are the configuration keys under which the
+	// units whose direction does not follow their suffix are listed, see HigherIsBetter.
+	KeyHigherIsBetter = "microbench-higher-is-better"
+	KeyLowerIsBetter  = "microbench-lower-is-better"
+
+	// DefaultCount is the number of times each benchmark is run by default.
+	DefaultCount = 10
+
+	flagExecUUID          = "microbench-exec-uuid"
+	flagRunProfile        = "microbench-run-profile"
+	flagCount             = "microbench-count"
+	flagBenchtime         = "microbench-benchtime"
+	flagTimeout           = "microbench-timeout"
+	flagCPU               = "microbench-cpu"
+	flagPackages          = "microbench-packages"
+	flagIncludePackages   = "microbench-include-packages"
+	flagExcludePackages   = "microbench-exclude-packages"
+	flagIncludeBenchmarks = "microbench-include-benchmarks"
+	flagExcludeBenchmarks = "microbench-exclude-benchmarks"
 )
 
 type Config struct {
@@ -41,7 +74,7 @@ type Config struct {
 	// DatabaseConfig used to save results to SQL. If this field
 	// is nil, saving results will be skipped and no error will
 	// be returned.
-	DatabaseConfig *psdb.Config
+	DatabaseConfig *database.Config
 
 	// execUUID refers to parent execution of the microbenchmark.
 	// If this field is empty, the corresponding column in SQL
@@ -51,14 +84,215 @@ type Config struct {
 	// runProfile defines whether the goproc profile shall be run for every micro-benchmark
 	// this option is set to false by default
 	runProfile bool
+
+	// Count is the number of times each benchmark is run, it is given to go test -count.
+	// DefaultCount is used if it is zero.
+	Count int
+
+	// Benchtime is the run time or number of iterations of each benchmark, it is given
+	// to go test -benchtime. The default of go test is used if it is empty.
+	Benchtime string
+
+	// Timeout of the go test command executing the benchmarks of a package, it is given
+	// to go test -timeout. The default of go test is used if it is zero.
+	Timeout time.Duration
+
+	// CPU lists the GOMAXPROCS values with which each benchmark is run, it is given
+	// to go test -cpu. The default of go test is used if it is empty.
+	CPU []int
+
+	// Packages lists the import paths of the only packages to benchmark, for instance
+	// the packages affected by a pull request. All the packages are benchmarked if
+	// it is empty.
+	Packages []string
+
+	// IncludePackages and ExcludePackages are regular expressions matched against the
+	// import path of the packages. A package is benchmarked if it matches any of the
+	// IncludePackages, or if IncludePackages is empty, and none of the ExcludePackages.
+	IncludePackages, ExcludePackages []string
+
+	// IncludeBenchmarks and ExcludeBenchmarks select the benchmarks by name the same way
+	// IncludePackages and ExcludePackages select the packages.
+	IncludeBenchmarks, ExcludeBenchmarks []string
+
+	// Overrides changes the parameters of some benchmarks. If it is nil, the overrides
+	// are loaded from the configuration using LoadOverrides.
+	Overrides []Override
+}
+
+// Override changes the parameters with which the matching benchmarks are run, for instance
+// to run slow benchmarks fewer times. Overrides are listed in the configuration file
+// under KeyOverrides:
+//
+//	microbench-overrides:
+//	  - package: vitess.io/vitess/go/vt/vtgate/engine
+//	    name: BenchmarkJoin
+//	    count: 3
+//	    benchtime: 100x
+//	    timeout: 30m
+//
+// A benchmark matching an override is run by a go test command of its own.
+type Override struct {
+	// Package is a regular expression matched against the import path of the package,
+	// an empty Package matches all the packages.
+	Package string `mapstructure:"package"`
+
+	// Name is a regular expression matched against the name of the benchmark.
+	Name string `mapstructure:"name"`
+
+	// Count, Benchtime and Timeout replace Config.Count, Config.Benchtime and
+	// Config.Timeout when they are not zero.
+	Count     int           `mapstructure:"count"`
+	Benchtime string        `mapstructure:"benchtime"`
+	Timeout   time.Duration `mapstructure:"timeout"`
+
+	pkg, name *regexp.Regexp
 }
 
 func (mbc *Config) AddToCommand(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&mbc.execUUID, flagExecUUID, "", "UUID of the parent execution, an empty string will set to NULL.")
 	cmd.Flags().BoolVar(&mbc.runProfile, flagRunProfile, false, "Run goproc profiling for each micro-benchmark.")
 
+	cmd.Flags().IntVar(&mbc.Count, flagCount, DefaultCount, "Number of times each micro-benchmark is run.")
+	cmd.Flags().StringVar(&mbc.Benchtime, flagBenchtime, "", "Run time or number of iterations of each micro-benchmark, as given to go test -benchtime.")
+	cmd.Flags().DurationVar(&mbc.Timeout, flagTimeout, 0, "Timeout of the micro-benchmarks of each package, as given to go test -timeout.")
+	cmd.Flags().IntSliceVar(&mbc.CPU, flagCPU, nil, "List of GOMAXPROCS values with which each micro-benchmark is run, as given to go test -cpu.")
+	cmd.Flags().StringSliceVar(&mbc.Packages, flagPackages, nil, "Import paths of the only packages to benchmark, all the packages are benchmarked if empty.")
+	cmd.Flags().StringSliceVar(&mbc.IncludePackages, flagIncludePackages, nil, "Regular expressions matching the import path of the packages to benchmark, all the packages are benchmarked if empty.")
+	cmd.Flags().StringSliceVar(&mbc.ExcludePackages, flagExcludePackages, nil, "Regular expressions matching the import path of the packages not to benchmark.")
+	cmd.Flags().StringSliceVar(&mbc.IncludeBenchmarks, flagIncludeBenchmarks, nil, "Regular expressions matching the name of the micro-benchmarks to run, all the micro-benchmarks are run if empty.")
+	cmd.Flags().StringSliceVar(&mbc.ExcludeBenchmarks, flagExcludeBenchmarks, nil, "Regular expressions matching the name of the micro-benchmarks not to run.")
+
 	_ = viper.BindPFlag(flagExecUUID, cmd.Flags().Lookup(flagExecUUID))
 	_ = viper.BindPFlag(flagRunProfile, cmd.Flags().Lookup(flagRunProfile))
+	_ = viper.BindPFlag(flagCount, cmd.Flags().Lookup(flagCount))
+	_ = viper.BindPFlag(flagBenchtime, cmd.Flags().Lookup(flagBenchtime))
+	_ = viper.BindPFlag(flagTimeout, cmd.Flags().Lookup(flagTimeout))
+	_ = viper.BindPFlag(flagCPU, cmd.Flags().Lookup(flagCPU))
+	_ = viper.BindPFlag(flagPackages, cmd.Flags().Lookup(flagPackages))
+	_ = viper.BindPFlag(flagIncludePackages, cmd.Flags().Lookup(flagIncludePackages))
+	_ = viper.BindPFlag(flagExcludePackages, cmd.Flags().Lookup(flagExcludePackages))
+	_ = viper.BindPFlag(flagIncludeBenchmarks, cmd.Flags().Lookup(flagIncludeBenchmarks))
+	_ = viper.BindPFlag(flagExcludeBenchmarks, cmd.Flags().Lookup(flagExcludeBenchmarks))
 
 	mbc.DatabaseConfig.AddToCommand(cmd)
 }
+
+// LoadOverrides returns the overrides listed in the configuration under KeyOverrides.
+func LoadOverrides(v *viper.Viper) ([]Override, error) {
+	if !v.IsSet(KeyOverrides) {
+		return []Override{}, nil
+	}
+
+	var overrides []Override
+	err := v.UnmarshalKey(KeyOverrides, &overrides)
+	if err != nil {
+		return nil, err
+	}
+	for i := range overrides {
+		if err = overrides[i].compile(); err != nil {
+			return nil, err
+		}
+	}
+	return overrides, nil
+}
+
+// LoadUnitDirections registers the direction of the units listed in the configuration
+// under KeyHigherIsBetter and KeyLowerIsBetter, the lists are comma-separated.
+func LoadUnitDirections(v *viper.Viper) error {
+	higher, lower := configUnits(v, KeyHigherIsBetter), configUnits(v, KeyLowerIsBetter)
+	for _, unit := range higher {
+		if slices.Contains(lower, unit) {
+			return fmt.Errorf("%s: %s", ErrorUnitDirectionConflict, unit)
+		}
+	}
+	for _, unit := range higher {
+		setHigherIsBetter(unit, true)
+	}
+	for _, unit := range lower {
+		setHigherIsBetter(unit, false)
+	}
+	return nil
+}
+
+// configUnits returns the units listed in the configuration under key.
+func configUnits(v *viper.Viper, key string) []string {
+	var units []string
+	for _, value := range v.GetStringSlice(key) {
+		for _, unit := range strings.Split(value, ",") {
+			if unit = strings.TrimSpace(unit); unit != "" {
+				units = append(units, unit)
+			}
+		}
+	}
+	return units
+}
+
+// compile validates the Override and compiles its patterns.
+func (o *Override) compile() (err error) {
+	if o.Name == "" {
+		return errors.New(ErrorOverrideMissingName)
+	}
+	if o.Count < 0 {
+		return fmt.Errorf("%s: %s", ErrorOverrideInvalidCount, o.Name)
+	}
+	if o.Count == 0 && o.Benchtime == "" && o.Timeout == 0 {
+		return fmt.Errorf("%s: %s", ErrorOverrideInvalidParams, o.Name)
+	}
+	if o.pkg, err = regexp.Compile(o.Package); err != nil {
+		return fmt.Errorf("%s: %s", ErrorInvalidPattern, err)
+	}
+	if o.name, err = regexp.Compile(o.Name); err != nil {
+		return fmt.Errorf("%s: %s", ErrorInvalidPattern, err)
+	}
+	return nil
+}
+
+func (o Override) matches(pkgPath, name string) bool {
+	return o.pkg.MatchString(pkgPath) && o.name.MatchString(name)
+}
+
+// filter selects names matching any of the include patterns, or all of them if
+// there are none, and none of the exclude patterns.
+type filter struct {
+	include, exclude []*regexp.Regexp
+}
+
+func newFilter(include, exclude []string) (f filter, err error) {
+	if f.include, err = compilePatterns(include); err != nil {
+		return filter{}, err
+	}
+	if f.exclude, err = compilePatterns(exclude); err != nil {
+		return filter{}, err
+	}
+	return f, nil
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", ErrorInvalidPattern, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+func (f filter) matches(name string) bool {
+	for _, re := range f.exclude {
+		if re.MatchString(name) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, re := range f.include {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package microbench
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/spf13/viper"
+)
+
+func TestLoadOverrides(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    []Override
+		wantErr string
+	}{
+		{name: "no overrides", config: "", want: []Override{}},
+		{
+			name: "overrides",
+			config: `
+microbench-overrides:
+  - package: vitess.io/vitess/go/vt/vtgate/engine
+    name: BenchmarkJoin
+    count: 3
+    timeout: 30m
+  - name: ^BenchmarkParse
+    benchtime: 100x
+`,
+			want: []Override{
+				{Package: "vitess.io/vitess/go/vt/vtgate/engine", Name: "BenchmarkJoin", Count: 3, Timeout: 30 * time.Minute},
+				{Name: "^BenchmarkParse", Benchtime: "100x"},
+			},
+		},
+		{
+			name: "missing name",
+			config: `
+microbench-overrides:
+  - package: vitess.io/vitess/go/vt/vtgate/engine
+    count: 3
+`,
+			wantErr: ErrorOverrideMissingName,
+		},
+		{
+			name: "negative count",
+			config: `
+microbench-overrides:
+  - name: BenchmarkJoin
+    count: -1
+`,
+			wantErr: ErrorOverrideInvalidCount + ": BenchmarkJoin",
+		},
+		{
+			name: "no parameter",
+			config: `
+microbench-overrides:
+  - name: BenchmarkJoin
+`,
+			wantErr: ErrorOverrideInvalidParams + ": BenchmarkJoin",
+		},
+		{
+			name: "invalid pattern",
+			config: `
+microbench-overrides:
+  - name: Benchmark(Join
+    count: 1
+`,
+			wantErr: ErrorInvalidPattern + ": .*",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			v := viper.New()
+			v.SetConfigType("yaml")
+			c.Assert(v.ReadConfig(strings.NewReader(tt.config)), qt.IsNil)
+
+			got, err := LoadOverrides(v)
+			if tt.wantErr != "" {
+				c.Assert(err, qt.ErrorMatches, tt.wantErr)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(got, qt.HasLen, len(tt.want))
+			for i, want := range tt.want {
+				c.Assert(got[i].Package, qt.Equals, want.Package)
+				c.Assert(got[i].Name, qt.Equals, want.Name)
+				c.Assert(got[i].Count, qt.Equals, want.Count)
+				c.Assert(got[i].Benchtime, qt.Equals, want.Benchtime)
+				c.Assert(got[i].Timeout, qt.Equals, want.Timeout)
+				c.Assert(got[i].matches(want.Package+"/sub", "BenchmarkJoin"), qt.Equals, i == 0)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name             string
+		include, exclude []string
+		matches          []string
+		excluded         []string
+	}{
+		{name: "no pattern", matches: []string{"vitess.io/vitess/go/vt/sqlparser", ""}},
+		{name: "include", include: []string{"sqlparser$", "/vtgate/"}, matches: []string{"vitess.io/vitess/go/vt/sqlparser", "vitess.io/vitess/go/vt/vtgate/engine"}, excluded: []string{"vitess.io/vitess/go/vt/sqlparser/cache"}},
+		{name: "exclude", exclude: []string{"/test/"}, matches: []string{"vitess.io/vitess/go/vt/sqlparser"}, excluded: []string{"vitess.io/vitess/go/test/endtoend"}},
+		{name: "exclude wins", include: []string{"vtgate"}, exclude: []string{"engine"}, matches: []string{"vitess.io/vitess/go/vt/vtgate"}, excluded: []string{"vitess.io/vitess/go/vt/vtgate/engine"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			f, err := newFilter(tt.include, tt.exclude)
+			c.Assert(err, qt.IsNil)
+			for _, name := range tt.matches {
+				c.Assert(f.matches(name), qt.IsTrue, qt.Commentf(name))
+			}
+			for _, name := range tt.excluded {
+				c.Assert(f.matches(name), qt.IsFalse, qt.Commentf(name))
+			}
+		})
+	}
+
+	_, err := newFilter([]string{"("}, nil)
+	qt.Assert(t, err, qt.ErrorMatches, ErrorInvalidPattern+": .*")
+}
+
+func TestLoadUnitDirections(t *testing.T) {
+	c := qt.New(t)
+	c.Cleanup(func() {
+		unitDirectionsMu.Lock()
+		defer unitDirectionsMu.Unlock()
+		for _, unit := range []string{"hits", "hit-%", "misses/s"} {
+			delete(unitDirections, unit)
+		}
+	})
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	c.Assert(v.ReadConfig(strings.NewReader("microbench-higher-is-better: hits, hit-%\nmicrobench-lower-is-better: misses/s\n")), qt.IsNil)
+	c.Assert(LoadUnitDirections(v), qt.IsNil)
+	c.Assert(HigherIsBetter("hits"), qt.IsTrue)
+	c.Assert(HigherIsBetter("hit-%"), qt.IsTrue)
+	c.Assert(HigherIsBetter("misses/s"), qt.IsFalse)
+
+	v = viper.New()
+	v.SetConfigType("yaml")
+	c.Assert(v.ReadConfig(strings.NewReader("microbench-higher-is-better: [hits]\nmicrobench-lower-is-better: [hits]\n")), qt.IsNil)
+	c.Assert(LoadUnitDirections(v), qt.ErrorMatches, ErrorUnitDirectionConflict+": hits")
+}
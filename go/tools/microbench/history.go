@@ -0,0 +1,285 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package microbench
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/vitessio/arewefastyet/go/tools/git"
+	"github.com/vitessio/arewefastyet/go/tools/macrobench"
+)
+
+const (
+	// DefaultHistoryLast is the number of cron runs of a HistoryFilter by default.
+	DefaultHistoryLast = 30
+
+	// MaxHistoryLast is the largest number of cron runs read for a HistoryFilter.
+	MaxHistoryLast = 1000
+
+	// DefaultStepWindow is the number of git refs compared on each side of a step change.
+	DefaultStepWindow = 3
+
+	// DefaultStepThreshold is the percentage beyond which a significant change of the
+	// level of a history is reported as a step change.
+	DefaultStepThreshold = 5.0
+)
+
+type (
+	// HistoryFilter selects the cron runs of a microbenchmark read by GetHistoryResults.
+	HistoryFilter struct {
+		PkgName string
+		Name    string
+
+		// Since and Until restrict the date at which the runs started.
+		Since *time.Time
+		Until *time.Time
+
+		// Last is the number of most recent runs, at most MaxHistoryLast. If 0, it
+		// is DefaultHistoryLast, or MaxHistoryLast when Since or Until is set.
+		Last int
+	}
+
+	// HistoryPoint summarizes the results of a benchmark at a git ref.
+	HistoryPoint struct {
+		GitRef string `json:"git_ref"`
+
+		// StartedAt is the date of the first run of the git ref.
+		StartedAt string `json:"started_at"`
+
+		// Commit is the metadata of the git ref, it is nil if unknown.
+		Commit *git.Commit `json:"commit,omitempty"`
+
+		// Runs is the number of samples of the git ref.
+		Runs int `json:"runs"`
+
+		// Metrics are the center and confidence interval of every unit measured.
+		Metrics map[string]macrobench.StatisticalSummary `json:"metrics"`
+
+		samples map[string][]float64
+	}
+
+	// BenchmarkHistory is the history of a benchmark, the oldest git ref first.
+	BenchmarkHistory struct {
+		BenchmarkId
+		Points []HistoryPoint `json:"points"`
+
+		// StepChanges are set by SetStepChanges.
+		StepChanges []StepChange `json:"step_changes"`
+	}
+
+	// StepChange is a lasting change of the level of a BenchmarkHistory, found between
+	// the git refs Before and After.
+	StepChange struct {
+		Before string `json:"before"`
+		After  string `json:"after"`
+		Unit   string `json:"unit"`
+
+		// Statistics compares the samples of the window before the change (Old) with
+		// the samples of the window starting at the change (New).
+		Statistics macrobench.StatisticalResult `json:"statistics"`
+
+		// Regression is true if the change makes the benchmark worse.
+		Regression bool `json:"regression"`
+	}
+)
+
+// limit returns the number of most recent runs read for the filter.
+func (filter HistoryFilter) limit() int {
+	switch {
+	case filter.Last > 0:
+		return min(filter.Last, MaxHistoryLast)
+	case filter.Since != nil || filter.Until != nil:
+		return MaxHistoryLast
+	}
+	return DefaultHistoryLast
+}
+
+// History summarizes the given results by benchmark and git ref using method. The
+// results must be sorted by date, like the ones returned by GetHistoryResults. The
+// units that are zero for every git ref, like B/op without testing.B.ReportAllocs,
+// are left out.
+func History(mbd DetailsArray, method macrobench.ComparisonMethod) []BenchmarkHistory {
+	matchUnknownConfigurations(mbd)
+
+	var histories []BenchmarkHistory
+	indexes := map[BenchmarkId]int{}
+	pointIndexes := map[BenchmarkId]map[string]int{}
+	for _, details := range mbd {
+		i, ok := indexes[details.BenchmarkId]
+		if !ok {
+			i = len(histories)
+			indexes[details.BenchmarkId] = i
+			pointIndexes[details.BenchmarkId] = map[string]int{}
+			histories = append(histories, BenchmarkHistory{BenchmarkId: details.BenchmarkId})
+		}
+		h := &histories[i]
+		j, ok := pointIndexes[details.BenchmarkId][details.GitRef]
+		if !ok {
+			j = len(h.Points)
+			pointIndexes[details.BenchmarkId][details.GitRef] = j
+			h.Points = append(h.Points, HistoryPoint{GitRef: details.GitRef, StartedAt: details.StartedAt, samples: map[string][]float64{}})
+		}
+		p := &h.Points[j]
+		p.Runs++
+		for unit, value := range details.Result.values() {
+			p.samples[unit] = append(p.samples[unit], value)
+		}
+	}
+
+	for i := range histories {
+		h := &histories[i]
+		units := h.units()
+		for j := range h.Points {
+			p := &h.Points[j]
+			p.Metrics = make(map[string]macrobench.StatisticalSummary, len(units))
+			for _, unit := range units {
+				if values, ok := p.samples[unit]; ok {
+					summary := method.Summary(values)
+					if math.IsNaN(summary.Center) {
+						summary.Center = 0
+					}
+					p.Metrics[unit] = summary
+				}
+			}
+		}
+	}
+	sort.SliceStable(histories, func(i, j int) bool {
+		return histories[i].BenchmarkId.less(histories[j].BenchmarkId)
+	})
+	return histories
+}
+
+// units returns the sorted units measured by the benchmark that are not zero for every
+// git ref.
+func (h BenchmarkHistory) units() []string {
+	nonZero := map[string]bool{}
+	for _, p := range h.Points {
+		for unit, values := range p.samples {
+			nonZero[unit] = nonZero[unit] || !allZeros(values)
+		}
+	}
+	var units []string
+	for unit, ok := range nonZero {
+		if ok {
+			units = append(units, unit)
+		}
+	}
+	sort.Strings(units)
+	return units
+}
+
+// SetCommits sets the metadata of the git refs of every history, commits being
+// indexed by SHA like the ones returned by git.GetCommits.
+func SetCommits(histories []BenchmarkHistory, commits map[string]*git.Commit) {
+	for i := range histories {
+		for j := range histories[i].Points {
+			histories[i].Points[j].Commit = commits[histories[i].Points[j].GitRef]
+		}
+	}
+}
+
+// SetStepChanges sets the step changes of the history. For each git ref, the samples
+// of the window git refs preceding it are compared with the samples of the window git
+// refs starting at it using method, a step change is found when they differ significantly
+// by more than threshold percent. Among the step changes of a unit that are less than
+// window git refs apart, only the most significant one is kept: the windows overlapping
+// a step have a similar delta but their samples are less clearly separated.
+func (h *BenchmarkHistory) SetStepChanges(method macrobench.ComparisonMethod, window int, threshold float64) {
+	h.StepChanges = nil
+	if window <= 0 || len(h.Points) < 2*window {
+		return
+	}
+
+	type candidate struct {
+		index int
+		unit  string
+		res   macrobench.StatisticalResult
+	}
+	var candidates []*candidate
+	var family []*macrobench.StatisticalResult
+	for _, unit := range h.units() {
+		for i := window; i+window <= len(h.Points); i++ {
+			before, after := h.windowSamples(unit, i-window, i), h.windowSamples(unit, i, i+window)
+			if len(before) == 0 || len(after) == 0 {
+				continue
+			}
+			cand := &candidate{index: i, unit: unit, res: method.Compare(before, after)}
+			candidates = append(candidates, cand)
+			family = append(family, &cand.res)
+		}
+	}
+	macrobench.AdjustPValues(family, method.Config())
+
+	var significant []*candidate
+	for _, cand := range candidates {
+		if !cand.res.Insignificant && math.Abs(cand.res.Delta) > threshold {
+			significant = append(significant, cand)
+		}
+	}
+	sort.SliceStable(significant, func(i, j int) bool {
+		if significant[i].res.AdjustedP != significant[j].res.AdjustedP {
+			return significant[i].res.AdjustedP < significant[j].res.AdjustedP
+		}
+		return math.Abs(significant[i].res.Delta) > math.Abs(significant[j].res.Delta)
+	})
+	var kept []*candidate
+	for _, cand := range significant {
+		overlaps := false
+		for _, k := range kept {
+			if k.unit == cand.unit && k.index-cand.index < window && cand.index-k.index < window {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			kept = append(kept, cand)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		if kept[i].index != kept[j].index {
+			return kept[i].index < kept[j].index
+		}
+		return kept[i].unit < kept[j].unit
+	})
+
+	for _, cand := range kept {
+		// the delta is the increase of the value, which is a regression unless higher is better
+		regression := cand.res.Delta > 0
+		if HigherIsBetter(cand.unit) {
+			regression = !regression
+		}
+		h.StepChanges = append(h.StepChanges, StepChange{
+			Before:     h.Points[cand.index-1].GitRef,
+			After:      h.Points[cand.index].GitRef,
+			Unit:       cand.unit,
+			Statistics: cand.res,
+			Regression: regression,
+		})
+	}
+}
+
+// windowSamples returns the samples of the unit measured at the git refs from index
+// start to end excluded.
+func (h BenchmarkHistory) windowSamples(unit string, start, end int) []float64 {
+	var values []float64
+	for _, p := range h.Points[start:end] {
+		values = append(values, p.samples[unit]...)
+	}
+	return values
+}
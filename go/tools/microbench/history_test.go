@@ -0,0 +1,162 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package microbench
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vitessio/arewefastyet/go/tools/git"
+	"github.com/vitessio/arewefastyet/go/tools/macrobench"
+)
+
+func TestHistory(t *testing.T) {
+	c := qt.New(t)
+	method, err := macrobench.NewComparisonMethod(macrobench.MethodMannWhitney, macrobench.DefaultStatisticalConfig())
+	c.Assert(err, qt.IsNil)
+
+	parse := BenchmarkId{PkgName: "vitess.io/vitess/go/vt/sqlparser", Name: "BenchmarkParse", SubBenchmarkName: "BenchmarkParse/select", Procs: 8}
+	parseProcs := parse
+	parseProcs.Procs = 16
+	details := func(id BenchmarkId, gitRef, startedAt string, nsPerOp, allocsPerOp float64) Details {
+		return Details{BenchmarkId: id, GitRef: gitRef, StartedAt: startedAt, Result: Result{NSPerOp: nsPerOp, AllocsPerOp: allocsPerOp, Metrics: map[string]float64{"rows/op": 2}}}
+	}
+	mbd := DetailsArray{
+		details(parse, "b", "2024-07-01T00:00:00Z", 100, 10),
+		details(parseProcs, "b", "2024-07-01T00:00:00Z", 60, 10),
+		details(parse, "b", "2024-07-01T00:00:00Z", 110, 10),
+		details(parse, "b", "2024-07-01T00:00:00Z", 120, 10),
+		details(parse, "a", "2024-07-02T00:00:00Z", 200, 0),
+		details(parse, "a", "2024-07-02T00:00:00Z", 220, 0),
+		// a git ref run again is merged with its first run
+		details(parse, "b", "2024-07-03T00:00:00Z", 130, 10),
+	}
+
+	histories := History(mbd, method)
+	c.Assert(histories, qt.HasLen, 2)
+	c.Assert(histories[0].BenchmarkId, qt.Equals, parse)
+	c.Assert(histories[1].BenchmarkId, qt.Equals, parseProcs)
+
+	points := histories[0].Points
+	c.Assert(points, qt.HasLen, 2)
+	c.Assert(points[0].GitRef, qt.Equals, "b")
+	c.Assert(points[0].StartedAt, qt.Equals, "2024-07-01T00:00:00Z")
+	c.Assert(points[0].Runs, qt.Equals, 4)
+	c.Assert(points[1].GitRef, qt.Equals, "a")
+	c.Assert(points[1].Runs, qt.Equals, 2)
+
+	// MB/s and B/op are never measured, allocs/op is measured even when it drops to zero
+	for _, p := range points {
+		c.Assert(p.Metrics, qt.HasLen, 3)
+		for _, unit := range []string{unitNanosecondPerOp, unitAllocsPerOp, "rows/op"} {
+			_, ok := p.Metrics[unit]
+			c.Assert(ok, qt.IsTrue, qt.Commentf("%s at %s", unit, p.GitRef))
+		}
+	}
+	c.Assert(points[0].Metrics[unitNanosecondPerOp].Center, qt.Equals, 115.0)
+	c.Assert(points[0].Metrics[unitNanosecondPerOp].Confidence > 0, qt.IsTrue)
+	c.Assert(points[1].Metrics[unitNanosecondPerOp].Center, qt.Equals, 210.0)
+	c.Assert(points[1].Metrics[unitAllocsPerOp].Center, qt.Equals, 0.0)
+	c.Assert(points[0].Metrics["rows/op"].Center, qt.Equals, 2.0)
+
+	SetCommits(histories, map[string]*git.Commit{"a": {SHA: "a", Subject: "sqlparser: faster parsing"}})
+	c.Assert(histories[0].Points[0].Commit, qt.IsNil)
+	c.Assert(histories[0].Points[1].Commit.Subject, qt.Equals, "sqlparser: faster parsing")
+}
+
+func TestBenchmarkHistory_SetStepChanges(t *testing.T) {
+	c := qt.New(t)
+	method, err := macrobench.NewComparisonMethod(macrobench.MethodMannWhitney, macrobench.DefaultStatisticalConfig())
+	c.Assert(err, qt.IsNil)
+
+	id := BenchmarkId{PkgName: "vitess.io/vitess/go/vt/sqlparser", Name: "BenchmarkParse", SubBenchmarkName: "BenchmarkParse"}
+	// ns/op increases by 30% at g4 and allocs/op halves at g7, MB/s only changes within the noise
+	var mbd DetailsArray
+	for i := 0; i < 10; i++ {
+		nsPerOp, allocsPerOp := 100.0, 10.0
+		if i >= 4 {
+			nsPerOp = 130
+		}
+		if i >= 7 {
+			allocsPerOp = 5
+		}
+		for run := 0; run < 5; run++ {
+			noise := float64(run%3) - 1
+			mbd = append(mbd, Details{
+				BenchmarkId: id,
+				GitRef:      fmt.Sprintf("g%d", i),
+				Result:      Result{NSPerOp: nsPerOp + noise, AllocsPerOp: allocsPerOp + noise/10, MBPerSec: 50 + noise},
+			})
+		}
+	}
+	histories := History(mbd, method)
+	c.Assert(histories, qt.HasLen, 1)
+	h := histories[0]
+
+	h.SetStepChanges(method, DefaultStepWindow, DefaultStepThreshold)
+	c.Assert(h.StepChanges, qt.HasLen, 2)
+	c.Assert(h.StepChanges[0].Before, qt.Equals, "g3")
+	c.Assert(h.StepChanges[0].After, qt.Equals, "g4")
+	c.Assert(h.StepChanges[0].Unit, qt.Equals, unitNanosecondPerOp)
+	c.Assert(h.StepChanges[0].Regression, qt.IsTrue)
+	c.Assert(math.Round(h.StepChanges[0].Statistics.Delta), qt.Equals, 30.0)
+	c.Assert(h.StepChanges[0].Statistics.Old.Center, qt.Equals, 100.0)
+	c.Assert(h.StepChanges[0].Statistics.New.Center, qt.Equals, 130.0)
+	c.Assert(h.StepChanges[1].Before, qt.Equals, "g6")
+	c.Assert(h.StepChanges[1].After, qt.Equals, "g7")
+	c.Assert(h.StepChanges[1].Unit, qt.Equals, unitAllocsPerOp)
+	c.Assert(h.StepChanges[1].Regression, qt.IsFalse)
+
+	// a change below the threshold is not reported
+	h.SetStepChanges(method, DefaultStepWindow, 40)
+	c.Assert(h.StepChanges, qt.HasLen, 1)
+	c.Assert(h.StepChanges[0].Unit, qt.Equals, unitAllocsPerOp)
+
+	// the history is too short for the window
+	h.SetStepChanges(method, 6, DefaultStepThreshold)
+	c.Assert(h.StepChanges, qt.HasLen, 0)
+}
+
+func TestHistoryFilter_limit(t *testing.T) {
+	c := qt.New(t)
+	since := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	c.Assert(HistoryFilter{}.limit(), qt.Equals, DefaultHistoryLast)
+	c.Assert(HistoryFilter{Last: 10}.limit(), qt.Equals, 10)
+	c.Assert(HistoryFilter{Last: 10, Since: &since}.limit(), qt.Equals, 10)
+	c.Assert(HistoryFilter{Since: &since}.limit(), qt.Equals, MaxHistoryLast)
+	c.Assert(HistoryFilter{Until: &since}.limit(), qt.Equals, MaxHistoryLast)
+	c.Assert(HistoryFilter{Last: MaxHistoryLast + 1}.limit(), qt.Equals, MaxHistoryLast)
+}
+
+func TestBenchmarkHistory_json(t *testing.T) {
+	c := qt.New(t)
+	h := BenchmarkHistory{
+		Points:      []HistoryPoint{{GitRef: "a", StartedAt: "2024-07-01T00:00:00Z", Runs: 2}},
+		StepChanges: []StepChange{{Before: "a", After: "b", Unit: "ns/op", Regression: true}},
+	}
+	raw, err := json.Marshal(h)
+	c.Assert(err, qt.IsNil)
+	var got map[string]json.RawMessage
+	c.Assert(json.Unmarshal(raw, &got), qt.IsNil)
+	c.Assert(string(got["points"]), qt.Equals, `[{"git_ref":"a","started_at":"2024-07-01T00:00:00Z","runs":2,"metrics":null}]`)
+	c.Assert(string(got["step_changes"]), qt.Contains, `"before":"a","after":"b","unit":"ns/op"`)
+	c.Assert(string(got["step_changes"]), qt.Contains, `"regression":true`)
+}
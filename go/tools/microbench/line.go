@@ -19,40 +19,63 @@
 package microbench
 
 import (
-	"errors"
+	"bytes"
+	"encoding/json"
 	"fmt"
-	"regexp"
+	"io"
+	"log"
+	"sort"
 	"strconv"
-	"time"
+	"strings"
 
-	"github.com/vitessio/arewefastyet/go/storage"
+	"golang.org/x/perf/benchfmt"
 )
 
 type microType string
 
 const (
-	ErrorLineUnrecognized = "the line format was unrecognized"
-	ErrorLineMalformed    = "the format of the line is malformed"
+	ErrorLineMalformed = "the format of the line is malformed"
 
-	// GeneralBenchmark are results that fits with the generalBenchTypeRegExpr regular expression.
+	// GeneralBenchmark are results in the Go benchmark data format.
 	GeneralBenchmark = microType("general")
 )
 
-// benchmarkResultsRegexp's submatch array contains 7 indexes defined below:
-//
-// submatch[0]:  whole string
-// submatch[1]:  benchmark name
-// submatch[2]:  number of ops (number)
-// submatch[3]:  ns/op (number)
-// submatch[4]:  MB/s (number)
-// submatch[5]:  B/op (number)
-// submatch[6]:  allocs/op (number)
-//
-// https://regex101.com/r/JdCpno/3
-var generalBenchTypeRegExpr = regexp.MustCompile(`(Benchmark.+\b)\s*([0-9]+)\s+([\d\.]+)\s+ns\/op(?:\s*)?(?:([\d\.]+) MB/s+\s*)?(?:([\d\.]+) B/op+\s*)?(?:([0-9]+) allocs/op\s*)?\n`)
-
-var benchTypeRegExprs = map[microType]*regexp.Regexp{
-	GeneralBenchmark: generalBenchTypeRegExpr,
+// Units of the metrics measured by the testing package, the other units are reported
+// with testing.B.ReportMetric.
+const (
+	unitNanosecondPerOp = "ns/op"
+	unitMBs             = "MB/s"
+	unitBytesPerOp      = "B/op"
+	unitAllocsPerOp     = "allocs/op"
+)
+
+// Keys of the configuration lines printed by go test before the results.
+const (
+	configGOOS   = "goos"
+	configGOARCH = "goarch"
+	configCPU    = "cpu"
+	configPkg    = "pkg"
+)
+
+// testEvent is an event of the JSON output of go test, see "go doc test2json".
+type testEvent struct {
+	Action string
+	Output string
+}
+
+// runConfig is the configuration under which the results of a benchmark were measured.
+type runConfig struct {
+	GOOS   string
+	GOARCH string
+	CPU    string
+	Pkg    string
+}
+
+// namePart is a part of a sub-benchmark name: "/key=value", or "/value" for the parts
+// without a key.
+type namePart struct {
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value"`
 }
 
 type lineResult struct {
@@ -61,107 +84,153 @@ type lineResult struct {
 	MBs             float64
 	BytesPerOp      float64
 	AllocsPerOp     float64
+
+	// Metrics are the values of the other units, by unit.
+	Metrics map[string]float64
 }
 
+// lineRun is a single result of a benchmark.
 type lineRun struct {
-	Time    time.Time
-	Action  string
-	Package string
-	Output  string
-	Elapsed string
-
-	id        int64
-	name      string
+	// name is the full name of the benchmark without the GOMAXPROCS suffix.
+	name string
+
+	// procs is the value of GOMAXPROCS, the "-N" suffix of the name. The testing
+	// package omits the suffix when it is 1.
+	procs int
+
+	nameParts []namePart
+	config    runConfig
 	benchType microType
-	submatch  []string
 	results   lineResult
 }
 
-func (line *lineRun) Parse() error {
-	line.applyRegularExpr()
-	if line.benchType != "" {
-		return line.parseSubmatch()
+// benchmarkOutput returns the output of go test from its JSON output, the lines that
+// are not events are ignored.
+func benchmarkOutput(jsonOutput []byte) io.Reader {
+	var output bytes.Buffer
+	for _, line := range bytes.Split(jsonOutput, []byte("\n")) {
+		var event testEvent
+		if err := json.Unmarshal(line, &event); err != nil || event.Action != "output" {
+			continue
+		}
+		output.WriteString(event.Output)
 	}
-	return nil
+	return &output
 }
 
-func (line *lineRun) applyRegularExpr() {
-	var submatch []string
-
-	for benchType, r := range benchTypeRegExprs {
-		submatch = r.FindStringSubmatch(line.Output)
-		if len(submatch) > 0 {
-			line.submatch = submatch
-			line.name = submatch[1]
-			line.benchType = benchType
-			break
+// parseBenchmarkOutput parses the output of go test in the Go benchmark data format
+// and returns its results, each one with the configuration lines preceding it.
+func parseBenchmarkOutput(r io.Reader) ([]lineRun, error) {
+	var lines []lineRun
+	reader := benchfmt.NewReader(r, "")
+	for reader.Scan() {
+		switch record := reader.Result().(type) {
+		case *benchfmt.SyntaxError:
+			// the output of the benchmarks may be interleaved with the result lines
+			log.Printf("%s: line %d: %s\n", ErrorLineMalformed, record.Line, record.Msg)
+		case *benchfmt.Result:
+			line, err := newLineRun(record)
+			if err != nil {
+				return nil, err
+			}
+			lines = append(lines, line)
 		}
 	}
-}
-
-func (line *lineRun) parseSubmatch() error {
-	switch line.benchType {
-	case GeneralBenchmark:
-		return line.parseGeneralBenchmark()
-	default:
-		break
+	if err := reader.Err(); err != nil {
+		return nil, err
 	}
-	return errors.New(ErrorLineUnrecognized)
+	return lines, nil
 }
 
-func getFloatSubMatch(parent, child string) (float64, error) {
-	if len(parent) == 0 {
-		return 0, nil
-	}
-	val, err := strconv.ParseFloat(child, 64)
-	if err != nil {
-		return 0, err
+func newLineRun(res *benchfmt.Result) (lineRun, error) {
+	line := lineRun{
+		name:      "Benchmark" + res.Name.String(),
+		procs:     1,
+		benchType: GeneralBenchmark,
+		config: runConfig{
+			GOOS:   res.GetConfig(configGOOS),
+			GOARCH: res.GetConfig(configGOARCH),
+			CPU:    res.GetConfig(configCPU),
+			Pkg:    res.GetConfig(configPkg),
+		},
+		results: lineResult{Op: res.Iters},
 	}
-	return val, nil
-}
 
-func (line *lineRun) parseGeneralBenchmark() error {
-	if len(line.submatch) != 7 {
-		return fmt.Errorf("%s: expected 7 arguments but got %d", ErrorLineMalformed, len(line.submatch))
+	_, parts := res.Name.Parts()
+	if len(parts) > 0 && parts[len(parts)-1][0] == '-' {
+		procs := parts[len(parts)-1]
+		var err error
+		line.procs, err = strconv.Atoi(string(procs[1:]))
+		if err != nil {
+			return lineRun{}, fmt.Errorf("%s: %s", ErrorLineMalformed, err.Error())
+		}
+		line.name = strings.TrimSuffix(line.name, string(procs))
+		parts = parts[:len(parts)-1]
 	}
-
-	var err error
-
-	// get number of ops
-	line.results.Op, err = strconv.Atoi(line.submatch[2])
-	if err != nil {
-		return fmt.Errorf("%s: %s", ErrorLineMalformed, err.Error())
+	for _, part := range parts {
+		key, value, ok := strings.Cut(string(part[1:]), "=")
+		if !ok {
+			key, value = "", key
+		}
+		line.nameParts = append(line.nameParts, namePart{Key: key, Value: value})
 	}
 
-	// get ns/op
-	line.results.NanosecondPerOp, err = strconv.ParseFloat(line.submatch[3], 64)
-	if err != nil {
-		return fmt.Errorf("%s: %s", ErrorLineMalformed, err.Error())
+	// the values are tidied by benchfmt, e.g. ns/op becomes sec/op, the original ones are kept
+	for _, v := range res.Values {
+		unit, value := v.Unit, v.Value
+		if v.OrigUnit != "" {
+			unit, value = v.OrigUnit, v.OrigValue
+		}
+		switch unit {
+		case unitNanosecondPerOp:
+			line.results.NanosecondPerOp = value
+		case unitMBs:
+			line.results.MBs = value
+		case unitBytesPerOp:
+			line.results.BytesPerOp = value
+		case unitAllocsPerOp:
+			line.results.AllocsPerOp = value
+		default:
+			if line.results.Metrics == nil {
+				line.results.Metrics = map[string]float64{}
+			}
+			line.results.Metrics[unit] = value
+		}
 	}
+	return line, nil
+}
 
-	line.results.MBs, err = getFloatSubMatch(line.submatch[4], line.submatch[4])
-	if err != nil {
-		return fmt.Errorf("%s: %s", ErrorLineMalformed, err.Error())
+// nameConfig returns the parts of the sub-benchmark name as a JSON array, or an
+// empty string if the name has no part.
+func (line *lineRun) nameConfig() string {
+	if len(line.nameParts) == 0 {
+		return ""
 	}
-
-	line.results.BytesPerOp, err = getFloatSubMatch(line.submatch[5], line.submatch[5])
+	b, err := json.Marshal(line.nameParts)
 	if err != nil {
-		return fmt.Errorf("%s: %s", ErrorLineMalformed, err.Error())
+		return ""
 	}
+	return string(b)
+}
 
-	line.results.AllocsPerOp, err = getFloatSubMatch(line.submatch[6], line.submatch[6])
-	if err != nil {
-		return fmt.Errorf("%s: %s", ErrorLineMalformed, err.Error())
-	}
-	return nil
+// detailsRow returns the values of the microbenchmark_details row of this line.
+func (line *lineRun) detailsRow(microBenchID int64) []interface{} {
+	return []interface{}{microBenchID, line.name, line.procs, line.nameConfig(), line.benchType, line.results.Op, line.results.NanosecondPerOp,
+		line.results.MBs, line.results.BytesPerOp, line.results.AllocsPerOp}
 }
 
-func (line *lineRun) InsertToMySQL(microBenchID int64, client storage.SQLClient) error {
-	query := "INSERT INTO microbenchmark_details(microbenchmark_no, name, bench_type, n, ns_per_op, mb_per_sec, bytes_per_op, allocs_per_op) VALUES(?, ?, ?, ?, ?, ?, ?, ?)"
-	res, err := client.Write(query, microBenchID, line.name, line.benchType, line.results.Op, line.results.NanosecondPerOp, line.results.MBs, line.results.BytesPerOp, line.results.AllocsPerOp)
-	if err != nil {
-		return err
+// metricsRows returns the microbenchmark_metrics rows of this line, whose details
+// row has the given id.
+func (line *lineRun) metricsRows(detailsID int64) [][]interface{} {
+	units := make([]string, 0, len(line.results.Metrics))
+	for unit := range line.results.Metrics {
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+
+	rows := make([][]interface{}, 0, len(units))
+	for _, unit := range units {
+		rows = append(rows, []interface{}{detailsID, unit, line.results.Metrics[unit]})
 	}
-	line.id = res
-	return nil
+	return rows
 }
@@ -19,231 +19,103 @@
 package microbench
 
 import (
-	qt "github.com/frankban/quicktest"
+	"reflect"
+	"strings"
 	"testing"
-)
-
-func Test_benchmarkRunLine_applyRegularExpr_checkBenchType(t *testing.T) {
-	tests := []struct {
-		name            string
-		benchTypeWanted microType
-		stringToParse   string
-	}{
-		// regular benchmarks
-		{name: "Valid benchmark with only space (1)", benchTypeWanted: GeneralBenchmark, stringToParse: "BenchmarkEmpty-16 1000000000 0.2439 ns/op\n"},
-		{name: "Valid benchmark with only space (2)", benchTypeWanted: GeneralBenchmark, stringToParse: "BenchmarkEmptySingleT-1 98413 16.9016 ns/op\n"},
-		{name: "Valid benchmark with normal go test output (1)", benchTypeWanted: GeneralBenchmark, stringToParse: "BenchmarkTestRegular-8   \t18983  \t1.2 ns/op\n"},
-		{name: "Valid benchmark with normal go test output (2)", benchTypeWanted: GeneralBenchmark, stringToParse: "BenchmarkEmpty-16 \t101609937 \t   156.3899 ns/op\n"},
-
-		// allocs benchmarks
-		{name: "Valid allocs benchmark with normal go test output (1)", benchTypeWanted: GeneralBenchmark, stringToParse: "BenchmarkTestAllocs-8   \t18983  \t1.2 ns/op \t1292 B/op \t0 allocs/op\n"},
-		{name: "Valid allocs benchmark with normal go test output (2)", benchTypeWanted: GeneralBenchmark, stringToParse: "BenchmarkAllocsSingleT-1 98413 16.9016 ns/op \t0 B/op \t198 allocs/op\n"},
-		{name: "Valid allocs benchmark with normal go test output (3)", benchTypeWanted: GeneralBenchmark, stringToParse: "BenchmarkAllocs-4 \t101609937 \t   156.9 ns/op \t0 B/op \t0 allocs/op\n"},
-		{name: "Valid allocs benchmark with only spaces", benchTypeWanted: GeneralBenchmark, stringToParse: "BenchmarkAllocs-4 101609937 156.9 ns/op 0 B/op 0 allocs/op\n"},
-		{name: "Valid allocs benchmark with only tabs", benchTypeWanted: GeneralBenchmark, stringToParse: "BenchmarkAllocs-32\t2039889\t12.1224 ns/op\t10 B/op\t1 allocs/op\n"},
-		{name: "Valid allocs benchmark with spaces and tabs combined", benchTypeWanted: GeneralBenchmark, stringToParse: "BenchmarkAllocs-16   \t1000000000 0.2439 ns/op  \t90 B/op    \t1 allocs/op\n"},
-
-		// SetBytes benchmarks
-		{name: "Valid bytes benchmark with normal go test output (1)", benchTypeWanted: GeneralBenchmark, stringToParse: "BenchmarkTestBytes-8   \t9821481  \t1.0071 ns/op \t3806098708.20 MB/s\n"},
-		{name: "Valid bytes benchmark with normal go test output (2)", benchTypeWanted: GeneralBenchmark, stringToParse: "BenchmarkBytes-32 \t2039889 \t   152.913 ns/op \t40572898.10 MB/s\n"},
-		{name: "Valid bytes benchmark with spaces and tabs combined (1)", benchTypeWanted: GeneralBenchmark, stringToParse: "BenchmarkBytes-16 1000000000  0.2439 ns/op \t3837911248885.89 MB/s\n"},
-		{name: "Valid bytes benchmark with spaces and tabs combined (2)", benchTypeWanted: GeneralBenchmark, stringToParse: "BenchmarkFunctionBytes-87   189233   161.9016 ns/op    \t39110351.97 MB/s\n"},
-		{name: "Valid bytes benchmark with only spaces", benchTypeWanted: GeneralBenchmark, stringToParse: "BenchmarkBytes-32 2039889 152.913 ns/op 40572898.10 MB/s\n"},
-		{name: "Valid bytes benchmark with only tabs", benchTypeWanted: GeneralBenchmark, stringToParse: "BenchmarkBytes-32\t2039889\t152.913 ns/op\t40572898.10 MB/s\n"},
-
-		// both allocs and SetBytes benchmarks
-		{name: "Valid mixed benchmark with normal go test output (1)", benchTypeWanted: GeneralBenchmark, stringToParse: "BenchmarkBytes-16 1000000000 0.2439 ns/op \t3837911248885.89 MB/s \t90 B/op \t1 allocs/op\n"},
-		{name: "Valid mixed benchmark with normal go test output (2)", benchTypeWanted: GeneralBenchmark, stringToParse: "BenchmarkBytes-32 \t2039889 \t   152.913 ns/op \t40572898.10 MB/s \t0 B/op \t0 allocs/op\n"},
-		{name: "Valid mixed benchmark with spaces and tabs combined (1)", benchTypeWanted: GeneralBenchmark, stringToParse: "BenchmarkBytes-16 1000000000 0.2439 ns/op \t3837911248885.89 MB/s\t1292 B/op \t0 allocs/op\n"},
-		{name: "Valid mixed benchmark with spaces and tabs combined (2)", benchTypeWanted: GeneralBenchmark, stringToParse: "BenchmarkFunctionBytes-87 189233 161.9016 ns/op   \t39110351.97 MB/s\t1292 B/op   \t0 allocs/op\n"},
-		{name: "Valid mixed benchmark with only spaces", benchTypeWanted: GeneralBenchmark, stringToParse: "BenchmarkBytes-32 2039889 152.913 ns/op 40572898.10 MB/s 0 B/op 0 allocs/op\n"},
-		{name: "Valid mixed benchmark with only tabs", benchTypeWanted: GeneralBenchmark, stringToParse: "BenchmarkBytes-32\t2039889\t152.913 ns/op\t40572898.10 MB/s\t0 B/op\t0 allocs/op\n"},
-
-		// wrong benchmarks
-		{name: "Wrong benchmark with extra space (1)", benchTypeWanted: "", stringToParse: "BenchmarkEmpty-16 1000000000 0.2439 \n"},
-		{name: "Wrong benchmark with no new line char (2)", benchTypeWanted: "", stringToParse: "BenchmarkTestRegular-8   \t18983  \t1.2 ns/op"},
-		{name: "Wrong benchmark with noise data (3)", benchTypeWanted: "", stringToParse: "BenchmarkEmptySingleT-1 98413 16.9016 ns/op  data\n"},
-		{name: "Wrong benchmark with missing attribute name (4)", benchTypeWanted: "", stringToParse: "BenchmarkFunctionBytes-16\t2039889\t152.913 ns/op\t40572898.10 \n"},
-		{name: "Wrong benchmark with partially missing attribute name (5)", benchTypeWanted: "", stringToParse: "BenchmarkFunctionBytes-16\t2039889\t152.913 ns/op\t40572898.10 /s\n"},
-		{name: "Wrong benchmark with empty string (6)", benchTypeWanted: "", stringToParse: ""},
-		{name: "Wrong benchmark with empty new line (7)", benchTypeWanted: "", stringToParse: "\n"},
-		{name: "Wrong benchmark with unrelated content (8)", benchTypeWanted: "", stringToParse: "Not a benchmark type\n"},
-		{name: "Wrong benchmark with wrong benchmark name prefix (9)", benchTypeWanted: "", stringToParse: "BenchEmpty-16 \t101609937 \t   156.3899 ns/op\n"},            // Should start with "Benchmark"
-		{name: "Wrong benchmark with misspelled attribute name (10)", benchTypeWanted: "", stringToParse: "BenchmarkAllocs-4 101609937 156.9 ns/op 0 B/op 0 alloc/op\n"}, // Should be "allocs" not "alloc"
-		{name: "Wrong benchmark with missing chars (11)", benchTypeWanted: "", stringToParse: "BenchmarkAllocs-4 101609937 156.9ns/op 0 B /op 0 allocs/op\n"},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			c := qt.New(t)
-			line := &lineRun{Output: tt.stringToParse}
-			line.applyRegularExpr()
-			gotBenchType := line.benchType
-			c.Assert(gotBenchType, qt.Equals, tt.benchTypeWanted)
-		})
-	}
-}
-
-func BenchmarkApplyRegularExprRegularBenchmark(b *testing.B) {
-	b.ReportAllocs()
-	line := &lineRun{Output: "BenchmarkEmpty-16 1000000000 0.2439 ns/op\n"}
-
-	for i := 0; i < b.N; i++ {
-		line.applyRegularExpr()
-		if line.benchType != GeneralBenchmark {
-			b.Errorf("Benchmark failed, got %v, want %v", line.benchType, GeneralBenchmark)
-		}
-	}
-}
-
-func BenchmarkApplyRegularExprAllocsBenchmark(b *testing.B) {
-	b.ReportAllocs()
-	line := &lineRun{Output: "BenchmarkAllocs-16 1000000000 0.2439 ns/op \t90 B/op \t1 allocs/op\n"}
-
-	for i := 0; i < b.N; i++ {
-		line.applyRegularExpr()
-		if line.benchType != GeneralBenchmark {
-			b.Errorf("Benchmark failed, got %v, want %v", line.benchType, GeneralBenchmark)
-		}
-	}
-}
-
-func BenchmarkApplyRegularExprBytesBenchmark(b *testing.B) {
-	b.ReportAllocs()
-	line := &lineRun{Output: "BenchmarkBytes-16 1000000000 0.2439 ns/op \t3837911248885.89 MB/s\n"}
-
-	for i := 0; i < b.N; i++ {
-		line.applyRegularExpr()
-		if line.benchType != GeneralBenchmark {
-			b.Errorf("Benchmark failed, got %v, want %v", line.benchType, GeneralBenchmark)
-		}
-	}
-}
-
-func BenchmarkApplyRegularExprMixedBenchmark(b *testing.B) {
-	b.ReportAllocs()
-	line := &lineRun{Output: "BenchmarkBytes-16 1000000000 0.2439 ns/op \t3837911248885.89 MB/s \t90 B/op \t1 allocs/op\n"}
-
-	for i := 0; i < b.N; i++ {
-		line.applyRegularExpr()
-		if line.benchType != GeneralBenchmark {
-			b.Errorf("Benchmark failed, got %v, want %v", line.benchType, GeneralBenchmark)
-		}
-	}
-}
 
-func Test_benchmarkRunLine_parseGeneralBenchmarkInvalidSubmatchLen(t *testing.T) {
-	tests := []struct {
-		name        string
-		submatch    []string
-		wantErr     bool
-	}{
-		{name: "submatch length 0", submatch: []string{}, wantErr: true},
-		{name: "submatch length 1", submatch: []string{""}, wantErr: true},
-		{name: "submatch length 2", submatch: []string{"", ""}, wantErr: true},
-		{name: "submatch length 3", submatch: []string{"", "", ""}, wantErr: true},
-		{name: "submatch length 4", submatch: []string{"", "", "", ""}, wantErr: true},
-		{name: "submatch length 5", submatch: []string{"", "", "", "", ""}, wantErr: true},
-		{name: "submatch length 6", submatch: []string{"", "", "", "", "", ""}, wantErr: true},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			c := qt.New(t)
-			line := &lineRun{submatch: tt.submatch}
-			err := line.parseGeneralBenchmark()
-			c.Assert(err != nil, qt.Equals, tt.wantErr)
-		})
-	}
-}
+	qt "github.com/frankban/quicktest"
+)
 
-func Test_benchmarkRunLine_parseGeneralBenchmark(t *testing.T) {
+const testBenchmarkOutput = `goos: linux
+goarch: amd64
+pkg: vitess.io/vitess/go/vt/vtgate/planbuilder
+cpu: AMD EPYC 7B13
+BenchmarkEmpty-16    	1000000000	         0.2439 ns/op
+BenchmarkAllocs-16   	   19836	       178.2396 ns/op	   40489 B/op	     190 allocs/op
+PASS
+ok  	vitess.io/vitess/go/vt/vtgate/planbuilder	2.130s
+`
+
+func Test_parseBenchmarkOutput(t *testing.T) {
+	config := runConfig{GOOS: "linux", GOARCH: "amd64", CPU: "AMD EPYC 7B13", Pkg: "vitess.io/vitess/go/vt/vtgate/planbuilder"}
 	tests := []struct {
-		name        string
-		submatch    []string
-		wantErr     bool
-		wantResults lineResult
+		name   string
+		output string
+		want   []lineRun
 	}{
-		{name: "regular benchmark (1)", submatch: []string{"BenchmarkEmpty-16 1000000000 0.2439 ns/op", "BenchmarkEmpty-16", "1000000000", "0.2439", "", "", ""}, wantResults: lineResult{Op: 1000000000, NanosecondPerOp: 0.2439}},
-		{name: "regular benchmark (2)", submatch: []string{"BenchmarkEmpty-16 19836 178.2396 ns/op", "BenchmarkEmpty-16", "19836", "178.2396", "", "", ""}, wantResults: lineResult{Op: 19836, NanosecondPerOp: 178.2396}},
-
-		{name: "allocs benchmark (1)", submatch: []string{"BenchmarkAllocs-16 1000000000 0.2439 ns/op \t90 B/op \t1 allocs/op\n", "BenchmarkAllocs-16", "1000000000", "0.2439", "", "90", "1"}, wantResults: lineResult{Op: 1000000000, NanosecondPerOp: 0.2439, BytesPerOp: 90, AllocsPerOp: 1}},
-		{name: "allocs benchmark (2)", submatch: []string{"BenchmarkAllocs-16 19836 178.2396 ns/op \t40489 B/op \t190 allocs/op\n", "BenchmarkAllocs-16", "19836", "178.2396", "", "40489", "190"}, wantResults: lineResult{Op: 19836, NanosecondPerOp: 178.2396, BytesPerOp: 40489, AllocsPerOp: 190}},
-
-		{name: "bytes benchmark (1)", submatch: []string{"BenchmarkBytes-16 1000000000 0.2439 ns/op \t3837911248885.89 MB/s\n", "BenchmarkBytes-16", "1000000000", "0.2439", "3837911248885.89", "", ""}, wantResults: lineResult{Op: 1000000000, NanosecondPerOp: 0.2439, MBs: 3837911248885.89}},
-		{name: "bytes benchmark (2)", submatch: []string{"BenchmarkBytes-16 19836 178.2396 ns/op \t985291124.89213 MB/s\n", "BenchmarkBytes-16", "19836", "178.2396", "985291124.89213", "", ""}, wantResults: lineResult{Op: 19836, NanosecondPerOp: 178.2396, MBs: 985291124.89213}},
-
-		{name: "mixed benchmark (1)", submatch: []string{"BenchmarkBytes-16 1000000000 0.2439 ns/op \t3837911248885.89 MB/s\n", "BenchmarkBytes-16", "1000000000", "0.2439", "3837911248885.89", "95", "2"}, wantResults: lineResult{Op: 1000000000, NanosecondPerOp: 0.2439, MBs: 3837911248885.89, BytesPerOp: 95, AllocsPerOp: 2}},
-		{name: "mixed benchmark (2)", submatch: []string{"BenchmarkBytes-16 19836 178.2396 ns/op \t985291124.89213 MB/s\n", "BenchmarkBytes-16", "19836", "178.2396", "985291124.89213", "173", "9"}, wantResults: lineResult{Op: 19836, NanosecondPerOp: 178.2396, MBs: 985291124.89213, BytesPerOp: 173, AllocsPerOp: 9}},
-
-		{name: "invalid number of ops", submatch: []string{"BenchmarkAllocs-16 wrong 0.2439 ns/op \t90 B/op \t1 allocs/op\n", "BenchmarkAllocs-16", "wrong", "0.2439", "", "90", "1"}, wantErr: true},
-		{name: "invalid ns/op", submatch: []string{"BenchmarkAllocs-16 19836 178.wrong ns/op \t40489 B/op \t190 allocs/op\n", "BenchmarkAllocs-16", "19836", "178.wrong", "", "40489", "190"}, wantErr: true},
-		{name: "invalid MB/s", submatch: []string{"BenchmarkBytes-16 19836 178.2396 ns/op \twrong.89213 MB/s\n", "BenchmarkBytes-16", "19836", "178.2396", "wrong.89213", "MB"}, wantErr: true},
-		{name: "invalid bytes/op", submatch: []string{"BenchmarkAllocs-16 1000000000 0.2439 ns/op \twrong B/op \t1 allocs/op\n", "BenchmarkAllocs-16", "1000000000", "0.2439", "", "wrong", "1"}, wantErr: true},
-		{name: "invalid allocs/op", submatch: []string{"BenchmarkAllocs-16 19836 178.2396 ns/op \t40489 B/op \twrong allocs/op\n", "BenchmarkAllocs-16", "19836", "178.2396", "", "40489", "wrong"}, wantErr: true},
+		{name: "regular and allocs benchmarks", output: testBenchmarkOutput, want: []lineRun{
+			{name: "BenchmarkEmpty", procs: 16, config: config, benchType: GeneralBenchmark, results: lineResult{Op: 1000000000, NanosecondPerOp: 0.2439}},
+			{name: "BenchmarkAllocs", procs: 16, config: config, benchType: GeneralBenchmark, results: lineResult{Op: 19836, NanosecondPerOp: 178.2396, BytesPerOp: 40489, AllocsPerOp: 190}},
+		}},
+		{name: "bytes and custom metrics", output: "BenchmarkBytes-8 100 1.5 ns/op 200.5 MB/s 12 rows/op 1.5e+03 plans/s\n", want: []lineRun{
+			{name: "BenchmarkBytes", procs: 8, benchType: GeneralBenchmark, results: lineResult{Op: 100, NanosecondPerOp: 1.5, MBs: 200.5, Metrics: map[string]float64{"rows/op": 12, "plans/s": 1500}}},
+		}},
+		{name: "sub-benchmark name parts", output: "BenchmarkPlan/planner=gen4/Select-4 100 12 ns/op\n", want: []lineRun{
+			{name: "BenchmarkPlan/planner=gen4/Select", procs: 4, nameParts: []namePart{{Key: "planner", Value: "gen4"}, {Value: "Select"}}, benchType: GeneralBenchmark, results: lineResult{Op: 100, NanosecondPerOp: 12}},
+		}},
+		{name: "GOMAXPROCS of 1", output: "BenchmarkPlan/rows=10 100 12 ns/op\n", want: []lineRun{
+			{name: "BenchmarkPlan/rows=10", procs: 1, nameParts: []namePart{{Key: "rows", Value: "10"}}, benchType: GeneralBenchmark, results: lineResult{Op: 100, NanosecondPerOp: 12}},
+		}},
+		{name: "verbose output", output: "=== RUN   BenchmarkPlan\nBenchmarkPlan\nBenchmarkPlan/rows=10\nBenchmarkPlan/rows=10-2   \t     100\t        12 ns/op\n--- BENCH: BenchmarkPlan\n", want: []lineRun{
+			{name: "BenchmarkPlan/rows=10", procs: 2, nameParts: []namePart{{Key: "rows", Value: "10"}}, benchType: GeneralBenchmark, results: lineResult{Op: 100, NanosecondPerOp: 12}},
+		}},
+		{name: "configuration changes", output: "cpu: first\nBenchmarkA-2 1 1 ns/op\ncpu: second\nBenchmarkA-2 1 2 ns/op\ncpu:\nBenchmarkA-2 1 3 ns/op\n", want: []lineRun{
+			{name: "BenchmarkA", procs: 2, config: runConfig{CPU: "first"}, benchType: GeneralBenchmark, results: lineResult{Op: 1, NanosecondPerOp: 1}},
+			{name: "BenchmarkA", procs: 2, config: runConfig{CPU: "second"}, benchType: GeneralBenchmark, results: lineResult{Op: 1, NanosecondPerOp: 2}},
+			{name: "BenchmarkA", procs: 2, benchType: GeneralBenchmark, results: lineResult{Op: 1, NanosecondPerOp: 3}},
+		}},
+		{name: "no benchmark", output: "BenchEmpty-16 \t101609937 \t   156.3899 ns/op\nNot a benchmark\n\nPASS\n"},
+		{name: "missing unit", output: "BenchmarkAllocs-4 101609937 156.9 ns/op 0\n"},
+		{name: "invalid iteration count", output: "BenchmarkAllocs-4 wrong 156.9 ns/op\n"},
+		{name: "invalid measurement", output: "goos: linux\nBenchmarkAllocs-4 100 156.wrong ns/op\n"},
+		{name: "malformed line between results", output: "BenchmarkA-2 1 1 ns/op\nBenchmarkA-2 junk printed by the benchmark\nBenchmarkA-2 1 2 ns/op\n", want: []lineRun{
+			{name: "BenchmarkA", procs: 2, benchType: GeneralBenchmark, results: lineResult{Op: 1, NanosecondPerOp: 1}},
+			{name: "BenchmarkA", procs: 2, benchType: GeneralBenchmark, results: lineResult{Op: 1, NanosecondPerOp: 2}},
+		}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c := qt.New(t)
-			line := &lineRun{submatch: tt.submatch}
-			err := line.parseGeneralBenchmark()
-
-			c.Assert(err != nil, qt.Equals, tt.wantErr)
-			if tt.wantErr {
-				return
-			}
-
-			gotResults := line.results
-
-			c.Assert(gotResults.Op, qt.Equals, tt.wantResults.Op)
-			c.Assert(gotResults.NanosecondPerOp, qt.Equals, tt.wantResults.NanosecondPerOp)
-			c.Assert(gotResults.MBs, qt.Equals, tt.wantResults.MBs)
-			c.Assert(gotResults.BytesPerOp, qt.Equals, tt.wantResults.BytesPerOp)
-			c.Assert(gotResults.AllocsPerOp, qt.Equals, tt.wantResults.AllocsPerOp)
+			got, err := parseBenchmarkOutput(strings.NewReader(tt.output))
+			c.Assert(err, qt.IsNil)
+			c.Assert(reflect.DeepEqual(got, tt.want), qt.IsTrue, qt.Commentf("got %+v\nwant %+v", got, tt.want))
 		})
 	}
 }
 
-func BenchmarkParseSimpleGeneralBenchmark(b *testing.B) {
-	var err error
-	line := lineRun{submatch: []string{"BenchmarkEmpty-16 1000000000 0.2439 ns/op", "BenchmarkEmpty-16", "1000000000", "0.2439", "", "", ""}}
-
-	for i := 0; i < b.N; i++ {
-		err = line.parseGeneralBenchmark()
-		if err != nil {
-			b.Errorf("Got an error: %v", err)
-		}
+func Test_benchmarkOutput(t *testing.T) {
+	c := qt.New(t)
+	jsonOutput := `{"Action":"start","Package":"vitess.io/vitess/go/sqltypes"}
+{"Action":"output","Package":"vitess.io/vitess/go/sqltypes","Output":"goos: linux\n"}
+{"Action":"output","Package":"vitess.io/vitess/go/sqltypes","Output":"BenchmarkParse-8   \t"}
+{"Action":"output","Package":"vitess.io/vitess/go/sqltypes","Output":"     100\t        12 ns/op\n"}
+not an event
+{"Action":"pass","Package":"vitess.io/vitess/go/sqltypes","Elapsed":1.2}
+`
+	got, err := parseBenchmarkOutput(benchmarkOutput([]byte(jsonOutput)))
+	c.Assert(err, qt.IsNil)
+	want := []lineRun{
+		{name: "BenchmarkParse", procs: 8, config: runConfig{GOOS: "linux"}, benchType: GeneralBenchmark, results: lineResult{Op: 100, NanosecondPerOp: 12}},
 	}
+	c.Assert(reflect.DeepEqual(got, want), qt.IsTrue, qt.Commentf("got %+v\nwant %+v", got, want))
 }
 
-func BenchmarkParseAllocsGeneralBenchmark(b *testing.B) {
-	var err error
-	line := lineRun{submatch: []string{"BenchmarkAllocs-16 1000000000 0.2439 ns/op \t90 B/op \t1 allocs/op\n", "BenchmarkAllocs-16", "1000000000", "0.2439", "", "90", "1"}}
-
-	for i := 0; i < b.N; i++ {
-		err = line.parseGeneralBenchmark()
-		if err != nil {
-			b.Errorf("Got an error: %v", err)
-		}
-	}
+func Test_lineRun_rows(t *testing.T) {
+	c := qt.New(t)
+	lines, err := parseBenchmarkOutput(strings.NewReader("BenchmarkRows/planner=gen4/Select-8 18983 1.2 ns/op 12.00 rows/op 90 B/op 1 allocs/op\nBenchmarkEmpty 10 1 ns/op\n"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(lines, qt.HasLen, 2)
+
+	c.Assert(lines[0].detailsRow(42), qt.DeepEquals, []interface{}{int64(42), "BenchmarkRows/planner=gen4/Select", 8, `[{"key":"planner","value":"gen4"},{"value":"Select"}]`,
+		GeneralBenchmark, 18983, 1.2, 0.0, 90.0, 1.0})
+	c.Assert(lines[0].metricsRows(7), qt.DeepEquals, [][]interface{}{{int64(7), "rows/op", 12.0}})
+	c.Assert(lines[1].detailsRow(42), qt.DeepEquals, []interface{}{int64(42), "BenchmarkEmpty", 1, "", GeneralBenchmark, 10, 1.0, 0.0, 0.0, 0.0})
+	c.Assert(lines[1].metricsRows(8), qt.HasLen, 0)
 }
 
-func BenchmarkParseBytesGeneralBenchmark(b *testing.B) {
-	var err error
-	line := lineRun{submatch: []string{"BenchmarkBytes-16 1000000000 0.2439 ns/op \t3837911248885.89 MB/s\n", "BenchmarkBytes-16", "1000000000", "0.2439", "3837911248885.89", "", ""}}
-
-	for i := 0; i < b.N; i++ {
-		err = line.parseGeneralBenchmark()
-		if err != nil {
-			b.Errorf("Got an error: %v", err)
-		}
-	}
-}
-
-func BenchmarkParseMixedGeneralBenchmark(b *testing.B) {
-	var err error
-	line := lineRun{submatch: []string{"BenchmarkBytes-16 1000000000 0.2439 ns/op \t3837911248885.89 MB/s\n", "BenchmarkBytes-16", "1000000000", "0.2439", "3837911248885.89", "645", "14"}}
-
+func BenchmarkParseBenchmarkOutput(b *testing.B) {
 	for i := 0; i < b.N; i++ {
-		err = line.parseGeneralBenchmark()
+		_, err := parseBenchmarkOutput(strings.NewReader(testBenchmarkOutput))
 		if err != nil {
 			b.Errorf("Got an error: %v", err)
 		}
@@ -17,17 +17,21 @@ limitations under the License.
 package microbench
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"go/types"
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/spf13/viper"
 	"github.com/vitessio/arewefastyet/go/storage"
-	"github.com/vitessio/arewefastyet/go/storage/psdb"
 	"github.com/vitessio/arewefastyet/go/tools/git"
 	"go.uber.org/multierr"
 	"golang.org/x/tools/go/packages"
@@ -36,9 +40,12 @@ import (
 const (
 	errorInvalidProfileType    = "invalid profile type"
 	errorInvalidPackageParsing = "invalid package parsing"
+	errorDetailsRowsMismatch   = "the inserted details rows do not match the results"
+	errorProfileTooLarge       = "profile too large to be stored"
 
-	profileCPU = "cpu"
-	profileMem = "mem"
+	// maxProfileSize is the size of the largest profile that fits in the MEDIUMBLOB
+	// column of microbenchmark_profiles, it is below the default max_allowed_packet.
+	maxProfileSize = 1<<24 - 1
 )
 
 type benchmark struct {
@@ -46,14 +53,22 @@ type benchmark struct {
 	filePath         string
 	name             string
 	pkgPath, pkgName string
-	sql              *psdb.Client
 	gitHash          string
 	execUUID         string
+
+	// config is the configuration printed by go test when executing the benchmark.
+	config runConfig
+
+	// lines are the results of the benchmark, once executed.
+	lines []lineRun
+
+	// profiles are the profiles of the benchmark by type, as written by go test.
+	profiles map[string][]byte
 }
 
 func (b *benchmark) registerToMySQL(client storage.SQLClient) error {
-	query := "INSERT INTO microbenchmark(exec_uuid, pkg_name, name, git_ref) VALUES(NULLIF(?, ''), ?, ?, ?)"
-	res, err := client.Write(query, b.execUUID, b.pkgName, b.name, b.gitHash)
+	query := "INSERT INTO microbenchmark(exec_uuid, pkg_name, name, git_ref, goos, goarch, cpu, pkg) VALUES(NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?)"
+	res, err := client.Write(query, b.execUUID, b.pkgName, b.name, b.gitHash, b.config.GOOS, b.config.GOARCH, b.config.CPU, b.config.Pkg)
 	if err != nil {
 		return err
 	}
@@ -61,70 +76,252 @@ func (b *benchmark) registerToMySQL(client storage.SQLClient) error {
 	return nil
 }
 
-func (b *benchmark) execute(rootDir string, w *os.File) error {
-	command := exec.Command("go", "test", "-bench=^"+b.name+"$", "-run==", "-json", "-count=10", b.pkgPath)
-	command.Dir = rootDir
-	out, err := command.Output()
+// insertBenchmarksToMySQL stores the results of all the given benchmarks at once,
+// if one of them cannot be stored none of them are. The metrics reported with
+// testing.B.ReportMetric are stored in microbenchmark_metrics, one row per unit
+// referencing the details row of their line.
+func insertBenchmarksToMySQL(client storage.SQLClient, benchmarks []benchmark) error {
+	query := "INSERT INTO microbenchmark_details(microbenchmark_no, name, procs, name_config, bench_type, n, ns_per_op, mb_per_sec, bytes_per_op, allocs_per_op) VALUES"
+	metricsQuery := "INSERT INTO microbenchmark_metrics(microbenchmark_details_id, unit, value) VALUES"
+	return client.WithTx(context.Background(), func(tx storage.SQLClient) error {
+		var rows [][]interface{}
+		var lines []*lineRun
+		var hasMetrics bool
+		for i := range benchmarks {
+			b := &benchmarks[i]
+			if err := b.registerToMySQL(tx); err != nil {
+				return err
+			}
+			for j := range b.lines {
+				line := &b.lines[j]
+				rows = append(rows, line.detailsRow(b.id))
+				lines = append(lines, line)
+				hasMetrics = hasMetrics || len(line.results.Metrics) > 0
+			}
+		}
+		if err := storage.BulkInsert(context.Background(), tx, query, rows); err != nil {
+			return err
+		}
+
+		if hasMetrics {
+			detailsIDs, err := insertedDetailsIDs(tx, benchmarks)
+			if err != nil {
+				return err
+			}
+			if len(detailsIDs) != len(lines) {
+				return fmt.Errorf("%s: %d rows for %d lines", errorDetailsRowsMismatch, len(detailsIDs), len(lines))
+			}
+			var metricsRows [][]interface{}
+			for i, line := range lines {
+				metricsRows = append(metricsRows, line.metricsRows(detailsIDs[i])...)
+			}
+			if err := storage.BulkInsert(context.Background(), tx, metricsQuery, metricsRows); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
 
+// insertProfilesToMySQL stores the profiles of the given benchmarks, once their results
+// are stored. They are inserted one by one as they can be large, the ones that do not
+// fit in the profile column or cannot be stored are skipped, the results are still useful
+// without them.
+func insertProfilesToMySQL(client storage.SQLClient, benchmarks []benchmark) {
+	for _, b := range benchmarks {
+		profileTypes := make([]string, 0, len(b.profiles))
+		for profileType := range b.profiles {
+			profileTypes = append(profileTypes, profileType)
+		}
+		sort.Strings(profileTypes)
+
+		for _, profileType := range profileTypes {
+			profile := b.profiles[profileType]
+			if len(profile) > maxProfileSize {
+				log.Printf("%s: %s profile of %s.%s is %d bytes\n", errorProfileTooLarge, profileType, b.pkgPath, b.name, len(profile))
+				continue
+			}
+			_, err := client.Write("INSERT INTO microbenchmark_profiles(microbenchmark_no, profile_type, profile) VALUES(?, ?, ?)", b.id, profileType, profile)
+			if err != nil {
+				// not stopping on error
+				log.Println(err.Error())
+			}
+		}
+	}
+}
+
+// insertedDetailsIDs returns the ids of the microbenchmark_details rows of the given
+// benchmarks, in the order they were inserted.
+func insertedDetailsIDs(client storage.SQLClient, benchmarks []benchmark) ([]int64, error) {
+	args := make([]interface{}, 0, len(benchmarks))
+	for _, b := range benchmarks {
+		args = append(args, b.id)
+	}
+	rows, err := client.Read("SELECT id FROM microbenchmark_details WHERE microbenchmark_no IN ("+
+		strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")+") ORDER BY id", args...)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer rows.Close()
 
-	if b.sql != nil {
-		if err := b.registerToMySQL(b.sql); err != nil {
-			return err
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
 		}
+		ids = append(ids, id)
 	}
+	return ids, rows.Err()
+}
 
-	lines := strings.Split(string(out), "\n")
-	for _, line := range lines {
-		var benchLine lineRun
-		err := json.Unmarshal([]byte(line), &benchLine)
-		if err != nil || benchLine.Output == "" {
-			continue
-		}
+// goTest is a go test command executing benchmarks of a single package.
+type goTest struct {
+	pkgPath    string
+	benchmarks []*benchmark
+	count      int
+	benchtime  string
+	timeout    time.Duration
+	cpu        []int
+}
 
-		err = benchLine.Parse()
-		if err != nil {
-			return err
+// newGoTests groups the benchmarks in as few go test commands as possible: one
+// per package, and one per benchmark matching an override.
+func newGoTests(cfg Config, benchmarks []benchmark) []goTest {
+	var tests []goTest
+	perPackage := map[string]int{}
+	for i := range benchmarks {
+		b := &benchmarks[i]
+		test := goTest{pkgPath: b.pkgPath, count: cfg.Count, benchtime: cfg.Benchtime, timeout: cfg.Timeout, cpu: cfg.CPU}
+		if test.count == 0 {
+			test.count = DefaultCount
 		}
 
-		if benchLine.benchType != "" {
-			log.Printf("%s - %s %f ns/op\n", b.pkgName, benchLine.name, benchLine.results.NanosecondPerOp)
-			fmt.Fprintf(w, "%s - %s %f ns/op\n", b.pkgName, benchLine.name, benchLine.results.NanosecondPerOp)
-			if b.sql != nil {
-				err = benchLine.InsertToMySQL(b.id, b.sql)
-				if err != nil {
-					return err
+		overridden := false
+		for _, o := range cfg.Overrides {
+			if o.matches(b.pkgPath, b.name) {
+				overridden = true
+				if o.Count != 0 {
+					test.count = o.Count
+				}
+				if o.Benchtime != "" {
+					test.benchtime = o.Benchtime
 				}
+				if o.Timeout != 0 {
+					test.timeout = o.Timeout
+				}
+				break
+			}
+		}
+		if !overridden {
+			if idx, ok := perPackage[b.pkgPath]; ok {
+				tests[idx].benchmarks = append(tests[idx].benchmarks, b)
+				continue
 			}
+			perPackage[b.pkgPath] = len(tests)
 		}
+		test.benchmarks = []*benchmark{b}
+		tests = append(tests, test)
 	}
-	return nil
+	return tests
+}
+
+func (t goTest) args() []string {
+	names := make([]string, 0, len(t.benchmarks))
+	for _, b := range t.benchmarks {
+		names = append(names, b.name)
+	}
+	args := []string{"test", "-bench=^(" + strings.Join(names, "|") + ")$", "-run=^$", "-json", "-count=" + strconv.Itoa(t.count)}
+	if t.benchtime != "" {
+		args = append(args, "-benchtime="+t.benchtime)
+	}
+	if t.timeout != 0 {
+		args = append(args, "-timeout="+t.timeout.String())
+	}
+	if len(t.cpu) > 0 {
+		cpu := make([]string, 0, len(t.cpu))
+		for _, n := range t.cpu {
+			cpu = append(cpu, strconv.Itoa(n))
+		}
+		args = append(args, "-cpu="+strings.Join(cpu, ","))
+	}
+	return append(args, t.pkgPath)
+}
+
+// execute runs the go test command and attaches the results to their benchmark.
+// If the command fails, for instance because it timed out, the results printed
+// before the failure are still attached and the error is returned.
+func (t goTest) execute(rootDir string, w *os.File) error {
+	command := exec.Command("go", t.args()...)
+	command.Dir = rootDir
+	out, runErr := command.Output()
+
+	lines, err := parseBenchmarkOutput(benchmarkOutput(out))
+	if err != nil {
+		return multierr.Append(runErr, err)
+	}
+
+	benchmarks := make(map[string]*benchmark, len(t.benchmarks))
+	for _, b := range t.benchmarks {
+		benchmarks[b.name] = b
+	}
+	for _, benchLine := range lines {
+		name, _, _ := strings.Cut(benchLine.name, "/")
+		b, ok := benchmarks[name]
+		if !ok {
+			continue
+		}
+		log.Printf("%s - %s-%d %f ns/op\n", b.pkgName, benchLine.name, benchLine.procs, benchLine.results.NanosecondPerOp)
+		fmt.Fprintf(w, "%s - %s-%d %f ns/op\n", b.pkgName, benchLine.name, benchLine.procs, benchLine.results.NanosecondPerOp)
+		b.config = benchLine.config
+		b.lines = append(b.lines, benchLine)
+	}
+	return runErr
 }
 
-func (b benchmark) executeProfile(rootDir, profileType string, w *os.File) error {
-	if profileType != profileCPU && profileType != profileMem {
+// profileArgs returns the arguments of the go test command writing the profile of the given
+// type of a benchmark of the command, run once with the parameters of the command.
+func (t goTest) profileArgs(b *benchmark, profileType, profileName string) []string {
+	t.benchmarks = []*benchmark{b}
+	t.count = 1
+	return append(t.args(), fmt.Sprintf("-%sprofile=%s", profileType, profileName))
+}
+
+// executeProfile profiles a benchmark of the command and keeps the profile to store it
+// along with the results. The profile is also written in rootDir.
+func (t goTest) executeProfile(b *benchmark, rootDir, profileType string, w *os.File) error {
+	if profileType != ProfileCPU && profileType != ProfileMem {
 		return errors.New(errorInvalidProfileType)
 	}
-	profileName := fmt.Sprintf("%sprof_%s.%s.out", profileType, b.pkgName, b.name)
-	command := exec.Command("go", "test", "-bench=^"+b.name+"$", "-run==", "-count=1", b.pkgPath, fmt.Sprintf("-%sprofile=%s", profileType, profileName))
+	profileName := ProfileFileName(profileType, b.pkgName, b.name)
+	command := exec.Command("go", t.profileArgs(b, profileType, profileName)...)
 	command.Dir = rootDir
 
 	_, err := command.Output()
 	if err != nil {
 		return err
 	}
-	log.Printf("CPU profile generated %s\n", profileName)
-	fmt.Fprintf(w, "CPU profile generated %s\n", profileName)
+	profile, err := os.ReadFile(filepath.Join(rootDir, profileName))
+	if err != nil {
+		return err
+	}
+	if b.profiles == nil {
+		b.profiles = map[string][]byte{}
+	}
+	b.profiles[profileType] = profile
+	log.Printf("%s profile generated %s\n", profileType, profileName)
+	fmt.Fprintf(w, "%s profile generated %s\n", profileType, profileName)
 	return nil
 }
 
 // Run runs "go test bench" on the given package (pkg) and outputs
 // the results to outputPath.
-// Profiling files will be written to the current working directory.
+// Only the benchmarks selected by the include and exclude patterns of cfg are run,
+// with a single go test command per package except for the overridden benchmarks.
+// Profiling files are written to the root directory and stored along with the results.
 func Run(cfg Config) error {
-	var sqlClient *psdb.Client
+	var sqlClient storage.Client
 	var err error
 
 	if cfg.DatabaseConfig != nil && cfg.DatabaseConfig.IsValid() {
@@ -148,45 +345,98 @@ func Run(cfg Config) error {
 	if err != nil {
 		return fmt.Errorf("%s:\n%s\n", errorInvalidPackageParsing, err)
 	}
-
-	w, err := os.Create(cfg.Output)
+	benchmarks, err = selectBenchmarks(cfg, benchmarks)
 	if err != nil {
 		return err
 	}
-	defer w.Close()
-
-	for _, benchmark := range benchmarks {
-		hash, err := git.GetCommitHash(cfg.RootDir)
+	if cfg.Overrides == nil {
+		cfg.Overrides, err = LoadOverrides(viper.GetViper())
 		if err != nil {
 			return err
 		}
-		benchmark.gitHash = hash
-		benchmark.sql = sqlClient
-		benchmark.execUUID = cfg.execUUID
+	}
+
+	hash, err := git.GetCommitHash(cfg.RootDir)
+	if err != nil {
+		return err
+	}
+
+	w, err := os.Create(cfg.Output)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
 
-		log.Println(benchmark.pkgPath)
+	for i := range benchmarks {
+		benchmarks[i].gitHash = hash
+		benchmarks[i].execUUID = cfg.execUUID
+	}
+	for _, test := range newGoTests(cfg, benchmarks) {
+		log.Println(test.pkgPath)
 
-		err = benchmark.execute(cfg.RootDir, w)
+		err = test.execute(cfg.RootDir, w)
 		if err != nil {
 			// not stopping execution on error
 			log.Println(err.Error())
 		}
 
 		if cfg.runProfile {
-			benchmark.runProfiles(cfg, w)
+			test.runProfiles(cfg.RootDir, w)
 		}
 		log.Println()
 	}
+
+	var executed []benchmark
+	for _, benchmark := range benchmarks {
+		if len(benchmark.lines) > 0 {
+			executed = append(executed, benchmark)
+		}
+	}
+	if sqlClient != nil {
+		if err := insertBenchmarksToMySQL(sqlClient, executed); err != nil {
+			return err
+		}
+		insertProfilesToMySQL(sqlClient, executed)
+	}
 	return nil
 }
 
-func (b benchmark) runProfiles(cfg Config, w *os.File) {
-	profiles := []string{profileMem, profileCPU}
-	for _, profile := range profiles {
-		err := b.executeProfile(cfg.RootDir, profile, w)
-		if err != nil && err.Error() != errorInvalidProfileType {
-			// not stopping execution on error
-			log.Println(err.Error())
+// selectBenchmarks returns the benchmarks whose package and name are selected by cfg.
+func selectBenchmarks(cfg Config, benchmarks []benchmark) ([]benchmark, error) {
+	var only map[string]bool
+	if len(cfg.Packages) > 0 {
+		only = make(map[string]bool, len(cfg.Packages))
+		for _, pkg := range cfg.Packages {
+			only[pkg] = true
+		}
+	}
+	packages, err := newFilter(cfg.IncludePackages, cfg.ExcludePackages)
+	if err != nil {
+		return nil, err
+	}
+	names, err := newFilter(cfg.IncludeBenchmarks, cfg.ExcludeBenchmarks)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []benchmark
+	for _, b := range benchmarks {
+		if (only == nil || only[b.pkgPath]) && packages.matches(b.pkgPath) && names.matches(b.name) {
+			selected = append(selected, b)
+		}
+	}
+	return selected, nil
+}
+
+func (t goTest) runProfiles(rootDir string, w *os.File) {
+	profiles := []string{ProfileMem, ProfileCPU}
+	for _, b := range t.benchmarks {
+		for _, profile := range profiles {
+			err := t.executeProfile(b, rootDir, profile, w)
+			if err != nil && err.Error() != errorInvalidProfileType {
+				// not stopping execution on error
+				log.Println(err.Error())
+			}
 		}
 	}
 }
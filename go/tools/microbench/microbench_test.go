@@ -19,8 +19,10 @@
 package microbench
 
 import (
-	qt "github.com/frankban/quicktest"
 	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
 )
 
 func TestMicroBenchmark(t *testing.T) {
@@ -48,3 +50,67 @@ func TestMicroBenchmark(t *testing.T) {
 		})
 	}
 }
+
+func TestNewGoTests(t *testing.T) {
+	c := qt.New(t)
+	overrides := []Override{
+		{Name: "^BenchmarkSlow$", Count: 2, Timeout: time.Hour},
+		{Package: "pkg2", Name: "Benchmark", Benchtime: "100x"},
+	}
+	for i := range overrides {
+		c.Assert(overrides[i].compile(), qt.IsNil)
+	}
+	cfg := Config{Benchtime: "2s", Timeout: 20 * time.Minute, CPU: []int{1, 8}, Overrides: overrides}
+	benchmarks := []benchmark{
+		{pkgPath: "pkg1", name: "BenchmarkA"},
+		{pkgPath: "pkg1", name: "BenchmarkSlow"},
+		{pkgPath: "pkg2", name: "BenchmarkC"},
+		{pkgPath: "pkg1", name: "BenchmarkB"},
+	}
+
+	tests := newGoTests(cfg, benchmarks)
+	var args [][]string
+	for _, test := range tests {
+		args = append(args, test.args())
+	}
+	c.Assert(args, qt.DeepEquals, [][]string{
+		{"test", "-bench=^(BenchmarkA|BenchmarkB)$", "-run=^$", "-json", "-count=10", "-benchtime=2s", "-timeout=20m0s", "-cpu=1,8", "pkg1"},
+		{"test", "-bench=^(BenchmarkSlow)$", "-run=^$", "-json", "-count=2", "-benchtime=2s", "-timeout=1h0m0s", "-cpu=1,8", "pkg1"},
+		{"test", "-bench=^(BenchmarkC)$", "-run=^$", "-json", "-count=10", "-benchtime=100x", "-timeout=20m0s", "-cpu=1,8", "pkg2"},
+	})
+	c.Assert(tests[0].benchmarks[1], qt.Equals, &benchmarks[3])
+	c.Assert(tests[1].profileArgs(tests[1].benchmarks[0], ProfileCPU, "cpu.out"), qt.DeepEquals, []string{
+		"test", "-bench=^(BenchmarkSlow)$", "-run=^$", "-json", "-count=1", "-benchtime=2s", "-timeout=1h0m0s", "-cpu=1,8", "pkg1", "-cpuprofile=cpu.out",
+	})
+
+	defaults := newGoTests(Config{Count: 3}, benchmarks[:1])[0].args()
+	c.Assert(defaults, qt.DeepEquals, []string{"test", "-bench=^(BenchmarkA)$", "-run=^$", "-json", "-count=3", "pkg1"})
+}
+
+func TestSelectBenchmarks(t *testing.T) {
+	c := qt.New(t)
+	benchmarks := []benchmark{
+		{pkgPath: "vitess.io/vitess/go/vt/sqlparser", name: "BenchmarkParse"},
+		{pkgPath: "vitess.io/vitess/go/vt/sqlparser", name: "BenchmarkNormalize"},
+		{pkgPath: "vitess.io/vitess/go/test/endtoend", name: "BenchmarkParse"},
+		{pkgPath: "vitess.io/vitess/go/vt/vtgate", name: "BenchmarkPlan"},
+	}
+	cfg := Config{
+		IncludePackages:   []string{"/vt/"},
+		ExcludeBenchmarks: []string{"Normalize"},
+	}
+	got, err := selectBenchmarks(cfg, benchmarks)
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.HasLen, 2)
+	c.Assert(got[0].pkgPath+"."+got[0].name, qt.Equals, "vitess.io/vitess/go/vt/sqlparser.BenchmarkParse")
+	c.Assert(got[1].pkgPath+"."+got[1].name, qt.Equals, "vitess.io/vitess/go/vt/vtgate.BenchmarkPlan")
+
+	cfg.Packages = []string{"vitess.io/vitess/go/vt/vtgate", "vitess.io/vitess/go/test/endtoend"}
+	got, err = selectBenchmarks(cfg, benchmarks)
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.HasLen, 1)
+	c.Assert(got[0].pkgPath+"."+got[0].name, qt.Equals, "vitess.io/vitess/go/vt/vtgate.BenchmarkPlan")
+
+	_, err = selectBenchmarks(Config{ExcludePackages: []string{"("}}, benchmarks)
+	c.Assert(err, qt.ErrorMatches, ErrorInvalidPattern+": .*")
+}
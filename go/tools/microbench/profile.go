@@ -0,0 +1,221 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package microbench
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/google/pprof/profile"
+	"github.com/vitessio/arewefastyet/go/storage"
+)
+
+const (
+	ErrorProfileNotFound      = "profile not found"
+	ErrorInvalidProfile       = "invalid profile"
+	ErrorUnknownProfileType   = "unknown profile type"
+	ErrorUnknownSampleType    = "unknown sample type"
+	ErrorProfileMissingSample = "profile has no sample type"
+
+	// ProfileCPU is the type of the profiles written by go test -cpuprofile.
+	ProfileCPU = "cpu"
+
+	// ProfileMem is the type of the profiles written by go test -memprofile.
+	ProfileMem = "mem"
+
+	// DefaultProfileDiffTop is the number of functions of a ProfileDiff by default.
+	DefaultProfileDiffTop = 10
+)
+
+// DefaultSampleType returns the sample type compared by default for the given type of
+// profile: the CPU time, or the bytes allocated during the benchmark.
+func DefaultSampleType(profileType string) (string, error) {
+	switch profileType {
+	case ProfileCPU:
+		return "cpu", nil
+	case ProfileMem:
+		return "alloc_space", nil
+	}
+	return "", fmt.Errorf("%s: %s", ErrorUnknownProfileType, profileType)
+}
+
+// ProfileFileName returns the name of the file in which go test writes the profile
+// of the given type for a benchmark.
+func ProfileFileName(profileType, pkgName, name string) string {
+	return fmt.Sprintf("%sprof_%s.%s.out", profileType, pkgName, name)
+}
+
+// GetProfile returns the profile of the given type of a benchmark at a git ref, as written
+// by go test. If the benchmark was profiled by several finished executions, the latest
+// profile is returned.
+func GetProfile(client storage.SQLClient, gitRef, pkgName, name, profileType string) ([]byte, error) {
+	result, err := client.Read("SELECT p.profile FROM execution e, microbenchmark m, microbenchmark_profiles p WHERE m.git_ref = ? AND m.pkg_name = ? AND m.name = ?"+
+		" AND p.profile_type = ? AND p.microbenchmark_no = m.microbenchmark_no AND e.uuid = m.exec_uuid AND e.status = \"finished\" ORDER BY m.microbenchmark_no DESC LIMIT 1",
+		gitRef, pkgName, name, profileType)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	if !result.Next() {
+		if err = result.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: %s %s.%s at %s", ErrorProfileNotFound, profileType, pkgName, name, gitRef)
+	}
+	var profile []byte
+	err = result.Scan(&profile)
+	if err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// ProfileDiff lists the functions whose share of a sample type changed the most between
+// the old and the new profile of a benchmark.
+type ProfileDiff struct {
+	SampleType string `json:"sample_type"`
+	Unit       string `json:"unit"`
+
+	// OldTotal and NewTotal are the sum of the values of all the samples of each profile.
+	OldTotal int64 `json:"old_total"`
+	NewTotal int64 `json:"new_total"`
+
+	// Functions are sorted by decreasing absolute Delta.
+	Functions []FunctionDelta `json:"functions"`
+}
+
+// FunctionDelta is the change of the flat value of a function, the value of the samples
+// in which the function is on top of the stack, between two profiles.
+type FunctionDelta struct {
+	Function string `json:"function"`
+	Old      int64  `json:"old"`
+	New      int64  `json:"new"`
+
+	// OldPercent and NewPercent are Old and New in percent of the total of their profile,
+	// so profiles of runs with different numbers of iterations can be compared.
+	OldPercent float64 `json:"old_percent"`
+	NewPercent float64 `json:"new_percent"`
+
+	// Delta is the difference between NewPercent and OldPercent, in percentage points.
+	Delta float64 `json:"delta"`
+}
+
+// DiffProfiles compares the flat values of the functions in the given sample type of
+// two profiles, as written by go test, and returns the top functions whose share
+// changed the most. All the functions are returned if top is not positive.
+func DiffProfiles(oldProfile, newProfile []byte, sampleType string, top int) (*ProfileDiff, error) {
+	oldP, err := parseProfile(oldProfile)
+	if err != nil {
+		return nil, err
+	}
+	newP, err := parseProfile(newProfile)
+	if err != nil {
+		return nil, err
+	}
+	oldFlat, oldTotal, unit, err := flatProfile(oldP, sampleType)
+	if err != nil {
+		return nil, err
+	}
+	newFlat, newTotal, _, err := flatProfile(newP, sampleType)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &ProfileDiff{SampleType: sampleType, Unit: unit, OldTotal: oldTotal, NewTotal: newTotal, Functions: []FunctionDelta{}}
+	functions := map[string]bool{}
+	for function := range oldFlat {
+		functions[function] = true
+	}
+	for function := range newFlat {
+		functions[function] = true
+	}
+	for function := range functions {
+		fd := FunctionDelta{
+			Function:   function,
+			Old:        oldFlat[function],
+			New:        newFlat[function],
+			OldPercent: percentOf(oldFlat[function], oldTotal),
+			NewPercent: percentOf(newFlat[function], newTotal),
+		}
+		fd.Delta = fd.NewPercent - fd.OldPercent
+		diff.Functions = append(diff.Functions, fd)
+	}
+	sort.Slice(diff.Functions, func(i, j int) bool {
+		di, dj := math.Abs(diff.Functions[i].Delta), math.Abs(diff.Functions[j].Delta)
+		if di != dj {
+			return di > dj
+		}
+		return diff.Functions[i].Function < diff.Functions[j].Function
+	})
+	if top > 0 && len(diff.Functions) > top {
+		diff.Functions = diff.Functions[:top]
+	}
+	return diff, nil
+}
+
+func percentOf(value, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(value) / float64(total) * 100
+}
+
+// parseProfile decodes a pprof profile as written by go test, compressed with gzip or not.
+func parseProfile(data []byte) (*profile.Profile, error) {
+	p, err := profile.ParseData(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", ErrorInvalidProfile, err)
+	}
+	if len(p.SampleType) == 0 {
+		return nil, errors.New(ErrorProfileMissingSample)
+	}
+	return p, nil
+}
+
+// flatProfile returns the sum of the values of the given sample type of the samples in
+// which each function is on top of the stack, the sum of the values of all the samples,
+// and the unit of the values. The function on top of an inlined stack is the innermost.
+func flatProfile(p *profile.Profile, sampleType string) (flat map[string]int64, total int64, unit string, err error) {
+	idx := -1
+	for i, st := range p.SampleType {
+		if st.Type == sampleType {
+			idx = i
+			unit = st.Unit
+			break
+		}
+	}
+	if idx == -1 {
+		return nil, 0, "", fmt.Errorf("%s: %s", ErrorUnknownSampleType, sampleType)
+	}
+
+	flat = map[string]int64{}
+	for _, sample := range p.Sample {
+		if idx >= len(sample.Value) {
+			return nil, 0, "", fmt.Errorf("%s: sample without %s value", ErrorInvalidProfile, sampleType)
+		}
+		value := sample.Value[idx]
+		total += value
+		if len(sample.Location) == 0 || len(sample.Location[0].Line) == 0 || sample.Location[0].Line[0].Function == nil {
+			continue
+		}
+		flat[sample.Location[0].Line[0].Function.Name] += value
+	}
+	return flat, total, unit, nil
+}
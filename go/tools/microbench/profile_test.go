@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package microbench
+
+import (
+	"bytes"
+	"runtime/pprof"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/pprof/profile"
+)
+
+// testProfile describes a CPU profile whose stacks are lists of function names,
+// the function on top of the stack first. Functions named "a+b" are a location
+// at which a is inlined into b.
+type testProfile map[string]int64
+
+// encode returns the gzipped protobuf encoding of the profile, the same as go test writes.
+func (tp testProfile) encode(c *qt.C, stacks map[string][]string) []byte {
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "samples", Unit: "count"}, {Type: "cpu", Unit: "nanoseconds"}},
+	}
+	functions := map[string]*profile.Function{}
+	locations := map[string]*profile.Location{}
+	for stack, value := range tp {
+		sample := &profile.Sample{Value: []int64{1, value}}
+		for _, name := range stacks[stack] {
+			location, ok := locations[name]
+			if !ok {
+				location = &profile.Location{ID: uint64(len(locations) + 1)}
+				locations[name] = location
+				p.Location = append(p.Location, location)
+				for _, fname := range strings.Split(name, "+") {
+					function, ok := functions[fname]
+					if !ok {
+						function = &profile.Function{ID: uint64(len(functions) + 1), Name: fname}
+						functions[fname] = function
+						p.Function = append(p.Function, function)
+					}
+					location.Line = append(location.Line, profile.Line{Function: function})
+				}
+			}
+			sample.Location = append(sample.Location, location)
+		}
+		p.Sample = append(p.Sample, sample)
+	}
+
+	var buf bytes.Buffer
+	c.Assert(p.Write(&buf), qt.IsNil)
+	return buf.Bytes()
+}
+
+func TestDiffProfiles(t *testing.T) {
+	c := qt.New(t)
+	stacks := map[string][]string{
+		"parse":     {"sqlparser.Parse", "main.Benchmark"},
+		"normalize": {"sqlparser.Normalize+sqlparser.Rewrite", "main.Benchmark"},
+		"rewrite":   {"sqlparser.Rewrite", "main.Benchmark"},
+		"gc":        {"runtime.gcBgMarkWorker"},
+		"empty":     {},
+	}
+	oldProfile := testProfile{"parse": 600, "normalize": 200, "rewrite": 100, "gc": 100}.encode(c, stacks)
+	newProfile := testProfile{"parse": 1200, "normalize": 500, "gc": 200, "empty": 100}.encode(c, stacks)
+
+	diff, err := DiffProfiles(oldProfile, newProfile, "cpu", 0)
+	c.Assert(err, qt.IsNil)
+	c.Assert(diff.SampleType, qt.Equals, "cpu")
+	c.Assert(diff.Unit, qt.Equals, "nanoseconds")
+	c.Assert(diff.OldTotal, qt.Equals, int64(1000))
+	c.Assert(diff.NewTotal, qt.Equals, int64(2000))
+	c.Assert(diff.Functions, qt.DeepEquals, []FunctionDelta{
+		{Function: "sqlparser.Rewrite", Old: 100, New: 0, OldPercent: 10, NewPercent: 0, Delta: -10},
+		{Function: "sqlparser.Normalize", Old: 200, New: 500, OldPercent: 20, NewPercent: 25, Delta: 5},
+		{Function: "runtime.gcBgMarkWorker", Old: 100, New: 200, OldPercent: 10, NewPercent: 10, Delta: 0},
+		{Function: "sqlparser.Parse", Old: 600, New: 1200, OldPercent: 60, NewPercent: 60, Delta: 0},
+	})
+
+	diff, err = DiffProfiles(oldProfile, newProfile, "cpu", 1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(diff.Functions, qt.HasLen, 1)
+	c.Assert(diff.Functions[0].Function, qt.Equals, "sqlparser.Rewrite")
+
+	_, err = DiffProfiles(oldProfile, newProfile, "alloc_space", 0)
+	c.Assert(err, qt.ErrorMatches, ErrorUnknownSampleType+": alloc_space")
+
+	_, err = DiffProfiles(oldProfile, []byte("not a profile"), "cpu", 0)
+	c.Assert(err, qt.ErrorMatches, ErrorInvalidProfile+": .*")
+}
+
+func TestParseProfile_runtime(t *testing.T) {
+	c := qt.New(t)
+	var buf bytes.Buffer
+	c.Assert(pprof.Lookup("allocs").WriteTo(&buf, 0), qt.IsNil)
+
+	p, err := parseProfile(buf.Bytes())
+	c.Assert(err, qt.IsNil)
+	sampleType, err := DefaultSampleType(ProfileMem)
+	c.Assert(err, qt.IsNil)
+	flat, total, unit, err := flatProfile(p, sampleType)
+	c.Assert(err, qt.IsNil)
+	c.Assert(unit, qt.Equals, "bytes")
+	c.Assert(total > 0, qt.IsTrue)
+	c.Assert(len(flat) > 0, qt.IsTrue)
+}
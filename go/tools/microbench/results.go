@@ -20,15 +20,19 @@ package microbench
 
 import (
 	"fmt"
+	gomath "math"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 
 	"github.com/vitessio/arewefastyet/go/storage"
 
 	"github.com/dustin/go-humanize"
+	"github.com/vitessio/arewefastyet/go/tools/macrobench"
 	"github.com/vitessio/arewefastyet/go/tools/math"
 )
 
@@ -40,13 +44,27 @@ type (
 		MBPerSec    float64
 		BytesPerOp  float64
 		AllocsPerOp float64
+
+		// Metrics are the other metrics, reported with testing.B.ReportMetric, by unit.
+		Metrics map[string]float64
 	}
 
 	// BenchmarkId represents the identification of a microbenchmark.
+	// The results measured with a different GOMAXPROCS or on a different
+	// platform have a different BenchmarkId.
 	BenchmarkId struct {
-		PkgName          string
-		Name             string
+		PkgName string
+		Name    string
+
+		// SubBenchmarkName is the full name of the benchmark, without
+		// the GOMAXPROCS suffix.
 		SubBenchmarkName string
+
+		// Procs is the value of GOMAXPROCS, it is 0 if unknown.
+		Procs  int
+		GOOS   string
+		GOARCH string
+		CPU    string
 	}
 
 	// Details refers to a single microbenchmark.
@@ -63,8 +81,17 @@ type (
 		BenchmarkId
 		Right, Left Result
 
-		// Difference between Right and Left.
+		// Difference between Right and Left, in percent of Right. It is positive when
+		// Right is better than Left, see HigherIsBetter.
 		Diff Result
+
+		// Statistics are the comparisons of all the samples of Left (old) and Right (new),
+		// by unit. Their Delta is the change from Left to Right in percent of Left.
+		Statistics map[string]macrobench.StatisticalResult
+
+		// Regressions are the units of the Statistics that regressed beyond the threshold
+		// the comparison was made with, see SetRegressions.
+		Regressions []string
 	}
 
 	DetailsArray    []Details
@@ -90,6 +117,65 @@ func NewBenchmarkId(pkgName string, name string, subBenchmarkName string) *Bench
 	}
 }
 
+// FullName returns the name of the benchmark as printed by go test, with
+// the GOMAXPROCS suffix.
+func (id BenchmarkId) FullName() string {
+	if id.Procs <= 1 {
+		return id.SubBenchmarkName
+	}
+	return id.SubBenchmarkName + "-" + strconv.Itoa(id.Procs)
+}
+
+// matchUnknownConfigurations sets the configuration of the Details stored before
+// it was recorded, whose Procs is 0 and whose SubBenchmarkName still has the
+// GOMAXPROCS suffix, to the one of the other Details of the same benchmark. It is
+// left unknown when the benchmark was measured with several configurations.
+func matchUnknownConfigurations(mbds ...DetailsArray) {
+	type key struct{ pkgName, name, fullName string }
+	known := map[key][]BenchmarkId{}
+	for _, mbd := range mbds {
+		for _, details := range mbd {
+			if details.Procs == 0 {
+				continue
+			}
+			k := key{details.PkgName, details.Name, details.FullName()}
+			if !slices.Contains(known[k], details.BenchmarkId) {
+				known[k] = append(known[k], details.BenchmarkId)
+			}
+		}
+	}
+	for _, mbd := range mbds {
+		for i := range mbd {
+			if mbd[i].Procs != 0 {
+				continue
+			}
+			ids := known[key{mbd[i].PkgName, mbd[i].Name, mbd[i].SubBenchmarkName}]
+			if len(ids) == 1 {
+				mbd[i].BenchmarkId = ids[0]
+			}
+		}
+	}
+}
+
+// less orders the BenchmarkIds by package, name and then configuration.
+func (id BenchmarkId) less(other BenchmarkId) bool {
+	switch {
+	case id.PkgName != other.PkgName:
+		return id.PkgName < other.PkgName
+	case id.Name != other.Name:
+		return id.Name < other.Name
+	case id.SubBenchmarkName != other.SubBenchmarkName:
+		return id.SubBenchmarkName < other.SubBenchmarkName
+	case id.Procs != other.Procs:
+		return id.Procs < other.Procs
+	case id.GOOS != other.GOOS:
+		return id.GOOS < other.GOOS
+	case id.GOARCH != other.GOARCH:
+		return id.GOARCH < other.GOARCH
+	}
+	return id.CPU < other.CPU
+}
+
 // NewResult creates a new Result.
 func NewResult(ops, NSPerOp, MBPerSec, BytesPerOp, AllocsPerOp float64) *Result {
 	return &Result{
@@ -101,8 +187,57 @@ func NewResult(ops, NSPerOp, MBPerSec, BytesPerOp, AllocsPerOp float64) *Result
 	}
 }
 
+var (
+	unitDirectionsMu sync.RWMutex
+
+	// unitDirections maps the units whose direction is known to true if a higher
+	// value is better. It is consulted before falling back to the suffix of the unit.
+	unitDirections = map[string]bool{
+		unitNanosecondPerOp: false,
+		unitMBs:             true,
+		unitBytesPerOp:      false,
+		unitAllocsPerOp:     false,
+		"sec/op":            false,
+		"B/s":               true,
+		"ops/s":             true,
+		"hit-ratio":         true,
+	}
+)
+
+// setHigherIsBetter registers the direction of the given unit, overriding the default
+// one, see LoadUnitDirections.
+func setHigherIsBetter(unit string, higherIsBetter bool) {
+	unitDirectionsMu.Lock()
+	defer unitDirectionsMu.Unlock()
+	unitDirections[unit] = higherIsBetter
+}
+
+// HigherIsBetter returns true if a higher value of the metric measured in the given unit
+// is better. The direction registered for the unit is used when there is one, otherwise
+// the rates, such as plans/s, are better when higher, while the costs per operation,
+// such as rows/op, are better when lower.
+func HigherIsBetter(unit string) bool {
+	unitDirectionsMu.RLock()
+	higherIsBetter, ok := unitDirections[unit]
+	unitDirectionsMu.RUnlock()
+	if ok {
+		return higherIsBetter
+	}
+	return strings.HasSuffix(unit, "/s")
+}
+
+// diffPercent returns the difference between right and left in percent of right, it is
+// positive when right is better than left.
+func diffPercent(right, left float64, higherIsBetter bool) float64 {
+	if higherIsBetter {
+		return (right - left) / right * 100
+	}
+	return (right - left) / right * -100
+}
+
 // MergeDetails merges two DetailsArray into a single ComparisonArray.
 func MergeDetails(rightMbd, leftMbd DetailsArray) (compareMbs ComparisonArray) {
+	matchUnknownConfigurations(rightMbd, leftMbd)
 	for _, details := range rightMbd {
 		compareMb := Comparison{
 			BenchmarkId: details.BenchmarkId,
@@ -111,12 +246,13 @@ func MergeDetails(rightMbd, leftMbd DetailsArray) (compareMbs ComparisonArray) {
 		for j := 0; j < len(leftMbd); j++ {
 			if leftMbd[j].BenchmarkId == details.BenchmarkId {
 				compareMb.Left = leftMbd[j].Result
-				compareMb.Diff.NSPerOp = (compareMb.Right.NSPerOp - compareMb.Left.NSPerOp) / compareMb.Right.NSPerOp * -100
-				compareMb.Diff.Ops = (compareMb.Right.Ops - compareMb.Left.Ops) / compareMb.Right.Ops * 100
-				compareMb.Diff.BytesPerOp = (compareMb.Right.BytesPerOp - compareMb.Left.BytesPerOp) / compareMb.Right.BytesPerOp * -100
-				compareMb.Diff.MBPerSec = (compareMb.Right.MBPerSec - compareMb.Left.MBPerSec) / compareMb.Right.MBPerSec * -100
-				compareMb.Diff.AllocsPerOp = (compareMb.Right.AllocsPerOp - compareMb.Left.AllocsPerOp) / compareMb.Right.AllocsPerOp * -100
+				compareMb.Diff.NSPerOp = diffPercent(compareMb.Right.NSPerOp, compareMb.Left.NSPerOp, false)
+				compareMb.Diff.Ops = diffPercent(compareMb.Right.Ops, compareMb.Left.Ops, true)
+				compareMb.Diff.BytesPerOp = diffPercent(compareMb.Right.BytesPerOp, compareMb.Left.BytesPerOp, false)
+				compareMb.Diff.MBPerSec = diffPercent(compareMb.Right.MBPerSec, compareMb.Left.MBPerSec, HigherIsBetter(unitMBs))
+				compareMb.Diff.AllocsPerOp = diffPercent(compareMb.Right.AllocsPerOp, compareMb.Left.AllocsPerOp, false)
 				math.CheckForNaN(&compareMb.Diff, 0)
+				compareMb.Diff.Metrics = diffMetrics(compareMb.Right.Metrics, compareMb.Left.Metrics)
 				break
 			}
 		}
@@ -130,23 +266,38 @@ func MergeDetails(rightMbd, leftMbd DetailsArray) (compareMbs ComparisonArray) {
 	return compareMbs
 }
 
+// diffMetrics returns the difference of the metrics measured in both right and left.
+func diffMetrics(right, left map[string]float64) map[string]float64 {
+	var diff map[string]float64
+	for unit, rightValue := range right {
+		leftValue, ok := left[unit]
+		if !ok {
+			continue
+		}
+		d := diffPercent(rightValue, leftValue, HigherIsBetter(unit))
+		if gomath.IsNaN(d) || gomath.IsInf(d, 0) {
+			d = 0
+		}
+		if diff == nil {
+			diff = map[string]float64{}
+		}
+		diff[unit] = d
+	}
+	return diff
+}
+
 // ReduceSimpleMedianByName reduces a DetailsArray by merging
 // all Details with the same benchmark name into a single
 // one. The results of each Details correspond to the median
-// of the merged elements.
+// of the merged elements. Details measured with a different
+// GOMAXPROCS or CPU are never merged.
 func (mbd DetailsArray) ReduceSimpleMedianByName() (reduceMbd DetailsArray) {
 	sort.SliceStable(mbd, func(i, j int) bool {
-		if mbd[i].PkgName == mbd[j].PkgName {
-			if mbd[i].Name == mbd[j].Name {
-				return mbd[i].SubBenchmarkName < mbd[j].SubBenchmarkName
-			}
-			return mbd[i].Name < mbd[j].Name
-		}
-		return mbd[i].PkgName < mbd[j].PkgName
+		return mbd[i].BenchmarkId.less(mbd[j].BenchmarkId)
 	})
 
 	reduceMbd = mbd.mergeUsingCondition(func(i, j int) bool {
-		return mbd[i].Name == mbd[j].Name && mbd[i].PkgName == mbd[j].PkgName && mbd[i].SubBenchmarkName == mbd[j].SubBenchmarkName
+		return mbd[i].BenchmarkId == mbd[j].BenchmarkId
 	})
 	return reduceMbd
 }
@@ -189,12 +340,19 @@ func (mbd DetailsArray) mergeUsingCondition(compareCondition func(i, j int) bool
 		var interMBPerSec []float64
 		var interBytesPerOp []float64
 		var interAllocsPerOp []float64
+		var interMetrics map[string][]float64
 		for j = i; j < len(mbd) && compareCondition(i, j); j++ {
 			interOps = append(interOps, mbd[j].Result.Ops)
 			interNSPerOp = append(interNSPerOp, mbd[j].Result.NSPerOp)
 			interMBPerSec = append(interMBPerSec, mbd[j].Result.MBPerSec)
 			interBytesPerOp = append(interBytesPerOp, mbd[j].Result.BytesPerOp)
 			interAllocsPerOp = append(interAllocsPerOp, mbd[j].Result.AllocsPerOp)
+			for unit, value := range mbd[j].Result.Metrics {
+				if interMetrics == nil {
+					interMetrics = map[string][]float64{}
+				}
+				interMetrics[unit] = append(interMetrics[unit], value)
+			}
 		}
 
 		interOpsResult := math.MedianFloat(interOps)
@@ -202,7 +360,14 @@ func (mbd DetailsArray) mergeUsingCondition(compareCondition func(i, j int) bool
 		interMBPerSecResult := math.MedianFloat(interMBPerSec)
 		interBytesPerOpResult := math.MedianFloat(interBytesPerOp)
 		interAllocsPerOpResult := math.MedianFloat(interAllocsPerOp)
-		reduceMbd = append(reduceMbd, *NewDetails(*NewBenchmarkId(mbd[i].PkgName, mbd[i].Name, mbd[i].SubBenchmarkName), mbd[i].GitRef, mbd[i].StartedAt, *NewResult(interOpsResult, interNSPerOpResult, interMBPerSecResult, interBytesPerOpResult, interAllocsPerOpResult)))
+		result := NewResult(interOpsResult, interNSPerOpResult, interMBPerSecResult, interBytesPerOpResult, interAllocsPerOpResult)
+		if len(interMetrics) > 0 {
+			result.Metrics = make(map[string]float64, len(interMetrics))
+		}
+		for unit, values := range interMetrics {
+			result.Metrics[unit] = math.MedianFloat(values)
+		}
+		reduceMbd = append(reduceMbd, *NewDetails(mbd[i].BenchmarkId, mbd[i].GitRef, mbd[i].StartedAt, *result))
 		i = j
 	}
 	return reduceMbd
@@ -211,52 +376,151 @@ func (mbd DetailsArray) mergeUsingCondition(compareCondition func(i, j int) bool
 // GetResultsForGitRef will fetch and return a DetailsArray
 // containing all the Details linked to the given git commit SHA.
 func GetResultsForGitRef(ref string, client storage.SQLClient) (mrs DetailsArray, err error) {
-	result, err := client.Read("select m.pkg_name, m.name, md.name, md.n, md.ns_per_op, md.bytes_per_op,"+
+	result, err := client.Read("select md.id, m.pkg_name, m.name, md.name, md.procs, m.goos, m.goarch, m.cpu, md.n, md.ns_per_op, md.bytes_per_op,"+
 		" md.allocs_per_op, md.mb_per_sec FROM execution e, microbenchmark m, microbenchmark_details md where m.git_ref = ? AND "+
-		"md.microbenchmark_no = m.microbenchmark_no and e.uuid = m.exec_uuid and e.status = \"finished\" order by m.microbenchmark_no desc", ref)
+		"md.microbenchmark_no = m.microbenchmark_no and e.uuid = m.exec_uuid and e.status = \"finished\" order by m.microbenchmark_no desc, md.id", ref)
 	if err != nil {
 		return nil, err
 	}
 	defer result.Close()
 
+	var detailsIDs []int64
 	for result.Next() {
 		var res Details
+		var detailsID int64
 		res.GitRef = ref
-		err = result.Scan(&res.PkgName, &res.Name, &res.SubBenchmarkName, &res.Result.Ops, &res.Result.NSPerOp, &res.Result.BytesPerOp,
+		err = result.Scan(&detailsID, &res.PkgName, &res.Name, &res.SubBenchmarkName, &res.Procs, &res.GOOS, &res.GOARCH, &res.CPU, &res.Result.Ops, &res.Result.NSPerOp, &res.Result.BytesPerOp,
 			&res.Result.AllocsPerOp, &res.Result.MBPerSec)
 		if err != nil {
 			return nil, err
 		}
 		mrs = append(mrs, res)
+		detailsIDs = append(detailsIDs, detailsID)
+	}
+
+	metrics, err := readMetrics(client, "select mm.microbenchmark_details_id, mm.unit, mm.value FROM execution e, microbenchmark m, microbenchmark_details md, microbenchmark_metrics mm"+
+		" where m.git_ref = ? and md.microbenchmark_no = m.microbenchmark_no and mm.microbenchmark_details_id = md.id and e.uuid = m.exec_uuid and e.status = \"finished\"", ref)
+	if err != nil {
+		return nil, err
 	}
+	mrs.attachMetrics(detailsIDs, metrics)
 	return mrs, nil
 }
 
-// GetLatestResultsFor will fetch and return a DetailsArray
-// containing all the Details linked to latest runs of the given benchmark name.
-func GetLatestResultsFor(name, subBenchmarkName string, count int, client storage.SQLClient) (mrs DetailsArray, err error) {
-	query := "select m.pkg_name, m.name, md.name, m.git_ref , md.n, md.ns_per_op, md.bytes_per_op," +
-		" md.allocs_per_op, md.mb_per_sec, m.started_at  from (select microbenchmark_no, pkg_name, name, microbenchmark.git_ref, started_at" +
-		" from microbenchmark join execution on exec_uuid = uuid where name = ? and source = \"cron\" and status = \"finished\" order by started_at desc limit ?) m, " +
-		"microbenchmark_details md where md.microbenchmark_no = m.microbenchmark_no and md.name = ?"
-	rows, err := client.Read(query, name, count, subBenchmarkName)
+// GetHistoryResults returns the results of the finished cron runs of a microbenchmark
+// selected by filter, for every sub-benchmark, sorted by date.
+func GetHistoryResults(client storage.SQLClient, filter HistoryFilter) (mrs DetailsArray, err error) {
+	where := "m.pkg_name = ? and m.name = ? and e.source = \"cron\" and e.status = \"finished\""
+	args := []interface{}{filter.PkgName, filter.Name}
+	if filter.Since != nil {
+		where += " and e.started_at >= ?"
+		args = append(args, *filter.Since)
+	}
+	if filter.Until != nil {
+		where += " and e.started_at < ?"
+		args = append(args, *filter.Until)
+	}
+	args = append(args, filter.limit())
+
+	query := "select md.id, m.pkg_name, m.name, md.name, md.procs, m.goos, m.goarch, m.cpu, m.git_ref, md.n, md.ns_per_op, md.bytes_per_op," +
+		" md.allocs_per_op, md.mb_per_sec, m.started_at from (select m.microbenchmark_no, m.pkg_name, m.name, m.git_ref, m.goos, m.goarch, m.cpu, e.started_at" +
+		" from microbenchmark m join execution e on m.exec_uuid = e.uuid where " + where + " order by e.started_at desc, m.microbenchmark_no desc limit ?) m," +
+		" microbenchmark_details md where md.microbenchmark_no = m.microbenchmark_no order by m.started_at, m.microbenchmark_no, md.id"
+	rows, err := client.Read(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	var detailsIDs []int64
 	for rows.Next() {
 		var res Details
-		err = rows.Scan(&res.PkgName, &res.Name, &res.SubBenchmarkName, &res.GitRef, &res.Result.Ops, &res.Result.NSPerOp, &res.Result.BytesPerOp,
+		var detailsID int64
+		err = rows.Scan(&detailsID, &res.PkgName, &res.Name, &res.SubBenchmarkName, &res.Procs, &res.GOOS, &res.GOARCH, &res.CPU, &res.GitRef, &res.Result.Ops, &res.Result.NSPerOp, &res.Result.BytesPerOp,
 			&res.Result.AllocsPerOp, &res.Result.MBPerSec, &res.StartedAt)
 		if err != nil {
 			return nil, err
 		}
 		mrs = append(mrs, res)
+		detailsIDs = append(detailsIDs, detailsID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(detailsIDs) == 0 {
+		return mrs, nil
+	}
+
+	args = make([]interface{}, 0, len(detailsIDs))
+	for _, id := range detailsIDs {
+		args = append(args, id)
 	}
+	metrics, err := readMetrics(client, "select microbenchmark_details_id, unit, value from microbenchmark_metrics where microbenchmark_details_id in ("+
+		strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	mrs.attachMetrics(detailsIDs, metrics)
 	return mrs, nil
 }
 
+// readMetrics returns the metrics of the rows of microbenchmark_metrics selected by
+// the query, whose columns are microbenchmark_details_id, unit and value, by details id.
+func readMetrics(client storage.SQLClient, query string, args ...interface{}) (map[int64]map[string]float64, error) {
+	rows, err := client.Read(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	metrics := map[int64]map[string]float64{}
+	for rows.Next() {
+		var detailsID int64
+		var unit string
+		var value float64
+		if err := rows.Scan(&detailsID, &unit, &value); err != nil {
+			return nil, err
+		}
+		if metrics[detailsID] == nil {
+			metrics[detailsID] = map[string]float64{}
+		}
+		metrics[detailsID][unit] = value
+	}
+	return metrics, rows.Err()
+}
+
+// attachMetrics sets the metrics of each Details, detailsIDs[i] being the id of
+// the details row of mbd[i].
+func (mbd DetailsArray) attachMetrics(detailsIDs []int64, metrics map[int64]map[string]float64) {
+	for i := range mbd {
+		mbd[i].Result.Metrics = metrics[detailsIDs[i]]
+	}
+}
+
+// values returns the value of every unit measured by the benchmark, the number
+// of operations is not a measurement and is left out.
+func (r Result) values() map[string]float64 {
+	values := make(map[string]float64, 4+len(r.Metrics))
+	values[unitNanosecondPerOp] = r.NSPerOp
+	values[unitMBs] = r.MBPerSec
+	values[unitBytesPerOp] = r.BytesPerOp
+	values[unitAllocsPerOp] = r.AllocsPerOp
+	for unit, value := range r.Metrics {
+		values[unit] = value
+	}
+	return values
+}
+
+// Units returns the sorted units of the metrics reported with testing.B.ReportMetric.
+func (r Result) Units() []string {
+	units := make([]string, 0, len(r.Metrics))
+	for unit := range r.Metrics {
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+	return units
+}
+
 func (r Result) OpsStr() string {
 	if r.Ops == 0 {
 		return "N/A"
@@ -103,6 +103,27 @@ func TestMicroBenchmarkResults_ReduceSimpleMedianByName(t *testing.T) {
 			// want bench 1 from pkg2
 			*NewDetails(*NewBenchmarkId("pkg2", ", ", "bench1"), "", "", *NewResult(0, 2.50, 0, 0, 0)),
 		}},
+
+		// tc5
+		{name: "Values with custom metrics", mbd: DetailsArray{
+			*NewDetails(*NewBenchmarkId("pkg1", "bench1", "bench1-pkg1"), "", "", Result{NSPerOp: 1, Metrics: map[string]float64{"rows/op": 10, "plans/s": 1}}),
+			*NewDetails(*NewBenchmarkId("pkg1", "bench1", "bench1-pkg1"), "", "", Result{NSPerOp: 2, Metrics: map[string]float64{"rows/op": 30}}),
+			*NewDetails(*NewBenchmarkId("pkg1", "bench1", "bench1-pkg1"), "", "", Result{NSPerOp: 3, Metrics: map[string]float64{"rows/op": 20, "plans/s": 3}}),
+		}, want: DetailsArray{
+			*NewDetails(*NewBenchmarkId("pkg1", "bench1", "bench1-pkg1"), "", "", Result{NSPerOp: 2, Metrics: map[string]float64{"rows/op": 20, "plans/s": 2}}),
+		}},
+
+		// tc6
+		{name: "Values with different GOMAXPROCS and CPUs", mbd: DetailsArray{
+			*NewDetails(BenchmarkId{PkgName: "pkg1", Name: "bench1", SubBenchmarkName: "bench1", Procs: 8, CPU: "cpu2"}, "", "", Result{NSPerOp: 1}),
+			*NewDetails(BenchmarkId{PkgName: "pkg1", Name: "bench1", SubBenchmarkName: "bench1", Procs: 16, CPU: "cpu1"}, "", "", Result{NSPerOp: 2}),
+			*NewDetails(BenchmarkId{PkgName: "pkg1", Name: "bench1", SubBenchmarkName: "bench1", Procs: 8, CPU: "cpu1"}, "", "", Result{NSPerOp: 3}),
+			*NewDetails(BenchmarkId{PkgName: "pkg1", Name: "bench1", SubBenchmarkName: "bench1", Procs: 8, CPU: "cpu1"}, "", "", Result{NSPerOp: 5}),
+		}, want: DetailsArray{
+			*NewDetails(BenchmarkId{PkgName: "pkg1", Name: "bench1", SubBenchmarkName: "bench1", Procs: 8, CPU: "cpu1"}, "", "", *NewResult(0, 4, 0, 0, 0)),
+			*NewDetails(BenchmarkId{PkgName: "pkg1", Name: "bench1", SubBenchmarkName: "bench1", Procs: 8, CPU: "cpu2"}, "", "", *NewResult(0, 1, 0, 0, 0)),
+			*NewDetails(BenchmarkId{PkgName: "pkg1", Name: "bench1", SubBenchmarkName: "bench1", Procs: 16, CPU: "cpu1"}, "", "", *NewResult(0, 2, 0, 0, 0)),
+		}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -296,6 +317,23 @@ func TestMergeMicroBenchmarkDetails(t *testing.T) {
 			{BenchmarkId: BenchmarkId{PkgName: "pkg2", Name: "bench1", SubBenchmarkName: "bench1-pkg2"}, Right: *NewResult(0, 5.00, 0, 0, 0), Left: *NewResult(0, 4.20, 0, 0, 0), Diff: Result{NSPerOp: -15.999999999999998}},
 			{BenchmarkId: BenchmarkId{PkgName: "pkg3", Name: "bench1", SubBenchmarkName: "bench1-pkg3"}, Right: *NewResult(0, 2385.00, 0, 0, 0), Left: *NewResult(0, 0.00, 0, 0, 0), Diff: Result{NSPerOp: -0}},
 		}},
+
+		// tc6
+		{name: "Compare throughput and custom metrics", args: args{
+			currentMbd: DetailsArray{
+				*NewDetails(*NewBenchmarkId("pkg1", "bench1", "bench1-pkg1"), "", "", Result{MBPerSec: 200, Metrics: map[string]float64{"rows/op": 10, "plans/s": 50, "new/op": 1}}),
+			},
+			lastReleaseMbd: DetailsArray{
+				*NewDetails(*NewBenchmarkId("pkg1", "bench1", "bench1-pkg1"), "", "", Result{MBPerSec: 100, Metrics: map[string]float64{"rows/op": 20, "plans/s": 100, "old/op": 1}}),
+			},
+		}, want: ComparisonArray{
+			{
+				BenchmarkId: BenchmarkId{PkgName: "pkg1", Name: "bench1", SubBenchmarkName: "bench1-pkg1"},
+				Right:       Result{MBPerSec: 200, Metrics: map[string]float64{"rows/op": 10, "plans/s": 50, "new/op": 1}},
+				Left:        Result{MBPerSec: 100, Metrics: map[string]float64{"rows/op": 20, "plans/s": 100, "old/op": 1}},
+				Diff:        Result{MBPerSec: 50, Metrics: map[string]float64{"rows/op": 100, "plans/s": -100}},
+			},
+		}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -330,3 +368,83 @@ func TestHumanReadableStrings(t *testing.T) {
 	c.Assert(r.NSPerOpStr(), qt.Equals, "2.5")
 	c.Assert(r.NSPerOpToDurationStr(), qt.Equals, "2.00 ns")
 }
+
+func TestHigherIsBetter(t *testing.T) {
+	tests := []struct {
+		unit string
+		want bool
+	}{
+		{unit: "ns/op", want: false},
+		{unit: "B/op", want: false},
+		{unit: "allocs/op", want: false},
+		{unit: "rows/op", want: false},
+		{unit: "MB/s", want: true},
+		{unit: "plans/s", want: true},
+		{unit: "hit-ratio", want: true},
+		{unit: "misses/s", want: false},
+	}
+	setHigherIsBetter("misses/s", false)
+	t.Cleanup(func() {
+		unitDirectionsMu.Lock()
+		delete(unitDirections, "misses/s")
+		unitDirectionsMu.Unlock()
+	})
+	for _, tt := range tests {
+		t.Run(tt.unit, func(t *testing.T) {
+			c := qt.New(t)
+			c.Assert(HigherIsBetter(tt.unit), qt.Equals, tt.want)
+		})
+	}
+}
+
+func TestDetailsArray_attachMetrics(t *testing.T) {
+	c := qt.New(t)
+	mbd := DetailsArray{
+		*NewDetails(*NewBenchmarkId("pkg1", "bench1", "BenchmarkA"), "", "", Result{}),
+		*NewDetails(*NewBenchmarkId("pkg1", "bench1", "BenchmarkB"), "", "", Result{}),
+		*NewDetails(*NewBenchmarkId("pkg1", "bench1", "BenchmarkA"), "", "", Result{}),
+		*NewDetails(*NewBenchmarkId("pkg1", "bench1", "BenchmarkA"), "", "", Result{}),
+	}
+	metrics := map[int64]map[string]float64{
+		10: {"rows/op": 1},
+		12: {"rows/op": 2},
+		20: {"rows/op": 3},
+	}
+	mbd.attachMetrics([]int64{10, 11, 12, 20}, metrics)
+
+	c.Assert(mbd[0].Result.Metrics, qt.DeepEquals, map[string]float64{"rows/op": 1})
+	c.Assert(mbd[1].Result.Metrics, qt.IsNil)
+	c.Assert(mbd[2].Result.Metrics, qt.DeepEquals, map[string]float64{"rows/op": 2})
+	c.Assert(mbd[3].Result.Metrics, qt.DeepEquals, map[string]float64{"rows/op": 3})
+	c.Assert(Result{Metrics: map[string]float64{"b/op": 1, "a/op": 2}}.Units(), qt.DeepEquals, []string{"a/op", "b/op"})
+}
+
+func TestBenchmarkId_FullName(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(BenchmarkId{SubBenchmarkName: "BenchmarkA/rows=10", Procs: 16}.FullName(), qt.Equals, "BenchmarkA/rows=10-16")
+	c.Assert(BenchmarkId{SubBenchmarkName: "BenchmarkA/rows=10", Procs: 1}.FullName(), qt.Equals, "BenchmarkA/rows=10")
+	c.Assert(BenchmarkId{SubBenchmarkName: "BenchmarkA-8"}.FullName(), qt.Equals, "BenchmarkA-8")
+}
+
+func Test_matchUnknownConfigurations(t *testing.T) {
+	c := qt.New(t)
+	known := BenchmarkId{PkgName: "pkg", Name: "BenchmarkA", SubBenchmarkName: "BenchmarkA/rows=10", Procs: 8, GOOS: "linux", GOARCH: "amd64", CPU: "AMD EPYC 7B13"}
+	single := BenchmarkId{PkgName: "pkg", Name: "BenchmarkB", SubBenchmarkName: "BenchmarkB", Procs: 1, GOOS: "linux", GOARCH: "amd64", CPU: "AMD EPYC 7B13"}
+	ambiguous := BenchmarkId{PkgName: "pkg", Name: "BenchmarkC", SubBenchmarkName: "BenchmarkC", Procs: 4, GOOS: "linux", GOARCH: "amd64", CPU: "first"}
+	ambiguousCPU := ambiguous
+	ambiguousCPU.CPU = "second"
+
+	old := DetailsArray{
+		{BenchmarkId: BenchmarkId{PkgName: "pkg", Name: "BenchmarkA", SubBenchmarkName: "BenchmarkA/rows=10-8"}},
+		{BenchmarkId: BenchmarkId{PkgName: "pkg", Name: "BenchmarkB", SubBenchmarkName: "BenchmarkB"}},
+		{BenchmarkId: BenchmarkId{PkgName: "pkg", Name: "BenchmarkC", SubBenchmarkName: "BenchmarkC-4"}},
+		// the suffix does not match the GOMAXPROCS of the newer results
+		{BenchmarkId: BenchmarkId{PkgName: "pkg", Name: "BenchmarkA", SubBenchmarkName: "BenchmarkA/rows=10-4"}},
+	}
+	matchUnknownConfigurations(old, DetailsArray{{BenchmarkId: known}, {BenchmarkId: single}, {BenchmarkId: ambiguous}, {BenchmarkId: ambiguousCPU}})
+
+	c.Assert(old[0].BenchmarkId, qt.Equals, known)
+	c.Assert(old[1].BenchmarkId, qt.Equals, single)
+	c.Assert(old[2].BenchmarkId, qt.Equals, BenchmarkId{PkgName: "pkg", Name: "BenchmarkC", SubBenchmarkName: "BenchmarkC-4"})
+	c.Assert(old[3].BenchmarkId, qt.Equals, BenchmarkId{PkgName: "pkg", Name: "BenchmarkA", SubBenchmarkName: "BenchmarkA/rows=10-4"})
+}
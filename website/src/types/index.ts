@@ -27,6 +27,7 @@ export type statusDataTypes = {
   workload: string;
   pull_nb?: number;
   golang_version: string;
+  microbench_packages?: string[];
   status: string;
 };
 